@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -12,13 +13,13 @@ var applyCmd = &cobra.Command{
 	Use:   "apply",
 	Short: "Apply configuration",
 	Run: func(cmd *cobra.Command, args []string) {
-		engine, err := core.NewEngine()
+		engine, err := core.NewEngine(core.Options{Lock: true})
 		if err != nil {
 			fmt.Printf("Failed to initialize engine: %v\n", err)
 			os.Exit(1)
 		}
 
-		if err := engine.Apply("main.tf"); err != nil {
+		if err := engine.Apply(context.Background(), "main.tf", core.ApplyOptions{}); err != nil {
 			fmt.Printf("Apply failed: %v\n", err)
 			os.Exit(1)
 		}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/Artemka007/derraform/internal/cli"
@@ -9,7 +8,8 @@ import (
 
 func main() {
 	if err := cli.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		// Ошибки провайдера могут содержать значения из конфигурации, cli.PrintError их скрывает
+		cli.PrintError(err)
 		os.Exit(1)
 	}
 }
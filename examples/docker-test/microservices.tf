@@ -27,6 +27,17 @@ resource "docker_container" "database" {
     internal = 5432
     external = 5432
   }
+
+  healthcheck {
+    test         = ["CMD-SHELL", "pg_isready -U admin -d myapp"]
+    interval     = "5s"
+    timeout      = "3s"
+    start_period = "10s"
+    retries      = 5
+  }
+
+  wait         = true
+  wait_timeout = "2m"
 }
 
 resource "docker_container" "api" {
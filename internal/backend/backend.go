@@ -0,0 +1,216 @@
+// Package backend создает хранилища state по блоку terraform { backend "..." {} }
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+const (
+	// DataDir рабочий каталог derraform в проекте
+	DataDir = ".terraform"
+	// savedConfigFile настройки бэкенда на момент последнего init
+	savedConfigFile = "backend.json"
+)
+
+// definition схема атрибутов бэкенда и функция его создания
+type definition struct {
+	schema providers.Schema
+	// factory создает бэкенд state указанного workspace
+	factory func(attributes map[string]cty.Value, workspace string) (state.Backend, error)
+}
+
+var backends = map[string]definition{
+	"local": {schema: localSchema, factory: newLocal},
+	"http":  {schema: httpSchema, factory: newHTTP},
+	"s3":    {schema: s3Schema, factory: newS3},
+}
+
+// New создает бэкенд state workspace по конфигурации. nil - локальный terraform.tfstate.
+func New(cfg *config.Backend, workspace string) (state.Backend, error) {
+	cfg = normalize(cfg)
+	if workspace == "" {
+		workspace = config.DefaultWorkspace
+	}
+	if err := ValidateWorkspaceName(workspace); err != nil {
+		return nil, err
+	}
+
+	def, exists := backends[cfg.Type]
+	if !exists {
+		return nil, fmt.Errorf("unknown backend type %q (supported: %s)", cfg.Type, supportedTypes())
+	}
+
+	// Атрибуты бэкенда проверяются так же, как атрибуты блока provider
+	if err := providers.ValidateProvider(def.schema, config.Provider{Name: cfg.Type, Attributes: cfg.Attributes}); err != nil {
+		return nil, fmt.Errorf("invalid backend %s: %w", cfg.Type, err)
+	}
+
+	backend, err := def.factory(cfg.Attributes, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backend %s: %w", cfg.Type, err)
+	}
+	return backend, nil
+}
+
+func supportedTypes() string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func normalize(cfg *config.Backend) *config.Backend {
+	if cfg == nil {
+		return &config.Backend{Type: "local", Attributes: map[string]cty.Value{}}
+	}
+	return cfg
+}
+
+// Equal сравнивает настройки двух бэкендов
+func Equal(a, b *config.Backend) bool {
+	keyA, errA := configKey(normalize(a))
+	keyB, errB := configKey(normalize(b))
+	return errA == nil && errB == nil && keyA == keyB
+}
+
+func configKey(cfg *config.Backend) (string, error) {
+	encoded, err := encodeAttributes(cfg.Attributes)
+	if err != nil {
+		return "", err
+	}
+	return cfg.Type + ":" + string(encoded), nil
+}
+
+// savedConfig содержимое .terraform/backend.json
+type savedConfig struct {
+	Type   string          `json:"type"`
+	Config json.RawMessage `json:"config"`
+}
+
+// LoadSaved возвращает настройки бэкенда, сохраненные последним init, или nil
+func LoadSaved() (*config.Backend, error) {
+	data, err := os.ReadFile(filepath.Join(DataDir, savedConfigFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var saved savedConfig
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", savedConfigFile, err)
+	}
+
+	var value ctyjson.SimpleJSONValue
+	if err := value.UnmarshalJSON(saved.Config); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", savedConfigFile, err)
+	}
+
+	attributes := make(map[string]cty.Value)
+	if !value.IsNull() && value.Type().IsObjectType() {
+		attributes = value.AsValueMap()
+	}
+	return &config.Backend{Type: saved.Type, Attributes: attributes}, nil
+}
+
+// Save запоминает настройки бэкенда для следующих запусков
+func Save(cfg *config.Backend) error {
+	cfg = normalize(cfg)
+
+	encoded, err := encodeAttributes(cfg.Attributes)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(savedConfig{Type: cfg.Type, Config: encoded}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(DataDir, 0755); err != nil {
+		return err
+	}
+	// Настройки могут содержать ключи доступа
+	return os.WriteFile(filepath.Join(DataDir, savedConfigFile), data, 0600)
+}
+
+func encodeAttributes(attributes map[string]cty.Value) ([]byte, error) {
+	if len(attributes) == 0 {
+		return []byte("{}"), nil
+	}
+	return ctyjson.SimpleJSONValue{Value: cty.ObjectVal(attributes)}.MarshalJSON()
+}
+
+// defaultWorkspaceDir каталог со state workspace, кроме default
+const defaultWorkspaceDir = "terraform.tfstate.d"
+
+var localSchema = providers.Schema{
+	Attributes: map[string]*providers.Attribute{
+		"path":          {Type: cty.String, Optional: true, Description: "State file path of the default workspace, terraform.tfstate by default"},
+		"workspace_dir": {Type: cty.String, Optional: true, Description: "Directory with the states of other workspaces, terraform.tfstate.d by default"},
+	},
+}
+
+// localBackend локальный файл state. State workspace, кроме default,
+// хранятся в <workspace_dir>/<name>/terraform.tfstate.
+type localBackend struct {
+	*state.LocalBackend
+	workspaceDir string
+}
+
+func newLocal(attributes map[string]cty.Value, workspace string) (state.Backend, error) {
+	path, err := providers.StringAttribute(attributes, "path")
+	if err != nil {
+		return nil, err
+	}
+	workspaceDir, err := providers.StringAttribute(attributes, "workspace_dir")
+	if err != nil {
+		return nil, err
+	}
+	if workspaceDir == "" {
+		workspaceDir = defaultWorkspaceDir
+	}
+
+	if workspace != config.DefaultWorkspace {
+		path = filepath.Join(workspaceDir, workspace, state.DefaultStateFile)
+	}
+	return &localBackend{LocalBackend: state.NewLocalBackend(path), workspaceDir: workspaceDir}, nil
+}
+
+func (b *localBackend) Workspaces(ctx context.Context) ([]string, error) {
+	names := []string{config.DefaultWorkspace}
+
+	entries, err := os.ReadDir(b.workspaceDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && ValidateWorkspaceName(entry.Name()) == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names[1:])
+	return names, nil
+}
+
+func (b *localBackend) DeleteWorkspace(ctx context.Context, name string) error {
+	if name == config.DefaultWorkspace {
+		return fmt.Errorf("the default workspace cannot be deleted")
+	}
+	return os.RemoveAll(filepath.Join(b.workspaceDir, name))
+}
@@ -0,0 +1,118 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// keySchema атрибуты блоков key и fallback. Задается ровно один способ получить ключ.
+var keySchema = providers.Schema{
+	Attributes: map[string]*providers.Attribute{
+		"passphrase":     {Type: cty.String, Optional: true, Sensitive: true, Description: "Passphrase the key is derived from with PBKDF2"},
+		"passphrase_env": {Type: cty.String, Optional: true, Description: "Environment variable holding the passphrase"},
+		"key_file":       {Type: cty.String, Optional: true, Description: "File with a 32-byte key: raw, hex or base64"},
+		"unencrypted":    {Type: cty.Bool, Optional: true, Description: "Read or write the state without encryption"},
+	},
+}
+
+// NewEncryption создает шифрование state по блоку encryption. nil - без шифрования.
+func NewEncryption(cfg *config.Encryption) (*state.Encryption, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	primary, err := newKey(cfg.Key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key block: %w", err)
+	}
+	fallbacks := make([]*state.Key, 0, len(cfg.Fallbacks))
+	for i, block := range cfg.Fallbacks {
+		key, err := newKey(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fallback block #%d: %w", i+1, err)
+		}
+		fallbacks = append(fallbacks, key)
+	}
+	return state.NewEncryption(primary, fallbacks...), nil
+}
+
+func newKey(block config.Block) (*state.Key, error) {
+	if err := providers.ValidateProvider(keySchema, config.Provider{Name: block.Type, Attributes: block.Attributes, Blocks: block.Blocks}); err != nil {
+		return nil, err
+	}
+
+	passphrase, err := providers.StringAttribute(block.Attributes, "passphrase")
+	if err != nil {
+		return nil, err
+	}
+	passphraseEnv, err := providers.StringAttribute(block.Attributes, "passphrase_env")
+	if err != nil {
+		return nil, err
+	}
+	keyFile, err := providers.StringAttribute(block.Attributes, "key_file")
+	if err != nil {
+		return nil, err
+	}
+	unencrypted, err := providers.BoolAttribute(block.Attributes, "unencrypted")
+	if err != nil {
+		return nil, err
+	}
+
+	sources := 0
+	for _, set := range []bool{passphrase != "", passphraseEnv != "", keyFile != "", unencrypted} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, fmt.Errorf("exactly one of passphrase, passphrase_env, key_file or unencrypted must be set")
+	}
+
+	switch {
+	case passphrase != "":
+		return state.NewPassphraseKey("passphrase", passphrase)
+	case passphraseEnv != "":
+		value := os.Getenv(passphraseEnv)
+		if value == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", passphraseEnv)
+		}
+		return state.NewPassphraseKey("passphrase from "+passphraseEnv, value)
+	case keyFile != "":
+		key, err := readKeyFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		return state.NewRawKey("key file "+keyFile, key)
+	default:
+		return state.NewUnencryptedKey(), nil
+	}
+}
+
+// readKeyFile читает 32-байтный ключ: как есть, в hex или base64
+// (например, из head -c 32 /dev/urandom | base64)
+func readKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	if len(data) == 32 {
+		return data, nil
+	}
+
+	text := string(bytes.TrimSpace(data))
+	if key, err := hex.DecodeString(text); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, fmt.Errorf("key file %s must contain a 32-byte key: raw, hex or base64", path)
+}
@@ -0,0 +1,327 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var httpSchema = providers.Schema{
+	Attributes: map[string]*providers.Attribute{
+		"address":                {Type: cty.String, Required: true, Description: "URL for GET and update requests"},
+		"update_method":          {Type: cty.String, Optional: true, Description: "HTTP method for writing state, POST by default"},
+		"lock_address":           {Type: cty.String, Optional: true, Description: "URL for lock requests, locking is disabled when empty"},
+		"lock_method":            {Type: cty.String, Optional: true, Description: "HTTP method for locking, LOCK by default"},
+		"unlock_address":         {Type: cty.String, Optional: true},
+		"unlock_method":          {Type: cty.String, Optional: true, Description: "HTTP method for unlocking, UNLOCK by default"},
+		"username":               {Type: cty.String, Optional: true},
+		"password":               {Type: cty.String, Optional: true, Sensitive: true},
+		"skip_cert_verification": {Type: cty.Bool, Optional: true},
+	},
+}
+
+// httpBackend хранит state на HTTP сервере, совместим с http backend Terraform:
+// GET читает state, POST записывает, LOCK/UNLOCK управляют блокировкой.
+// Занятая блокировка - ответ 423 Locked или 409 Conflict с LockInfo в теле.
+// State workspace, кроме default, адресуется параметром ?workspace=<name>,
+// а удаляется запросом DELETE. Перечислить workspace протокол не позволяет.
+type httpBackend struct {
+	// baseAddress адрес state workspace default
+	baseAddress   string
+	address       string
+	updateMethod  string
+	lockAddress   string
+	lockMethod    string
+	unlockAddress string
+	unlockMethod  string
+	username      string
+	password      string
+
+	client *http.Client
+
+	mu sync.Mutex
+	// locks взятые этим процессом блокировки, нужны для UNLOCK и ?ID= при записи
+	locks map[string]state.LockInfo
+}
+
+func newHTTP(attributes map[string]cty.Value, workspace string) (state.Backend, error) {
+	b := &httpBackend{locks: make(map[string]state.LockInfo)}
+
+	var err error
+	if b.address, err = providers.StringAttribute(attributes, "address"); err != nil {
+		return nil, err
+	}
+	if b.updateMethod, err = providers.StringAttribute(attributes, "update_method"); err != nil {
+		return nil, err
+	}
+	if b.lockAddress, err = providers.StringAttribute(attributes, "lock_address"); err != nil {
+		return nil, err
+	}
+	if b.lockMethod, err = providers.StringAttribute(attributes, "lock_method"); err != nil {
+		return nil, err
+	}
+	if b.unlockAddress, err = providers.StringAttribute(attributes, "unlock_address"); err != nil {
+		return nil, err
+	}
+	if b.unlockMethod, err = providers.StringAttribute(attributes, "unlock_method"); err != nil {
+		return nil, err
+	}
+	if b.username, err = providers.StringAttribute(attributes, "username"); err != nil {
+		return nil, err
+	}
+	if b.password, err = providers.StringAttribute(attributes, "password"); err != nil {
+		return nil, err
+	}
+	skipVerify, err := providers.BoolAttribute(attributes, "skip_cert_verification")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, address := range []string{b.address, b.lockAddress, b.unlockAddress} {
+		if address == "" {
+			continue
+		}
+		if u, err := url.Parse(address); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("invalid address %q: expected an http or https URL", address)
+		}
+	}
+
+	if b.updateMethod == "" {
+		b.updateMethod = http.MethodPost
+	}
+	if b.lockMethod == "" {
+		b.lockMethod = "LOCK"
+	}
+	if b.unlockMethod == "" {
+		b.unlockMethod = "UNLOCK"
+	}
+	if b.unlockAddress == "" {
+		b.unlockAddress = b.lockAddress
+	}
+	b.baseAddress = b.address
+	if workspace != config.DefaultWorkspace {
+		b.address = withQuery(b.address, "workspace", workspace)
+		b.lockAddress = withQuery(b.lockAddress, "workspace", workspace)
+		b.unlockAddress = withQuery(b.unlockAddress, "workspace", workspace)
+	}
+	// Учетные данные можно не хранить в конфигурации
+	if b.username == "" {
+		b.username = os.Getenv("TF_HTTP_USERNAME")
+	}
+	if b.password == "" {
+		b.password = os.Getenv("TF_HTTP_PASSWORD")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if skipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	b.client = &http.Client{Transport: transport, Timeout: 60 * time.Second}
+
+	return b, nil
+}
+
+func (b *httpBackend) String() string {
+	return b.address
+}
+
+func (b *httpBackend) Read(ctx context.Context) ([]byte, error) {
+	resp, body, err := b.do(ctx, http.MethodGet, b.address, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if len(body) == 0 {
+			return nil, nil
+		}
+		return body, nil
+	case http.StatusNoContent, http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, unexpectedStatus("read state", resp, body)
+	}
+}
+
+func (b *httpBackend) Write(ctx context.Context, data []byte) error {
+	address := b.address
+
+	// Как и Terraform, передаем ID блокировки, чтобы сервер мог проверить держателя
+	b.mu.Lock()
+	for id := range b.locks {
+		address = withQuery(address, "ID", id)
+	}
+	b.mu.Unlock()
+
+	resp, body, err := b.do(ctx, b.updateMethod, address, data)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	default:
+		return unexpectedStatus("write state", resp, body)
+	}
+}
+
+func (b *httpBackend) Lock(ctx context.Context, info state.LockInfo) (*state.LockInfo, error) {
+	if b.lockAddress == "" {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, body, err := b.do(ctx, b.lockMethod, b.lockAddress, data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		b.mu.Lock()
+		b.locks[info.ID] = info
+		b.mu.Unlock()
+		return nil, nil
+	case http.StatusLocked, http.StatusConflict:
+		lockErr := &state.LockError{Err: fmt.Errorf("%s", resp.Status)}
+		var held state.LockInfo
+		if json.Unmarshal(body, &held) == nil && held.ID != "" {
+			lockErr.Info = &held
+		}
+		return nil, lockErr
+	default:
+		return nil, unexpectedStatus("lock state", resp, body)
+	}
+}
+
+func (b *httpBackend) Unlock(ctx context.Context, id string) error {
+	if b.unlockAddress == "" {
+		return nil
+	}
+
+	b.mu.Lock()
+	info, held := b.locks[id]
+	delete(b.locks, id)
+	b.mu.Unlock()
+	if !held {
+		// force-unlock чужой блокировки: известен только ID
+		info = state.LockInfo{ID: id}
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	resp, body, err := b.do(ctx, b.unlockMethod, b.unlockAddress, data)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return unexpectedStatus("unlock state", resp, body)
+	}
+	return nil
+}
+
+func (b *httpBackend) Workspaces(ctx context.Context) ([]string, error) {
+	return nil, fmt.Errorf("http backend: %w", state.ErrWorkspacesNotListable)
+}
+
+func (b *httpBackend) DeleteWorkspace(ctx context.Context, name string) error {
+	if name == config.DefaultWorkspace {
+		return fmt.Errorf("the default workspace cannot be deleted")
+	}
+
+	resp, body, err := b.do(ctx, http.MethodDelete, withQuery(b.baseAddress, "workspace", name), nil)
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return unexpectedStatus("delete state", resp, body)
+	}
+}
+
+// withQuery добавляет параметр к адресу. Адреса проверены при создании бэкенда.
+func withQuery(address, name, value string) string {
+	if address == "" {
+		return ""
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return address
+	}
+	query := u.Query()
+	query.Set(name, value)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// do выполняет запрос и читает тело ответа целиком
+func (b *httpBackend) do(ctx context.Context, method, address string, data []byte) (*http.Response, []byte, error) {
+	var reader io.Reader
+	if data != nil {
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, address, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if data != nil {
+		sum := md5.Sum(data)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	}
+	if b.username != "" {
+		req.SetBasicAuth(b.username, b.password)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s %s: %w", method, address, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s %s: failed to read response: %w", method, address, err)
+	}
+	return resp, body, nil
+}
+
+// unexpectedStatus ошибка с кодом ответа и началом тела для диагностики
+func unexpectedStatus(operation string, resp *http.Response, body []byte) error {
+	const maxBody = 512
+	if len(body) > maxBody {
+		body = body[:maxBody]
+	}
+	message := fmt.Sprintf("failed to %s: %s %s returned %s", operation, resp.Request.Method, resp.Request.URL.Redacted(), resp.Status)
+	if len(bytes.TrimSpace(body)) > 0 {
+		message += ": " + string(bytes.TrimSpace(body))
+	}
+	return fmt.Errorf("%s", message)
+}
@@ -0,0 +1,220 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// fakeHTTPServer сервер http backend: state по workspace и одна блокировка на workspace
+type fakeHTTPServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	states map[string][]byte
+	locks  map[string]state.LockInfo
+	// writes адреса запросов записи state с query
+	writes []string
+}
+
+func newFakeHTTPServer(t *testing.T) *fakeHTTPServer {
+	t.Helper()
+	f := &fakeHTTPServer{states: make(map[string][]byte), locks: make(map[string]state.LockInfo)}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeHTTPServer) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	workspace := r.URL.Query().Get("workspace")
+	body, _ := io.ReadAll(r.Body)
+
+	switch r.Method {
+	case http.MethodGet:
+		data, exists := f.states[workspace]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodPost:
+		f.writes = append(f.writes, r.URL.RequestURI())
+		if held, locked := f.locks[workspace]; locked && r.URL.Query().Get("ID") != held.ID {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.states[workspace] = body
+	case http.MethodDelete:
+		delete(f.states, workspace)
+	case "LOCK":
+		if held, locked := f.locks[workspace]; locked {
+			w.WriteHeader(http.StatusLocked)
+			json.NewEncoder(w).Encode(held)
+			return
+		}
+		var info state.LockInfo
+		if err := json.Unmarshal(body, &info); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.locks[workspace] = info
+	case "UNLOCK":
+		var info state.LockInfo
+		json.Unmarshal(body, &info)
+		held, locked := f.locks[workspace]
+		if !locked {
+			return
+		}
+		if held.ID != info.ID {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(held)
+			return
+		}
+		delete(f.locks, workspace)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// newHTTPBackend создает http backend workspace с блокировкой на том же адресе
+func newHTTPBackend(t *testing.T, server *fakeHTTPServer, workspace string) state.Backend {
+	t.Helper()
+	backend, err := New(&config.Backend{Type: "http", Attributes: map[string]cty.Value{
+		"address":      cty.StringVal(server.URL + "/state"),
+		"lock_address": cty.StringVal(server.URL + "/state"),
+	}}, workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backend
+}
+
+func TestHTTPBackendLock(t *testing.T) {
+	server := newFakeHTTPServer(t)
+	ctx := context.Background()
+	holder := state.NewStateManager(newHTTPBackend(t, server, config.DefaultWorkspace), nil)
+	other := state.NewStateManager(newHTTPBackend(t, server, config.DefaultWorkspace), nil)
+
+	lock, err := holder.Lock(ctx, "apply", 0)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+
+	_, err = other.Lock(ctx, "plan", 0)
+	var lockErr *state.LockError
+	if !errors.As(err, &lockErr) {
+		t.Fatalf("error = %v, want *state.LockError", err)
+	}
+	if lockErr.Info == nil || lockErr.Info.ID != lock.Info.ID || !strings.Contains(err.Error(), "423 Locked") {
+		t.Errorf("error = %v, want the holder %s from the 423 response", err, lock.Info.ID)
+	}
+
+	// Запись передает ID блокировки, чтобы сервер проверил держателя
+	if err := holder.Backend().Write(ctx, []byte(`{"version": 2}`)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if want := "/state?ID=" + lock.Info.ID; server.writes[0] != want {
+		t.Errorf("write request = %s, want %s", server.writes[0], want)
+	}
+	err = other.Backend().Write(ctx, []byte(`{"version": 2}`))
+	if err == nil || !strings.Contains(err.Error(), "failed to write state: POST") || !strings.Contains(err.Error(), "returned 409 Conflict") {
+		t.Errorf("Write without lock = %v, want 409 Conflict", err)
+	}
+
+	if err := other.ForceUnlock("0000"); err == nil || !strings.Contains(err.Error(), "failed to unlock state") {
+		t.Errorf("ForceUnlock with wrong ID = %v, want an error", err)
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	next, err := other.Lock(ctx, "plan", 0)
+	if err != nil {
+		t.Fatalf("Lock after unlock: %v", err)
+	}
+	if err := other.ForceUnlock(next.Info.ID); err != nil {
+		t.Fatalf("ForceUnlock: %v", err)
+	}
+	if len(server.locks) != 0 {
+		t.Errorf("locks = %v, want none after force-unlock", server.locks)
+	}
+}
+
+func TestHTTPBackendLockPerWorkspace(t *testing.T) {
+	server := newFakeHTTPServer(t)
+	ctx := context.Background()
+
+	lock, err := state.NewStateManager(newHTTPBackend(t, server, config.DefaultWorkspace), nil).Lock(ctx, "apply", 0)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	defer lock.Unlock()
+
+	staging, err := state.NewStateManager(newHTTPBackend(t, server, "staging"), nil).Lock(ctx, "apply", 0)
+	if err != nil {
+		t.Fatalf("Lock of another workspace: %v", err)
+	}
+	defer staging.Unlock()
+	if held := server.locks["staging"]; held.ID != staging.Info.ID {
+		t.Errorf("staging lock = %+v, want %s", held, staging.Info.ID)
+	}
+}
+
+func TestHTTPBackendWithoutLocking(t *testing.T) {
+	server := newFakeHTTPServer(t)
+	backend, err := New(&config.Backend{Type: "http", Attributes: map[string]cty.Value{
+		"address": cty.StringVal(server.URL + "/state"),
+	}}, config.DefaultWorkspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager := state.NewStateManager(backend, nil)
+	for i := 0; i < 2; i++ {
+		lock, err := manager.Lock(context.Background(), "apply", 0)
+		if err != nil {
+			t.Fatalf("Lock: %v", err)
+		}
+		if err := lock.Unlock(); err != nil {
+			t.Fatalf("Unlock: %v", err)
+		}
+	}
+}
+
+func TestHTTPBackendLockUnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "lock table is unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	backend, err := New(&config.Backend{Type: "http", Attributes: map[string]cty.Value{
+		"address":      cty.StringVal(server.URL + "/state"),
+		"lock_address": cty.StringVal(server.URL + "/lock"),
+		"lock_method":  cty.StringVal("PUT"),
+	}}, config.DefaultWorkspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = state.NewStateManager(backend, nil).Lock(context.Background(), "apply", 0)
+	want := "PUT " + server.URL + "/lock returned 503 Service Unavailable: lock table is unavailable"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("error = %v, want it to contain %q", err, want)
+	}
+	var lockErr *state.LockError
+	if errors.As(err, &lockErr) {
+		t.Error("server error is reported as a held lock")
+	}
+}
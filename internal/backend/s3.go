@@ -0,0 +1,385 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var s3Schema = providers.Schema{
+	Attributes: map[string]*providers.Attribute{
+		"bucket":               {Type: cty.String, Required: true},
+		"key":                  {Type: cty.String, Required: true, Description: "Object key of the state, e.g. prod/terraform.tfstate"},
+		"region":               {Type: cty.String, Optional: true, Description: "us-east-1 by default or AWS_REGION"},
+		"endpoint":             {Type: cty.String, Optional: true, Description: "Custom endpoint for S3-compatible storage, e.g. http://localhost:9000 for MinIO"},
+		"access_key":           {Type: cty.String, Optional: true, Description: "AWS_ACCESS_KEY_ID by default"},
+		"secret_key":           {Type: cty.String, Optional: true, Sensitive: true, Description: "AWS_SECRET_ACCESS_KEY by default"},
+		"session_token":        {Type: cty.String, Optional: true, Sensitive: true},
+		"use_path_style":       {Type: cty.Bool, Optional: true, Description: "Use http://host/bucket/key URLs, required by MinIO"},
+		"use_lockfile":         {Type: cty.Bool, Optional: true, Description: "Lock state with a <key>.tflock object created by a conditional write"},
+		"workspace_key_prefix": {Type: cty.String, Optional: true, Description: "Prefix of non-default workspace states: <prefix>/<workspace>/<key>, env: by default"},
+	},
+}
+
+// s3Backend хранит state объектом в S3-совместимом хранилище (AWS S3, MinIO).
+// Блокировка - объект <key>.tflock, который создается PUT с If-None-Match: *,
+// поэтому взять его может только один процесс.
+// State workspace, кроме default, хранится под ключом <workspace_key_prefix>/<name>/<key>.
+type s3Backend struct {
+	bucket string
+	// key ключ state текущего workspace, baseKey - workspace default
+	key         string
+	baseKey     string
+	prefix      string
+	region      string
+	endpoint    *url.URL
+	pathStyle   bool
+	useLockfile bool
+	creds       awsCredentials
+
+	client *http.Client
+
+	mu sync.Mutex
+	// locks взятые этим процессом блокировки по ID
+	locks map[string]bool
+}
+
+func newS3(attributes map[string]cty.Value, workspace string) (state.Backend, error) {
+	b := &s3Backend{locks: make(map[string]bool)}
+
+	var err error
+	if b.bucket, err = providers.StringAttribute(attributes, "bucket"); err != nil {
+		return nil, err
+	}
+	if b.key, err = providers.StringAttribute(attributes, "key"); err != nil {
+		return nil, err
+	}
+	if b.region, err = providers.StringAttribute(attributes, "region"); err != nil {
+		return nil, err
+	}
+	endpoint, err := providers.StringAttribute(attributes, "endpoint")
+	if err != nil {
+		return nil, err
+	}
+	if b.creds.AccessKey, err = providers.StringAttribute(attributes, "access_key"); err != nil {
+		return nil, err
+	}
+	if b.creds.SecretKey, err = providers.StringAttribute(attributes, "secret_key"); err != nil {
+		return nil, err
+	}
+	if b.creds.SessionToken, err = providers.StringAttribute(attributes, "session_token"); err != nil {
+		return nil, err
+	}
+	if b.pathStyle, err = providers.BoolAttribute(attributes, "use_path_style"); err != nil {
+		return nil, err
+	}
+	if b.useLockfile, err = providers.BoolAttribute(attributes, "use_lockfile"); err != nil {
+		return nil, err
+	}
+	if b.prefix, err = providers.StringAttribute(attributes, "workspace_key_prefix"); err != nil {
+		return nil, err
+	}
+
+	b.key = strings.TrimPrefix(b.key, "/")
+	if b.bucket == "" || b.key == "" {
+		return nil, fmt.Errorf("'bucket' and 'key' are required")
+	}
+	b.prefix = strings.Trim(b.prefix, "/")
+	if b.prefix == "" {
+		b.prefix = "env:"
+	}
+	b.baseKey = b.key
+	b.key = b.workspaceKey(workspace)
+
+	if b.region == "" {
+		b.region = firstEnv("AWS_REGION", "AWS_DEFAULT_REGION")
+	}
+	if b.region == "" {
+		b.region = "us-east-1"
+	}
+	if b.creds.AccessKey == "" && b.creds.SecretKey == "" {
+		b.creds = awsCredentials{
+			AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+	if b.creds.AccessKey == "" || b.creds.SecretKey == "" {
+		return nil, fmt.Errorf("credentials are not set: use 'access_key' and 'secret_key' or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	if endpoint == "" {
+		endpoint = "https://s3." + b.region + ".amazonaws.com"
+	}
+	if b.endpoint, err = url.Parse(endpoint); err != nil || b.endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q", endpoint)
+	}
+
+	b.client = &http.Client{Timeout: 60 * time.Second}
+	return b, nil
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func (b *s3Backend) String() string {
+	return "s3://" + b.bucket + "/" + b.key
+}
+
+func (b *s3Backend) lockKey() string {
+	return b.key + ".tflock"
+}
+
+// workspaceKey ключ объекта state workspace
+func (b *s3Backend) workspaceKey(workspace string) string {
+	if workspace == config.DefaultWorkspace {
+		return b.baseKey
+	}
+	return b.prefix + "/" + workspace + "/" + b.baseKey
+}
+
+func (b *s3Backend) Read(ctx context.Context) ([]byte, error) {
+	resp, body, err := b.do(ctx, http.MethodGet, b.key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if len(body) == 0 {
+			return nil, nil
+		}
+		return body, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, unexpectedStatus("read state", resp, body)
+	}
+}
+
+func (b *s3Backend) Write(ctx context.Context, data []byte) error {
+	headers := map[string]string{"Content-Type": "application/json"}
+	resp, body, err := b.do(ctx, http.MethodPut, b.key, nil, data, headers)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return unexpectedStatus("write state", resp, body)
+	}
+	return nil
+}
+
+func (b *s3Backend) Lock(ctx context.Context, info state.LockInfo) (*state.LockInfo, error) {
+	if !b.useLockfile {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{"Content-Type": "application/json", "If-None-Match": "*"}
+	resp, body, err := b.do(ctx, http.MethodPut, b.lockKey(), nil, data, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		b.mu.Lock()
+		b.locks[info.ID] = true
+		b.mu.Unlock()
+		return nil, nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		// 412 - объект блокировки уже есть, 409 - параллельная условная запись
+		held, _ := b.LockInfo(ctx)
+		return nil, &state.LockError{Info: held, Err: fmt.Errorf("%s", resp.Status)}
+	default:
+		return nil, unexpectedStatus("lock state", resp, body)
+	}
+}
+
+func (b *s3Backend) Unlock(ctx context.Context, id string) error {
+	if !b.useLockfile {
+		return nil
+	}
+
+	b.mu.Lock()
+	delete(b.locks, id)
+	b.mu.Unlock()
+
+	held, err := b.LockInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if held == nil {
+		return fmt.Errorf("state is not locked")
+	}
+	if held.ID != id {
+		return fmt.Errorf("lock ID %q does not match the existing lock (%s)", id, held)
+	}
+
+	resp, body, err := b.do(ctx, http.MethodDelete, b.lockKey(), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return unexpectedStatus("unlock state", resp, body)
+	}
+	return nil
+}
+
+func (b *s3Backend) LockInfo(ctx context.Context) (*state.LockInfo, error) {
+	resp, body, err := b.do(ctx, http.MethodGet, b.lockKey(), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var info state.LockInfo
+		if err := json.Unmarshal(body, &info); err != nil {
+			return nil, fmt.Errorf("invalid lock object %s: %w", b.lockKey(), err)
+		}
+		return &info, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, unexpectedStatus("read lock", resp, body)
+	}
+}
+
+func (b *s3Backend) Workspaces(ctx context.Context) ([]string, error) {
+	names := []string{config.DefaultWorkspace}
+
+	// Ищем ключи вида <prefix>/<workspace>/<key>, ListObjectsV2 отдает их страницами
+	query := url.Values{"list-type": {"2"}, "prefix": {b.prefix + "/"}}
+	for {
+		resp, body, err := b.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, unexpectedStatus("list workspaces", resp, body)
+		}
+
+		var result struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("invalid ListObjectsV2 response: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			name, key, found := strings.Cut(strings.TrimPrefix(object.Key, b.prefix+"/"), "/")
+			if found && key == b.baseKey && ValidateWorkspaceName(name) == nil {
+				names = append(names, name)
+			}
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+
+	sort.Strings(names[1:])
+	return names, nil
+}
+
+func (b *s3Backend) DeleteWorkspace(ctx context.Context, name string) error {
+	if name == config.DefaultWorkspace {
+		return fmt.Errorf("the default workspace cannot be deleted")
+	}
+
+	key := b.workspaceKey(name)
+	for _, objectKey := range []string{key, key + ".tflock"} {
+		resp, body, err := b.do(ctx, http.MethodDelete, objectKey, nil, nil, nil)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+			return unexpectedStatus("delete state", resp, body)
+		}
+	}
+	return nil
+}
+
+// objectURL адрес объекта в path-style (host/bucket/key) или virtual-hosted (bucket.host/key) виде
+func (b *s3Backend) objectURL(key string) *url.URL {
+	u := *b.endpoint
+	basePath := strings.TrimSuffix(u.Path, "/")
+
+	path := basePath + "/" + key
+	if b.pathStyle {
+		path = basePath + "/" + b.bucket + "/" + key
+	} else {
+		u.Host = b.bucket + "." + u.Host
+	}
+
+	u.Path = path
+	u.RawPath = uriEncode(path, false)
+	u.RawQuery = ""
+	return &u
+}
+
+// do выполняет подписанный запрос к объекту и читает тело ответа целиком.
+// Пустой key с query - запрос к самому бакету.
+func (b *s3Backend) do(ctx context.Context, method, key string, query url.Values, data []byte, headers map[string]string) (*http.Response, []byte, error) {
+	var reader io.Reader
+	if data != nil {
+		reader = bytes.NewReader(data)
+	}
+
+	u := b.objectURL(key)
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	payloadHash := sha256Hex(data)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signV4(req, payloadHash, b.creds, b.region, "s3", time.Now())
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s %s: %w", method, b.String(), err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s %s: failed to read response: %w", method, b.String(), err)
+	}
+	return resp, body, nil
+}
@@ -0,0 +1,197 @@
+package backend
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// fakeS3Server бакет S3 в памяти с path-style адресами и условной записью If-None-Match
+type fakeS3Server struct {
+	*httptest.Server
+	bucket string
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	// pageSize сколько ключей отдавать на странице ListObjectsV2
+	pageSize int
+}
+
+func newFakeS3Server(t *testing.T) *fakeS3Server {
+	t.Helper()
+	f := &fakeS3Server{bucket: "tfstate", objects: make(map[string][]byte), pageSize: 1000}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeS3Server) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") == "" || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, r)
+	case r.Method == http.MethodGet:
+		data, exists := f.objects[key]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case r.Method == http.MethodPut:
+		if _, exists := f.objects[key]; exists && r.Header.Get("If-None-Match") == "*" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		f.objects[key], _ = io.ReadAll(r.Body)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// list ответ ListObjectsV2 с постраничной выдачей через continuation-token
+func (f *fakeS3Server) list(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) && key > r.URL.Query().Get("continuation-token") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	type object struct {
+		Key string `xml:"Key"`
+	}
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Contents              []object `xml:"Contents"`
+		IsTruncated           bool     `xml:"IsTruncated"`
+		NextContinuationToken string   `xml:"NextContinuationToken,omitempty"`
+	}{}
+	if len(keys) > f.pageSize {
+		keys = keys[:f.pageSize]
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[len(keys)-1]
+	}
+	for _, key := range keys {
+		result.Contents = append(result.Contents, object{Key: key})
+	}
+	xml.NewEncoder(w).Encode(result)
+}
+
+// newS3Backend создает s3 backend workspace, attributes дополняют настройки по умолчанию
+func newS3Backend(t *testing.T, server *fakeS3Server, workspace string, attributes map[string]cty.Value) state.Backend {
+	t.Helper()
+	all := map[string]cty.Value{
+		"bucket":         cty.StringVal(server.bucket),
+		"key":            cty.StringVal("prod/terraform.tfstate"),
+		"endpoint":       cty.StringVal(server.URL),
+		"access_key":     cty.StringVal("AKIDEXAMPLE"),
+		"secret_key":     cty.StringVal("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"),
+		"use_path_style": cty.True,
+		"use_lockfile":   cty.True,
+	}
+	for name, value := range attributes {
+		all[name] = value
+	}
+	backend, err := New(&config.Backend{Type: "s3", Attributes: all}, workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backend
+}
+
+func TestS3BackendLock(t *testing.T) {
+	server := newFakeS3Server(t)
+	ctx := context.Background()
+	holder := state.NewStateManager(newS3Backend(t, server, config.DefaultWorkspace, nil), nil)
+	other := state.NewStateManager(newS3Backend(t, server, config.DefaultWorkspace, nil), nil)
+
+	lock, err := holder.Lock(ctx, "apply", 0)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	if _, exists := server.objects["prod/terraform.tfstate.tflock"]; !exists {
+		t.Fatalf("objects = %v, want the .tflock object", server.objects)
+	}
+
+	_, err = other.Lock(ctx, "plan", 0)
+	var lockErr *state.LockError
+	if !errors.As(err, &lockErr) {
+		t.Fatalf("error = %v, want *state.LockError", err)
+	}
+	if lockErr.Info == nil || lockErr.Info.ID != lock.Info.ID || !strings.Contains(err.Error(), "412 Precondition Failed") {
+		t.Errorf("error = %v, want the holder %s", err, lock.Info.ID)
+	}
+	if held, err := other.LockInfo(); err != nil || held == nil || held.ID != lock.Info.ID {
+		t.Errorf("LockInfo = %+v, %v, want %s", held, err, lock.Info.ID)
+	}
+
+	err = other.ForceUnlock("0000")
+	if err == nil || !strings.Contains(err.Error(), `lock ID "0000" does not match the existing lock (ID: `+lock.Info.ID) {
+		t.Errorf("ForceUnlock with wrong ID = %v", err)
+	}
+	if err := other.ForceUnlock(lock.Info.ID); err != nil {
+		t.Fatalf("ForceUnlock: %v", err)
+	}
+	if err := other.ForceUnlock(lock.Info.ID); err == nil || err.Error() != "state is not locked" {
+		t.Errorf("ForceUnlock without lock = %v, want state is not locked", err)
+	}
+
+	next, err := other.Lock(ctx, "plan", 0)
+	if err != nil {
+		t.Fatalf("Lock after force-unlock: %v", err)
+	}
+	// Держатель снятой блокировки не может снять новую
+	if err := lock.Unlock(); err == nil || !strings.Contains(err.Error(), "does not match the existing lock") {
+		t.Errorf("Unlock of a force-unlocked lock = %v, want an ID mismatch", err)
+	}
+	if err := next.Unlock(); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if len(server.objects) != 0 {
+		t.Errorf("objects = %v, want none after unlock", server.objects)
+	}
+}
+
+func TestS3BackendLockfileDisabled(t *testing.T) {
+	server := newFakeS3Server(t)
+	manager := state.NewStateManager(newS3Backend(t, server, config.DefaultWorkspace, map[string]cty.Value{"use_lockfile": cty.False}), nil)
+
+	lock, err := manager.Lock(context.Background(), "apply", 0)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	if len(server.objects) != 0 {
+		t.Errorf("objects = %v, want no lock object", server.objects)
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+}
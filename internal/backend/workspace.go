@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Artemka007/derraform/internal/config"
+)
+
+const (
+	// environmentFile имя выбранного workspace
+	environmentFile = "environment"
+	// WorkspaceEnv переменная окружения, которая переопределяет выбранный workspace
+	WorkspaceEnv = "TF_WORKSPACE"
+)
+
+var workspaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ValidateWorkspaceName проверяет, что имя workspace можно использовать в путях и ключах объектов
+func ValidateWorkspaceName(name string) error {
+	if !workspaceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid workspace name %q: use letters, digits, '-', '_' and '.'", name)
+	}
+	return nil
+}
+
+// CurrentWorkspace возвращает выбранный workspace: TF_WORKSPACE, затем .terraform/environment,
+// затем default
+func CurrentWorkspace() (string, error) {
+	if name := os.Getenv(WorkspaceEnv); name != "" {
+		if err := ValidateWorkspaceName(name); err != nil {
+			return "", fmt.Errorf("%s: %w", WorkspaceEnv, err)
+		}
+		return name, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(DataDir, environmentFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config.DefaultWorkspace, nil
+		}
+		return "", err
+	}
+
+	name := strings.TrimSpace(string(data))
+	if name == "" {
+		return config.DefaultWorkspace, nil
+	}
+	if err := ValidateWorkspaceName(name); err != nil {
+		return "", fmt.Errorf("%s: %w", filepath.Join(DataDir, environmentFile), err)
+	}
+	return name, nil
+}
+
+// SelectWorkspace запоминает выбранный workspace для следующих запусков
+func SelectWorkspace(name string) error {
+	if err := ValidateWorkspaceName(name); err != nil {
+		return err
+	}
+	if current := os.Getenv(WorkspaceEnv); current != "" && current != name {
+		return fmt.Errorf("the workspace is overridden by %s=%s, unset it to switch workspaces", WorkspaceEnv, current)
+	}
+
+	if err := os.MkdirAll(DataDir, 0755); err != nil {
+		return err
+	}
+	if name == config.DefaultWorkspace {
+		err := os.Remove(filepath.Join(DataDir, environmentFile))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return os.WriteFile(filepath.Join(DataDir, environmentFile), []byte(name+"\n"), 0644)
+}
@@ -0,0 +1,265 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestValidateWorkspaceName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"default", true},
+		{"staging", true},
+		{"feature-42_b.v2", true},
+		{"2024", true},
+		{"", false},
+		{"-staging", false},
+		{".hidden", false},
+		{"../prod", false},
+		{"team/staging", false},
+		{"staging env", false},
+	}
+
+	for _, test := range tests {
+		err := ValidateWorkspaceName(test.name)
+		if (err == nil) != test.valid {
+			t.Errorf("ValidateWorkspaceName(%q) = %v, want valid %v", test.name, err, test.valid)
+		}
+	}
+}
+
+func TestCurrentWorkspace(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment string
+		env         string
+		want        string
+		err         string
+	}{
+		{name: "nothing selected", want: config.DefaultWorkspace},
+		{name: "selected", environment: "staging\n", want: "staging"},
+		{name: "empty file", environment: "\n", want: config.DefaultWorkspace},
+		{name: "TF_WORKSPACE wins", environment: "staging\n", env: "prod", want: "prod"},
+		{name: "invalid file", environment: "../prod\n", err: `.terraform/environment: invalid workspace name "../prod"`},
+		{name: "invalid TF_WORKSPACE", env: "a b", err: `TF_WORKSPACE: invalid workspace name "a b"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			t.Setenv(WorkspaceEnv, test.env)
+			if test.environment != "" {
+				if err := os.MkdirAll(DataDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(DataDir, environmentFile), []byte(test.environment), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := CurrentWorkspace()
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("workspace = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSelectWorkspace(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(WorkspaceEnv, "")
+
+	steps := []struct {
+		name string
+		want string
+	}{
+		{"staging", "staging"},
+		{"prod", "prod"},
+		{config.DefaultWorkspace, config.DefaultWorkspace},
+		// Повторный выбор default, когда файла уже нет
+		{config.DefaultWorkspace, config.DefaultWorkspace},
+	}
+	for _, step := range steps {
+		if err := SelectWorkspace(step.name); err != nil {
+			t.Fatalf("SelectWorkspace(%q): %v", step.name, err)
+		}
+		if got, err := CurrentWorkspace(); err != nil || got != step.want {
+			t.Errorf("after selecting %q workspace = %q, %v, want %q", step.name, got, err, step.want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(DataDir, environmentFile)); !os.IsNotExist(err) {
+		t.Errorf("environment file is left after selecting default: %v", err)
+	}
+
+	if err := SelectWorkspace("../prod"); err == nil {
+		t.Error("invalid name was selected")
+	}
+
+	t.Setenv(WorkspaceEnv, "prod")
+	if err := SelectWorkspace("prod"); err != nil {
+		t.Errorf("selecting the TF_WORKSPACE workspace: %v", err)
+	}
+	err := SelectWorkspace("staging")
+	if err == nil || !strings.Contains(err.Error(), "the workspace is overridden by TF_WORKSPACE=prod") {
+		t.Errorf("error = %v, want TF_WORKSPACE override error", err)
+	}
+}
+
+func TestLocalBackendWorkspaces(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	attributes := map[string]cty.Value{
+		"path":          cty.StringVal(filepath.Join(dir, "default.tfstate")),
+		"workspace_dir": cty.StringVal(filepath.Join(dir, "workspaces")),
+	}
+
+	tests := []struct {
+		workspace string
+		path      string
+	}{
+		{config.DefaultWorkspace, filepath.Join(dir, "default.tfstate")},
+		{"staging", filepath.Join(dir, "workspaces", "staging", state.DefaultStateFile)},
+		{"prod", filepath.Join(dir, "workspaces", "prod", state.DefaultStateFile)},
+	}
+	for _, test := range tests {
+		b, err := New(&config.Backend{Type: "local", Attributes: attributes}, test.workspace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != test.path {
+			t.Errorf("workspace %s state = %s, want %s", test.workspace, b, test.path)
+		}
+		if err := b.Write(ctx, []byte("{}")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Каталог с недопустимым именем не считается workspace
+	if err := os.MkdirAll(filepath.Join(dir, "workspaces", ".trash"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := New(&config.Backend{Type: "local", Attributes: attributes}, config.DefaultWorkspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := b.(state.WorkspaceManager)
+	assertWorkspaces(t, manager, []string{"default", "prod", "staging"})
+
+	if err := manager.DeleteWorkspace(ctx, "staging"); err != nil {
+		t.Fatalf("DeleteWorkspace: %v", err)
+	}
+	assertWorkspaces(t, manager, []string{"default", "prod"})
+	if err := manager.DeleteWorkspace(ctx, config.DefaultWorkspace); err == nil {
+		t.Error("default workspace was deleted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "default.tfstate")); err != nil {
+		t.Errorf("default state: %v", err)
+	}
+}
+
+func TestS3BackendWorkspaces(t *testing.T) {
+	server := newFakeS3Server(t)
+	server.pageSize = 2
+	ctx := context.Background()
+
+	for _, workspace := range []string{config.DefaultWorkspace, "staging", "prod", "dev"} {
+		b := newS3Backend(t, server, workspace, nil)
+		if err := b.Write(ctx, []byte("{}")); err != nil {
+			t.Fatal(err)
+		}
+		// Блокировка и state с другим key не должны давать лишних workspace
+		if _, err := state.NewStateManager(b, nil).Lock(ctx, "apply", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server.objects["env:/other/terraform.tfstate"] = []byte("{}")
+
+	if _, exists := server.objects["env:/staging/prod/terraform.tfstate"]; !exists {
+		t.Fatalf("objects = %v, want staging state under env:/staging/", keys(server.objects))
+	}
+
+	manager := newS3Backend(t, server, config.DefaultWorkspace, nil).(state.WorkspaceManager)
+	assertWorkspaces(t, manager, []string{"default", "dev", "prod", "staging"})
+
+	if err := manager.DeleteWorkspace(ctx, "staging"); err != nil {
+		t.Fatalf("DeleteWorkspace: %v", err)
+	}
+	for _, key := range []string{"env:/staging/prod/terraform.tfstate", "env:/staging/prod/terraform.tfstate.tflock"} {
+		if _, exists := server.objects[key]; exists {
+			t.Errorf("%s is left after deleting the workspace", key)
+		}
+	}
+	assertWorkspaces(t, manager, []string{"default", "dev", "prod"})
+
+	custom := newS3Backend(t, server, "qa", map[string]cty.Value{"workspace_key_prefix": cty.StringVal("/workspaces/")})
+	if want := "s3://tfstate/workspaces/qa/prod/terraform.tfstate"; custom.String() != want {
+		t.Errorf("state = %s, want %s", custom, want)
+	}
+}
+
+func TestHTTPBackendWorkspaces(t *testing.T) {
+	server := newFakeHTTPServer(t)
+	ctx := context.Background()
+
+	staging := newHTTPBackend(t, server, "staging")
+	if err := staging.Write(ctx, []byte(`{"serial": 1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := newHTTPBackend(t, server, config.DefaultWorkspace).Read(ctx); data != nil {
+		t.Errorf("default state = %s, want workspaces stored separately", data)
+	}
+
+	manager := staging.(state.WorkspaceManager)
+	if _, err := manager.Workspaces(ctx); !errors.Is(err, state.ErrWorkspacesNotListable) {
+		t.Errorf("Workspaces error = %v, want ErrWorkspacesNotListable", err)
+	}
+	if err := manager.DeleteWorkspace(ctx, "staging"); err != nil {
+		t.Fatalf("DeleteWorkspace: %v", err)
+	}
+	if _, exists := server.states["staging"]; exists {
+		t.Error("staging state is left after delete")
+	}
+	// Удаление отсутствующего workspace не ошибка
+	if err := manager.DeleteWorkspace(ctx, "missing"); err != nil {
+		t.Errorf("DeleteWorkspace of a missing workspace: %v", err)
+	}
+}
+
+func assertWorkspaces(t *testing.T, manager state.WorkspaceManager, want []string) {
+	t.Helper()
+	got, err := manager.Workspaces(context.Background())
+	if err != nil {
+		t.Fatalf("Workspaces: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("workspaces = %v, want %v", got, want)
+	}
+}
+
+func keys(objects map[string][]byte) []string {
+	names := make([]string, 0, len(objects))
+	for name := range objects {
+		names = append(names, name)
+	}
+	return names
+}
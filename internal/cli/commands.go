@@ -1,43 +1,198 @@
 package cli
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/Artemka007/derraform/internal/core"
+	"github.com/Artemka007/derraform/internal/ui"
 	"github.com/spf13/cobra"
 )
 
+// defaultConfigFile файл конфигурации в текущем каталоге
+const defaultConfigFile = "main.tf"
+
+// Флаги блокировки state, общие для plan, apply и destroy
+var (
+	lockState   bool
+	lockTimeout time.Duration
+)
+
+func addLockFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&lockState, "lock", true, "Lock the state file while the operation runs")
+	cmd.Flags().DurationVar(&lockTimeout, "lock-timeout", 0, "Duration to retry a state lock held by another process")
+}
+
+// variableFlags значения -var name=value, общие для всех команд
+var variableFlags []string
+
+// parseVariableFlags разбирает значения -var name=value
+func parseVariableFlags(flags []string) (map[string]string, error) {
+	variables := make(map[string]string, len(flags))
+	for _, flag := range flags {
+		name, value, ok := strings.Cut(flag, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid -var %q: expected NAME=VALUE", flag)
+		}
+		variables[strings.TrimSpace(name)] = value
+	}
+	return variables, nil
+}
+
+// jsonOutput флаг -json команд plan и apply
+var jsonOutput bool
+
+// jsonView вывод событий -json, nil - вывод для человека
+var jsonView *ui.JSONView
+
+func newEngine() (*core.Engine, error) {
+	variables, err := parseVariableFlags(variableFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	options := core.Options{
+		Lock:        lockState,
+		LockTimeout: lockTimeout,
+		Variables:   variables,
+	}
+	if jsonOutput && jsonView == nil {
+		jsonView = ui.NewJSONView(os.Stdout)
+	}
+	if jsonView != nil {
+		options.Hooks = jsonView
+	}
+
+	if options.Logger, err = newLogger(); err != nil {
+		return nil, err
+	}
+
+	engine, err := core.NewEngine(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize engine: %w", err)
+	}
+	return engine, nil
+}
+
+var initOptions core.InitOptions
+
 var initCmd = &cobra.Command{
 	Use:   "init",
-	Short: "Initialize project",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Initializing...")
-		// Инициализация state файла
+	Short: "Initialize the state backend and install provider plugins",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		return engine.Init(defaultConfigFile, initOptions)
 	},
 }
 
+var planOptions core.PlanOptions
+
 var planCmd = &cobra.Command{
 	Use:   "plan",
 	Short: "Show execution plan",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Generating plan...")
-		// Парсинг конфига и план изменений
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		return withInterrupts(engine, func(ctx context.Context) error {
+			return engine.Plan(ctx, defaultConfigFile, planOptions)
+		})
 	},
 }
 
+var applyOptions core.ApplyOptions
+
 var applyCmd = &cobra.Command{
 	Use:   "apply",
 	Short: "Apply configuration",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Applying configuration...")
-		// Применение изменений
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		return withInterrupts(engine, func(ctx context.Context) error {
+			return engine.Apply(ctx, defaultConfigFile, applyOptions)
+		})
 	},
 }
 
+var destroyOptions core.DestroyOptions
+
 var destroyCmd = &cobra.Command{
 	Use:   "destroy",
 	Short: "Destroy infrastructure",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Destroying infrastructure...")
-		// Удаление ресурсов
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		return withInterrupts(engine, func(ctx context.Context) error {
+			return engine.Destroy(ctx, defaultConfigFile, destroyOptions)
+		})
 	},
 }
+
+var forceUnlock bool
+
+var forceUnlockCmd = &cobra.Command{
+	Use:   "force-unlock LOCK_ID",
+	Short: "Release a stuck lock on the state",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+
+		if !forceUnlock {
+			info, err := engine.LockInfo(defaultConfigFile)
+			if err != nil {
+				return err
+			}
+			if info != nil {
+				fmt.Printf("Current lock: %s\n", info)
+			}
+			fmt.Println("Removing the lock while another process is still running may corrupt the state.")
+			if !confirm("Do you really want to force-unlock?") {
+				return fmt.Errorf("force-unlock cancelled")
+			}
+		}
+
+		return engine.ForceUnlock(defaultConfigFile, args[0])
+	},
+}
+
+func init() {
+	initCmd.Flags().BoolVar(&initOptions.MigrateState, "migrate-state", false, "Copy existing state to the newly configured backend")
+	initCmd.Flags().BoolVar(&initOptions.Reconfigure, "reconfigure", false, "Switch backends without migrating existing state")
+	initCmd.Flags().DurationVar(&lockTimeout, "lock-timeout", 0, "Duration to retry a state lock during migration")
+
+	addLockFlags(planCmd)
+	planCmd.Flags().StringVar(&planOptions.GenerateConfigOut, "generate-config-out", "", "Write configuration for import blocks without a resource block to this new file")
+	planCmd.Flags().StringArrayVar(&planOptions.Replace, "replace", nil, "Plan to recreate this resource even if it has not changed (can be repeated)")
+	planCmd.Flags().StringArrayVar(&planOptions.Targets, "target", nil, "Plan only this resource (TYPE.NAME or TYPE.NAME[KEY]) and its dependencies (can be repeated)")
+	planCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print machine-readable JSON events instead of human-readable output")
+	addLockFlags(applyCmd)
+	applyCmd.Flags().BoolVar(&jsonOutput, "json", false, "Print machine-readable JSON events instead of human-readable output")
+	applyCmd.Flags().StringArrayVar(&applyOptions.Replace, "replace", nil, "Recreate this resource even if it has not changed (can be repeated)")
+	applyCmd.Flags().StringArrayVar(&applyOptions.Targets, "target", nil, "Apply only this resource (TYPE.NAME or TYPE.NAME[KEY]) and its dependencies (can be repeated)")
+	addLockFlags(destroyCmd)
+	destroyCmd.Flags().StringArrayVar(&destroyOptions.Targets, "target", nil, "Destroy only this resource (TYPE.NAME or TYPE.NAME[KEY]) and the resources that depend on it (can be repeated)")
+
+	forceUnlockCmd.Flags().BoolVar(&forceUnlock, "force", false, "Don't ask for confirmation")
+}
+
+// confirm спрашивает подтверждение, принимается только ответ "yes"
+func confirm(question string) bool {
+	fmt.Printf("%s Only 'yes' will be accepted: ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(answer) == "yes"
+}
@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/Artemka007/derraform/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var importOptions core.ImportOptions
+
+var importCmd = &cobra.Command{
+	Use:   "import ADDRESS ID",
+	Short: "Bring an existing object under management, e.g. import docker_container.web web",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		return withInterrupts(engine, func(ctx context.Context) error {
+			return engine.Import(ctx, defaultConfigFile, args[0], args[1], importOptions)
+		})
+	},
+}
+
+func init() {
+	addLockFlags(importCmd)
+	importCmd.Flags().StringVar(&importOptions.GenerateConfigOut, "generate-config-out", "", "Write configuration for a resource without a resource block to this new file")
+
+	rootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Artemka007/derraform/internal/core"
+)
+
+// withInterrupts выполняет операцию движка с обработкой SIGINT/SIGTERM. Первый сигнал
+// просит движок остановиться после текущих операций и сохранить state, второй
+// отменяет контекст и прерывает и их.
+func withInterrupts(engine *core.Engine, run func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	go func() {
+		select {
+		case <-signals:
+		case <-ctx.Done():
+			return
+		}
+		fmt.Fprintln(os.Stderr, "Interrupt received: waiting for operations in progress to finish and saving state. Press Ctrl-C again to abort them.")
+		engine.Stop()
+
+		select {
+		case <-signals:
+		case <-ctx.Done():
+			return
+		}
+		fmt.Fprintln(os.Stderr, "Two interrupts received: aborting operations in progress, state may not reflect them.")
+		cancel()
+	}()
+
+	return run(ctx)
+}
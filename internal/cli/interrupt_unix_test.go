@@ -0,0 +1,62 @@
+//go:build unix
+
+package cli
+
+import (
+	"context"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Artemka007/derraform/internal/core"
+	"github.com/Artemka007/derraform/internal/logging"
+)
+
+func TestWithInterrupts(t *testing.T) {
+	tests := []struct {
+		name    string
+		signals []syscall.Signal
+		// canceled второй сигнал отменяет контекст
+		canceled bool
+	}{
+		{name: "no signal"},
+		{name: "first interrupt only stops the engine", signals: []syscall.Signal{syscall.SIGINT}},
+		{name: "second interrupt cancels", signals: []syscall.Signal{syscall.SIGINT, syscall.SIGTERM}, canceled: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logger, err := logging.New(logging.Config{Level: logging.INFO, Output: io.Discard})
+			if err != nil {
+				t.Fatal(err)
+			}
+			engine, err := core.NewEngine(core.Options{Logger: logger})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = withInterrupts(engine, func(ctx context.Context) error {
+				for _, sig := range test.signals {
+					if err := syscall.Kill(syscall.Getpid(), sig); err != nil {
+						return err
+					}
+				}
+				select {
+				case <-ctx.Done():
+					if !test.canceled {
+						t.Error("context canceled after a single interrupt")
+					}
+				case <-time.After(200 * time.Millisecond):
+					if test.canceled {
+						t.Error("context was not canceled after the second interrupt")
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
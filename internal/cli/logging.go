@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/Artemka007/derraform/internal/logging"
+)
+
+// Флаги логирования, общие для всех команд. Заменяют TF_LOG и TF_LOG_FORMAT,
+// уровни подсистем из TF_LOG_CORE, TF_LOG_PROVIDER и TF_LOG_STATE остаются в силе.
+var (
+	logLevelFlag  string
+	logFormatFlag string
+)
+
+// sharedLogger общий лог команды, создается при первом обращении
+var sharedLogger *logging.Logger
+
+// newLogger создает лог по переменным TF_LOG* и флагам --log-level, --log-format
+func newLogger() (*logging.Logger, error) {
+	if sharedLogger != nil {
+		return sharedLogger, nil
+	}
+
+	cfg, err := logging.ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if logLevelFlag != "" {
+		if cfg.Level, err = logging.ParseLevel(logLevelFlag); err != nil {
+			return nil, fmt.Errorf("--log-level: %w", err)
+		}
+	}
+	if logFormatFlag != "" {
+		if cfg.Format, err = logging.ParseFormat(logFormatFlag); err != nil {
+			return nil, fmt.Errorf("--log-format: %w", err)
+		}
+	}
+
+	// В режиме -json stdout занят событиями: предупреждения становятся событиями diagnostic
+	if jsonView != nil {
+		cfg.Output = jsonView.LogOutput()
+		cfg.Format = logging.FormatJSON
+	}
+
+	sharedLogger, err = logging.New(cfg)
+	return sharedLogger, err
+}
+
+// closeLogger закрывает файл TF_LOG_PATH
+func closeLogger() {
+	if sharedLogger != nil {
+		sharedLogger.Close()
+	}
+}
@@ -1,21 +1,71 @@
 package cli
 
 import (
+	"os"
+	"strings"
+
+	"github.com/Artemka007/derraform/internal/ui"
 	"github.com/spf13/cobra"
 )
 
+// debugOutput показывать стек Go в сообщениях об ошибках
+var debugOutput bool
+
 var rootCmd = &cobra.Command{
-	Use:   "myterraform",
-	Short: "Terraform clone for Docker",
+	Use:           "myterraform",
+	Short:         "Terraform clone for Docker",
+	SilenceUsage:  true,
+	SilenceErrors: true,
 }
 
 func Execute() error {
+	defer closeLogger()
+
+	rootCmd.SetArgs(normalizeArgs(os.Args[1:]))
 	return rootCmd.Execute()
 }
 
+// PrintError показывает ошибку, которой завершилась команда. В режиме -json
+// ошибка пишется событиями diagnostic.
+func PrintError(err error) {
+	if jsonView != nil {
+		jsonView.Error(err)
+		return
+	}
+
+	reporter := ui.NewErrorReporter()
+	reporter.Debug = debugOutput
+	reporter.PrintError(err)
+}
+
+// normalizeArgs разрешает флаги в стиле Terraform с одним дефисом: -lock=false -> --lock=false
+func normalizeArgs(args []string) []string {
+	result := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			copy(result[i:], args[i:])
+			break
+		}
+		if len(arg) > 2 && arg[0] == '-' && arg[1] != '-' {
+			name, _, _ := strings.Cut(arg[1:], "=")
+			if len(name) > 1 {
+				arg = "-" + arg
+			}
+		}
+		result[i] = arg
+	}
+	return result
+}
+
 func init() {
+	rootCmd.PersistentFlags().StringArrayVar(&variableFlags, "var", nil, "Set a variable, NAME=VALUE (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&logLevelFlag, "log-level", "", "Log level: TRACE, DEBUG, INFO, WARN, ERROR or OFF (overrides TF_LOG)")
+	rootCmd.PersistentFlags().StringVar(&logFormatFlag, "log-format", "", "Log format: text or json (overrides TF_LOG_FORMAT)")
+	rootCmd.PersistentFlags().BoolVar(&debugOutput, "debug", false, "Show Go stack traces in error output")
+
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(planCmd)
 	rootCmd.AddCommand(applyCmd)
 	rootCmd.AddCommand(destroyCmd)
+	rootCmd.AddCommand(forceUnlockCmd)
 }
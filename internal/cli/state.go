@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/Artemka007/derraform/internal/core"
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Advanced state management",
+}
+
+// stateEditOptions флаги команд, которые изменяют state
+var stateEditOptions core.StateEditOptions
+
+func addStateEditFlags(cmd *cobra.Command) {
+	addLockFlags(cmd)
+	cmd.Flags().StringVar(&stateEditOptions.Backup, "backup", "", "Path for the backup of the previous state, '-' disables it (default: next to the state with a timestamp)")
+	cmd.Flags().BoolVar(&stateEditOptions.DryRun, "dry-run", false, "Only show what would change")
+}
+
+var stateListID string
+
+var stateListCmd = &cobra.Command{
+	Use:   "list [ADDRESS...]",
+	Short: "List resources in the state",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		addresses, err := engine.StateList(defaultConfigFile, args, stateListID)
+		if err != nil {
+			return err
+		}
+		for _, address := range addresses {
+			fmt.Println(address)
+		}
+		return nil
+	},
+}
+
+var stateShowCmd = &cobra.Command{
+	Use:   "show ADDRESS",
+	Short: "Show the attributes of a resource in the state",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		resourceState, err := engine.StateShow(defaultConfigFile, args[0])
+		if err != nil {
+			return err
+		}
+		output, err := formatResourceState(args[0], resourceState)
+		if err != nil {
+			return err
+		}
+		fmt.Print(output)
+		return nil
+	},
+}
+
+var stateMvCmd = &cobra.Command{
+	Use:   "mv SOURCE DESTINATION",
+	Short: "Move a resource to another address in the state",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		return engine.StateMove(defaultConfigFile, args[0], args[1], stateEditOptions)
+	},
+}
+
+var stateRmCmd = &cobra.Command{
+	Use:   "rm ADDRESS...",
+	Short: "Forget resources without destroying them",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		return engine.StateRemove(defaultConfigFile, args, stateEditOptions)
+	},
+}
+
+var statePullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Print the current state to stdout",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		data, err := engine.StatePull(defaultConfigFile)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	},
+}
+
+var statePushForce bool
+
+var statePushCmd = &cobra.Command{
+	Use:   "push PATH",
+	Short: "Replace the state with a local state file ('-' reads stdin)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		return engine.PushState(defaultConfigFile, args[0], statePushForce, stateEditOptions)
+	},
+}
+
+var stateReplaceProviderCmd = &cobra.Command{
+	Use:   "replace-provider FROM TO",
+	Short: "Reassign resources in the state to another provider, e.g. docker -> docker.edge",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		return engine.ReplaceProvider(defaultConfigFile, args[0], args[1], stateEditOptions)
+	},
+}
+
+var stateRekeyCmd = &cobra.Command{
+	Use:   "rekey",
+	Short: "Rewrite the state with the primary encryption key, e.g. after rotating keys",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		return engine.StateRekey(defaultConfigFile, stateEditOptions)
+	},
+}
+
+// formatResourceState показывает запись state в виде блока resource
+func formatResourceState(address string, resourceState *state.ResourceState) (string, error) {
+	parsed, err := state.ParseAddress(address)
+	if err != nil {
+		return "", err
+	}
+
+	file := hclwrite.NewEmptyFile()
+	block := file.Body().AppendNewBlock("resource", []string{parsed.Type, parsed.Name})
+	body := block.Body()
+
+	names := make([]string, 0, len(resourceState.Attributes))
+	for name := range resourceState.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if _, exists := resourceState.Attributes["id"]; !exists {
+		body.SetAttributeValue("id", cty.StringVal(resourceState.ID))
+	}
+	for _, name := range names {
+		if slices.Contains(resourceState.SensitiveAttributes, name) {
+			body.SetAttributeRaw(name, hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: []byte(logging.SensitivePlaceholder)}})
+			continue
+		}
+		// Атрибуты state хранятся как JSON, тип значения выводим из него
+		data, err := json.Marshal(resourceState.Attributes[name])
+		if err != nil {
+			return "", err
+		}
+		valueType, err := ctyjson.ImpliedType(data)
+		if err != nil {
+			return "", fmt.Errorf("attribute %s: %w", name, err)
+		}
+		value, err := ctyjson.Unmarshal(data, valueType)
+		if err != nil {
+			return "", fmt.Errorf("attribute %s: %w", name, err)
+		}
+		body.SetAttributeValue(name, value)
+	}
+
+	return fmt.Sprintf("# %s:\n%s", parsed, hclwrite.Format(file.Bytes())), nil
+}
+
+func init() {
+	stateListCmd.Flags().StringVar(&stateListID, "id", "", "Only list resources with this ID")
+
+	addStateEditFlags(stateMvCmd)
+	addStateEditFlags(stateRmCmd)
+	addStateEditFlags(statePushCmd)
+	addStateEditFlags(stateReplaceProviderCmd)
+	addStateEditFlags(stateRekeyCmd)
+	statePushCmd.Flags().BoolVar(&statePushForce, "force", false, "Push even if the lineage differs or the serial is lower")
+
+	stateCmd.AddCommand(stateListCmd)
+	stateCmd.AddCommand(stateShowCmd)
+	stateCmd.AddCommand(stateMvCmd)
+	stateCmd.AddCommand(stateRmCmd)
+	stateCmd.AddCommand(statePullCmd)
+	stateCmd.AddCommand(statePushCmd)
+	stateCmd.AddCommand(stateReplaceProviderCmd)
+	stateCmd.AddCommand(stateRekeyCmd)
+	rootCmd.AddCommand(stateCmd)
+}
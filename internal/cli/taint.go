@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var taintCmd = &cobra.Command{
+	Use:   "taint ADDRESS",
+	Short: "Mark a resource to be recreated by the next apply",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		return engine.Taint(defaultConfigFile, args[0], stateEditOptions)
+	},
+}
+
+var untaintCmd = &cobra.Command{
+	Use:   "untaint ADDRESS",
+	Short: "Remove the tainted mark, e.g. after a partially failed create was fixed by hand",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		return engine.Untaint(defaultConfigFile, args[0], stateEditOptions)
+	},
+}
+
+func init() {
+	addStateEditFlags(taintCmd)
+	addStateEditFlags(untaintCmd)
+
+	rootCmd.AddCommand(taintCmd)
+	rootCmd.AddCommand(untaintCmd)
+}
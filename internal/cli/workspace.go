@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Manage workspaces: named states of the same configuration",
+}
+
+var workspaceNewCmd = &cobra.Command{
+	Use:   "new NAME",
+	Short: "Create a workspace and switch to it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		return engine.NewWorkspace(defaultConfigFile, args[0])
+	},
+}
+
+var workspaceSelectCmd = &cobra.Command{
+	Use:   "select NAME",
+	Short: "Switch to an existing workspace",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		return engine.SelectWorkspace(defaultConfigFile, args[0])
+	},
+}
+
+var workspaceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List workspaces, the current one is marked with *",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		names, current, err := engine.Workspaces(defaultConfigFile)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			marker := " "
+			if name == current {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, name)
+		}
+		return nil
+	},
+}
+
+var workspaceShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the name of the current workspace",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		name, err := engine.CurrentWorkspace()
+		if err != nil {
+			return err
+		}
+		fmt.Println(name)
+		return nil
+	},
+}
+
+var workspaceDeleteForce bool
+
+var workspaceDeleteCmd = &cobra.Command{
+	Use:   "delete NAME",
+	Short: "Delete a workspace and its state",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		return engine.DeleteWorkspace(defaultConfigFile, args[0], workspaceDeleteForce)
+	},
+}
+
+func init() {
+	addLockFlags(workspaceDeleteCmd)
+	workspaceDeleteCmd.Flags().BoolVar(&workspaceDeleteForce, "force", false, "Delete the workspace even if its state still has resources")
+
+	workspaceCmd.AddCommand(workspaceNewCmd)
+	workspaceCmd.AddCommand(workspaceSelectCmd)
+	workspaceCmd.AddCommand(workspaceListCmd)
+	workspaceCmd.AddCommand(workspaceShowCmd)
+	workspaceCmd.AddCommand(workspaceDeleteCmd)
+	rootCmd.AddCommand(workspaceCmd)
+}
@@ -4,23 +4,155 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/zclconf/go-cty/cty"
 )
 
 type Config struct {
 	Resources []Resource
+	Providers []Provider
+	// Backend хранилище state из блока terraform { backend "..." {} }, nil - локальный файл
+	Backend *Backend
+	// Encryption шифрование state из блока terraform { encryption {} }, nil - без шифрования
+	Encryption *Encryption
+	// Imports блоки import: существующие объекты, которые нужно взять под управление
+	Imports []Import
+	// Variables входные переменные, доступные в выражениях как var.name
+	Variables []Variable
+	// Outputs значения, которые показываются после apply
+	Outputs []Output
 }
 
 type Resource struct {
-	Type       string
+	Type string
+	Name string
+	// Provider адрес провайдера из мета-аргумента provider, например "docker.edge".
+	// Пустая строка означает провайдер по умолчанию.
+	Provider string
+	// DependsOn адреса ресурсов из depends_on, которые создаются раньше этого
+	DependsOn  []string
+	Lifecycle  Lifecycle
+	Timeouts   Timeouts
+	Attributes map[string]cty.Value
+	Blocks     []Block
+	// Dir директория файла конфигурации, относительно нее разрешаются пути
+	Dir string
+	// DeclRange заголовок блока resource в файле, для сообщений об ошибках
+	DeclRange hcl.Range
+}
+
+// Lifecycle блок lifecycle ресурса
+type Lifecycle struct {
+	// CreateBeforeDestroy при замене сначала создать новый объект, затем удалить старый
+	CreateBeforeDestroy bool
+}
+
+// Timeouts блок timeouts ресурса: сколько ждать операцию провайдера, 0 - без ограничения
+type Timeouts struct {
+	Create time.Duration
+	Update time.Duration
+	Delete time.Duration
+}
+
+// Provider блок настроек провайдера, например provider "docker" {}
+type Provider struct {
 	Name       string
 	Attributes map[string]cty.Value
+	Blocks     []Block
 }
 
-func ParseFile(filename string) (*Config, error) {
+// Backend настройки хранилища state, например backend "http" { address = "..." }
+type Backend struct {
+	Type       string
+	Attributes map[string]cty.Value
+}
+
+// Encryption ключи шифрования state: key для записи и чтения,
+// fallback только для чтения (смена ключа, переход на шифрование)
+type Encryption struct {
+	Key       Block
+	Fallbacks []Block
+}
+
+// Import блок import { to = docker_container.web  id = "web" }
+type Import struct {
+	// To адрес ресурса, в который импортируется объект
+	To string
+	// ID идентификатор объекта у провайдера
+	ID string
+	// Provider адрес провайдера, пустая строка - провайдер ресурса
+	Provider string
+}
+
+// Block вложенный блок ресурса (healthcheck, ports и т.п.)
+type Block struct {
+	Type       string
+	Labels     []string
+	Attributes map[string]cty.Value
+	Blocks     []Block
+}
+
+// BlocksOfType возвращает все вложенные блоки указанного типа
+func (r Resource) BlocksOfType(blockType string) []Block {
+	return blocksOfType(r.Blocks, blockType)
+}
+
+// BlocksOfType возвращает все вложенные блоки указанного типа
+func (p Provider) BlocksOfType(blockType string) []Block {
+	return blocksOfType(p.Blocks, blockType)
+}
+
+// BlocksOfType возвращает все вложенные блоки указанного типа
+func (b Block) BlocksOfType(blockType string) []Block {
+	return blocksOfType(b.Blocks, blockType)
+}
+
+func blocksOfType(blocks []Block, blockType string) []Block {
+	var result []Block
+	for _, block := range blocks {
+		if block.Type == blockType {
+			result = append(result, block)
+		}
+	}
+	return result
+}
+
+// Scope значения, доступные в выражениях конфигурации
+type Scope struct {
+	// Workspace имя текущего workspace, доступно как terraform.workspace
+	Workspace string
+	// Variables значения переменных из -var name=value
+	Variables map[string]string
+	// AllowUnsetVariables обязательные переменные без значения становятся неизвестными.
+	// Так работают команды, которым из конфигурации нужны только бэкенд и блоки.
+	AllowUnsetVariables bool
+}
+
+// DefaultWorkspace workspace, который существует всегда
+const DefaultWorkspace = "default"
+
+// evalContext переменные для вычисления атрибутов ресурсов и провайдеров
+func (s Scope) evalContext(variables []Variable) *hcl.EvalContext {
+	workspace := s.Workspace
+	if workspace == "" {
+		workspace = DefaultWorkspace
+	}
+	return &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"terraform": cty.ObjectVal(map[string]cty.Value{
+				"workspace": cty.StringVal(workspace),
+			}),
+			"var": variablesObject(variables),
+		},
+	}
+}
+
+func ParseFile(filename string, scope Scope) (*Config, error) {
 	src, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
@@ -28,14 +160,14 @@ func ParseFile(filename string) (*Config, error) {
 
 	file, diags := hclsyntax.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
 	if diags.HasErrors() {
-		return nil, fmt.Errorf("failed to parse HCL: %s", diags.Error())
+		return nil, fmt.Errorf("failed to parse HCL: %w", diags)
 	}
 
-	return parseConfig(file)
+	return parseConfig(file, scope)
 }
 
 // parseConfig преобразует HCL AST в нашу конфигурацию
-func parseConfig(file *hcl.File) (*Config, error) {
+func parseConfig(file *hcl.File, scope Scope) (*Config, error) {
 	config := &Config{
 		Resources: []Resource{},
 	}
@@ -47,21 +179,78 @@ func parseConfig(file *hcl.File) (*Config, error) {
 				Type:       "resource",
 				LabelNames: []string{"type", "name"},
 			},
+			{
+				Type:       "provider",
+				LabelNames: []string{"name"},
+			},
+			{
+				Type: "terraform",
+			},
+			{
+				Type: "import",
+			},
+			{
+				Type:       "variable",
+				LabelNames: []string{"name"},
+			},
+			{
+				Type:       "output",
+				LabelNames: []string{"name"},
+			},
 		},
 	})
 
 	if diags.HasErrors() {
-		return nil, fmt.Errorf("failed to parse body: %s", diags.Error())
+		return nil, fmt.Errorf("failed to parse body: %w", diags)
+	}
+
+	// Переменные разбираются первыми: на них ссылаются выражения остальных блоков
+	for _, block := range content.Blocks {
+		if block.Type != "variable" {
+			continue
+		}
+		variable, err := parseVariableBlock(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse variable %s: %w", block.Labels[0], err)
+		}
+		config.Variables = append(config.Variables, variable)
+	}
+	if err := resolveVariables(config.Variables, scope.Variables, scope.AllowUnsetVariables); err != nil {
+		return nil, err
 	}
+	ctx := scope.evalContext(config.Variables)
 
-	// Обрабатываем все resource блоки
+	// Обрабатываем все resource и provider блоки
 	for _, block := range content.Blocks {
-		if block.Type == "resource" {
-			resource, err := parseResourceBlock(block)
+		switch block.Type {
+		case "resource":
+			resource, err := parseResourceBlock(block, ctx)
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse resource block: %w", err)
 			}
 			config.Resources = append(config.Resources, resource)
+		case "provider":
+			provider, err := parseProviderBlock(block, ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse provider block: %w", err)
+			}
+			config.Providers = append(config.Providers, provider)
+		case "terraform":
+			if err := parseTerraformBlock(block, config); err != nil {
+				return nil, fmt.Errorf("failed to parse terraform block: %w", err)
+			}
+		case "import":
+			imp, err := parseImportBlock(block, ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse import block: %w", err)
+			}
+			config.Imports = append(config.Imports, imp)
+		case "output":
+			output, err := parseOutputBlock(block, ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse output %s: %w", block.Labels[0], err)
+			}
+			config.Outputs = append(config.Outputs, output)
 		}
 	}
 
@@ -69,27 +258,307 @@ func parseConfig(file *hcl.File) (*Config, error) {
 }
 
 // parseResourceBlock парсит отдельный resource блок
-func parseResourceBlock(block *hcl.Block) (Resource, error) {
+func parseResourceBlock(block *hcl.Block, ctx *hcl.EvalContext) (Resource, error) {
 	resource := Resource{
-		Type:       block.Labels[0],
-		Name:       block.Labels[1],
-		Attributes: make(map[string]cty.Value),
+		Type:      block.Labels[0],
+		Name:      block.Labels[1],
+		Dir:       filepath.Dir(block.DefRange.Filename),
+		DeclRange: block.DefRange,
 	}
 
-	// Парсим атрибуты внутри resource блока
-	attrs, diags := block.Body.JustAttributes()
-	if diags.HasErrors() {
-		// Игнорируем ошибки атрибутов, возможно есть nested blocks
-		diags = nil
+	body, ok := block.Body.(*hclsyntax.Body)
+	if !ok {
+		return resource, fmt.Errorf("unsupported body type for resource %s.%s", resource.Type, resource.Name)
+	}
+
+	// provider = docker.edge это ссылка, а не значение, поэтому разбираем ее отдельно
+	if attr, exists := body.Attributes["provider"]; exists {
+		traversal, diags := hcl.AbsTraversalForExpr(attr.Expr)
+		if diags.HasErrors() {
+			return resource, fmt.Errorf("invalid provider reference: %w", diags)
+		}
+		resource.Provider = traversalString(traversal)
 	}
 
-	for name, attr := range attrs {
-		value, diags := attr.Expr.Value(nil) // nil EvalContext для простоты
+	if attr, exists := body.Attributes["depends_on"]; exists {
+		references, diags := hcl.ExprList(attr.Expr)
 		if diags.HasErrors() {
-			return resource, fmt.Errorf("failed to evaluate attribute %s: %s", name, diags.Error())
+			return resource, fmt.Errorf("depends_on must be a list of resource references: %w", diags)
+		}
+		for _, reference := range references {
+			traversal, diags := hcl.AbsTraversalForExpr(reference)
+			if diags.HasErrors() {
+				return resource, fmt.Errorf("invalid reference in depends_on: %w", diags)
+			}
+			resource.DependsOn = append(resource.DependsOn, traversalString(traversal))
+		}
+	}
+
+	for _, nested := range body.Blocks {
+		var err error
+		switch nested.Type {
+		case "lifecycle":
+			resource.Lifecycle, err = parseLifecycleBlock(nested.AsHCLBlock())
+		case "timeouts":
+			resource.Timeouts, err = parseTimeoutsBlock(nested.AsHCLBlock())
+		}
+		if err != nil {
+			return resource, fmt.Errorf("invalid %s block: %w", nested.Type, err)
 		}
-		resource.Attributes[name] = value
 	}
 
+	attributes, blocks, err := parseBody(body, ctx, "provider", "depends_on", "lifecycle", "timeouts")
+	if err != nil {
+		return resource, err
+	}
+	resource.Attributes = attributes
+	resource.Blocks = blocks
+
 	return resource, nil
 }
+
+// parseLifecycleBlock парсит блок lifecycle. Значения должны быть известны без переменных.
+func parseLifecycleBlock(block *hcl.Block) (Lifecycle, error) {
+	var lifecycle Lifecycle
+	content, diags := block.Body.Content(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "create_before_destroy"},
+		},
+	})
+	if diags.HasErrors() {
+		return lifecycle, diags
+	}
+
+	var err error
+	lifecycle.CreateBeforeDestroy, err = staticBool(content.Attributes["create_before_destroy"])
+	return lifecycle, err
+}
+
+// parseTimeoutsBlock парсит блок timeouts, значения - длительности вида "30s" или "1h30m"
+func parseTimeoutsBlock(block *hcl.Block) (Timeouts, error) {
+	var timeouts Timeouts
+	content, diags := block.Body.Content(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "create"},
+			{Name: "update"},
+			{Name: "delete"},
+		},
+	})
+	if diags.HasErrors() {
+		return timeouts, diags
+	}
+
+	for _, field := range []struct {
+		name   string
+		target *time.Duration
+	}{
+		{"create", &timeouts.Create},
+		{"update", &timeouts.Update},
+		{"delete", &timeouts.Delete},
+	} {
+		attr := content.Attributes[field.name]
+		if attr == nil {
+			continue
+		}
+		text, err := staticString(attr)
+		if err != nil {
+			return timeouts, err
+		}
+		duration, err := time.ParseDuration(text)
+		if err != nil || duration <= 0 {
+			return timeouts, fmt.Errorf("%s must be a positive duration such as \"30s\" or \"10m\", got %q", field.name, text)
+		}
+		*field.target = duration
+	}
+	return timeouts, nil
+}
+
+// traversalString превращает ссылку вида docker.edge в строку
+func traversalString(traversal hcl.Traversal) string {
+	var result string
+	for i, step := range traversal {
+		var name string
+		switch s := step.(type) {
+		case hcl.TraverseRoot:
+			name = s.Name
+		case hcl.TraverseAttr:
+			name = s.Name
+		}
+		if i > 0 {
+			result += "."
+		}
+		result += name
+	}
+	return result
+}
+
+// parseProviderBlock парсит блок provider
+func parseProviderBlock(block *hcl.Block, ctx *hcl.EvalContext) (Provider, error) {
+	provider := Provider{Name: block.Labels[0]}
+
+	body, ok := block.Body.(*hclsyntax.Body)
+	if !ok {
+		return provider, fmt.Errorf("unsupported body type for provider %s", provider.Name)
+	}
+
+	attributes, blocks, err := parseBody(body, ctx)
+	if err != nil {
+		return provider, err
+	}
+	provider.Attributes = attributes
+	provider.Blocks = blocks
+
+	return provider, nil
+}
+
+// parseImportBlock парсит блок import. to и provider это ссылки, id вычисляется как значение.
+func parseImportBlock(block *hcl.Block, ctx *hcl.EvalContext) (Import, error) {
+	var imp Import
+
+	content, diags := block.Body.Content(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "to", Required: true},
+			{Name: "id", Required: true},
+			{Name: "provider"},
+		},
+	})
+	if diags.HasErrors() {
+		return imp, diags
+	}
+
+	to, diags := hcl.AbsTraversalForExpr(content.Attributes["to"].Expr)
+	if diags.HasErrors() {
+		return imp, fmt.Errorf("invalid resource address in to: %w", diags)
+	}
+	imp.To = string(hclwrite.TokensForTraversal(to).Bytes())
+
+	if attr, exists := content.Attributes["provider"]; exists {
+		traversal, diags := hcl.AbsTraversalForExpr(attr.Expr)
+		if diags.HasErrors() {
+			return imp, fmt.Errorf("invalid provider reference: %w", diags)
+		}
+		imp.Provider = traversalString(traversal)
+	}
+
+	id, diags := content.Attributes["id"].Expr.Value(ctx)
+	if diags.HasErrors() {
+		return imp, fmt.Errorf("failed to evaluate attribute id: %w", diags)
+	}
+	id, _ = id.UnmarkDeep()
+	if !id.IsKnown() {
+		// id зависит от переменной без значения (AllowUnsetVariables)
+		return imp, nil
+	}
+	if id.IsNull() || !id.IsKnown() || id.Type() != cty.String || id.AsString() == "" {
+		return imp, fmt.Errorf("id must be a non-empty string")
+	}
+	imp.ID = id.AsString()
+
+	return imp, nil
+}
+
+// parseTerraformBlock парсит блок terraform: backend и encryption.
+// Они нужны до выбора workspace, поэтому переменные в них недоступны.
+func parseTerraformBlock(block *hcl.Block, config *Config) error {
+	body, ok := block.Body.(*hclsyntax.Body)
+	if !ok {
+		return fmt.Errorf("unsupported body type for terraform block")
+	}
+
+	_, blocks, err := parseBody(body, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, nested := range blocks {
+		switch nested.Type {
+		case "backend":
+			if len(nested.Labels) != 1 {
+				return fmt.Errorf("backend block must have exactly one label: the backend type")
+			}
+			if config.Backend != nil {
+				return fmt.Errorf("only one backend may be configured")
+			}
+			config.Backend = &Backend{Type: nested.Labels[0], Attributes: nested.Attributes}
+		case "encryption":
+			if config.Encryption != nil {
+				return fmt.Errorf("only one encryption block may be configured")
+			}
+			encryption, err := parseEncryptionBlock(nested)
+			if err != nil {
+				return fmt.Errorf("invalid encryption block: %w", err)
+			}
+			config.Encryption = encryption
+		}
+	}
+	return nil
+}
+
+// parseEncryptionBlock разбирает encryption { key {} fallback {} }
+func parseEncryptionBlock(block Block) (*Encryption, error) {
+	if len(block.Labels) > 0 || len(block.Attributes) > 0 {
+		return nil, fmt.Errorf("only key and fallback blocks are allowed")
+	}
+
+	encryption := &Encryption{}
+	keys := 0
+	for _, nested := range block.Blocks {
+		switch nested.Type {
+		case "key":
+			encryption.Key = nested
+			keys++
+		case "fallback":
+			encryption.Fallbacks = append(encryption.Fallbacks, nested)
+		default:
+			return nil, fmt.Errorf("unsupported block %q", nested.Type)
+		}
+	}
+	if keys != 1 {
+		return nil, fmt.Errorf("exactly one key block is required")
+	}
+	return encryption, nil
+}
+
+// parseBody вычисляет атрибуты в контексте ctx и рекурсивно разбирает вложенные блоки.
+// Атрибуты и блоки из skip пропускаются, их обрабатывает вызывающий код.
+func parseBody(body *hclsyntax.Body, ctx *hcl.EvalContext, skip ...string) (map[string]cty.Value, []Block, error) {
+	attributes := make(map[string]cty.Value)
+	for name, attr := range body.Attributes {
+		if contains(skip, name) {
+			continue
+		}
+		value, diags := attr.Expr.Value(ctx)
+		if diags.HasErrors() {
+			return nil, nil, fmt.Errorf("failed to evaluate attribute %s: %w", name, diags)
+		}
+		attributes[name] = value
+	}
+
+	var blocks []Block
+	for _, nested := range body.Blocks {
+		if contains(skip, nested.Type) {
+			continue
+		}
+		nestedAttrs, nestedBlocks, err := parseBody(nested.Body, ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse block %s: %w", nested.Type, err)
+		}
+		blocks = append(blocks, Block{
+			Type:       nested.Type,
+			Labels:     nested.Labels,
+			Attributes: nestedAttrs,
+			Blocks:     nestedBlocks,
+		})
+	}
+
+	return attributes, blocks, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
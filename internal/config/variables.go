@@ -0,0 +1,361 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// VariableEnvPrefix префикс переменных окружения со значениями переменных: TF_VAR_name
+const VariableEnvPrefix = "TF_VAR_"
+
+// SensitiveMark пометка cty для значений, которые не должны попадать в вывод.
+// HCL переносит пометки через выражения, поэтому помеченным становится
+// и любое значение, вычисленное из sensitive переменной.
+const SensitiveMark = "sensitive"
+
+// Variable блок variable "name" { default = ... sensitive = true }
+type Variable struct {
+	Name        string
+	Description string
+	// Default значение по умолчанию, cty.NilVal - переменная обязательна
+	Default   cty.Value
+	Sensitive bool
+	// Value итоговое значение: из -var, TF_VAR_name или default
+	Value cty.Value
+}
+
+// Output блок output "name" { value = ... }
+type Output struct {
+	Name        string
+	Description string
+	Sensitive   bool
+	// Value значение с пометками sensitive
+	Value cty.Value
+}
+
+// parseVariableBlock парсит блок variable. default вычисляется без переменных.
+func parseVariableBlock(block *hcl.Block) (Variable, error) {
+	variable := Variable{Name: block.Labels[0]}
+
+	content, diags := block.Body.Content(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "default"},
+			{Name: "description"},
+			{Name: "sensitive"},
+		},
+	})
+	if diags.HasErrors() {
+		return variable, diags
+	}
+
+	if attr, exists := content.Attributes["default"]; exists {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return variable, fmt.Errorf("failed to evaluate default: %w", diags)
+		}
+		variable.Default = value
+	}
+
+	var err error
+	if variable.Description, err = staticString(content.Attributes["description"]); err != nil {
+		return variable, err
+	}
+	if variable.Sensitive, err = staticBool(content.Attributes["sensitive"]); err != nil {
+		return variable, err
+	}
+	return variable, nil
+}
+
+// parseOutputBlock парсит блок output. Значение, зависящее от sensitive данных,
+// нужно явно объявить sensitive, чтобы оно не попало в вывод случайно.
+func parseOutputBlock(block *hcl.Block, ctx *hcl.EvalContext) (Output, error) {
+	output := Output{Name: block.Labels[0]}
+
+	content, diags := block.Body.Content(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{
+			{Name: "value", Required: true},
+			{Name: "description"},
+			{Name: "sensitive"},
+		},
+	})
+	if diags.HasErrors() {
+		return output, diags
+	}
+
+	value, diags := content.Attributes["value"].Expr.Value(ctx)
+	if diags.HasErrors() {
+		return output, fmt.Errorf("failed to evaluate value: %w", diags)
+	}
+	output.Value = value
+
+	var err error
+	if output.Description, err = staticString(content.Attributes["description"]); err != nil {
+		return output, err
+	}
+	if output.Sensitive, err = staticBool(content.Attributes["sensitive"]); err != nil {
+		return output, err
+	}
+
+	if IsSensitive(value) && !output.Sensitive {
+		return output, fmt.Errorf("output %s refers to sensitive values: set sensitive = true to show it as (sensitive value)", output.Name)
+	}
+	if output.Sensitive {
+		output.Value = value.Mark(SensitiveMark)
+	}
+	return output, nil
+}
+
+// resolveVariables назначает значения переменным: -var важнее TF_VAR_name, TF_VAR_name важнее default
+func resolveVariables(variables []Variable, values map[string]string, allowUnset bool) error {
+	declared := make(map[string]bool, len(variables))
+	for i := range variables {
+		variable := &variables[i]
+		if declared[variable.Name] {
+			return fmt.Errorf("duplicate variable %q", variable.Name)
+		}
+		declared[variable.Name] = true
+
+		raw, exists := values[variable.Name]
+		if !exists {
+			raw, exists = os.LookupEnv(VariableEnvPrefix + variable.Name)
+		}
+
+		switch {
+		case exists:
+			value, err := parseVariableValue(raw, variable.Default)
+			if err != nil {
+				return fmt.Errorf("invalid value for variable %q: %w", variable.Name, err)
+			}
+			variable.Value = value
+		case variable.Default != cty.NilVal:
+			variable.Value = variable.Default
+		case allowUnset:
+			variable.Value = cty.DynamicVal
+		default:
+			return fmt.Errorf("no value for required variable %q: set it with -var %s=VALUE or %s%s", variable.Name, variable.Name, VariableEnvPrefix, variable.Name)
+		}
+
+		if variable.Sensitive {
+			variable.Value = variable.Value.Mark(SensitiveMark)
+		}
+	}
+
+	for _, name := range sortedNames(values) {
+		if !declared[name] {
+			return fmt.Errorf("value given for undeclared variable %q", name)
+		}
+	}
+	return nil
+}
+
+// parseVariableValue разбирает значение из командной строки или окружения.
+// Строка берется как есть, значения других типов (по типу default) разбираются как выражение HCL.
+func parseVariableValue(raw string, defaultValue cty.Value) (cty.Value, error) {
+	if defaultValue == cty.NilVal || defaultValue.IsNull() || defaultValue.Type() == cty.String {
+		return cty.StringVal(raw), nil
+	}
+
+	expr, diags := hclsyntax.ParseExpression([]byte(raw), "<value>", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return cty.NilVal, fmt.Errorf("%s", diags.Error())
+	}
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return cty.NilVal, fmt.Errorf("%s", diags.Error())
+	}
+	return convert.Convert(value, defaultValue.Type())
+}
+
+// variablesObject значения переменных для выражений var.name
+func variablesObject(variables []Variable) cty.Value {
+	values := make(map[string]cty.Value, len(variables))
+	for _, variable := range variables {
+		values[variable.Name] = variable.Value
+	}
+	return cty.ObjectVal(values)
+}
+
+// IsSensitive сообщает, содержит ли значение sensitive данные
+func IsSensitive(value cty.Value) bool {
+	_, pvm := value.UnmarkDeepWithPaths()
+	for _, marked := range pvm {
+		if _, sensitive := marked.Marks[SensitiveMark]; sensitive {
+			return true
+		}
+	}
+	return false
+}
+
+// SensitiveStrings возвращает строки из sensitive частей значения, чтобы их можно было
+// вырезать из вывода
+func SensitiveStrings(value cty.Value) []string {
+	unmarked, pvm := value.UnmarkDeepWithPaths()
+	var result []string
+	for _, marked := range pvm {
+		if _, sensitive := marked.Marks[SensitiveMark]; !sensitive {
+			continue
+		}
+		if part, err := marked.Path.Apply(unmarked); err == nil {
+			result = append(result, Strings(part)...)
+		}
+	}
+	return result
+}
+
+// Strings возвращает все строки и числа внутри значения без пометок
+func Strings(value cty.Value) []string {
+	var result []string
+	cty.Walk(value, func(_ cty.Path, v cty.Value) (bool, error) {
+		if v.IsNull() || !v.IsKnown() {
+			return false, nil
+		}
+		switch v.Type() {
+		case cty.String:
+			result = append(result, v.AsString())
+		case cty.Number:
+			result = append(result, v.AsBigFloat().Text('f', -1))
+		}
+		return true, nil
+	})
+	return result
+}
+
+// Unmarked копия ресурса без пометок: в таком виде значения получает провайдер
+func (r Resource) Unmarked() Resource {
+	r.Attributes = unmarkAttributes(r.Attributes)
+	r.Blocks = unmarkBlocks(r.Blocks)
+	return r
+}
+
+// Unmarked копия блока provider без пометок
+func (p Provider) Unmarked() Provider {
+	p.Attributes = unmarkAttributes(p.Attributes)
+	p.Blocks = unmarkBlocks(p.Blocks)
+	return p
+}
+
+// SensitiveAttributes имена атрибутов и вложенных блоков ресурса, в которых есть sensitive значения
+func (r Resource) SensitiveAttributes() []string {
+	var names []string
+	for _, name := range sortedNames(r.Attributes) {
+		if IsSensitive(r.Attributes[name]) {
+			names = append(names, name)
+		}
+	}
+	for _, block := range r.Blocks {
+		if blockSensitive(block) && !contains(names, block.Type) {
+			names = append(names, block.Type)
+		}
+	}
+	return names
+}
+
+// SensitiveStrings строки из всех sensitive значений конфигурации
+func (c *Config) SensitiveStrings() []string {
+	var result []string
+	for _, variable := range c.Variables {
+		result = append(result, SensitiveStrings(variable.Value)...)
+	}
+	for _, output := range c.Outputs {
+		result = append(result, SensitiveStrings(output.Value)...)
+	}
+	for _, resource := range c.Resources {
+		result = append(result, attributesSensitiveStrings(resource.Attributes, resource.Blocks)...)
+	}
+	for _, provider := range c.Providers {
+		result = append(result, attributesSensitiveStrings(provider.Attributes, provider.Blocks)...)
+	}
+	return result
+}
+
+func attributesSensitiveStrings(attributes map[string]cty.Value, blocks []Block) []string {
+	var result []string
+	for _, value := range attributes {
+		result = append(result, SensitiveStrings(value)...)
+	}
+	for _, block := range blocks {
+		result = append(result, attributesSensitiveStrings(block.Attributes, block.Blocks)...)
+	}
+	return result
+}
+
+func blockSensitive(block Block) bool {
+	for _, value := range block.Attributes {
+		if IsSensitive(value) {
+			return true
+		}
+	}
+	for _, nested := range block.Blocks {
+		if blockSensitive(nested) {
+			return true
+		}
+	}
+	return false
+}
+
+func unmarkAttributes(attributes map[string]cty.Value) map[string]cty.Value {
+	if attributes == nil {
+		return nil
+	}
+	result := make(map[string]cty.Value, len(attributes))
+	for name, value := range attributes {
+		result[name], _ = value.UnmarkDeep()
+	}
+	return result
+}
+
+func unmarkBlocks(blocks []Block) []Block {
+	if blocks == nil {
+		return nil
+	}
+	result := make([]Block, len(blocks))
+	for i, block := range blocks {
+		block.Attributes = unmarkAttributes(block.Attributes)
+		block.Blocks = unmarkBlocks(block.Blocks)
+		result[i] = block
+	}
+	return result
+}
+
+func staticString(attr *hcl.Attribute) (string, error) {
+	if attr == nil {
+		return "", nil
+	}
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return "", fmt.Errorf("failed to evaluate %s: %w", attr.Name, diags)
+	}
+	if value.IsNull() || value.Type() != cty.String {
+		return "", fmt.Errorf("%s must be a string", attr.Name)
+	}
+	return value.AsString(), nil
+}
+
+func staticBool(attr *hcl.Attribute) (bool, error) {
+	if attr == nil {
+		return false, nil
+	}
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return false, fmt.Errorf("failed to evaluate %s: %w", attr.Name, diags)
+	}
+	if value.IsNull() || value.Type() != cty.Bool {
+		return false, fmt.Errorf("%s must be a bool", attr.Name)
+	}
+	return value.True(), nil
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,257 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// parseTestConfig парсит конфигурацию из строки
+func parseTestConfig(t *testing.T, src string, scope Scope) (*Config, error) {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "main.tf")
+	if err := os.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return ParseFile(filename, scope)
+}
+
+func TestResolveVariables(t *testing.T) {
+	const variables = `
+variable "image" {
+  default = "nginx:1.27"
+}
+
+variable "replicas" {
+  default = 1
+}
+
+variable "password" {
+  sensitive = true
+}
+
+resource "docker_container" "web" {
+  image = var.image
+  replicas = var.replicas
+  env = {
+    PASSWORD = var.password
+  }
+}
+`
+	tests := []struct {
+		name       string
+		values     map[string]string
+		env        map[string]string
+		allowUnset bool
+		image      cty.Value
+		replicas   cty.Value
+		password   cty.Value
+		err        string
+	}{
+		{
+			name:     "defaults and -var",
+			values:   map[string]string{"password": "hunter2"},
+			image:    cty.StringVal("nginx:1.27"),
+			replicas: cty.NumberIntVal(1),
+			password: cty.StringVal("hunter2"),
+		},
+		{
+			name:     "TF_VAR_ values are converted to the default type",
+			env:      map[string]string{"TF_VAR_password": "hunter2", "TF_VAR_replicas": "3", "TF_VAR_image": "nginx:1.28"},
+			image:    cty.StringVal("nginx:1.28"),
+			replicas: cty.NumberIntVal(3),
+			password: cty.StringVal("hunter2"),
+		},
+		{
+			name:     "-var is preferred to TF_VAR_",
+			values:   map[string]string{"password": "from-flag"},
+			env:      map[string]string{"TF_VAR_password": "from-env"},
+			image:    cty.StringVal("nginx:1.27"),
+			replicas: cty.NumberIntVal(1),
+			password: cty.StringVal("from-flag"),
+		},
+		{
+			name:       "unset variable is unknown when allowed",
+			allowUnset: true,
+			image:      cty.StringVal("nginx:1.27"),
+			replicas:   cty.NumberIntVal(1),
+			password:   cty.DynamicVal,
+		},
+		{name: "required variable", err: `no value for required variable "password": set it with -var password=VALUE or TF_VAR_password`},
+		{name: "undeclared variable", values: map[string]string{"password": "x", "tag": "v1"}, err: `value given for undeclared variable "tag"`},
+		{name: "value of the wrong type", values: map[string]string{"password": "x", "replicas": `"three"`}, err: `invalid value for variable "replicas"`},
+		{name: "invalid expression", values: map[string]string{"password": "x", "replicas": "1 +"}, err: `invalid value for variable "replicas"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, name := range []string{"image", "replicas", "password"} {
+				if value, exists := test.env[VariableEnvPrefix+name]; exists {
+					t.Setenv(VariableEnvPrefix+name, value)
+				} else {
+					t.Setenv(VariableEnvPrefix+name, "")
+					os.Unsetenv(VariableEnvPrefix + name)
+				}
+			}
+
+			cfg, err := parseTestConfig(t, variables, Scope{Variables: test.values, AllowUnsetVariables: test.allowUnset})
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			values := make(map[string]cty.Value)
+			for _, variable := range cfg.Variables {
+				values[variable.Name] = variable.Value
+			}
+			if !values["image"].RawEquals(test.image) || !values["replicas"].RawEquals(test.replicas) {
+				t.Errorf("image, replicas = %#v, %#v, want %#v, %#v", values["image"], values["replicas"], test.image, test.replicas)
+			}
+			if !values["password"].RawEquals(test.password.Mark(SensitiveMark)) {
+				t.Errorf("password = %#v, want %#v marked sensitive", values["password"], test.password)
+			}
+		})
+	}
+}
+
+func TestSensitiveMarks(t *testing.T) {
+	const src = `
+variable "password" {
+  sensitive = true
+}
+
+variable "user" {
+  default = "app"
+}
+
+resource "docker_container" "db" {
+  image = "postgres:16"
+  env = {
+    POSTGRES_USER     = var.user
+    POSTGRES_PASSWORD = var.password
+  }
+  command = ["--password=${var.password}"]
+
+  healthcheck {
+    test = ["pg_isready", "-U", var.user]
+  }
+
+  auth {
+    password = "${var.password}-auth"
+  }
+}
+
+output "user" {
+  value = var.user
+}
+
+output "dsn" {
+  value     = "postgres://${var.user}:${var.password}@db"
+  sensitive = true
+}
+`
+	cfg, err := parseTestConfig(t, src, Scope{Variables: map[string]string{"password": "hunter2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := cfg.Resources[0]
+	if got, want := db.SensitiveAttributes(), []string{"command", "env", "auth"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SensitiveAttributes() = %v, want %v", got, want)
+	}
+
+	unmarked := db.Unmarked()
+	for name, value := range unmarked.Attributes {
+		if IsSensitive(value) || value.ContainsMarked() {
+			t.Errorf("Unmarked() attribute %s is still marked", name)
+		}
+	}
+	if password := unmarked.Blocks[1].Attributes["password"]; password.ContainsMarked() || password.AsString() != "hunter2-auth" {
+		t.Errorf("Unmarked() auth.password = %#v, want hunter2-auth without marks", password)
+	}
+	if !IsSensitive(db.Attributes["env"]) {
+		t.Error("Unmarked() changed the original resource")
+	}
+
+	got := cfg.SensitiveStrings()
+	sort.Strings(got)
+	want := []string{"--password=hunter2", "hunter2", "hunter2", "hunter2-auth", "postgres://app:hunter2@db"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SensitiveStrings() = %q, want %q", got, want)
+	}
+
+	outputs := make(map[string]Output)
+	for _, output := range cfg.Outputs {
+		outputs[output.Name] = output
+	}
+	if IsSensitive(outputs["user"].Value) || !IsSensitive(outputs["dsn"].Value) {
+		t.Errorf("outputs = %#v, want only dsn sensitive", outputs)
+	}
+}
+
+func TestSensitiveOutputMustBeDeclared(t *testing.T) {
+	const src = `
+variable "password" {
+  sensitive = true
+  default   = "hunter2"
+}
+
+output "dsn" {
+  value = "postgres://app:${var.password}@db"
+}
+`
+	_, err := parseTestConfig(t, src, Scope{})
+	want := "output dsn refers to sensitive values: set sensitive = true"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("error = %v, want it to contain %q", err, want)
+	}
+}
+
+func TestSensitiveStrings(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     cty.Value
+		sensitive bool
+		want      []string
+	}{
+		{"unmarked", cty.StringVal("public"), false, nil},
+		{"marked string", cty.StringVal("hunter2").Mark(SensitiveMark), true, []string{"hunter2"}},
+		{"marked number", cty.NumberIntVal(4242).Mark(SensitiveMark), true, []string{"4242"}},
+		{"other mark", cty.StringVal("public").Mark("other"), false, nil},
+		{
+			"marked element of a map",
+			cty.MapVal(map[string]cty.Value{"USER": cty.StringVal("app"), "PASSWORD": cty.StringVal("hunter2").Mark(SensitiveMark)}),
+			true, []string{"hunter2"},
+		},
+		{
+			"marked object",
+			cty.ObjectVal(map[string]cty.Value{"a": cty.StringVal("x1"), "b": cty.ListVal([]cty.Value{cty.StringVal("y2")})}).Mark(SensitiveMark),
+			true, []string{"x1", "y2"},
+		},
+		{"null", cty.NullVal(cty.String).Mark(SensitiveMark), true, nil},
+		{"unknown", cty.UnknownVal(cty.String).Mark(SensitiveMark), true, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := SensitiveStrings(test.value)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("SensitiveStrings() = %q, want %q", got, test.want)
+			}
+			if got := IsSensitive(test.value); got != test.sensitive {
+				t.Errorf("IsSensitive() = %v, want %v", got, test.sensitive)
+			}
+		})
+	}
+}
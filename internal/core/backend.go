@@ -0,0 +1,271 @@
+// internal/core/backend.go
+package core
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/Artemka007/derraform/internal/backend"
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/errors"
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/Artemka007/derraform/internal/state"
+)
+
+// InitOptions флаги команды init
+type InitOptions struct {
+	// MigrateState копирует state из прежнего бэкенда в новый
+	MigrateState bool
+	// Reconfigure переключает бэкенд, не трогая state в прежнем
+	Reconfigure bool
+}
+
+// Init настраивает бэкенд state и устанавливает провайдеры-плагины
+func (e *Engine) Init(configFile string, options InitOptions) error {
+	if options.MigrateState && options.Reconfigure {
+		return errors.NewError(errors.CodeConfig, "-migrate-state and -reconfigure are mutually exclusive")
+	}
+
+	// init можно запускать и в каталоге без конфигурации
+	cfg, err := e.parseConfig(configFile, true)
+	if err != nil {
+		return err
+	}
+
+	if err := e.initBackend(cfg.Backend, options); err != nil {
+		return errors.WrapError(err, errors.CodeBackend, "Failed to initialize backend")
+	}
+
+	if err := e.installPlugins(); err != nil {
+		return err
+	}
+
+	e.logger.Info("Initialization completed!")
+	return nil
+}
+
+// parseConfig парсит конфигурацию для выбранного workspace.
+// С optional отсутствующий файл считается пустой конфигурацией.
+func (e *Engine) parseConfig(configFile string, optional bool) (*config.Config, error) {
+	workspace, err := backend.CurrentWorkspace()
+	if err != nil {
+		return nil, errors.WrapError(err, errors.CodeBackend, "Failed to determine the current workspace")
+	}
+	e.workspace = workspace
+
+	cfg, err := config.ParseFile(configFile, config.Scope{
+		Workspace:           workspace,
+		Variables:           e.options.Variables,
+		AllowUnsetVariables: optional,
+	})
+	if err != nil {
+		if !optional || !stderrors.Is(err, fs.ErrNotExist) {
+			return nil, errors.WrapError(err, errors.CodeConfig, "Failed to parse configuration file")
+		}
+		cfg = &config.Config{}
+	}
+
+	if err := e.configureEncryption(cfg); err != nil {
+		return nil, errors.WrapError(err, errors.CodeConfig, "Invalid state encryption")
+	}
+	e.unmarkSensitive(cfg)
+	return cfg, nil
+}
+
+// unmarkSensitive запоминает sensitive значения конфигурации, чтобы вырезать их из вывода,
+// и снимает с ресурсов и провайдеров пометки: настоящие значения получает только провайдер
+func (e *Engine) unmarkSensitive(cfg *config.Config) {
+	logging.AddSensitive(cfg.SensitiveStrings()...)
+
+	e.sensitiveAttributes = make(map[string][]string)
+	for i, resource := range cfg.Resources {
+		if names := resource.SensitiveAttributes(); len(names) > 0 {
+			e.sensitiveAttributes[resource.Type+"."+resource.Name] = names
+		}
+		cfg.Resources[i] = resource.Unmarked()
+	}
+	for i, provider := range cfg.Providers {
+		cfg.Providers[i] = provider.Unmarked()
+	}
+}
+
+// configureEncryption создает шифрование state по блоку encryption
+func (e *Engine) configureEncryption(cfg *config.Config) error {
+	encryption, err := backend.NewEncryption(cfg.Encryption)
+	if err != nil {
+		return err
+	}
+	if encryption != nil {
+		warned := false
+		encryption.OnFallback = func(key *state.Key) {
+			if !warned {
+				e.stateLogger.Warn("State was read with the fallback key (%s). It will be rewritten with the primary key on the next change, run state rekey to do it now.", key)
+				warned = true
+			}
+		}
+	}
+	e.encryption = encryption
+	return nil
+}
+
+// loadBackend парсит конфигурацию и подключает state выбранного workspace
+func (e *Engine) loadBackend(configFile string, optional bool) error {
+	cfg, err := e.parseConfig(configFile, optional)
+	if err != nil {
+		return err
+	}
+	e.config = cfg
+
+	if err := e.configureBackend(cfg); err != nil {
+		return errors.WrapError(err, errors.CodeBackend, "Failed to configure backend")
+	}
+	return nil
+}
+
+// configureBackend создает хранилище state по блоку backend.
+// Бэкенд, измененный после последнего init (или без init, если он не локальный), требует init.
+func (e *Engine) configureBackend(cfg *config.Config) error {
+	saved, err := backend.LoadSaved()
+	if err != nil {
+		return err
+	}
+	if !backend.Equal(saved, cfg.Backend) {
+		return fmt.Errorf("backend configuration changed since the last init: run init -migrate-state to copy the existing state or init -reconfigure to ignore it")
+	}
+
+	b, err := backend.New(cfg.Backend, e.workspace)
+	if err != nil {
+		return err
+	}
+	e.stateManager = state.NewStateManager(b, e.encryption)
+	e.stateLogger.Debug("Using state backend %s (workspace %s)", b, e.workspace)
+	return nil
+}
+
+// initBackend сравнивает бэкенд с тем, что был при прошлом init, и при необходимости
+// переносит state. Без сохраненных настроек прежним считается локальный terraform.tfstate.
+func (e *Engine) initBackend(current *config.Backend, options InitOptions) error {
+	saved, err := backend.LoadSaved()
+	if err != nil {
+		return err
+	}
+
+	to, err := backend.New(current, e.workspace)
+	if err != nil {
+		return err
+	}
+	e.stateManager = state.NewStateManager(to, e.encryption)
+
+	if backend.Equal(saved, current) {
+		e.logger.Info("Using state backend %s", to)
+		return backend.Save(current)
+	}
+
+	from, err := backend.New(saved, e.workspace)
+	if err != nil {
+		return fmt.Errorf("failed to open previous backend: %w", err)
+	}
+
+	switch {
+	case options.Reconfigure:
+		e.logger.Info("Switching state backend from %s to %s without migrating state", from, to)
+	case options.MigrateState:
+		if err := e.migrateWorkspaces(saved, current, from); err != nil {
+			return err
+		}
+	default:
+		previous, err := state.NewStateManager(from, e.encryption).Load()
+		if err != nil {
+			return fmt.Errorf("failed to read state from %s: %w", from, err)
+		}
+		if len(previous.Resources) > 0 {
+			return fmt.Errorf("backend changed from %s to %s and the previous backend has %d resources: run init -migrate-state to copy them or init -reconfigure to leave them behind",
+				from, to, len(previous.Resources))
+		}
+		e.logger.Info("Using state backend %s", to)
+	}
+
+	return backend.Save(current)
+}
+
+// migrateWorkspaces переносит state всех workspace прежнего бэкенда. Если прежний бэкенд
+// не умеет перечислять workspace, переносится только выбранный.
+func (e *Engine) migrateWorkspaces(fromConfig, toConfig *config.Backend, from state.Backend) error {
+	workspaces := []string{e.workspace}
+	if manager, ok := from.(state.WorkspaceManager); ok {
+		names, err := manager.Workspaces(context.Background())
+		switch {
+		case err == nil:
+			workspaces = names
+		case stderrors.Is(err, state.ErrWorkspacesNotListable):
+			e.logger.Warn("%v, only workspace %q will be migrated", err, e.workspace)
+		default:
+			return fmt.Errorf("failed to list workspaces of %s: %w", from, err)
+		}
+	}
+
+	for _, workspace := range workspaces {
+		source, err := backend.New(fromConfig, workspace)
+		if err != nil {
+			return err
+		}
+		destination, err := backend.New(toConfig, workspace)
+		if err != nil {
+			return err
+		}
+		if len(workspaces) > 1 {
+			e.logger.Info("Workspace %s:", workspace)
+		}
+		if err := e.migrateState(source, destination); err != nil {
+			return fmt.Errorf("workspace %s: %w", workspace, err)
+		}
+	}
+	return nil
+}
+
+// migrateState копирует state между бэкендами, удерживая блокировки обоих
+func (e *Engine) migrateState(from, to state.Backend) error {
+	e.logger.Info("Migrating state from %s to %s...", from, to)
+
+	source := state.NewStateManager(from, e.encryption)
+	destination := state.NewStateManager(to, e.encryption)
+	ctx := context.Background()
+
+	sourceLock, err := source.Lock(ctx, "migrate", e.options.LockTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to lock source state: %w", err)
+	}
+	defer sourceLock.Unlock()
+
+	destinationLock, err := destination.Lock(ctx, "migrate", e.options.LockTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to lock destination state: %w", err)
+	}
+	defer destinationLock.Unlock()
+
+	existing, err := source.Load()
+	if err != nil {
+		return fmt.Errorf("failed to read state from %s: %w", from, err)
+	}
+	if len(existing.Resources) == 0 && existing.Lineage == "" {
+		e.logger.Info("No state to migrate")
+		return nil
+	}
+
+	target, err := destination.Load()
+	if err != nil {
+		return fmt.Errorf("failed to read state from %s: %w", to, err)
+	}
+	if len(target.Resources) > 0 && target.Lineage != existing.Lineage {
+		return fmt.Errorf("%s already contains a different state with %d resources, refusing to overwrite it", to, len(target.Resources))
+	}
+
+	if err := destination.Push(existing, true); err != nil {
+		return fmt.Errorf("failed to write state to %s: %w", to, err)
+	}
+
+	e.logger.Info("Copied %d resources to %s", len(existing.Resources), to)
+	return nil
+}
@@ -4,297 +4,640 @@ package core
 import (
 	"context"
 	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
 
 	"github.com/Artemka007/derraform/internal/config"
 	"github.com/Artemka007/derraform/internal/errors"
 	"github.com/Artemka007/derraform/internal/logging"
-	"github.com/Artemka007/derraform/internal/providers/docker"
+	"github.com/Artemka007/derraform/internal/plugin"
+	"github.com/Artemka007/derraform/internal/providers"
 	"github.com/Artemka007/derraform/internal/state"
+	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/zclconf/go-cty/cty"
 )
 
+// Options настройки запуска движка, задаются флагами CLI
+type Options struct {
+	// Lock блокировать state на время plan, apply и destroy
+	Lock bool
+	// LockTimeout сколько ждать блокировку, занятую другим процессом
+	LockTimeout time.Duration
+	// Variables значения переменных из -var name=value
+	Variables map[string]string
+	// Logger общий лог, движок передает его провайдерам. nil - INFO в stdout.
+	Logger *logging.Logger
+	// Hooks получают события plan и apply, nil - события не нужны
+	Hooks Hooks
+}
+
 type Engine struct {
+	options      Options
 	config       *config.Config
 	stateManager *state.StateManager
-	dockerClient *docker.DockerClient
 	logger       *logging.Logger
+	// stateLogger и providerLogger логи подсистем state и provider со своими уровнями
+	stateLogger    *logging.Logger
+	providerLogger *logging.Logger
+	hooks          Hooks
+	// workspace выбранный workspace, определяет state и terraform.workspace
+	workspace string
+	// encryption шифрование state из блока terraform, nil - без шифрования
+	encryption *state.Encryption
+	// sensitiveAttributes атрибуты ресурсов, вычисленные из sensitive значений, ключ - адрес ресурса
+	sensitiveAttributes map[string][]string
+
+	// Блоки provider и созданные по ним провайдеры, ключ - адрес провайдера (docker, docker.edge)
+	providerConfigs   map[string]config.Provider
+	providerInstances map[string]providers.Provider
+
+	// Установленные провайдеры-плагины из lock файла
+	lockFile *plugin.LockFile
+
+	// stopping выставляется Stop: новые ресурсы не начинаются, начатые доводятся до конца
+	stopping atomic.Bool
 }
 
-func NewEngine() (*Engine, error) {
-	// Создаем Docker клиент
-	dockerClient, err := docker.NewDockerClient()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Docker client: %w", err)
-	}
+func NewEngine(options Options) (*Engine, error) {
+	// Провайдеры создаются лениво, при первом обращении к ним
 
-	// Создаем state manager
-	stateManager := state.NewStateManager("terraform.tfstate")
+	// State по умолчанию хранится локально, блок backend заменяет хранилище при загрузке конфигурации
+	stateManager := state.NewStateManager(state.NewLocalBackend(state.DefaultStateFile), nil)
 
-	// Создаем логгер
-	logger := logging.NewLogger(logging.INFO)
+	logger := options.Logger
+	if logger == nil {
+		logger = logging.NewLogger(logging.INFO)
+	}
+	hooks := options.Hooks
+	if hooks == nil {
+		hooks = noHooks{}
+	}
 
 	return &Engine{
-		stateManager: stateManager,
-		dockerClient: dockerClient,
-		logger:       logger,
+		options:           options,
+		stateManager:      stateManager,
+		logger:            logger.Named(logging.SubsystemCore),
+		stateLogger:       logger.Named(logging.SubsystemState),
+		providerLogger:    logger.Named(logging.SubsystemProvider),
+		hooks:             hooks,
+		providerConfigs:   make(map[string]config.Provider),
+		providerInstances: make(map[string]providers.Provider),
 	}, nil
 }
 
-func (e *Engine) Apply(configFile string) error {
+// ApplyOptions флаги команды apply
+type ApplyOptions struct {
+	// Replace адреса ресурсов, которые нужно пересоздать, даже если они не изменились
+	Replace []string
+	// Targets адреса из -target: применяются только они и их зависимости
+	Targets []string
+}
+
+// Stop просит движок остановиться: операции, которые уже выполняются, доводятся
+// до конца и сохраняются в state, новые не начинаются. Можно вызывать из другой горутины,
+// например из обработчика сигнала. Отмена контекста прерывает и начатые операции.
+func (e *Engine) Stop() {
+	e.stopping.Store(true)
+}
+
+// stopRequested сообщает, что новые операции начинать нельзя
+func (e *Engine) stopRequested(ctx context.Context) bool {
+	return e.stopping.Load() || ctx.Err() != nil
+}
+
+func (e *Engine) Apply(ctx context.Context, configFile string, options ApplyOptions) error {
 	e.logger.Info("Starting deployment...")
+	defer e.closeProviders()
 
 	// Парсим конфигурацию
-	cfg, err := config.ParseFile(configFile)
+	if err := e.loadConfig(configFile); err != nil {
+		return err
+	}
+
+	unlock, err := e.lockState(ctx, "apply")
 	if err != nil {
-		return errors.WrapError(err, "CONFIG_ERROR", "Failed to parse configuration file")
+		return err
 	}
-	e.config = cfg
+	defer unlock()
 
-	e.logger.Info("Found %d resources to process", len(cfg.Resources))
+	graph, resources, err := e.configGraph()
+	if err != nil {
+		return err
+	}
+	replace, err := e.replaceAddresses(options.Replace)
+	if err != nil {
+		return err
+	}
+	targets, err := e.targetSet(graph, options.Targets, false)
+	if err != nil {
+		return err
+	}
 
-	// Применяем каждый ресурс
-	for _, resource := range cfg.Resources {
-		resourceID := fmt.Sprintf("%s.%s", resource.Type, resource.Name)
-		e.logger.Info("Processing resource: %s", resourceID)
+	// Объекты из блоков import попадают в state до планирования изменений
+	if err := e.applyImports(ctx, targets); err != nil {
+		return err
+	}
+	e.logger.Info("Found %d resources to process", len(resources))
+
+	// Ошибка ресурса останавливает только зависящие от него ресурсы,
+	// независимые продолжают применяться
+	var failures []error
+	summary := ChangeSummary{Operation: "apply"}
+	failed := make(map[string]bool)
+	skipped, processed, notStarted := 0, 0, 0
+	for _, resourceID := range graph.order {
+		if !targeted(targets, resourceID) {
+			continue
+		}
+		if e.stopRequested(ctx) {
+			notStarted++
+			continue
+		}
+		resource := resources[resourceID]
+		processed++
+
+		if dependency := firstIn(graph.dependencies[resourceID], failed); dependency != "" {
+			e.logger.Warn("Skipping %s: its dependency %s was not applied", resourceID, dependency)
+			failed[resourceID] = true
+			skipped++
+			continue
+		}
 
-		if err := e.applyResource(resource); err != nil {
-			e.logger.Error("Failed to apply resource %s: %v", resourceID, err)
-			return errors.ResourceError(resourceID, "Failed to apply resource", err)
+		resourceLog := e.logger.With("address", resourceID).With("operation", "apply")
+		started := time.Now()
+		resourceLog.Info("Processing resource: %s", resourceID)
+		action, err := e.applyResource(ctx, resource, graph.dependencies[resourceID], replace[resourceID])
+		if err != nil {
+			resourceLog.With("duration", time.Since(started)).Error("Failed to apply resource %s: %v", resourceID, err)
+			failures = append(failures, resourceError(resource, "Failed to apply resource", err, graph.dependencies[resourceID]))
+			failed[resourceID] = true
+			continue
 		}
 
-		e.logger.Info("Resource %s applied successfully", resourceID)
+		summary.count(action)
+		resourceLog.With("duration", time.Since(started)).Info("Resource %s applied successfully", resourceID)
+	}
+
+	if notStarted > 0 {
+		return errors.WrapError(errors.Join(failures...), errors.CodeInterrupted, fmt.Sprintf("Apply interrupted: %d resources applied, %d failed, %d not started; state of the processed ones is saved",
+			processed-len(failed), len(failed), notStarted))
+	}
+	if len(failures) > 0 {
+		return errors.WrapError(errors.Join(failures...), errors.CodeApply, fmt.Sprintf("Apply failed: %d resources failed, %d skipped, %d applied",
+			len(failed)-skipped, skipped, processed-len(failed)))
+	}
+	if targets != nil {
+		e.logger.Warn("Applied only the targeted resources: run apply without -target to bring the rest of the configuration up to date")
 	}
 
 	e.logger.Info("Deployment completed successfully!")
+	e.hooks.ChangeSummary(summary)
+	e.printOutputs()
 	return nil
 }
 
-func (e *Engine) applyResource(resource config.Resource) error {
-	switch resource.Type {
-	case "docker_container":
-		return e.applyDockerContainer(resource)
-	case "docker_network":
-		return e.applyDockerNetwork(resource)
-	case "docker_volume":
-		return e.applyDockerVolume(resource)
-	case "docker_image":
-		return e.applyDockerImage(resource)
-	default:
-		return errors.NewError("UNKNOWN_RESOURCE",
-			fmt.Sprintf("Unknown resource type: %s", resource.Type))
+// configGraph строит граф ресурсов конфигурации по depends_on
+func (e *Engine) configGraph() (*resourceGraph, map[string]config.Resource, error) {
+	resources := make(map[string]config.Resource, len(e.config.Resources))
+	addresses := make([]string, 0, len(e.config.Resources))
+	dependencies := make(map[string][]string)
+	for _, resource := range e.config.Resources {
+		address := resource.Type + "." + resource.Name
+		resources[address] = resource
+		addresses = append(addresses, address)
+		dependencies[address] = resource.DependsOn
 	}
-}
-
-// applyDockerContainer применяет конфигурацию Docker контейнера
-func (e *Engine) applyDockerContainer(resource config.Resource) error {
-	e.logger.Debug("Applying Docker container: %s", resource.Name)
 
-	// Преобразуем атрибуты в Docker конфиг
-	containerConfig, err := e.resourceToContainerConfig(resource)
+	graph, err := newResourceGraph(addresses, dependencies)
 	if err != nil {
-		return fmt.Errorf("failed to parse container config: %w", err)
+		return nil, nil, errors.WrapError(err, errors.CodeConfig, "Invalid depends_on")
 	}
+	return graph, resources, nil
+}
 
-	// Создаем контейнер
-	containerID, err := e.dockerClient.CreateContainer(context.Background(), containerConfig)
-	if err != nil {
-		return fmt.Errorf("failed to create container: %w", err)
+// printOutputs показывает значения блоков output, sensitive значения скрываются
+func (e *Engine) printOutputs() {
+	e.hooks.Outputs(e.config.Outputs)
+	if len(e.config.Outputs) == 0 {
+		return
 	}
 
-	// Сохраняем состояние
-	if err := e.stateManager.SaveResourceState(resource.Type, resource.Name, map[string]interface{}{
-		"id":    containerID,
-		"name":  containerConfig.Name,
-		"image": containerConfig.Image,
-	}); err != nil {
-		return fmt.Errorf("failed to save state: %w", err)
+	e.logger.Info("Outputs:")
+	for _, output := range e.config.Outputs {
+		e.logger.Info("  %s = %s", output.Name, formatValue(output.Value))
 	}
-
-	e.logger.Info("Docker container %s applied successfully", resource.Name)
-	return nil
 }
 
-// applyDockerNetwork применяет конфигурацию Docker сети
-func (e *Engine) applyDockerNetwork(resource config.Resource) error {
-	e.logger.Debug("Applying Docker network: %s", resource.Name)
-
-	// Преобразуем атрибуты в сетевой конфиг
-	networkConfig, err := e.resourceToNetworkConfig(resource)
-	if err != nil {
-		return fmt.Errorf("failed to parse network config: %w", err)
+// formatValue показывает значение в синтаксисе HCL или (sensitive value)
+func formatValue(value cty.Value) string {
+	if config.IsSensitive(value) {
+		return logging.SensitivePlaceholder
 	}
-
-	// Создаем сеть
-	networkID, err := e.dockerClient.CreateNetwork(context.Background(), networkConfig)
-	if err != nil {
-		return fmt.Errorf("failed to create network: %w", err)
+	if !value.IsWhollyKnown() {
+		return "(known after apply)"
 	}
+	return string(hclwrite.TokensForValue(value).Bytes())
+}
 
-	// Сохраняем состояние
-	if err := e.stateManager.SaveResourceState(resource.Type, resource.Name, map[string]interface{}{
-		"id":   networkID,
-		"name": networkConfig.Name,
-	}); err != nil {
-		return fmt.Errorf("failed to save state: %w", err)
+// loadConfig парсит конфигурацию и запоминает настройки провайдеров
+func (e *Engine) loadConfig(configFile string) error {
+	if err := e.loadBackend(configFile, false); err != nil {
+		return err
 	}
 
-	e.logger.Info("Docker network %s applied successfully", resource.Name)
+	if err := e.configureProviders(e.config); err != nil {
+		return errors.WrapError(err, errors.CodeConfig, "Failed to configure providers")
+	}
 	return nil
 }
 
-// applyDockerVolume применяет конфигурацию Docker тома
-func (e *Engine) applyDockerVolume(resource config.Resource) error {
-	e.logger.Debug("Applying Docker volume: %s", resource.Name)
+// resourceError ошибка ресурса с местом его объявления в конфигурации и зависимостями
+func resourceError(resource config.Resource, message string, cause error, dependsOn []string) *errors.TerraformError {
+	err := errors.ResourceError(resource.Type+"."+resource.Name, message, cause)
+	if resource.DeclRange.Filename != "" {
+		err.Subject = resource.DeclRange.Ptr()
+	}
+	err.DependsOn = dependsOn
+	return err
+}
 
-	// Для томов пока просто логируем
-	e.logger.Info("Volume support will be implemented later: %s", resource.Name)
-	return nil
+// replaceAddresses проверяет адреса из -replace: каждый должен быть ресурсом конфигурации
+func (e *Engine) replaceAddresses(addresses []string) (map[string]bool, error) {
+	declared := make(map[string]bool, len(e.config.Resources))
+	for _, resource := range e.config.Resources {
+		declared[resource.Type+"."+resource.Name] = true
+	}
+
+	replace := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		parsed, err := state.ParseAddress(address)
+		if err != nil {
+			return nil, errors.WrapError(err, errors.CodeConfig, "Invalid -replace address")
+		}
+		if !declared[parsed.String()] {
+			return nil, errors.NewError(errors.CodeConfig, fmt.Sprintf("Invalid -replace address: no resource %s in configuration", parsed))
+		}
+		replace[parsed.String()] = true
+	}
+	return replace, nil
 }
 
-// applyDockerImage применяет конфигурацию Docker образа
-func (e *Engine) applyDockerImage(resource config.Resource) error {
-	e.logger.Debug("Applying Docker image: %s", resource.Name)
+// planResource планирует изменения ресурса. Tainted объект и объект из -replace
+// всегда пересоздаются.
+func (e *Engine) planResource(ctx context.Context, impl providers.Resource, resource config.Resource, prior *state.ResourceState, replace bool) (*providers.PlannedChange, error) {
+	var change *providers.PlannedChange
+	switch {
+	case prior != nil && prior.Tainted:
+		change = &providers.PlannedChange{Action: providers.ActionReplace, Reason: "tainted"}
+	case prior != nil && replace:
+		change = &providers.PlannedChange{Action: providers.ActionReplace, Reason: "requested with -replace"}
+	default:
+		var err error
+		if change, err = impl.Plan(ctx, resource, prior); err != nil {
+			return nil, err
+		}
+	}
 
-	// Для образов пока просто логируем
-	e.logger.Info("Image support will be implemented later: %s", resource.Name)
-	return nil
+	if change.Action == providers.ActionReplace && resource.Lifecycle.CreateBeforeDestroy {
+		if name, value := conflictingUniqueAttribute(impl.Schema(), resource, prior); name != "" {
+			return nil, errors.NewError(errors.CodeConfig, fmt.Sprintf(
+				"create_before_destroy cannot replace %s.%s: the replacement would have the same %s %q as the object it replaces, and %s must be unique. "+
+					"Change %s together with the replacement or remove create_before_destroy",
+				resource.Type, resource.Name, name, value, name, name))
+		}
+	}
+	return change, nil
 }
 
-func (e *Engine) resourceToContainerConfig(resource config.Resource) (*docker.ContainerConfig, error) {
-	config := &docker.ContainerConfig{
-		Name: resource.Name,
+// conflictingUniqueAttribute возвращает уникальный атрибут, значение которого у замены
+// совпадет со значением старого объекта. Незаданный атрибут получит то же значение
+// по умолчанию, что и старый объект.
+func conflictingUniqueAttribute(schema providers.Schema, resource config.Resource, prior *state.ResourceState) (string, string) {
+	for _, name := range sortedKeys(schema.Attributes) {
+		if !schema.Attributes[name].Unique {
+			continue
+		}
+		current, _ := prior.Attributes[name].(string)
+		value, exists := resource.Attributes[name]
+		if !exists || value.IsNull() {
+			return name, current
+		}
+		value, _ = value.Unmark()
+		if value.IsKnown() && value.Type() == cty.String && value.AsString() == current {
+			return name, current
+		}
 	}
+	return "", ""
+}
 
-	// Извлекаем image (обязательный атрибут)
-	imageVal, exists := resource.Attributes["image"]
-	if !exists {
-		return nil, fmt.Errorf("missing required attribute 'image'")
+// applyResource планирует и применяет изменения одного ресурса, возвращает выполненное действие
+func (e *Engine) applyResource(ctx context.Context, resource config.Resource, dependencies []string, replace bool) (providers.Action, error) {
+	impl, err := e.resourceFor(ctx, resource)
+	if err != nil {
+		return providers.ActionNoop, err
 	}
 
-	if imageVal.Type() == cty.String {
-		config.Image = imageVal.AsString()
-	} else {
-		return nil, fmt.Errorf("attribute 'image' must be a string")
+	prior, err := e.priorState(ctx, impl, resource)
+	if err != nil {
+		return providers.ActionNoop, err
 	}
 
-	// Обрабатываем порты
-	if portsVal, exists := resource.Attributes["ports"]; exists {
-		if portsVal.Type().IsObjectType() || portsVal.Type().IsMapType() {
-			config.Ports = make(map[string]string)
-			portsMap := portsVal.AsValueMap()
+	change, err := e.planResource(ctx, impl, resource, prior, replace)
+	if err != nil {
+		return providers.ActionNoop, fmt.Errorf("failed to plan changes: %w", err)
+	}
+	if change.Action == providers.ActionNoop {
+		e.logger.Info("Resource %s.%s is up to date", resource.Type, resource.Name)
+		return change.Action, nil
+	}
 
-			for key, value := range portsMap {
-				if value.Type() == cty.String {
-					config.Ports[key] = value.AsString()
-				}
-			}
-		}
+	address := resource.Type + "." + resource.Name
+	e.hooks.PlannedChange(address, change)
+	e.hooks.ApplyStart(address, change.Action)
+	if err := e.applyChange(ctx, impl, resource, dependencies, prior, change); err != nil {
+		e.hooks.ApplyErrored(address, change.Action, err)
+		return change.Action, err
 	}
 
-	// Обрабатываем environment variables
-	if envVal, exists := resource.Attributes["env"]; exists {
-		if envVal.Type().IsObjectType() || envVal.Type().IsMapType() {
-			config.Env = make(map[string]string)
-			envMap := envVal.AsValueMap()
+	id := ""
+	if current, err := e.stateManager.GetResourceState(resource.Type, resource.Name); err == nil && current != nil {
+		id = current.ID
+	}
+	e.hooks.ApplyComplete(address, change.Action, id)
+	return change.Action, nil
+}
 
-			for key, value := range envMap {
-				if value.Type() == cty.String {
-					config.Env[key] = value.AsString()
-				}
-			}
+// applyChange выполняет запланированное изменение объекта и записывает результат в state
+func (e *Engine) applyChange(ctx context.Context, impl providers.Resource, resource config.Resource, dependencies []string, prior *state.ResourceState, change *providers.PlannedChange) error {
+	switch change.Action {
+	case providers.ActionReplace:
+		e.logger.Info("Replacing %s.%s: %s", resource.Type, resource.Name, change.Reason)
+		if resource.Lifecycle.CreateBeforeDestroy {
+			return e.createBeforeDestroy(ctx, impl, resource, dependencies, prior, change)
+		}
+		if err := deleteObject(ctx, impl, prior, resource.Timeouts); err != nil {
+			return fmt.Errorf("failed to replace resource: %w", err)
+		}
+		if err := e.stateManager.RemoveResourceState(resource.Type, resource.Name); err != nil {
+			return fmt.Errorf("failed to save state: %w", err)
 		}
+		prior = nil
 	}
 
-	// Обрабатываем сети
-	if networksVal, exists := resource.Attributes["networks"]; exists {
-		if networksVal.Type().IsListType() || networksVal.Type().IsTupleType() {
-			networksList := networksVal.AsValueSlice()
-			config.Networks = make([]string, len(networksList))
-
-			for i, netVal := range networksList {
-				if netVal.Type() == cty.String {
-					config.Networks[i] = netVal.AsString()
-				}
-			}
+	// Состояние сохраняем даже при ошибке, если объект уже создан: он помечается
+	// tainted и пересоздается следующим apply, а не теряется из state
+	newState, applyErr := applyObject(ctx, impl, resource, prior, change)
+	if newState != nil {
+		if applyErr != nil {
+			e.logger.Warn("%s.%s was created only partially and is marked as tainted", resource.Type, resource.Name)
+		}
+		if err := e.saveResourceState(impl, resource, dependencies, newState, applyErr != nil); err != nil {
+			return err
 		}
 	}
 
-	// Обрабатываем команду
-	if commandVal, exists := resource.Attributes["command"]; exists {
-		if commandVal.Type().IsListType() {
-			commandList := commandVal.AsValueSlice()
-			config.Command = make([]string, len(commandList))
+	return applyErr
+}
 
-			for i, cmdVal := range commandList {
-				if cmdVal.Type() == cty.String {
-					config.Command[i] = cmdVal.AsString()
-				}
+// createBeforeDestroy заменяет объект с lifecycle { create_before_destroy = true }:
+// старый объект удаляется только после того, как новый создан
+func (e *Engine) createBeforeDestroy(ctx context.Context, impl providers.Resource, resource config.Resource, dependencies []string, prior *state.ResourceState, change *providers.PlannedChange) error {
+	resourceID := resource.Type + "." + resource.Name
+
+	newState, applyErr := applyObject(ctx, impl, resource, nil, change)
+	if applyErr != nil {
+		// Старый объект остается в state, частично созданный новый удаляем
+		if newState != nil {
+			if err := deleteObject(ctx, impl, newState, resource.Timeouts); err != nil {
+				e.logger.Warn("Failed to remove partially created replacement for %s (id %s): %v", resourceID, newState.ID, err)
 			}
 		}
+		return applyErr
 	}
 
-	return config, nil
-}
-
-// resourceToNetworkConfig преобразует Resource в Docker NetworkConfig
-func (e *Engine) resourceToNetworkConfig(resource config.Resource) (*docker.NetworkConfig, error) {
-	config := &docker.NetworkConfig{
-		Name:   resource.Name,
-		Driver: "bridge", // Значение по умолчанию
+	if err := e.saveResourceState(impl, resource, dependencies, newState, false); err != nil {
+		return err
+	}
+	if err := deleteObject(ctx, impl, prior, resource.Timeouts); err != nil {
+		return fmt.Errorf("%s was replaced, but the previous object %s was not destroyed: %w", resourceID, prior.ID, err)
 	}
+	return nil
+}
 
-	// Извлекаем driver если есть
-	if driverVal, exists := resource.Attributes["driver"]; exists {
-		if driverVal.Type() == cty.String {
-			config.Driver = driverVal.AsString()
-		}
+// saveResourceState дополняет состояние ресурса данными движка и записывает его в state
+func (e *Engine) saveResourceState(impl providers.Resource, resource config.Resource, dependencies []string, newState *state.ResourceState, tainted bool) error {
+	newState.Provider = providerAddress(resource)
+	newState.SchemaVersion = impl.Schema().Version
+	newState.SensitiveAttributes = e.resourceSensitiveAttributes(impl, resource)
+	newState.Dependencies = dependencies
+	newState.Tainted = tainted
+	if err := e.stateManager.SaveResourceState(resource.Type, resource.Name, newState); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
 	}
+	return nil
+}
 
-	return config, nil
+// PlanOptions флаги команды plan
+type PlanOptions struct {
+	// GenerateConfigOut файл для HCL объектов из блоков import, у которых нет блока resource
+	GenerateConfigOut string
+	// Replace адреса ресурсов, которые нужно пересоздать, даже если они не изменились
+	Replace []string
+	// Targets адреса из -target: планируются только они и их зависимости
+	Targets []string
 }
 
 // Plan показывает план изменений
-func (e *Engine) Plan(configFile string) error {
+func (e *Engine) Plan(ctx context.Context, configFile string, options PlanOptions) error {
 	e.logger.Info("Generating execution plan...")
+	defer e.closeProviders()
+
+	if err := e.loadConfig(configFile); err != nil {
+		return err
+	}
 
-	cfg, err := config.ParseFile(configFile)
+	unlock, err := e.lockState(ctx, "plan")
 	if err != nil {
 		return err
 	}
+	defer unlock()
+
+	counts := make(map[providers.Action]int)
+
+	graph, resources, err := e.configGraph()
+	if err != nil {
+		return err
+	}
+	replace, err := e.replaceAddresses(options.Replace)
+	if err != nil {
+		return err
+	}
+	targets, err := e.targetSet(graph, options.Targets, false)
+	if err != nil {
+		return err
+	}
+
+	pending, err := e.pendingImports(targets)
+	if err != nil {
+		return err
+	}
+	imports := 0
 
 	e.logger.Info("Plan:")
-	for _, resource := range cfg.Resources {
-		e.logger.Info("  + create %s.%s", resource.Type, resource.Name)
+	for _, resourceID := range graph.order {
+		if !targeted(targets, resourceID) {
+			continue
+		}
+		if e.stopRequested(ctx) {
+			return errors.NewError(errors.CodeInterrupted, "Plan interrupted")
+		}
+		resource := resources[resourceID]
+
+		impl, err := e.resourceFor(ctx, resource)
+		if err != nil {
+			return resourceError(resource, "Failed to plan resource", err, graph.dependencies[resourceID])
+		}
+
+		var prior *state.ResourceState
+		if imp, exists := pending[resourceID]; exists {
+			// Импортированный объект только читается: в state его запишет apply
+			if _, prior, err = e.importResource(ctx, imp); err != nil {
+				return resourceError(resource, "Failed to import resource", err, graph.dependencies[resourceID])
+			}
+			e.logger.Info("  <= import %s (id %s)", resourceID, imp.id)
+			imports++
+		} else if prior, err = e.priorState(ctx, impl, resource); err != nil {
+			return resourceError(resource, "Failed to plan resource", err, graph.dependencies[resourceID])
+		}
+
+		change, err := e.planResource(ctx, impl, resource, prior, replace[resourceID])
+		if err != nil {
+			return resourceError(resource, "Failed to plan resource", err, graph.dependencies[resourceID])
+		}
+		counts[change.Action]++
+		if change.Action != providers.ActionNoop {
+			e.hooks.PlannedChange(resourceID, change)
+		}
+
+		switch change.Action {
+		case providers.ActionCreate:
+			e.logger.Info("  + create %s", resourceID)
+		case providers.ActionUpdate:
+			e.logger.Info("  ~ update %s (%s)", resourceID, change.Reason)
+		case providers.ActionReplace:
+			// +/- новый объект создается раньше, чем удаляется старый
+			symbol := "-/+"
+			if resource.Lifecycle.CreateBeforeDestroy {
+				symbol = "+/-"
+			}
+			e.logger.Info("  %s replace %s (%s)", symbol, resourceID, change.Reason)
+		}
+	}
+
+	generated, err := e.planGeneratedImports(ctx, pending, options)
+	if err != nil {
+		return err
 	}
+	imports += generated
 
-	e.logger.Info("This plan would create %d resources.", len(cfg.Resources))
+	summary := fmt.Sprintf("Plan: %d to add, %d to change, %d to replace.",
+		counts[providers.ActionCreate], counts[providers.ActionUpdate], counts[providers.ActionReplace])
+	if imports > 0 {
+		summary = fmt.Sprintf("Plan: %d to import, %d to add, %d to change, %d to replace.",
+			imports, counts[providers.ActionCreate], counts[providers.ActionUpdate], counts[providers.ActionReplace])
+	}
+	e.logger.Info("%s", summary)
+	e.hooks.ChangeSummary(ChangeSummary{
+		Operation: "plan",
+		Add:       counts[providers.ActionCreate] + counts[providers.ActionReplace],
+		Change:    counts[providers.ActionUpdate],
+		Remove:    counts[providers.ActionReplace],
+		Import:    imports,
+	})
 	return nil
 }
 
-// Destroy удаляет все ресурсы
-func (e *Engine) Destroy() error {
+// DestroyOptions флаги команды destroy
+type DestroyOptions struct {
+	// Targets адреса из -target: удаляются только они и зависящие от них ресурсы
+	Targets []string
+}
+
+// Destroy удаляет все ресурсы. Конфигурация нужна только для настроек провайдеров.
+func (e *Engine) Destroy(ctx context.Context, configFile string, options DestroyOptions) error {
 	e.logger.Info("Destroying all resources...")
+	defer e.closeProviders()
+
+	if err := e.loadConfig(configFile); err != nil {
+		return err
+	}
 
-	// Загружаем состояние
-	state, err := e.stateManager.Load()
+	unlock, err := e.lockState(ctx, "destroy")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := e.stateManager.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load state: %w", err)
 	}
 
-	// Удаляем ресурсы в обратном порядке
-	for resourceID, resourceState := range state.Resources {
-		e.logger.Info("Destroying resource: %s", resourceID)
+	graph, err := stateGraph(current)
+	if err != nil {
+		return errors.WrapError(err, errors.CodeState, "Invalid dependencies in state")
+	}
+	targets, err := e.targetSet(graph, options.Targets, true)
+	if err != nil {
+		return err
+	}
+
+	var failures []error
+	// remaining ресурсы, которые остались существовать: от них нельзя удалять зависимости
+	remaining := make(map[string]bool)
+	notStarted := 0
 
-		switch resourceState.Type {
-		case "docker_container":
-			if err := e.dockerClient.DestroyContainer(context.Background(), resourceState.ID); err != nil {
-				e.logger.Error("Failed to destroy container %s: %v", resourceID, err)
-			}
-		case "docker_network":
-			if err := e.dockerClient.DestroyNetwork(context.Background(), resourceState.ID); err != nil {
-				e.logger.Error("Failed to destroy network %s: %v", resourceID, err)
-			}
+	// Зависимые ресурсы удаляются раньше своих зависимостей
+	for _, resourceID := range graph.reverseOrder() {
+		if !targeted(targets, resourceID) {
+			continue
 		}
+		if e.stopRequested(ctx) {
+			remaining[resourceID] = true
+			notStarted++
+			continue
+		}
+		if dependent := firstIn(graph.dependents[resourceID], remaining); dependent != "" {
+			e.logger.Warn("Skipping %s: %s, which depends on it, was not destroyed", resourceID, dependent)
+			remaining[resourceID] = true
+			continue
+		}
+
+		resourceLog := e.logger.With("address", resourceID).With("operation", "destroy")
+		started := time.Now()
+		resourceLog.Info("Destroying resource: %s", resourceID)
+		if err := e.destroyResource(ctx, resourceID, current.Resources[resourceID]); err != nil {
+			resourceLog.With("duration", time.Since(started)).Error("Failed to destroy resource %s: %v", resourceID, err)
+			destroyErr := errors.ResourceError(resourceID, "Failed to destroy resource", err)
+			destroyErr.DependsOn = graph.dependencies[resourceID]
+			failures = append(failures, destroyErr)
+			remaining[resourceID] = true
+			continue
+		}
+		resourceLog.With("duration", time.Since(started)).Debug("Destroyed resource %s", resourceID)
+	}
+
+	if notStarted > 0 {
+		return errors.WrapError(errors.Join(failures...), errors.CodeInterrupted, fmt.Sprintf("Destroy interrupted: %d resources remain in state", len(remaining)))
+	}
+	if len(failures) > 0 {
+		return errors.WrapError(errors.Join(failures...), errors.CodeDestroy, fmt.Sprintf("Destroy failed: %d resources remain in state", len(remaining)))
+	}
+
+	if targets != nil {
+		e.logger.Info("Destruction of the targeted resources completed!")
+		return nil
 	}
 
 	// Очищаем состояние
@@ -305,3 +648,87 @@ func (e *Engine) Destroy() error {
 	e.logger.Info("Destruction completed!")
 	return nil
 }
+
+// destroyResource удаляет объект и сразу убирает его из state, чтобы при
+// ошибке на следующем ресурсе state соответствовал действительности
+func (e *Engine) destroyResource(ctx context.Context, resourceID string, resourceState state.ResourceState) error {
+	impl, err := e.resourceForState(ctx, resourceState)
+	if err != nil {
+		return err
+	}
+	if err := upgradeResourceState(ctx, impl, &resourceState); err != nil {
+		return err
+	}
+	if err := deleteObject(ctx, impl, &resourceState, e.resourceTimeouts(resourceID)); err != nil {
+		return err
+	}
+	if err := e.stateManager.RemoveResource(resourceID); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	return nil
+}
+
+// stateGraph строит граф записей state по сохраненным зависимостям.
+// Зависимости, которых уже нет в state, не учитываются.
+func stateGraph(current *state.State) (*resourceGraph, error) {
+	addresses := make([]string, 0, len(current.Resources))
+	for address := range current.Resources {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	dependencies := make(map[string][]string)
+	for address, resourceState := range current.Resources {
+		for _, dependency := range resourceState.Dependencies {
+			if _, exists := current.Resources[dependency]; exists {
+				dependencies[address] = append(dependencies[address], dependency)
+			}
+		}
+	}
+	return newResourceGraph(addresses, dependencies)
+}
+
+// lockState блокирует state на время операции и возвращает функцию разблокировки
+func (e *Engine) lockState(ctx context.Context, operation string) (func(), error) {
+	if !e.options.Lock {
+		return func() {}, nil
+	}
+
+	if e.options.LockTimeout > 0 {
+		e.stateLogger.Info("Acquiring state lock (timeout %s)...", e.options.LockTimeout)
+	}
+	lock, err := e.stateManager.Lock(ctx, operation, e.options.LockTimeout)
+	if err != nil {
+		return nil, errors.WrapError(err, errors.CodeStateLock, "Failed to acquire state lock")
+	}
+	if lock.Stale != nil {
+		e.stateLogger.Warn("Found a state lock left by a process that exited without unlocking (%s). It has been replaced.", lock.Stale)
+	}
+	e.stateLogger.Debug("Acquired state lock %s", lock.Info.ID)
+
+	return func() {
+		if err := lock.Unlock(); err != nil {
+			e.stateLogger.Error("Failed to release state lock %s: %v", lock.Info.ID, err)
+		}
+	}, nil
+}
+
+// ForceUnlock снимает блокировку state, оставшуюся после другого процесса
+func (e *Engine) ForceUnlock(configFile, lockID string) error {
+	if err := e.loadConfig(configFile); err != nil {
+		return err
+	}
+	if err := e.stateManager.ForceUnlock(lockID); err != nil {
+		return errors.WrapError(err, errors.CodeStateLock, "Failed to unlock state")
+	}
+	e.logger.Info("State has been successfully unlocked!")
+	return nil
+}
+
+// LockInfo возвращает текущую блокировку state или nil
+func (e *Engine) LockInfo(configFile string) (*state.LockInfo, error) {
+	if err := e.loadConfig(configFile); err != nil {
+		return nil, err
+	}
+	return e.stateManager.LockInfo()
+}
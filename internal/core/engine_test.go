@@ -0,0 +1,231 @@
+package core
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/errors"
+	"github.com/Artemka007/derraform/internal/state"
+)
+
+// chainConfig две ветки: app <- db <- web и независимый cache
+const chainConfig = `
+resource "docker_network" "app" {}
+
+resource "docker_container" "db" {
+  image      = "postgres:16"
+  depends_on = [docker_network.app]
+}
+
+resource "docker_container" "web" {
+  image      = "nginx:1.27"
+  depends_on = [docker_container.db]
+}
+
+resource "docker_container" "cache" {
+  image = "redis:7"
+}
+`
+
+// stateIDs ID записей state по адресу, tainted записи помечаются суффиксом
+func stateIDs(t *testing.T) map[string]string {
+	t.Helper()
+	ids := make(map[string]string)
+	for address, resourceState := range readTestStateIfExists(t).Resources {
+		ids[address] = resourceState.ID
+		if resourceState.Tainted {
+			ids[address] += " (tainted)"
+		}
+	}
+	return ids
+}
+
+// failedResources адреса ресурсов из ошибок, объединенных в err
+func failedResources(t *testing.T, err error) map[string][]string {
+	t.Helper()
+	var terraErr *errors.TerraformError
+	if !stderrors.As(err, &terraErr) {
+		t.Fatalf("error = %v, want a TerraformError", err)
+	}
+	failed := make(map[string][]string)
+	for _, joined := range errors.Joined(terraErr.Cause) {
+		var resourceErr *errors.TerraformError
+		if !stderrors.As(joined, &resourceErr) {
+			t.Fatalf("joined error = %v, want a TerraformError", joined)
+		}
+		failed[resourceErr.Resource] = resourceErr.DependsOn
+	}
+	return failed
+}
+
+func TestApplyContinuesAfterFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		fail    string
+		partial bool
+		// want ID записей state после apply
+		want    map[string]string
+		applied []string
+		// failed ресурсы с ошибкой и их зависимости
+		failed map[string][]string
+		err    string
+	}{
+		{
+			name:    "no failures",
+			want:    map[string]string{"docker_network.app": "app-1", "docker_container.cache": "cache-1", "docker_container.db": "db-1", "docker_container.web": "web-1"},
+			applied: []string{"app", "cache", "db", "web"},
+		},
+		{
+			name:    "dependents are skipped, independent branch is applied",
+			fail:    "db",
+			want:    map[string]string{"docker_network.app": "app-1", "docker_container.cache": "cache-1"},
+			applied: []string{"app", "cache", "db"},
+			failed:  map[string][]string{"docker_container.db": {"docker_network.app"}},
+			err:     "Apply failed: 1 resources failed, 1 skipped, 2 applied",
+		},
+		{
+			name:    "failure at the root of a branch",
+			fail:    "app",
+			want:    map[string]string{"docker_container.cache": "cache-1"},
+			applied: []string{"app", "cache"},
+			failed:  map[string][]string{"docker_network.app": nil},
+			err:     "Apply failed: 1 resources failed, 2 skipped, 1 applied",
+		},
+		{
+			name:    "partially created object is kept as tainted",
+			fail:    "db",
+			partial: true,
+			want:    map[string]string{"docker_network.app": "app-1", "docker_container.cache": "cache-1", "docker_container.db": "db-1 (tainted)"},
+			applied: []string{"app", "cache", "db"},
+			failed:  map[string][]string{"docker_container.db": {"docker_network.app"}},
+			err:     "Apply failed: 1 resources failed, 1 skipped, 2 applied",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			writeTestConfig(t, chainConfig)
+			r := newTestResource()
+			if test.fail != "" {
+				r.failures[test.fail] = fmt.Errorf("%s is broken", test.fail)
+				r.partial[test.fail] = test.partial
+			}
+
+			engine := newTestEngine(t)
+			useTestProvider(engine, r)
+			err := engine.Apply(context.Background(), defaultTestConfig, ApplyOptions{})
+			if test.err != "" {
+				assertErrorCode(t, err, errors.CodeApply, test.err)
+				if failed := failedResources(t, err); !reflect.DeepEqual(failed, test.failed) {
+					t.Errorf("failed resources = %v, want %v", failed, test.failed)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			applied := append([]string(nil), r.applied...)
+			sort.Strings(applied)
+			if !reflect.DeepEqual(applied, test.applied) {
+				t.Errorf("applied = %v, want %v", applied, test.applied)
+			}
+			if got := stateIDs(t); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("state = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// Повторный apply после ошибки доделывает только то, что не применилось
+func TestApplyAfterFailure(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeTestConfig(t, chainConfig)
+	r := newTestResource()
+	r.failures["db"] = fmt.Errorf("db is broken")
+	r.partial["db"] = true
+
+	engine := newTestEngine(t)
+	useTestProvider(engine, r)
+	if err := engine.Apply(context.Background(), defaultTestConfig, ApplyOptions{}); err == nil {
+		t.Fatal("first apply succeeded")
+	}
+
+	delete(r.failures, "db")
+	r.applied = nil
+	engine = newTestEngine(t)
+	useTestProvider(engine, r)
+	if err := engine.Apply(context.Background(), defaultTestConfig, ApplyOptions{}); err != nil {
+		t.Fatalf("second apply: %v", err)
+	}
+
+	if !reflect.DeepEqual(r.applied, []string{"db", "web"}) {
+		t.Errorf("applied = %v, want db and web", r.applied)
+	}
+	if !reflect.DeepEqual(r.deleted, []string{"db-1"}) {
+		t.Errorf("deleted = %v, want the tainted db-1", r.deleted)
+	}
+	want := map[string]string{"docker_network.app": "app-1", "docker_container.cache": "cache-1", "docker_container.db": "db-2", "docker_container.web": "web-1"}
+	if got := stateIDs(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("state = %v, want %v", got, want)
+	}
+}
+
+func TestDestroyContinuesAfterFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		fail string
+		want map[string]string
+		err  string
+	}{
+		{name: "no failures", want: map[string]string{}},
+		{
+			name: "dependencies of a remaining resource are kept",
+			fail: "db-1",
+			want: map[string]string{"docker_network.app": "app-1", "docker_container.db": "db-1"},
+			err:  "Destroy failed: 2 resources remain in state",
+		},
+		{
+			name: "failure at the end of a branch",
+			fail: "web-1",
+			want: map[string]string{"docker_network.app": "app-1", "docker_container.db": "db-1", "docker_container.web": "web-1"},
+			err:  "Destroy failed: 3 resources remain in state",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			writeTestConfig(t, chainConfig)
+			writeTestState(t, map[string]state.ResourceState{
+				"docker_network.app":     {Type: "docker_network", ID: "app-1"},
+				"docker_container.db":    {Type: "docker_container", ID: "db-1", Dependencies: []string{"docker_network.app"}},
+				"docker_container.web":   {Type: "docker_container", ID: "web-1", Dependencies: []string{"docker_container.db"}},
+				"docker_container.cache": {Type: "docker_container", ID: "cache-1"},
+			})
+			r := newTestResource()
+			if test.fail != "" {
+				r.deleteFailures[test.fail] = fmt.Errorf("%s is busy", test.fail)
+			}
+
+			engine := newTestEngine(t)
+			useTestProvider(engine, r)
+			err := engine.Destroy(context.Background(), defaultTestConfig, DestroyOptions{})
+			if test.err != "" {
+				assertErrorCode(t, err, errors.CodeDestroy, test.err)
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := stateIDs(t); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("state = %v, want %v", got, test.want)
+			}
+			if len(r.deleted)+len(test.want) != 4 {
+				t.Errorf("deleted = %v, want every resource not left in state", r.deleted)
+			}
+		})
+	}
+}
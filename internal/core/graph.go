@@ -0,0 +1,122 @@
+// internal/core/graph.go
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// resourceGraph зависимости между ресурсами. Ресурс обрабатывается после всех,
+// от которых зависит; независимые ресурсы сохраняют исходный порядок.
+type resourceGraph struct {
+	// order адреса в порядке создания
+	order        []string
+	dependencies map[string][]string
+	dependents   map[string][]string
+}
+
+// newResourceGraph строит граф по адресам в исходном порядке и их зависимостям.
+// Зависимость от адреса, которого нет в графе, и циклы считаются ошибкой.
+func newResourceGraph(addresses []string, dependencies map[string][]string) (*resourceGraph, error) {
+	g := &resourceGraph{
+		dependencies: make(map[string][]string, len(addresses)),
+		dependents:   make(map[string][]string, len(addresses)),
+	}
+
+	position := make(map[string]int, len(addresses))
+	for i, address := range addresses {
+		if _, exists := position[address]; exists {
+			return nil, fmt.Errorf("duplicate resource %s", address)
+		}
+		position[address] = i
+	}
+
+	for _, address := range addresses {
+		for _, dependency := range dependencies[address] {
+			if _, exists := position[dependency]; !exists {
+				return nil, fmt.Errorf("%s depends on %s, which is not declared", address, dependency)
+			}
+			if dependency == address {
+				return nil, fmt.Errorf("%s depends on itself", address)
+			}
+			g.dependencies[address] = append(g.dependencies[address], dependency)
+			g.dependents[dependency] = append(g.dependents[dependency], address)
+		}
+	}
+
+	// Алгоритм Кана: из готовых к обработке ресурсов берем самый ранний в конфигурации
+	remaining := make(map[string]int, len(addresses))
+	var ready []string
+	for _, address := range addresses {
+		remaining[address] = len(g.dependencies[address])
+		if remaining[address] == 0 {
+			ready = append(ready, address)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool { return position[ready[i]] < position[ready[j]] })
+		address := ready[0]
+		ready = ready[1:]
+		g.order = append(g.order, address)
+
+		for _, dependent := range g.dependents[address] {
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(g.order) != len(addresses) {
+		var cycle []string
+		for _, address := range addresses {
+			if remaining[address] > 0 {
+				cycle = append(cycle, address)
+			}
+		}
+		return nil, fmt.Errorf("dependency cycle between %s", strings.Join(cycle, ", "))
+	}
+	return g, nil
+}
+
+// reverseOrder порядок удаления: зависимые ресурсы раньше своих зависимостей
+func (g *resourceGraph) reverseOrder() []string {
+	order := make([]string, len(g.order))
+	for i, address := range g.order {
+		order[len(g.order)-1-i] = address
+	}
+	return order
+}
+
+// firstIn возвращает первый из соседей адреса (зависимостей или зависимых), попавший в set
+func firstIn(neighbours []string, set map[string]bool) string {
+	for _, neighbour := range neighbours {
+		if set[neighbour] {
+			return neighbour
+		}
+	}
+	return ""
+}
+
+// closure возвращает адреса from вместе со всеми их зависимостями, а при dependents -
+// со всеми ресурсами, которые от них зависят
+func (g *resourceGraph) closure(from []string, dependents bool) map[string]bool {
+	edges := g.dependencies
+	if dependents {
+		edges = g.dependents
+	}
+
+	result := make(map[string]bool)
+	stack := append([]string(nil), from...)
+	for len(stack) > 0 {
+		address := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if result[address] {
+			continue
+		}
+		result[address] = true
+		stack = append(stack, edges[address]...)
+	}
+	return result
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/providers"
+)
+
+// Hooks получает события plan и apply по мере выполнения. Через них работает
+// машиночитаемый вывод -json.
+type Hooks interface {
+	// PlannedChange изменение ресурса, найденное планированием (без no-op)
+	PlannedChange(address string, change *providers.PlannedChange)
+	// ApplyStart начало изменения объекта
+	ApplyStart(address string, action providers.Action)
+	// ApplyComplete объект изменен, id - его ID после изменения
+	ApplyComplete(address string, action providers.Action, id string)
+	// ApplyErrored изменение объекта завершилось ошибкой
+	ApplyErrored(address string, action providers.Action, err error)
+	// Outputs значения блоков output после apply
+	Outputs(outputs []config.Output)
+	// ChangeSummary итог plan или apply
+	ChangeSummary(summary ChangeSummary)
+}
+
+// ChangeSummary число изменений по видам. Замена считается и созданием, и удалением.
+type ChangeSummary struct {
+	// Operation plan или apply
+	Operation string
+	Add       int
+	Change    int
+	Remove    int
+	Import    int
+}
+
+// count учитывает изменение одного ресурса
+func (s *ChangeSummary) count(action providers.Action) {
+	switch action {
+	case providers.ActionCreate:
+		s.Add++
+	case providers.ActionUpdate:
+		s.Change++
+	case providers.ActionReplace:
+		s.Add++
+		s.Remove++
+	case providers.ActionDelete:
+		s.Remove++
+	}
+}
+
+// noHooks используется, если Options.Hooks не заданы
+type noHooks struct{}
+
+func (noHooks) PlannedChange(string, *providers.PlannedChange) {}
+func (noHooks) ApplyStart(string, providers.Action)            {}
+func (noHooks) ApplyComplete(string, providers.Action, string) {}
+func (noHooks) ApplyErrored(string, providers.Action, error)   {}
+func (noHooks) Outputs([]config.Output)                        {}
+func (noHooks) ChangeSummary(ChangeSummary)                    {}
@@ -0,0 +1,362 @@
+// internal/core/import.go
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/errors"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// ImportOptions флаги команды import
+type ImportOptions struct {
+	// GenerateConfigOut файл, в который записывается HCL импортированных объектов,
+	// у которых нет блока resource. Файл не должен существовать.
+	GenerateConfigOut string
+}
+
+// pendingImport импорт, который еще не выполнен: адреса нет в state
+type pendingImport struct {
+	address  state.Address
+	id       string
+	resource config.Resource
+	// inConfig есть ли для адреса блок resource
+	inConfig bool
+}
+
+// Import берет под управление существующий объект: читает его через провайдер,
+// записывает в state и показывает, чем он отличается от конфигурации
+func (e *Engine) Import(ctx context.Context, configFile, address, id string, options ImportOptions) error {
+	parsed, err := state.ParseAddress(address)
+	if err != nil {
+		return errors.WrapError(err, errors.CodeConfig, "Invalid resource address")
+	}
+	defer e.closeProviders()
+
+	if err := e.loadConfig(configFile); err != nil {
+		return err
+	}
+
+	unlock, err := e.lockState(ctx, "import")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := e.stateManager.Load()
+	if err != nil {
+		return errors.WrapError(err, errors.CodeState, "Failed to load state")
+	}
+	if _, exists := current.Resources[parsed.String()]; exists {
+		return errors.NewError(errors.CodeImport, fmt.Sprintf("%s is already managed, remove it from state first with state rm", parsed))
+	}
+
+	pending, err := e.newPendingImport(config.Import{To: address, ID: id})
+	if err != nil {
+		return err
+	}
+	if !pending.inConfig && options.GenerateConfigOut == "" {
+		return errors.NewError(errors.CodeImport, fmt.Sprintf("%s is not in the configuration: add a resource block for it or use -generate-config-out", parsed))
+	}
+
+	impl, imported, err := e.importResource(ctx, pending)
+	if err != nil {
+		return errors.ResourceError(parsed.String(), "Failed to import resource", err)
+	}
+	// Конфигурацию пишем до state: если файл уже существует, импорт не выполняется
+	if !pending.inConfig {
+		if err := e.writeGeneratedConfig(options.GenerateConfigOut, []generatedResource{{pending.resource, impl.Schema(), imported}}); err != nil {
+			return err
+		}
+	}
+	if err := e.stateManager.SaveResourceState(parsed.Type, parsed.Name, imported); err != nil {
+		return errors.WrapError(err, errors.CodeState, "Failed to save state")
+	}
+	e.logger.Info("Imported %s (id %s)", parsed, id)
+	if !pending.inConfig {
+		return nil
+	}
+
+	change, err := impl.Plan(ctx, pending.resource, imported)
+	if err != nil {
+		return errors.ResourceError(parsed.String(), "Failed to plan resource", err)
+	}
+	if change.Action == providers.ActionNoop {
+		e.logger.Info("%s matches the configuration", parsed)
+	} else {
+		e.logger.Info("%s differs from the configuration, the next apply will %s it: %s", parsed, change.Action, change.Reason)
+	}
+	return nil
+}
+
+// pendingImports возвращает блоки import конфигурации, адресов которых еще нет в state.
+// При -target остаются только импорты в выбранные ресурсы.
+func (e *Engine) pendingImports(targets map[string]bool) (map[string]*pendingImport, error) {
+	current, err := e.stateManager.Load()
+	if err != nil {
+		return nil, errors.WrapError(err, errors.CodeState, "Failed to load state")
+	}
+
+	pending := make(map[string]*pendingImport)
+	for _, block := range e.config.Imports {
+		imp, err := e.newPendingImport(block)
+		if err != nil {
+			return nil, err
+		}
+		address := imp.address.String()
+		if _, exists := pending[address]; exists {
+			return nil, errors.NewError(errors.CodeConfig, fmt.Sprintf("Duplicate import block for %s", address))
+		}
+		if _, exists := current.Resources[address]; exists || !targeted(targets, address) {
+			continue
+		}
+		pending[address] = imp
+	}
+	return pending, nil
+}
+
+// newPendingImport находит ресурс, в который импортируется объект. Если блока resource
+// нет, ресурс создается по адресу, чтобы для него можно было сгенерировать конфигурацию.
+func (e *Engine) newPendingImport(block config.Import) (*pendingImport, error) {
+	address, err := state.ParseAddress(block.To)
+	if err != nil {
+		return nil, errors.WrapError(err, errors.CodeConfig, "Invalid import address")
+	}
+	if address.Key != "" {
+		return nil, errors.NewError(errors.CodeConfig, fmt.Sprintf("Cannot import into %s: resources have no instances", address))
+	}
+
+	pending := &pendingImport{
+		address:  address,
+		id:       block.ID,
+		resource: config.Resource{Type: address.Type, Name: address.Name, Provider: block.Provider},
+	}
+	for _, resource := range e.config.Resources {
+		if resource.Type != address.Type || resource.Name != address.Name {
+			continue
+		}
+		if block.Provider != "" && providerAddress(resource) != block.Provider {
+			return nil, errors.NewError(errors.CodeConfig, fmt.Sprintf("Import into %s uses provider %s, but the resource uses %s", address, block.Provider, providerAddress(resource)))
+		}
+		pending.resource = resource
+		pending.inConfig = true
+	}
+	return pending, nil
+}
+
+// importResource читает объект через провайдер. Конфигурация ресурса проверяется по схеме,
+// только если блок resource существует.
+func (e *Engine) importResource(ctx context.Context, pending *pendingImport) (providers.Resource, *state.ResourceState, error) {
+	var impl providers.Resource
+	var err error
+	if pending.inConfig {
+		impl, err = e.resourceFor(ctx, pending.resource)
+	} else {
+		impl, err = e.resourceImpl(ctx, providerAddress(pending.resource), pending.resource.Type)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	imported, err := impl.Import(ctx, pending.id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to import %s: %w", pending.id, err)
+	}
+	imported.Type = pending.resource.Type
+	imported.Provider = providerAddress(pending.resource)
+	imported.SchemaVersion = impl.Schema().Version
+	imported.SensitiveAttributes = e.resourceSensitiveAttributes(impl, pending.resource)
+	return impl, imported, nil
+}
+
+// applyImports импортирует объекты блоков import в state до применения изменений
+func (e *Engine) applyImports(ctx context.Context, targets map[string]bool) error {
+	pending, err := e.pendingImports(targets)
+	if err != nil {
+		return err
+	}
+
+	for _, address := range sortedKeys(pending) {
+		imp := pending[address]
+		if !imp.inConfig {
+			return errors.NewError(errors.CodeImport, fmt.Sprintf("Import block targets %s, which is not in the configuration: add a resource block for it (plan -generate-config-out can write one)", address))
+		}
+
+		e.logger.Info("Importing %s (id %s)", address, imp.id)
+		_, imported, err := e.importResource(ctx, imp)
+		if err != nil {
+			return errors.ResourceError(address, "Failed to import resource", err)
+		}
+		if err := e.stateManager.SaveResourceState(imp.address.Type, imp.address.Name, imported); err != nil {
+			return errors.WrapError(err, errors.CodeState, "Failed to save state")
+		}
+	}
+	return nil
+}
+
+// planGeneratedImports читает объекты блоков import без блока resource и записывает
+// для них конфигурацию в options.GenerateConfigOut. Возвращает число таких импортов.
+func (e *Engine) planGeneratedImports(ctx context.Context, pending map[string]*pendingImport, options PlanOptions) (int, error) {
+	var resources []generatedResource
+	for _, address := range sortedKeys(pending) {
+		imp := pending[address]
+		if imp.inConfig {
+			continue
+		}
+		if options.GenerateConfigOut == "" {
+			return 0, errors.NewError(errors.CodeImport, fmt.Sprintf("Import block targets %s, which is not in the configuration: add a resource block for it or use -generate-config-out", address))
+		}
+
+		impl, imported, err := e.importResource(ctx, imp)
+		if err != nil {
+			return 0, errors.ResourceError(address, "Failed to import resource", err)
+		}
+		e.logger.Info("  <= import %s (id %s), configuration will be generated", address, imp.id)
+		resources = append(resources, generatedResource{imp.resource, impl.Schema(), imported})
+	}
+
+	if len(resources) == 0 {
+		return 0, nil
+	}
+	return len(resources), e.writeGeneratedConfig(options.GenerateConfigOut, resources)
+}
+
+// generatedResource импортированный объект, для которого генерируется блок resource
+type generatedResource struct {
+	resource config.Resource
+	schema   providers.Schema
+	state    *state.ResourceState
+}
+
+// writeGeneratedConfig записывает блоки resource для импортированных объектов.
+// Существующий файл не перезаписывается, чтобы не потерять правки.
+func (e *Engine) writeGeneratedConfig(path string, resources []generatedResource) error {
+	file := hclwrite.NewEmptyFile()
+	body := file.Body()
+	for i, generated := range resources {
+		if i > 0 {
+			body.AppendNewline()
+		}
+		if err := appendResourceBlock(body, generated); err != nil {
+			return errors.ResourceError(generated.resource.Type+"."+generated.resource.Name, "Failed to generate configuration", err)
+		}
+	}
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return errors.WrapError(err, errors.CodeImport, "Failed to write generated configuration")
+	}
+	defer out.Close()
+
+	header := "# Generated from imported objects. Review it and move it into the main configuration.\n\n"
+	if _, err := out.Write(append([]byte(header), hclwrite.Format(file.Bytes())...)); err != nil {
+		return errors.WrapError(err, errors.CodeImport, "Failed to write generated configuration")
+	}
+	e.logger.Info("Configuration for %d imported resources written to %s", len(resources), path)
+	return nil
+}
+
+// appendResourceBlock добавляет блок resource по атрибутам state, отбирая их по схеме ресурса
+func appendResourceBlock(body *hclwrite.Body, generated generatedResource) error {
+	block := body.AppendNewBlock("resource", []string{generated.resource.Type, generated.resource.Name})
+	if generated.resource.Provider != "" && generated.resource.Provider != providers.ProviderName(generated.resource.Type) {
+		block.Body().SetAttributeTraversal("provider", providerTraversal(generated.resource.Provider))
+	}
+	return appendSchemaBody(block.Body(), generated.schema, generated.state.Attributes)
+}
+
+// appendSchemaBody записывает заданные в конфигурации атрибуты и вложенные блоки.
+// Вычисляемые атрибуты, пустые значения и false пропускаются. Значения sensitive
+// атрибутов не записываются: файл попадет в репозиторий, вместо них остается комментарий.
+func appendSchemaBody(body *hclwrite.Body, schema providers.Schema, attributes map[string]interface{}) error {
+	for _, name := range sortedKeys(schema.Attributes) {
+		attribute := schema.Attributes[name]
+		raw, exists := attributes[name]
+		if attribute.Computed || name == "id" || !exists || raw == nil {
+			continue
+		}
+
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return fmt.Errorf("attribute %s: %w", name, err)
+		}
+		value, err := ctyjson.Unmarshal(data, attribute.Type)
+		if err != nil {
+			return fmt.Errorf("attribute %s: %w", name, err)
+		}
+		if isEmptyValue(value) {
+			continue
+		}
+		if attribute.Sensitive {
+			body.AppendUnstructuredTokens(hclwrite.Tokens{
+				{Type: hclsyntax.TokenComment, Bytes: []byte(fmt.Sprintf("# %s is sensitive and was not imported; set it from a variable\n", name))},
+			})
+			continue
+		}
+		body.SetAttributeValue(name, value)
+	}
+
+	for _, name := range sortedKeys(schema.Blocks) {
+		// Атрибут с тем же именем (ports как map) уже записан выше
+		if _, isAttribute := schema.Attributes[name]; isAttribute {
+			continue
+		}
+		items, _ := attributes[name].([]interface{})
+		for _, item := range items {
+			nested, ok := item.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("block %s: expected an object in state", name)
+			}
+			block := body.AppendNewBlock(name, nil)
+			if err := appendSchemaBody(block.Body(), schema.Blocks[name].Schema, nested); err != nil {
+				return fmt.Errorf("block %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// providerTraversal ссылка на провайдер для мета-аргумента provider: docker.edge
+func providerTraversal(address string) hcl.Traversal {
+	name, alias, hasAlias := strings.Cut(address, ".")
+	traversal := hcl.Traversal{hcl.TraverseRoot{Name: name}}
+	if hasAlias {
+		traversal = append(traversal, hcl.TraverseAttr{Name: alias})
+	}
+	return traversal
+}
+
+func isEmptyValue(value cty.Value) bool {
+	switch {
+	case value.IsNull():
+		return true
+	case value.Type() == cty.String:
+		return value.AsString() == ""
+	case value.Type() == cty.Bool:
+		return value.False()
+	case value.Type().IsListType() || value.Type().IsMapType() || value.Type().IsSetType():
+		return value.LengthInt() == 0
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
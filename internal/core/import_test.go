@@ -0,0 +1,457 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/errors"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var generatedTestSchema = providers.Schema{
+	Attributes: map[string]*providers.Attribute{
+		"name":     {Type: cty.String, Optional: true},
+		"image":    {Type: cty.String, Required: true},
+		"env":      {Type: cty.Map(cty.String), Optional: true, Sensitive: true},
+		"wait":     {Type: cty.Bool, Optional: true},
+		"image_id": {Type: cty.String, Computed: true},
+	},
+	Blocks: map[string]*providers.NestedBlock{
+		"auth": {Schema: providers.Schema{
+			Attributes: map[string]*providers.Attribute{
+				"username": {Type: cty.String, Required: true},
+				"password": {Type: cty.String, Optional: true, Sensitive: true},
+			},
+		}},
+	},
+}
+
+func TestAppendResourceBlock(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   string
+		attributes map[string]interface{}
+		contains   []string
+		excludes   []string
+	}{
+		{
+			name: "configured attributes",
+			attributes: map[string]interface{}{
+				"id":       "3f2a9c",
+				"name":     "web",
+				"image":    "nginx:1.27",
+				"wait":     false,
+				"image_id": "sha256:abc",
+			},
+			contains: []string{`resource "docker_container" "web" {`, `name  = "web"`, `image = "nginx:1.27"`},
+			excludes: []string{"id", "wait", "sha256:abc"},
+		},
+		{
+			name: "sensitive attribute",
+			attributes: map[string]interface{}{
+				"image": "postgres:16",
+				"env":   map[string]interface{}{"POSTGRES_PASSWORD": "hunter2"},
+			},
+			contains: []string{"# env is sensitive and was not imported; set it from a variable"},
+			excludes: []string{"hunter2", "POSTGRES_PASSWORD", "env ="},
+		},
+		{
+			name: "empty sensitive attribute",
+			attributes: map[string]interface{}{
+				"image": "postgres:16",
+				"env":   map[string]interface{}{},
+			},
+			excludes: []string{"env"},
+		},
+		{
+			name: "sensitive attribute in a nested block",
+			attributes: map[string]interface{}{
+				"image": "registry.example.com/app",
+				"auth":  []interface{}{map[string]interface{}{"username": "ci", "password": "p@ss"}},
+			},
+			contains: []string{"auth {", `username = "ci"`, "# password is sensitive"},
+			excludes: []string{"p@ss"},
+		},
+		{
+			name:       "provider alias",
+			provider:   "docker.edge",
+			attributes: map[string]interface{}{"image": "nginx"},
+			contains:   []string{"provider = docker.edge"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := hclwrite.NewEmptyFile()
+			err := appendResourceBlock(file.Body(), generatedResource{
+				resource: config.Resource{Type: "docker_container", Name: "web", Provider: test.provider},
+				schema:   generatedTestSchema,
+				state:    &state.ResourceState{Attributes: test.attributes},
+			})
+			if err != nil {
+				t.Fatalf("appendResourceBlock: %v", err)
+			}
+
+			out := string(hclwrite.Format(file.Bytes()))
+			for _, want := range test.contains {
+				if !strings.Contains(out, want) {
+					t.Errorf("generated configuration does not contain %q:\n%s", want, out)
+				}
+			}
+			for _, unwanted := range test.excludes {
+				if strings.Contains(out, unwanted) {
+					t.Errorf("generated configuration contains %q:\n%s", unwanted, out)
+				}
+			}
+		})
+	}
+}
+
+// testProvider провайдер docker для тестов движка, объекты хранятся в testResource
+type testProvider struct {
+	resource *testResource
+}
+
+func (p *testProvider) Schema() providers.Schema { return providers.Schema{} }
+
+func (p *testProvider) Configure(ctx context.Context, cfg config.Provider) error { return nil }
+
+func (p *testProvider) Resources() map[string]providers.Resource {
+	return map[string]providers.Resource{"docker_container": p.resource, "docker_network": p.resource}
+}
+
+// testResource объекты в памяти. Plan создает отсутствующий объект и пересоздает
+// объект с другим image, Apply падает для ресурсов из failures.
+type testResource struct {
+	mu sync.Mutex
+	// objects атрибуты существующих объектов по ID
+	objects map[string]map[string]interface{}
+	// failures ошибка Apply по имени ресурса
+	failures map[string]error
+	// partial Apply ресурсов, которые возвращают объект вместе с ошибкой
+	partial map[string]bool
+	// deleteFailures ошибка Delete по ID объекта
+	deleteFailures map[string]error
+	// onApply вызывается перед Apply ресурса, его ошибка возвращается из Apply
+	onApply func(ctx context.Context, name string) error
+	// onDelete вызывается перед Delete объекта, его ошибка возвращается из Delete
+	onDelete func(ctx context.Context) error
+	applied  []string
+	deleted  []string
+	// created число созданных объектов по имени ресурса, из него строится ID
+	created map[string]int
+}
+
+func newTestResource() *testResource {
+	return &testResource{
+		objects:        make(map[string]map[string]interface{}),
+		failures:       make(map[string]error),
+		partial:        make(map[string]bool),
+		deleteFailures: make(map[string]error),
+		created:        make(map[string]int),
+	}
+}
+
+// useTestProvider подменяет провайдер docker движка на r
+func useTestProvider(engine *Engine, r *testResource) {
+	engine.providerInstances["docker"] = &testProvider{resource: r}
+}
+
+func (r *testResource) Schema() providers.Schema {
+	return providers.Schema{Attributes: map[string]*providers.Attribute{
+		"image": {Type: cty.String, Optional: true},
+	}}
+}
+
+func (r *testResource) Plan(ctx context.Context, cfg config.Resource, prior *state.ResourceState) (*providers.PlannedChange, error) {
+	switch {
+	case prior == nil:
+		return &providers.PlannedChange{Action: providers.ActionCreate}, nil
+	case prior.Attributes["image"] != configImage(cfg):
+		return &providers.PlannedChange{Action: providers.ActionReplace, Reason: "image changed"}, nil
+	default:
+		return &providers.PlannedChange{Action: providers.ActionNoop}, nil
+	}
+}
+
+func (r *testResource) Apply(ctx context.Context, cfg config.Resource, prior *state.ResourceState, change *providers.PlannedChange) (*state.ResourceState, error) {
+	if r.onApply != nil {
+		if err := r.onApply(ctx, cfg.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.applied = append(r.applied, cfg.Name)
+	err := r.failures[cfg.Name]
+	if err != nil && !r.partial[cfg.Name] {
+		return nil, err
+	}
+
+	r.created[cfg.Name]++
+	id := fmt.Sprintf("%s-%d", cfg.Name, r.created[cfg.Name])
+	attributes := map[string]interface{}{"image": configImage(cfg)}
+	r.objects[id] = attributes
+	return &state.ResourceState{ID: id, Attributes: attributes}, err
+}
+
+func (r *testResource) Read(ctx context.Context, current *state.ResourceState) (*state.ResourceState, error) {
+	return current, nil
+}
+
+func (r *testResource) Delete(ctx context.Context, current *state.ResourceState) error {
+	if r.onDelete != nil {
+		if err := r.onDelete(ctx); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.deleteFailures[current.ID]; err != nil {
+		return err
+	}
+	r.deleted = append(r.deleted, current.ID)
+	delete(r.objects, current.ID)
+	return nil
+}
+
+func (r *testResource) Import(ctx context.Context, id string) (*state.ResourceState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attributes, exists := r.objects[id]
+	if !exists {
+		return nil, fmt.Errorf("no object %s", id)
+	}
+	return &state.ResourceState{ID: id, Attributes: attributes}, nil
+}
+
+func configImage(cfg config.Resource) string {
+	image, exists := cfg.Attributes["image"]
+	if !exists || image.IsNull() {
+		return ""
+	}
+	return image.AsString()
+}
+
+// writeTestConfig записывает конфигурацию в defaultTestConfig текущего каталога
+func writeTestConfig(t *testing.T, content string) {
+	t.Helper()
+	if err := os.WriteFile(defaultTestConfig, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	const webConfig = `
+resource "docker_container" "web" {
+  image = "nginx:1.27"
+}
+`
+	tests := []struct {
+		name    string
+		config  string
+		address string
+		id      string
+		// managed адрес уже есть в state
+		managed  bool
+		generate string
+		code     errors.Code
+		err      string
+	}{
+		{name: "resource in configuration", config: webConfig, address: "docker_container.web", id: "c1"},
+		{name: "object differs from configuration", config: webConfig, address: "docker_container.web", id: "c2"},
+		{
+			name: "already managed", config: webConfig, address: "docker_container.web", id: "c1", managed: true,
+			code: errors.CodeImport, err: "docker_container.web is already managed",
+		},
+		{
+			name: "not in configuration", address: "docker_container.web", id: "c1",
+			code: errors.CodeImport, err: "add a resource block for it or use -generate-config-out",
+		},
+		{name: "generated configuration", address: "docker_container.web", id: "c1", generate: "generated.tf"},
+		{
+			name: "missing object", config: webConfig, address: "docker_container.web", id: "c404",
+			code: errors.CodeResource, err: "Failed to import resource",
+		},
+		{
+			name: "instance address", config: webConfig, address: "docker_container.web[0]", id: "c1",
+			code: errors.CodeConfig, err: "resources have no instances",
+		},
+		{
+			name: "invalid address", config: webConfig, address: "web", id: "c1",
+			code: errors.CodeConfig, err: "Invalid resource address",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			writeTestConfig(t, test.config)
+			if test.managed {
+				writeTestState(t, map[string]state.ResourceState{
+					"docker_container.web": {Type: "docker_container", ID: "c0"},
+				})
+			}
+			r := newTestResource()
+			r.objects["c1"] = map[string]interface{}{"image": "nginx:1.27"}
+			r.objects["c2"] = map[string]interface{}{"image": "nginx:1.26"}
+
+			engine := newTestEngine(t)
+			useTestProvider(engine, r)
+			err := engine.Import(context.Background(), defaultTestConfig, test.address, test.id, ImportOptions{GenerateConfigOut: test.generate})
+			if test.err != "" {
+				assertErrorCode(t, err, test.code, test.err)
+				if _, statErr := os.Stat(test.generate); test.generate != "" && statErr == nil {
+					t.Error("failed import wrote the configuration")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			imported := readTestState(t).Resources[test.address]
+			if imported.ID != test.id || imported.Type != "docker_container" || imported.Provider != "docker" {
+				t.Errorf("state = %+v, want %s imported with provider docker", imported, test.id)
+			}
+			if test.generate != "" {
+				data, err := os.ReadFile(test.generate)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(data), `resource "docker_container" "web" {`) {
+					t.Errorf("generated configuration:\n%s", data)
+				}
+			}
+		})
+	}
+}
+
+func TestImportGeneratedConfigExists(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeTestConfig(t, "")
+	if err := os.WriteFile("generated.tf", []byte("# edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := newTestResource()
+	r.objects["c1"] = map[string]interface{}{"image": "nginx"}
+
+	engine := newTestEngine(t)
+	useTestProvider(engine, r)
+	err := engine.Import(context.Background(), defaultTestConfig, "docker_container.web", "c1", ImportOptions{GenerateConfigOut: "generated.tf"})
+	if err == nil {
+		t.Fatal("import overwrote an existing file")
+	}
+	if data, _ := os.ReadFile("generated.tf"); string(data) != "# edited\n" {
+		t.Errorf("generated.tf = %q, want it unchanged", data)
+	}
+	if _, exists := readTestStateIfExists(t).Resources["docker_container.web"]; exists {
+		t.Error("import saved the resource although the configuration was not written")
+	}
+}
+
+func TestApplyImportBlocks(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		// want ID ресурсов в state после apply
+		want map[string]string
+		err  string
+	}{
+		{
+			name: "import before apply",
+			config: `
+import {
+  to = docker_container.web
+  id = "c1"
+}
+resource "docker_container" "web" {
+  image = "nginx:1.27"
+}
+resource "docker_network" "app" {}
+`,
+			want: map[string]string{"docker_container.web": "c1", "docker_network.app": "app-1"},
+		},
+		{
+			name: "import without resource block",
+			config: `
+import {
+  to = docker_container.web
+  id = "c1"
+}
+`,
+			err: "Import block targets docker_container.web, which is not in the configuration",
+		},
+		{
+			name: "duplicate import",
+			config: `
+import {
+  to = docker_container.web
+  id = "c1"
+}
+import {
+  to = docker_container.web
+  id = "c2"
+}
+resource "docker_container" "web" {
+  image = "nginx:1.27"
+}
+`,
+			err: "Duplicate import block for docker_container.web",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			writeTestConfig(t, test.config)
+			r := newTestResource()
+			r.objects["c1"] = map[string]interface{}{"image": "nginx:1.27"}
+
+			engine := newTestEngine(t)
+			useTestProvider(engine, r)
+			err := engine.Apply(context.Background(), defaultTestConfig, ApplyOptions{})
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := make(map[string]string)
+			for address, resourceState := range readTestState(t).Resources {
+				got[address] = resourceState.ID
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("state = %v, want %v", got, test.want)
+			}
+			// Импортированный объект совпадает с конфигурацией и не пересоздается
+			if !reflect.DeepEqual(r.applied, []string{"app"}) {
+				t.Errorf("applied = %v, want only app", r.applied)
+			}
+		})
+	}
+}
+
+// readTestStateIfExists как readTestState, но отсутствующий state считается пустым
+func readTestStateIfExists(t *testing.T) *state.State {
+	t.Helper()
+	if _, err := os.Stat(state.DefaultStateFile); os.IsNotExist(err) {
+		return &state.State{}
+	}
+	return readTestState(t)
+}
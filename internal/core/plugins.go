@@ -0,0 +1,118 @@
+// internal/core/plugins.go
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/Artemka007/derraform/internal/plugin"
+	"github.com/Artemka007/derraform/internal/providers"
+)
+
+// installPlugins находит провайдеры-плагины в локальном каталоге, проверяет рукопожатие
+// и записывает их контрольные суммы в lock файл
+func (e *Engine) installPlugins() error {
+	e.logger.Info("Initializing provider plugins...")
+
+	for _, name := range providers.Registered() {
+		e.logger.Info("- Using built-in provider %s", name)
+	}
+
+	plugins, err := plugin.Discover(plugin.DefaultPluginDir)
+	if err != nil {
+		return err
+	}
+
+	lockFile := &plugin.LockFile{Providers: make(map[string]plugin.LockedProvider)}
+	for _, p := range plugins {
+		if _, builtin := providers.Lookup(p.Name); builtin {
+			return fmt.Errorf("plugin %s conflicts with the built-in provider %s", p.Path, p.Name)
+		}
+
+		// Запускаем плагин, чтобы убедиться, что он говорит на нашем протоколе
+		client, err := plugin.Launch(context.Background(), p.Name, p.Path, e.providerLogger.With("provider", p.Name))
+		if err != nil {
+			return fmt.Errorf("failed to verify provider plugin %s: %w", p.Name, err)
+		}
+		client.Close()
+
+		lockFile.Providers[p.Name] = plugin.LockedProvider{Path: p.Path, Hash: p.Hash}
+		e.logger.Info("- Installed provider %s from %s (%s)", p.Name, p.Path, p.Hash)
+	}
+
+	if err := lockFile.Save(plugin.LockFileName); err != nil {
+		return fmt.Errorf("failed to write %s: %w", plugin.LockFileName, err)
+	}
+	e.lockFile = lockFile
+
+	e.logger.Info("Provider checksums are recorded in %s", plugin.LockFileName)
+	return nil
+}
+
+// providerKnown сообщает, есть ли встроенный провайдер или установленный плагин с таким именем
+func (e *Engine) providerKnown(name string) (bool, error) {
+	if _, exists := providers.Lookup(name); exists {
+		return true, nil
+	}
+
+	lockFile, err := e.loadLockFile()
+	if err != nil {
+		return false, err
+	}
+	_, exists := lockFile.Providers[name]
+	return exists, nil
+}
+
+// newProvider создает встроенный провайдер или запускает процесс плагина
+func (e *Engine) newProvider(ctx context.Context, name string) (providers.Provider, error) {
+	if factory, exists := providers.Lookup(name); exists {
+		provider := factory()
+		if setter, ok := provider.(providers.LoggerSetter); ok {
+			setter.SetLogger(e.providerLogger.With("provider", name))
+		}
+		return provider, nil
+	}
+
+	lockFile, err := e.loadLockFile()
+	if err != nil {
+		return nil, err
+	}
+	locked, exists := lockFile.Providers[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown provider: %s (run init to install provider plugins)", name)
+	}
+	if err := locked.Verify(); err != nil {
+		return nil, fmt.Errorf("provider plugin %s changed since init, run init again: %w", name, err)
+	}
+
+	e.logger.Debug("Launching provider plugin %s", name)
+	return plugin.Launch(ctx, name, locked.Path, e.providerLogger.With("provider", name))
+}
+
+func (e *Engine) loadLockFile() (*plugin.LockFile, error) {
+	if e.lockFile != nil {
+		return e.lockFile, nil
+	}
+
+	lockFile, err := plugin.ReadLockFile(plugin.LockFileName)
+	if err != nil {
+		return nil, err
+	}
+	e.lockFile = lockFile
+	return lockFile, nil
+}
+
+// closeProviders останавливает процессы плагинов
+func (e *Engine) closeProviders() {
+	for address, provider := range e.providerInstances {
+		closeProvider(provider)
+		delete(e.providerInstances, address)
+	}
+}
+
+func closeProvider(provider providers.Provider) {
+	if closer, ok := provider.(io.Closer); ok {
+		closer.Close()
+	}
+}
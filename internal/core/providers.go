@@ -0,0 +1,241 @@
+// internal/core/providers.go
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/zclconf/go-cty/cty"
+
+	// Встроенные провайдеры регистрируются при импорте
+	_ "github.com/Artemka007/derraform/internal/providers/docker"
+)
+
+// configureProviders запоминает блоки provider.
+// Сами провайдеры создаются позже, при первом обращении, там же проверяется схема.
+func (e *Engine) configureProviders(cfg *config.Config) error {
+	for _, provider := range cfg.Providers {
+		known, err := e.providerKnown(provider.Name)
+		if err != nil {
+			return err
+		}
+		if !known {
+			return fmt.Errorf("unknown provider: %s", provider.Name)
+		}
+
+		address, err := providerConfigAddress(provider)
+		if err != nil {
+			return fmt.Errorf("invalid provider %s: %w", provider.Name, err)
+		}
+		if _, exists := e.providerConfigs[address]; exists {
+			return fmt.Errorf("duplicate provider configuration: %s", address)
+		}
+		e.providerConfigs[address] = provider
+	}
+	return nil
+}
+
+// providerFor возвращает провайдер по адресу (docker или docker.<alias>),
+// создавая и кешируя его при первом обращении
+func (e *Engine) providerFor(ctx context.Context, address string) (providers.Provider, error) {
+	if provider, exists := e.providerInstances[address]; exists {
+		return provider, nil
+	}
+
+	name, alias, _ := strings.Cut(address, ".")
+
+	// Провайдер без блока provider настраивается пустой конфигурацией (из окружения),
+	// а для alias блок обязателен
+	providerConfig, exists := e.providerConfigs[address]
+	if !exists {
+		if alias != "" {
+			return nil, fmt.Errorf("provider %s is not configured", address)
+		}
+		providerConfig = config.Provider{Name: name}
+	}
+
+	provider, err := e.newProvider(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := providers.ValidateProvider(provider.Schema(), providerConfig); err != nil {
+		closeProvider(provider)
+		return nil, fmt.Errorf("invalid provider %s: %w", address, err)
+	}
+
+	logging.AddSensitive(schemaSensitiveStrings(provider.Schema(), providerConfig.Attributes, providerConfig.Blocks)...)
+
+	e.logger.Debug("Configuring provider %s", address)
+	if err := provider.Configure(ctx, providerConfig); err != nil {
+		closeProvider(provider)
+		return nil, fmt.Errorf("failed to configure provider %s: %w", address, err)
+	}
+
+	e.providerInstances[address] = provider
+	return provider, nil
+}
+
+// resourceFor возвращает реализацию ресурса и проверяет конфигурацию по его схеме
+func (e *Engine) resourceFor(ctx context.Context, resource config.Resource) (providers.Resource, error) {
+	address := providerAddress(resource)
+	if name, _, _ := strings.Cut(address, "."); name != providers.ProviderName(resource.Type) {
+		return nil, fmt.Errorf("provider %s cannot manage resource type %s", address, resource.Type)
+	}
+
+	impl, err := e.resourceImpl(ctx, address, resource.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := providers.ValidateResource(impl.Schema(), resource); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	logging.AddSensitive(schemaSensitiveStrings(impl.Schema(), resource.Attributes, resource.Blocks)...)
+	return impl, nil
+}
+
+// resourceSensitiveAttributes атрибуты ресурса, которые не показываются в выводе:
+// объявленные sensitive в схеме и вычисленные из sensitive значений конфигурации
+func (e *Engine) resourceSensitiveAttributes(impl providers.Resource, resource config.Resource) []string {
+	schema := impl.Schema()
+	names := append([]string(nil), e.sensitiveAttributes[resource.Type+"."+resource.Name]...)
+	for name, attribute := range schema.Attributes {
+		if attribute.Sensitive && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	for name, block := range schema.Blocks {
+		if schemaHasSensitive(block.Schema) && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// schemaSensitiveStrings строки из значений атрибутов, объявленных sensitive в схеме
+func schemaSensitiveStrings(schema providers.Schema, attributes map[string]cty.Value, blocks []config.Block) []string {
+	var result []string
+	for name, attribute := range schema.Attributes {
+		if value, exists := attributes[name]; exists && attribute.Sensitive {
+			result = append(result, config.Strings(value)...)
+		}
+	}
+	for _, block := range blocks {
+		if nested, exists := schema.Blocks[block.Type]; exists {
+			result = append(result, schemaSensitiveStrings(nested.Schema, block.Attributes, block.Blocks)...)
+		}
+	}
+	return result
+}
+
+func schemaHasSensitive(schema providers.Schema) bool {
+	for _, attribute := range schema.Attributes {
+		if attribute.Sensitive {
+			return true
+		}
+	}
+	for _, block := range schema.Blocks {
+		if schemaHasSensitive(block.Schema) {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceForState возвращает реализацию ресурса по записи в state
+func (e *Engine) resourceForState(ctx context.Context, resourceState state.ResourceState) (providers.Resource, error) {
+	address := resourceState.Provider
+	if address == "" {
+		address = providers.ProviderName(resourceState.Type)
+	}
+	return e.resourceImpl(ctx, address, resourceState.Type)
+}
+
+// priorState возвращает сохраненное состояние ресурса, приведенное к текущей схеме
+func (e *Engine) priorState(ctx context.Context, impl providers.Resource, resource config.Resource) (*state.ResourceState, error) {
+	prior, err := e.stateManager.GetResourceState(resource.Type, resource.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	if err := upgradeResourceState(ctx, impl, prior); err != nil {
+		return nil, err
+	}
+	return prior, nil
+}
+
+// upgradeResourceState переводит атрибуты записи state на текущую версию схемы ресурса
+func upgradeResourceState(ctx context.Context, impl providers.Resource, resourceState *state.ResourceState) error {
+	if resourceState == nil {
+		return nil
+	}
+
+	current := impl.Schema().Version
+	if resourceState.SchemaVersion == current {
+		return nil
+	}
+	if resourceState.SchemaVersion > current {
+		return fmt.Errorf("state of %s was written with schema version %d, but the provider supports only %d",
+			resourceState.Type, resourceState.SchemaVersion, current)
+	}
+
+	upgrader, ok := impl.(providers.StateUpgrader)
+	if !ok {
+		return fmt.Errorf("provider cannot upgrade state of %s from schema version %d to %d",
+			resourceState.Type, resourceState.SchemaVersion, current)
+	}
+
+	attributes, err := upgrader.UpgradeState(ctx, resourceState.SchemaVersion, resourceState.Attributes)
+	if err != nil {
+		return fmt.Errorf("failed to upgrade state of %s from schema version %d: %w",
+			resourceState.Type, resourceState.SchemaVersion, err)
+	}
+	resourceState.Attributes = attributes
+	resourceState.SchemaVersion = current
+	return nil
+}
+
+func (e *Engine) resourceImpl(ctx context.Context, address, resourceType string) (providers.Resource, error) {
+	provider, err := e.providerFor(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	impl, exists := provider.Resources()[resourceType]
+	if !exists {
+		return nil, fmt.Errorf("unknown resource type: %s", resourceType)
+	}
+	return impl, nil
+}
+
+// providerAddress возвращает адрес провайдера ресурса: из мета-аргумента provider
+// или по префиксу типа (docker_container -> docker)
+func providerAddress(resource config.Resource) string {
+	if resource.Provider == "" {
+		return providers.ProviderName(resource.Type)
+	}
+	return resource.Provider
+}
+
+// providerConfigAddress возвращает адрес блока provider с учетом alias
+func providerConfigAddress(provider config.Provider) (string, error) {
+	alias, err := providers.StringAttribute(provider.Attributes, "alias")
+	if err != nil {
+		return "", err
+	}
+	if alias == "" {
+		return provider.Name, nil
+	}
+	if strings.Contains(alias, ".") {
+		return "", fmt.Errorf("invalid alias %q", alias)
+	}
+	return provider.Name + "." + alias, nil
+}
@@ -0,0 +1,183 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// upgradingResource ресурс схемы версии 2: в версии 1 ports стал списком,
+// в версии 2 атрибут name переименован в container_name
+type upgradingResource struct {
+	fakeResource
+	calls []int
+}
+
+func (r *upgradingResource) UpgradeState(ctx context.Context, version int, attributes map[string]interface{}) (map[string]interface{}, error) {
+	r.calls = append(r.calls, version)
+	upgraded := make(map[string]interface{}, len(attributes))
+	for key, value := range attributes {
+		upgraded[key] = value
+	}
+	if version < 1 {
+		port, ok := upgraded["ports"].(string)
+		if !ok {
+			return nil, fmt.Errorf("ports is %T, want a string", upgraded["ports"])
+		}
+		upgraded["ports"] = []interface{}{port}
+	}
+	if version < 2 {
+		upgraded["container_name"] = upgraded["name"]
+		delete(upgraded, "name")
+	}
+	return upgraded, nil
+}
+
+func TestUpgradeResourceState(t *testing.T) {
+	schema := providers.Schema{Version: 2, Attributes: map[string]*providers.Attribute{
+		"container_name": {Type: cty.String, Optional: true},
+		"ports":          {Type: cty.List(cty.String), Optional: true},
+	}}
+
+	tests := []struct {
+		name       string
+		upgrader   bool
+		version    int
+		attributes map[string]interface{}
+		want       map[string]interface{}
+		calls      []int
+		err        string
+	}{
+		{
+			name: "from version 0", upgrader: true, version: 0,
+			attributes: map[string]interface{}{"name": "web", "ports": "80"},
+			want:       map[string]interface{}{"container_name": "web", "ports": []interface{}{"80"}},
+			calls:      []int{0},
+		},
+		{
+			name: "from version 1", upgrader: true, version: 1,
+			attributes: map[string]interface{}{"name": "web", "ports": []interface{}{"80"}},
+			want:       map[string]interface{}{"container_name": "web", "ports": []interface{}{"80"}},
+			calls:      []int{1},
+		},
+		{
+			name: "current version", upgrader: true, version: 2,
+			attributes: map[string]interface{}{"container_name": "web"},
+			want:       map[string]interface{}{"container_name": "web"},
+		},
+		{
+			name: "newer version", upgrader: true, version: 3,
+			attributes: map[string]interface{}{"container_name": "web"},
+			err:        "state of docker_container was written with schema version 3, but the provider supports only 2",
+		},
+		{
+			name: "resource without upgrader", version: 1,
+			attributes: map[string]interface{}{"name": "web"},
+			err:        "provider cannot upgrade state of docker_container from schema version 1 to 2",
+		},
+		{
+			name: "upgrader error", upgrader: true, version: 0,
+			attributes: map[string]interface{}{"name": "web", "ports": 80},
+			calls:      []int{0},
+			err:        "failed to upgrade state of docker_container from schema version 0: ports is int, want a string",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resourceState := &state.ResourceState{Type: "docker_container", ID: "c1", SchemaVersion: test.version, Attributes: test.attributes}
+			upgrading := &upgradingResource{fakeResource: fakeResource{schema: schema}}
+			var impl providers.Resource = &upgrading.fakeResource
+			if test.upgrader {
+				impl = upgrading
+			}
+
+			err := upgradeResourceState(context.Background(), impl, resourceState)
+			if !reflect.DeepEqual(upgrading.calls, test.calls) {
+				t.Errorf("UpgradeState calls = %v, want %v", upgrading.calls, test.calls)
+			}
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				if resourceState.SchemaVersion != test.version {
+					t.Errorf("schema_version = %d after a failed upgrade, want %d", resourceState.SchemaVersion, test.version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resourceState.SchemaVersion != schema.Version || !reflect.DeepEqual(resourceState.Attributes, test.want) {
+				t.Errorf("state = version %d %v, want version %d %v", resourceState.SchemaVersion, resourceState.Attributes, schema.Version, test.want)
+			}
+		})
+	}
+
+	if err := upgradeResourceState(context.Background(), &fakeResource{schema: schema}, nil); err != nil {
+		t.Errorf("upgrade of a missing resource: %v", err)
+	}
+}
+
+func TestApplySensitiveValues(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeTestConfig(t, `
+variable "image" {
+  sensitive = true
+}
+
+resource "docker_container" "web" {
+  image = var.image
+}
+
+resource "docker_network" "app" {}
+
+output "image" {
+  value     = var.image
+  sensitive = true
+}
+`)
+	var out bytes.Buffer
+	logger, err := logging.New(logging.Config{Level: logging.TRACE, Output: &out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err := NewEngine(Options{Logger: logger, Variables: map[string]string{"image": "registry.local/app-s3cr3t:1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newTestResource()
+	useTestProvider(engine, r)
+
+	// testResource читает image через AsString, который паникует на помеченном значении
+	if err := engine.Apply(context.Background(), defaultTestConfig, ApplyOptions{}); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	if got := r.objects["web-1"]["image"]; got != "registry.local/app-s3cr3t:1" {
+		t.Errorf("provider got image %v, want the real value", got)
+	}
+	// Значение, которое видит провайдер, вырезается и из его сообщений
+	engine.providerLogger.Info("Created container from %s", r.objects["web-1"]["image"])
+	resources := readTestState(t).Resources
+	if got := resources["docker_container.web"].SensitiveAttributes; !reflect.DeepEqual(got, []string{"image"}) {
+		t.Errorf("web sensitive attributes = %v, want [image]", got)
+	}
+	if got := resources["docker_network.app"].SensitiveAttributes; len(got) != 0 {
+		t.Errorf("app sensitive attributes = %v, want none", got)
+	}
+	if strings.Contains(out.String(), "app-s3cr3t") {
+		t.Errorf("log contains the sensitive value:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "image = (sensitive value)") {
+		t.Errorf("log does not show the sensitive output:\n%s", out.String())
+	}
+}
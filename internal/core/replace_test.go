@@ -0,0 +1,278 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/errors"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// fakeResource ресурс с заданной схемой, Plan которого возвращает change
+type fakeResource struct {
+	schema providers.Schema
+	change *providers.PlannedChange
+}
+
+func (r *fakeResource) Schema() providers.Schema { return r.schema }
+
+func (r *fakeResource) Plan(ctx context.Context, cfg config.Resource, prior *state.ResourceState) (*providers.PlannedChange, error) {
+	return r.change, nil
+}
+
+func (r *fakeResource) Apply(ctx context.Context, cfg config.Resource, prior *state.ResourceState, change *providers.PlannedChange) (*state.ResourceState, error) {
+	return prior, nil
+}
+
+func (r *fakeResource) Read(ctx context.Context, current *state.ResourceState) (*state.ResourceState, error) {
+	return current, nil
+}
+
+func (r *fakeResource) Delete(ctx context.Context, current *state.ResourceState) error { return nil }
+
+func (r *fakeResource) Import(ctx context.Context, id string) (*state.ResourceState, error) {
+	return &state.ResourceState{ID: id}, nil
+}
+
+func TestPlanResourceReplace(t *testing.T) {
+	uniqueName := providers.Schema{Attributes: map[string]*providers.Attribute{
+		"name":  {Type: cty.String, Optional: true, Unique: true},
+		"image": {Type: cty.String, Required: true},
+	}}
+	plainName := providers.Schema{Attributes: map[string]*providers.Attribute{
+		"name": {Type: cty.String, Optional: true},
+	}}
+	replaceImage := &providers.PlannedChange{Action: providers.ActionReplace, Reason: "image changed"}
+
+	tests := []struct {
+		name       string
+		schema     providers.Schema
+		change     *providers.PlannedChange
+		attributes map[string]cty.Value
+		cbd        bool
+		tainted    bool
+		replace    bool
+		want       providers.Action
+		err        string
+	}{
+		{
+			name:   "destroy before create keeps the name",
+			schema: uniqueName, change: replaceImage,
+			want: providers.ActionReplace,
+		},
+		{
+			name:   "default name",
+			schema: uniqueName, change: replaceImage, cbd: true,
+			err: `the replacement would have the same name "web"`,
+		},
+		{
+			name:   "same explicit name",
+			schema: uniqueName, change: replaceImage, cbd: true,
+			attributes: map[string]cty.Value{"name": cty.StringVal("web")},
+			err:        "name must be unique",
+		},
+		{
+			name:   "tainted object",
+			schema: uniqueName, cbd: true, tainted: true,
+			err: "create_before_destroy cannot replace docker_container.web",
+		},
+		{
+			name:   "requested with -replace",
+			schema: uniqueName, cbd: true, replace: true,
+			err: "create_before_destroy cannot replace docker_container.web",
+		},
+		{
+			name:   "new name",
+			schema: uniqueName, change: replaceImage, cbd: true,
+			attributes: map[string]cty.Value{"name": cty.StringVal("web-v2")},
+			want:       providers.ActionReplace,
+		},
+		{
+			name:   "name is not unique",
+			schema: plainName, change: replaceImage, cbd: true,
+			want: providers.ActionReplace,
+		},
+		{
+			name:   "update in place",
+			schema: uniqueName, change: &providers.PlannedChange{Action: providers.ActionUpdate}, cbd: true,
+			want: providers.ActionUpdate,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			impl := &fakeResource{schema: test.schema, change: test.change}
+			resource := config.Resource{
+				Type:       "docker_container",
+				Name:       "web",
+				Attributes: test.attributes,
+				Lifecycle:  config.Lifecycle{CreateBeforeDestroy: test.cbd},
+			}
+			prior := &state.ResourceState{
+				ID:         "3f2a9c",
+				Tainted:    test.tainted,
+				Attributes: map[string]interface{}{"name": "web", "image": "nginx:1.26"},
+			}
+
+			change, err := newTestEngine(t).planResource(context.Background(), impl, resource, prior, test.replace)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if change.Action != test.want {
+				t.Errorf("action = %s, want %s", change.Action, test.want)
+			}
+		})
+	}
+}
+
+func TestTaintUntaint(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(engine *Engine, options StateEditOptions) error
+		tainted bool
+		want    bool
+		code    errors.Code
+		err     string
+	}{
+		{
+			name: "taint",
+			run: func(engine *Engine, options StateEditOptions) error {
+				return engine.Taint(defaultTestConfig, "docker_container.web", options)
+			},
+			want: true,
+		},
+		{
+			name: "taint a tainted object",
+			run: func(engine *Engine, options StateEditOptions) error {
+				return engine.Taint(defaultTestConfig, "docker_container.web", options)
+			},
+			tainted: true,
+			want:    true,
+		},
+		{
+			name: "untaint",
+			run: func(engine *Engine, options StateEditOptions) error {
+				return engine.Untaint(defaultTestConfig, "docker_container.web", options)
+			},
+			tainted: true,
+		},
+		{
+			name: "untaint an object that is not tainted",
+			run: func(engine *Engine, options StateEditOptions) error {
+				return engine.Untaint(defaultTestConfig, "docker_container.web", options)
+			},
+			code: errors.CodeState,
+			err:  "docker_container.web is not tainted",
+		},
+		{
+			name: "taint a missing resource",
+			run: func(engine *Engine, options StateEditOptions) error {
+				return engine.Taint(defaultTestConfig, "docker_container.db", options)
+			},
+			code: errors.CodeState,
+			err:  "no resource docker_container.db in state",
+		},
+		{
+			name: "invalid address",
+			run: func(engine *Engine, options StateEditOptions) error {
+				return engine.Taint(defaultTestConfig, "docker_container", options)
+			},
+			code: errors.CodeConfig,
+			err:  "Invalid resource address",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			writeTestState(t, map[string]state.ResourceState{
+				"docker_container.web": {Type: "docker_container", ID: "web-1", Tainted: test.tainted},
+			})
+
+			err := test.run(newTestEngine(t), StateEditOptions{Backup: "-"})
+			if test.err != "" {
+				assertErrorCode(t, err, test.code, test.err)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := readTestState(t).Resources["docker_container.web"].Tainted; got != test.want {
+				t.Errorf("tainted = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestApplyReplace(t *testing.T) {
+	const webConfig = `
+resource "docker_container" "web" {
+  image = "nginx:1.27"
+}
+
+resource "docker_container" "db" {
+  image = "postgres:16"
+}
+`
+	tests := []struct {
+		name    string
+		replace []string
+		tainted bool
+		// deleted пересозданные объекты
+		deleted []string
+		err     string
+	}{
+		{name: "up to date"},
+		{name: "requested with -replace", replace: []string{"docker_container.web"}, deleted: []string{"web-1"}},
+		{name: "tainted object", tainted: true, deleted: []string{"web-1"}},
+		{name: "address not in configuration", replace: []string{"docker_container.cache"}, err: "no resource docker_container.cache in configuration"},
+		{name: "instance address", replace: []string{"docker_container.web[0]"}, err: "no resource docker_container.web[0] in configuration"},
+		{name: "invalid address", replace: []string{"web"}, err: "Invalid -replace address"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			writeTestConfig(t, webConfig)
+			writeTestState(t, map[string]state.ResourceState{
+				"docker_container.web": {Type: "docker_container", ID: "web-1", Tainted: test.tainted, Attributes: map[string]interface{}{"image": "nginx:1.27"}},
+				"docker_container.db":  {Type: "docker_container", ID: "db-1", Attributes: map[string]interface{}{"image": "postgres:16"}},
+			})
+			r := newTestResource()
+			r.created["web"] = 1
+
+			engine := newTestEngine(t)
+			useTestProvider(engine, r)
+			err := engine.Apply(context.Background(), defaultTestConfig, ApplyOptions{Replace: test.replace})
+			if test.err != "" {
+				assertErrorCode(t, err, errors.CodeConfig, test.err)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(r.deleted, test.deleted) {
+				t.Errorf("deleted = %v, want %v", r.deleted, test.deleted)
+			}
+			want := map[string]string{"docker_container.web": "web-1", "docker_container.db": "db-1"}
+			if test.deleted != nil {
+				want["docker_container.web"] = "web-2"
+			}
+			if got := stateIDs(t); !reflect.DeepEqual(got, want) {
+				t.Errorf("state = %v, want %v", got, want)
+			}
+		})
+	}
+}
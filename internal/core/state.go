@@ -0,0 +1,370 @@
+// internal/core/state.go
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/errors"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+)
+
+// StateEditOptions флаги команд, которые изменяют state
+type StateEditOptions struct {
+	// Backup куда сохранить state перед изменением: пусто - рядом со state
+	// с меткой времени в имени, "-" - не сохранять
+	Backup string
+	// DryRun только показать изменения, не записывая state
+	DryRun bool
+}
+
+// StateList возвращает адреса записей state, подходящие под фильтры и ID
+func (e *Engine) StateList(configFile string, filters []string, id string) ([]string, error) {
+	addresses, err := parseAddresses(filters)
+	if err != nil {
+		return nil, err
+	}
+	current, err := e.loadState(configFile)
+	if err != nil {
+		return nil, err
+	}
+	return current.Filter(addresses, id), nil
+}
+
+// StateShow возвращает запись state по адресу
+func (e *Engine) StateShow(configFile, address string) (*state.ResourceState, error) {
+	parsed, err := state.ParseAddress(address)
+	if err != nil {
+		return nil, errors.WrapError(err, errors.CodeConfig, "Invalid resource address")
+	}
+	current, err := e.loadState(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	resourceState, exists := current.Resources[parsed.String()]
+	if !exists {
+		if instances := current.Filter([]state.Address{parsed}, ""); len(instances) > 0 {
+			return nil, errors.NewError(errors.CodeState, fmt.Sprintf("%s has several instances, specify one of: %s", parsed, strings.Join(instances, ", ")))
+		}
+		return nil, errors.NewError(errors.CodeState, fmt.Sprintf("No resource %s in state", parsed))
+	}
+	return &resourceState, nil
+}
+
+// StatePull возвращает state в текущем формате
+func (e *Engine) StatePull(configFile string) ([]byte, error) {
+	current, err := e.loadState(configFile)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(current, "", "  ")
+}
+
+// StateMove переносит запись state на новый адрес, например после переименования ресурса в конфигурации
+func (e *Engine) StateMove(configFile, source, destination string, options StateEditOptions) error {
+	src, err := state.ParseAddress(source)
+	if err != nil {
+		return errors.WrapError(err, errors.CodeConfig, "Invalid source address")
+	}
+	dst, err := state.ParseAddress(destination)
+	if err != nil {
+		return errors.WrapError(err, errors.CodeConfig, "Invalid destination address")
+	}
+
+	return e.editState(configFile, "state mv", options, func(current *state.State) error {
+		moves, err := current.Move(src, dst)
+		if err != nil {
+			return err
+		}
+		for _, move := range moves {
+			e.logger.Info("%s %s to %s", dryRunVerb(options, "Moved", "Would move"), move[0], move[1])
+		}
+		return nil
+	})
+}
+
+// StateRemove убирает записи из state, не удаляя сами объекты
+func (e *Engine) StateRemove(configFile string, addresses []string, options StateEditOptions) error {
+	parsed, err := parseAddresses(addresses)
+	if err != nil {
+		return err
+	}
+
+	return e.editState(configFile, "state rm", options, func(current *state.State) error {
+		removed, err := current.Remove(parsed)
+		if err != nil {
+			return err
+		}
+		for _, address := range removed {
+			e.logger.Info("%s %s", dryRunVerb(options, "Removed", "Would remove"), address)
+		}
+		return nil
+	})
+}
+
+// ReplaceProvider переназначает записи state с одного провайдера на другой,
+// например с docker на docker.edge
+func (e *Engine) ReplaceProvider(configFile, from, to string, options StateEditOptions) error {
+	for _, address := range []string{from, to} {
+		if err := validateProviderAddress(address); err != nil {
+			return errors.WrapError(err, errors.CodeConfig, "Invalid provider address")
+		}
+	}
+
+	return e.editState(configFile, "state replace-provider", options, func(current *state.State) error {
+		replaced := current.ReplaceProvider(from, to, providers.ProviderName)
+		if len(replaced) == 0 {
+			return fmt.Errorf("no resource in state uses provider %s", from)
+		}
+		for _, address := range replaced {
+			e.logger.Info("%s %s: %s -> %s", dryRunVerb(options, "Replaced provider of", "Would replace provider of"), address, from, to)
+		}
+		return nil
+	})
+}
+
+// Taint помечает объект в state, чтобы следующий apply его пересоздал
+func (e *Engine) Taint(configFile, address string, options StateEditOptions) error {
+	return e.setTainted(configFile, "taint", address, true, options)
+}
+
+// Untaint снимает пометку tainted, объект остается как есть
+func (e *Engine) Untaint(configFile, address string, options StateEditOptions) error {
+	return e.setTainted(configFile, "untaint", address, false, options)
+}
+
+func (e *Engine) setTainted(configFile, operation, address string, tainted bool, options StateEditOptions) error {
+	parsed, err := state.ParseAddress(address)
+	if err != nil {
+		return errors.WrapError(err, errors.CodeConfig, "Invalid resource address")
+	}
+
+	return e.editState(configFile, operation, options, func(current *state.State) error {
+		resourceState, exists := current.Resources[parsed.String()]
+		if !exists {
+			return fmt.Errorf("no resource %s in state", parsed)
+		}
+		if !tainted && !resourceState.Tainted {
+			return fmt.Errorf("%s is not tainted", parsed)
+		}
+
+		resourceState.Tainted = tainted
+		current.Resources[parsed.String()] = resourceState
+		if tainted {
+			e.logger.Info("%s %s as tainted", dryRunVerb(options, "Marked", "Would mark"), parsed)
+		} else {
+			e.logger.Info("%s the tainted mark from %s", dryRunVerb(options, "Removed", "Would remove"), parsed)
+		}
+		return nil
+	})
+}
+
+// StateRekey перезаписывает state основным ключом шифрования после смены ключа
+// или перехода на шифрование
+func (e *Engine) StateRekey(configFile string, options StateEditOptions) error {
+	return e.editState(configFile, "state rekey", options, func(current *state.State) error {
+		if e.encryption == nil {
+			return fmt.Errorf("no encryption is configured in the terraform block")
+		}
+		e.logger.Info("%s state with the primary key", dryRunVerb(options, "Rewrote", "Would rewrite"))
+		return nil
+	})
+}
+
+// PushState заменяет state содержимым файла (или stdin для "-").
+// State другого lineage или более старый serial принимаются только с force.
+func (e *Engine) PushState(configFile, path string, force bool, options StateEditOptions) error {
+	if err := e.loadBackend(configFile, true); err != nil {
+		return err
+	}
+
+	unlock, err := e.lockState(context.Background(), "state push")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var data []byte
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state: %w", err)
+	}
+
+	// state pull выдает расшифрованный state, поэтому незашифрованный ввод принимается
+	// и при настроенном шифровании: Push запишет его зашифрованным основным ключом
+	var pushed *state.State
+	if state.IsEncrypted(data) {
+		pushed, err = e.stateManager.Decode(data)
+	} else {
+		pushed, err = state.Parse(data)
+	}
+	if err != nil {
+		return errors.WrapError(err, errors.CodeState, "Failed to parse pushed state")
+	}
+
+	if options.DryRun {
+		e.logger.Info("Would push state: %d resources, serial %d, lineage %s", len(pushed.Resources), pushed.Serial, pushed.Lineage)
+		return nil
+	}
+	if err := e.backupState(options.Backup); err != nil {
+		return err
+	}
+	if err := e.stateManager.Push(pushed, force); err != nil {
+		return errors.WrapError(err, errors.CodeState, "Failed to push state")
+	}
+
+	e.logger.Info("State pushed: %d resources, serial %d, lineage %s", len(pushed.Resources), pushed.Serial, pushed.Lineage)
+	return nil
+}
+
+// loadState подключает бэкенд и читает state без блокировки
+func (e *Engine) loadState(configFile string) (*state.State, error) {
+	if err := e.loadBackend(configFile, true); err != nil {
+		return nil, err
+	}
+	current, err := e.stateManager.Load()
+	if err != nil {
+		return nil, errors.WrapError(err, errors.CodeState, "Failed to load state")
+	}
+	return current, nil
+}
+
+// editState под блокировкой читает state, применяет edit и, если это не dry run,
+// сохраняет копию прежнего state и записывает новый
+func (e *Engine) editState(configFile, operation string, options StateEditOptions, edit func(current *state.State) error) error {
+	if err := e.loadBackend(configFile, true); err != nil {
+		return err
+	}
+
+	unlock, err := e.lockState(context.Background(), operation)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := e.stateManager.Load()
+	if err != nil {
+		return errors.WrapError(err, errors.CodeState, "Failed to load state")
+	}
+	if err := edit(current); err != nil {
+		return errors.WrapError(err, errors.CodeState, fmt.Sprintf("Failed to run %s", operation))
+	}
+	if options.DryRun {
+		return nil
+	}
+
+	if err := e.backupState(options.Backup); err != nil {
+		return err
+	}
+	if err := e.stateManager.Save(current); err != nil {
+		return errors.WrapError(err, errors.CodeState, "Failed to save state")
+	}
+	return nil
+}
+
+// localStateFile реализуют бэкенды, которые хранят state в локальном файле
+type localStateFile interface {
+	Filename() string
+}
+
+// backupState сохраняет текущий state в локальный файл перед его изменением
+func (e *Engine) backupState(path string) error {
+	if path == "-" {
+		return nil
+	}
+
+	data, err := e.stateManager.Backend().Read(context.Background())
+	if err != nil {
+		return errors.WrapError(err, errors.CodeState, "Failed to read state for backup")
+	}
+	if data == nil {
+		return nil
+	}
+
+	if path == "" {
+		base := state.DefaultStateFile
+		if file, ok := e.stateManager.Backend().(localStateFile); ok {
+			base = file.Filename()
+		} else if e.workspace != config.DefaultWorkspace {
+			base += "." + e.workspace
+		}
+		path = fmt.Sprintf("%s.%d.backup", base, time.Now().Unix())
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.WrapError(err, errors.CodeState, "Failed to write state backup")
+	}
+	written, err := writeNewFile(path, data)
+	if err != nil {
+		return errors.WrapError(err, errors.CodeState, "Failed to write state backup")
+	}
+	e.logger.Info("Backup of the previous state written to %s", written)
+	return nil
+}
+
+// writeNewFile записывает данные, не затирая существующий файл: если path занят
+// (например, копией от команды в ту же секунду), к имени добавляется номер
+func writeNewFile(path string, data []byte) (string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 0; ; i++ {
+		candidate := path
+		if i > 0 {
+			candidate = fmt.Sprintf("%s.%d%s", base, i, ext)
+		}
+
+		file, err := os.OpenFile(candidate, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := file.Write(data); err != nil {
+			file.Close()
+			return "", err
+		}
+		return candidate, file.Close()
+	}
+}
+
+func parseAddresses(values []string) ([]state.Address, error) {
+	addresses := make([]state.Address, 0, len(values))
+	for _, value := range values {
+		address, err := state.ParseAddress(value)
+		if err != nil {
+			return nil, errors.WrapError(err, errors.CodeConfig, "Invalid resource address")
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// validateProviderAddress проверяет адрес провайдера вида docker или docker.edge
+func validateProviderAddress(address string) error {
+	name, alias, hasAlias := strings.Cut(address, ".")
+	if name == "" || (hasAlias && (alias == "" || strings.Contains(alias, "."))) {
+		return fmt.Errorf("invalid provider address %q: expected NAME or NAME.ALIAS", address)
+	}
+	return nil
+}
+
+func dryRunVerb(options StateEditOptions, verb, dryRun string) string {
+	if options.DryRun {
+		return dryRun
+	}
+	return verb
+}
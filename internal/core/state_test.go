@@ -0,0 +1,356 @@
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/errors"
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/Artemka007/derraform/internal/state"
+)
+
+// defaultTestConfig файл конфигурации во временном каталоге теста
+const defaultTestConfig = "main.tf"
+
+const encryptedConfig = `
+terraform {
+  encryption {
+    key {
+      passphrase = "correct horse battery staple"
+    }
+  }
+}
+`
+
+func newTestEngine(t *testing.T) *Engine {
+	t.Helper()
+	logger, err := logging.New(logging.Config{Level: logging.INFO, Output: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err := NewEngine(Options{Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+// state pull -> правка -> state push должен работать и для зашифрованного state
+func TestStatePullPushEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile(defaultTestConfig, []byte(encryptedConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := newTestEngine(t)
+	if err := engine.loadBackend(defaultTestConfig, true); err != nil {
+		t.Fatal(err)
+	}
+	err := engine.stateManager.Save(&state.State{Resources: map[string]state.ResourceState{
+		"docker_network.app": {Type: "docker_network", ID: "net-1", Attributes: map[string]interface{}{"name": "app"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pulled, err := newTestEngine(t).StatePull(defaultTestConfig)
+	if err != nil {
+		t.Fatalf("state pull: %v", err)
+	}
+	if state.IsEncrypted(pulled) {
+		t.Fatal("state pull returned encrypted state")
+	}
+
+	edited, err := state.Parse(pulled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	network := edited.Resources["docker_network.app"]
+	network.ID = "net-2"
+	edited.Resources["docker_network.app"] = network
+	data, err := json.Marshal(edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pushPath := filepath.Join(dir, "edited.tfstate")
+	if err := os.WriteFile(pushPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := newTestEngine(t).PushState(defaultTestConfig, pushPath, false, StateEditOptions{Backup: "-"}); err != nil {
+		t.Fatalf("state push: %v", err)
+	}
+
+	raw, err := os.ReadFile(state.DefaultStateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.IsEncrypted(raw) {
+		t.Fatal("pushed state was written unencrypted")
+	}
+
+	pushed, err := newTestEngine(t).StatePull(defaultTestConfig)
+	if err != nil {
+		t.Fatalf("state pull after push: %v", err)
+	}
+	result, err := state.Parse(pushed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Resources["docker_network.app"].ID; got != "net-2" {
+		t.Errorf("pushed resource ID = %q, want net-2", got)
+	}
+	if result.Serial <= edited.Serial {
+		t.Errorf("serial = %d, want greater than %d", result.Serial, edited.Serial)
+	}
+}
+
+// Зашифрованный ввод state push по-прежнему принимается
+func TestStatePushEncryptedInput(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile(defaultTestConfig, []byte(encryptedConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := newTestEngine(t)
+	if err := engine.loadBackend(defaultTestConfig, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.stateManager.Save(&state.State{Resources: map[string]state.ResourceState{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(state.DefaultStateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pushPath := filepath.Join(dir, "copy.tfstate")
+	if err := os.WriteFile(pushPath, raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := newTestEngine(t).PushState(defaultTestConfig, pushPath, false, StateEditOptions{Backup: "-"}); err != nil {
+		t.Fatalf("state push of encrypted file: %v", err)
+	}
+}
+
+// writeTestState записывает state с указанными записями в локальный terraform.tfstate
+func writeTestState(t *testing.T, resources map[string]state.ResourceState) {
+	t.Helper()
+	engine := newTestEngine(t)
+	if err := engine.loadBackend(defaultTestConfig, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.stateManager.Save(&state.State{Resources: resources}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestState(t *testing.T) *state.State {
+	t.Helper()
+	data, err := os.ReadFile(state.DefaultStateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := state.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return current
+}
+
+func TestStateEditCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(engine *Engine, options StateEditOptions) error
+		// want адреса записей после команды и их провайдеры
+		want map[string]string
+		code errors.Code
+		err  string
+	}{
+		{
+			name: "mv",
+			run: func(engine *Engine, options StateEditOptions) error {
+				return engine.StateMove(defaultTestConfig, "docker_container.web", "docker_container.frontend", options)
+			},
+			want: map[string]string{"docker_container.frontend[0]": "", "docker_container.frontend[1]": "", "docker_network.app": "docker"},
+		},
+		{
+			name: "mv to existing address",
+			run: func(engine *Engine, options StateEditOptions) error {
+				return engine.StateMove(defaultTestConfig, "docker_container.web[0]", "docker_container.web[1]", options)
+			},
+			code: errors.CodeState,
+			err:  "Failed to run state mv",
+		},
+		{
+			name: "mv with invalid address",
+			run: func(engine *Engine, options StateEditOptions) error {
+				return engine.StateMove(defaultTestConfig, "docker_container.web", "web", options)
+			},
+			code: errors.CodeConfig,
+			err:  "Invalid destination address",
+		},
+		{
+			name: "rm",
+			run: func(engine *Engine, options StateEditOptions) error {
+				return engine.StateRemove(defaultTestConfig, []string{"docker_container.web[1]", "docker_network.app"}, options)
+			},
+			want: map[string]string{"docker_container.web[0]": ""},
+		},
+		{
+			name: "rm without match",
+			run: func(engine *Engine, options StateEditOptions) error {
+				return engine.StateRemove(defaultTestConfig, []string{"docker_volume.data"}, options)
+			},
+			code: errors.CodeState,
+			err:  "no resource in state matches the given addresses",
+		},
+		{
+			name: "replace-provider",
+			run: func(engine *Engine, options StateEditOptions) error {
+				return engine.ReplaceProvider(defaultTestConfig, "docker", "docker.edge", options)
+			},
+			want: map[string]string{"docker_container.web[0]": "docker.edge", "docker_container.web[1]": "docker.edge", "docker_network.app": "docker.edge"},
+		},
+		{
+			name: "replace-provider without resources",
+			run: func(engine *Engine, options StateEditOptions) error {
+				return engine.ReplaceProvider(defaultTestConfig, "docker.edge", "docker", options)
+			},
+			code: errors.CodeState,
+			err:  "no resource in state uses provider docker.edge",
+		},
+		{
+			name: "replace-provider with invalid address",
+			run: func(engine *Engine, options StateEditOptions) error {
+				return engine.ReplaceProvider(defaultTestConfig, "docker", "docker.edge.eu", options)
+			},
+			code: errors.CodeConfig,
+			err:  "Invalid provider address",
+		},
+	}
+
+	for _, test := range tests {
+		for _, dryRun := range []bool{false, true} {
+			name := test.name
+			if dryRun {
+				name += " dry run"
+			}
+			t.Run(name, func(t *testing.T) {
+				t.Chdir(t.TempDir())
+				writeTestState(t, map[string]state.ResourceState{
+					"docker_container.web[0]": {Type: "docker_container", ID: "c0"},
+					"docker_container.web[1]": {Type: "docker_container", ID: "c1"},
+					"docker_network.app":      {Type: "docker_network", ID: "n1", Provider: "docker"},
+				})
+				before := readTestState(t)
+
+				err := test.run(newTestEngine(t), StateEditOptions{Backup: "edit.backup", DryRun: dryRun})
+				if test.err != "" {
+					assertErrorCode(t, err, test.code, test.err)
+					if readTestState(t).Serial != before.Serial {
+						t.Error("failed command wrote the state")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				after := readTestState(t)
+				if dryRun {
+					if after.Serial != before.Serial || len(after.Resources) != len(before.Resources) {
+						t.Errorf("dry run changed the state: %v", after.Resources)
+					}
+					if _, err := os.Stat("edit.backup"); !os.IsNotExist(err) {
+						t.Errorf("dry run wrote a backup: %v", err)
+					}
+					return
+				}
+
+				if after.Serial != before.Serial+1 || after.Lineage != before.Lineage {
+					t.Errorf("serial, lineage = %d, %s, want %d, %s", after.Serial, after.Lineage, before.Serial+1, before.Lineage)
+				}
+				got := make(map[string]string, len(after.Resources))
+				for address, resourceState := range after.Resources {
+					got[address] = resourceState.Provider
+				}
+				if !reflect.DeepEqual(got, test.want) {
+					t.Errorf("state = %v, want %v", got, test.want)
+				}
+
+				data, err := os.ReadFile("edit.backup")
+				if err != nil {
+					t.Fatalf("backup: %v", err)
+				}
+				backup, err := state.Parse(data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if backup.Serial != before.Serial || len(backup.Resources) != len(before.Resources) {
+					t.Errorf("backup = serial %d with %d resources, want the state before the command", backup.Serial, len(backup.Resources))
+				}
+			})
+		}
+	}
+}
+
+func TestStateEditDefaultBackup(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeTestState(t, map[string]state.ResourceState{
+		"docker_container.web": {Type: "docker_container", ID: "c0"},
+		"docker_container.db":  {Type: "docker_container", ID: "c1"},
+	})
+
+	for _, address := range []string{"docker_container.web", "docker_container.db"} {
+		if err := newTestEngine(t).StateRemove(defaultTestConfig, []string{address}, StateEditOptions{}); err != nil {
+			t.Fatalf("state rm %s: %v", address, err)
+		}
+	}
+
+	// Две команды в одну секунду не затирают копии друг друга
+	backups, err := filepath.Glob(state.DefaultStateFile + ".*.backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("backups = %v, want one per command", backups)
+	}
+
+	if err := newTestEngine(t).StateRemove(defaultTestConfig, []string{"docker_container.web"}, StateEditOptions{Backup: "-"}); err == nil {
+		t.Fatal("state rm of a removed resource succeeded")
+	}
+	if after, _ := filepath.Glob(state.DefaultStateFile + ".*.backup"); len(after) != 2 {
+		t.Errorf("backups = %v, want no backup with -backup=-", after)
+	}
+}
+
+func TestStateShow(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeTestState(t, map[string]state.ResourceState{
+		"docker_container.web[0]": {Type: "docker_container", ID: "c0"},
+		"docker_container.web[1]": {Type: "docker_container", ID: "c1"},
+		"docker_network.app":      {Type: "docker_network", ID: "n1"},
+	})
+
+	shown, err := newTestEngine(t).StateShow(defaultTestConfig, "docker_container.web[1]")
+	if err != nil || shown.ID != "c1" {
+		t.Errorf("state show = %+v, %v, want c1", shown, err)
+	}
+	_, err = newTestEngine(t).StateShow(defaultTestConfig, "docker_container.web")
+	assertErrorCode(t, err, errors.CodeState, "docker_container.web has several instances, specify one of: docker_container.web[0], docker_container.web[1]")
+	_, err = newTestEngine(t).StateShow(defaultTestConfig, "docker_volume.data")
+	assertErrorCode(t, err, errors.CodeState, "No resource docker_volume.data in state")
+
+	listed, err := newTestEngine(t).StateList(defaultTestConfig, []string{"docker_container.web"}, "")
+	if err != nil || !reflect.DeepEqual(listed, []string{"docker_container.web[0]", "docker_container.web[1]"}) {
+		t.Errorf("state list = %v, %v", listed, err)
+	}
+}
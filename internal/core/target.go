@@ -0,0 +1,75 @@
+// internal/core/target.go
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Artemka007/derraform/internal/errors"
+	"github.com/Artemka007/derraform/internal/state"
+)
+
+// parseTargets разбирает адреса из -target: адрес ресурса (docker_container.web) или
+// экземпляра (docker_container.web[0], docker_container.web["blue"]). Экземпляры есть
+// в state после state mv. Модулей в конфигурации нет, их адреса отклоняются.
+func parseTargets(values []string) ([]state.Address, error) {
+	targets := make([]state.Address, 0, len(values))
+	for _, value := range values {
+		if strings.HasPrefix(value, "module.") {
+			return nil, errors.NewError(errors.CodeConfig, fmt.Sprintf("Invalid -target %s: modules are not supported, target resources directly", value))
+		}
+		target, err := state.ParseAddress(value)
+		if err != nil {
+			return nil, errors.WrapError(err, errors.CodeConfig, "Invalid -target address")
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// targetSet выбирает ресурсы графа, подходящие под -target, вместе с их зависимостями,
+// а для destroy - вместе с зависимыми ресурсами. nil означает, что -target не задан.
+func (e *Engine) targetSet(graph *resourceGraph, values []string, dependents bool) (map[string]bool, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	targets, err := parseTargets(values)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []string
+	for _, target := range targets {
+		found := false
+		for _, address := range graph.order {
+			parsed, err := state.ParseAddress(address)
+			if err == nil && target.Matches(parsed) {
+				matched = append(matched, address)
+				found = true
+			}
+		}
+		if !found {
+			message := fmt.Sprintf("Invalid -target: no resource matches %s", target)
+			if target.Key != "" && slices.Contains(graph.order, target.Resource().String()) {
+				message += fmt.Sprintf(" (%s has no instances, target it without an index)", target.Resource())
+			}
+			return nil, errors.NewError(errors.CodeConfig, message)
+		}
+	}
+
+	set := graph.closure(matched, dependents)
+	related := "dependencies"
+	if dependents {
+		related = "dependents"
+	}
+	e.logger.Warn("Resource targeting is in effect: only %d of %d resources (%s and their %s) are processed. "+
+		"The run is partial and other changes may be missing; use -target only to recover from errors.",
+		len(set), len(graph.order), strings.Join(values, ", "), related)
+	return set, nil
+}
+
+// targeted сообщает, обрабатывается ли ресурс с учетом -target
+func targeted(set map[string]bool, address string) bool {
+	return set == nil || set[address]
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/state"
+)
+
+func TestParseTargets(t *testing.T) {
+	tests := []struct {
+		value string
+		err   string
+	}{
+		{value: "docker_container.web"},
+		{value: "docker_container.web[0]"},
+		{value: `docker_container.web["blue"]`},
+		{value: "module.app.docker_container.web", err: "modules are not supported"},
+		{value: "docker_container.web[-1]", err: "Invalid -target address"},
+		{value: "docker_container", err: "Invalid -target address"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			targets, err := parseTargets([]string{test.value})
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(targets) != 1 || targets[0].String() != test.value {
+					t.Fatalf("targets = %v, want %s", targets, test.value)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("error = %v, want it to contain %q", err, test.err)
+			}
+		})
+	}
+}
+
+// Граф destroy строится из state, где после state mv бывают ключи экземпляров
+func TestTargetSetStateGraph(t *testing.T) {
+	current := &state.State{Resources: map[string]state.ResourceState{
+		"docker_network.app":         {},
+		"docker_container.web[0]":    {Dependencies: []string{"docker_network.app"}},
+		`docker_container.web["b"]`:  {Dependencies: []string{"docker_network.app"}},
+		"docker_container.db":        {Dependencies: []string{"docker_network.app"}},
+		"docker_container.worker[1]": {},
+	}}
+	graph, err := stateGraph(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		targets    []string
+		dependents bool
+		want       []string
+		err        string
+	}{
+		{
+			name:       "instance",
+			targets:    []string{"docker_container.web[0]"},
+			dependents: true,
+			want:       []string{"docker_container.web[0]"},
+		},
+		{
+			name:       "string instance key",
+			targets:    []string{`docker_container.web["b"]`},
+			dependents: true,
+			want:       []string{`docker_container.web["b"]`},
+		},
+		{
+			name:       "resource covers all instances",
+			targets:    []string{"docker_container.web"},
+			dependents: true,
+			want:       []string{`docker_container.web["b"]`, "docker_container.web[0]"},
+		},
+		{
+			name:    "instance with dependencies",
+			targets: []string{"docker_container.web[0]"},
+			want:    []string{"docker_container.web[0]", "docker_network.app"},
+		},
+		{
+			name:       "dependents of the network",
+			targets:    []string{"docker_network.app"},
+			dependents: true,
+			want:       []string{"docker_container.db", `docker_container.web["b"]`, "docker_container.web[0]", "docker_network.app"},
+		},
+		{
+			name:    "missing instance",
+			targets: []string{"docker_container.web[5]"},
+			err:     "no resource matches docker_container.web[5]",
+		},
+		{
+			name:    "index on a resource without instances",
+			targets: []string{"docker_container.db[0]"},
+			err:     "docker_container.db has no instances",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			set, err := newTestEngine(t).targetSet(graph, test.targets, test.dependents)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for address := range set {
+				got = append(got, address)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("targets = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// internal/core/timeouts.go
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+)
+
+// applyObject создает или изменяет объект с таймаутом create или update из блока timeouts
+func applyObject(ctx context.Context, impl providers.Resource, resource config.Resource, prior *state.ResourceState, change *providers.PlannedChange) (*state.ResourceState, error) {
+	operation, timeout := "create", resource.Timeouts.Create
+	if prior != nil {
+		operation, timeout = "update", resource.Timeouts.Update
+	}
+
+	ctx, cancel := operationContext(ctx, timeout)
+	defer cancel()
+	newState, err := impl.Apply(ctx, resource, prior, change)
+	return newState, timeoutError(ctx, operation, timeout, err)
+}
+
+// deleteObject удаляет объект с таймаутом delete из блока timeouts
+func deleteObject(ctx context.Context, impl providers.Resource, current *state.ResourceState, timeouts config.Timeouts) error {
+	ctx, cancel := operationContext(ctx, timeouts.Delete)
+	defer cancel()
+	return timeoutError(ctx, "delete", timeouts.Delete, impl.Delete(ctx, current))
+}
+
+// resourceTimeouts блок timeouts ресурса конфигурации по адресу записи state.
+// Если ресурса уже нет в конфигурации, ограничений нет.
+func (e *Engine) resourceTimeouts(address string) config.Timeouts {
+	parsed, err := state.ParseAddress(address)
+	if err != nil {
+		return config.Timeouts{}
+	}
+	for _, resource := range e.config.Resources {
+		if resource.Type == parsed.Type && resource.Name == parsed.Name {
+			return resource.Timeouts
+		}
+	}
+	return config.Timeouts{}
+}
+
+// operationContext ограничивает операцию временем timeout, 0 - без ограничения
+func operationContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// timeoutError поясняет ошибку операции, прерванной по таймауту
+func timeoutError(ctx context.Context, operation string, timeout time.Duration, err error) error {
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s timed out after %s (see the timeouts block): %w", operation, timeout, err)
+	}
+	return err
+}
@@ -0,0 +1,203 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Artemka007/derraform/internal/errors"
+	"github.com/Artemka007/derraform/internal/state"
+)
+
+// interruptConfig цепочка app <- db <- web, порядок apply однозначен
+const interruptConfig = `
+resource "docker_network" "app" {}
+
+resource "docker_container" "db" {
+  depends_on = [docker_network.app]
+}
+
+resource "docker_container" "web" {
+  depends_on = [docker_container.db]
+}
+`
+
+func TestApplyInterrupted(t *testing.T) {
+	tests := []struct {
+		name string
+		// interrupt вызывается в Apply ресурса db
+		interrupt func(engine *Engine, cancel context.CancelFunc)
+		// abortsDB отмена контекста прерывает и начатую операцию
+		abortsDB bool
+		want     map[string]string
+		err      string
+	}{
+		{
+			name:      "stop finishes the operation in progress",
+			interrupt: func(engine *Engine, cancel context.CancelFunc) { engine.Stop() },
+			want:      map[string]string{"docker_network.app": "app-1", "docker_container.db": "db-1"},
+			err:       "Apply interrupted: 2 resources applied, 0 failed, 1 not started",
+		},
+		{
+			name:      "cancel aborts the operation in progress",
+			interrupt: func(engine *Engine, cancel context.CancelFunc) { cancel() },
+			abortsDB:  true,
+			want:      map[string]string{"docker_network.app": "app-1"},
+			err:       "Apply interrupted: 1 resources applied, 1 failed, 1 not started",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			writeTestConfig(t, interruptConfig)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			engine := newTestEngine(t)
+			r := newTestResource()
+			r.onApply = func(ctx context.Context, name string) error {
+				if name != "db" {
+					return nil
+				}
+				test.interrupt(engine, cancel)
+				if test.abortsDB {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+				return nil
+			}
+			useTestProvider(engine, r)
+
+			err := engine.Apply(ctx, defaultTestConfig, ApplyOptions{})
+			assertErrorCode(t, err, errors.CodeInterrupted, test.err)
+			if got := stateIDs(t); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("state = %v, want %v", got, test.want)
+			}
+			for _, name := range r.applied {
+				if name == "web" {
+					t.Error("web was started after the interrupt")
+				}
+			}
+		})
+	}
+}
+
+func TestDestroyInterrupted(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeTestConfig(t, interruptConfig)
+	writeTestState(t, map[string]state.ResourceState{
+		"docker_network.app":   {Type: "docker_network", ID: "app-1"},
+		"docker_container.db":  {Type: "docker_container", ID: "db-1", Dependencies: []string{"docker_network.app"}},
+		"docker_container.web": {Type: "docker_container", ID: "web-1", Dependencies: []string{"docker_container.db"}},
+	})
+
+	engine := newTestEngine(t)
+	useTestProvider(engine, newTestResource())
+	engine.Stop()
+	err := engine.Destroy(context.Background(), defaultTestConfig, DestroyOptions{})
+	assertErrorCode(t, err, errors.CodeInterrupted, "Destroy interrupted: 3 resources remain in state")
+	if got := stateIDs(t); len(got) != 3 {
+		t.Errorf("state = %v, want all resources kept", got)
+	}
+}
+
+func TestApplyTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts string
+		// prior объект уже существует с другим image и заменяется
+		prior bool
+		err   string
+	}{
+		{name: "create timed out", timeouts: `create = "20ms"`, err: "create timed out after 20ms (see the timeouts block)"},
+		{name: "delete timed out", timeouts: `delete = "20ms"`, prior: true, err: "delete timed out after 20ms (see the timeouts block)"},
+		{name: "timeout of another operation", timeouts: `update = "20ms"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			writeTestConfig(t, `
+resource "docker_container" "web" {
+  image = "nginx:1.27"
+
+  timeouts {
+    `+test.timeouts+`
+  }
+}
+`)
+			if test.prior {
+				writeTestState(t, map[string]state.ResourceState{
+					"docker_container.web": {Type: "docker_container", ID: "web-0", Attributes: map[string]interface{}{"image": "nginx:1.26"}},
+				})
+			}
+
+			r := newTestResource()
+			// Операции, у которых есть таймаут, ждут его истечения
+			waitDeadline := func(ctx context.Context) error {
+				if _, ok := ctx.Deadline(); !ok {
+					return nil
+				}
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			r.onApply = func(ctx context.Context, name string) error { return waitDeadline(ctx) }
+			r.onDelete = waitDeadline
+
+			engine := newTestEngine(t)
+			useTestProvider(engine, r)
+			started := time.Now()
+			err := engine.Apply(context.Background(), defaultTestConfig, ApplyOptions{})
+			if elapsed := time.Since(started); elapsed > 5*time.Second {
+				t.Errorf("apply took %s", elapsed)
+			}
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("error = %v, want it to contain %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestTimeoutError(t *testing.T) {
+	expired, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-expired.Done()
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want string
+	}{
+		{name: "no error", ctx: expired},
+		{name: "deadline exceeded", ctx: expired, err: context.DeadlineExceeded, want: "create timed out after 1m0s (see the timeouts block): context deadline exceeded"},
+		{name: "canceled", ctx: canceled, err: context.Canceled, want: "context canceled"},
+		{name: "error before the deadline", ctx: context.Background(), err: errors.NewError(errors.CodeResource, "boom"), want: "[RESOURCE_ERROR] boom"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := timeoutError(test.ctx, "create", time.Minute, test.err)
+			if test.want == "" {
+				if err != nil {
+					t.Errorf("error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.want {
+				t.Errorf("error = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
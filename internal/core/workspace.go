@@ -0,0 +1,200 @@
+// internal/core/workspace.go
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Artemka007/derraform/internal/backend"
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/errors"
+	"github.com/Artemka007/derraform/internal/state"
+)
+
+// CurrentWorkspace возвращает имя выбранного workspace
+func (e *Engine) CurrentWorkspace() (string, error) {
+	return backend.CurrentWorkspace()
+}
+
+// Workspaces возвращает workspace бэкенда и имя выбранного
+func (e *Engine) Workspaces(configFile string) ([]string, string, error) {
+	if err := e.loadBackend(configFile, true); err != nil {
+		return nil, "", err
+	}
+
+	manager, err := e.workspaceManager()
+	if err != nil {
+		return nil, "", err
+	}
+	names, err := manager.Workspaces(context.Background())
+	if err != nil {
+		return nil, "", errors.WrapError(err, errors.CodeBackend, "Failed to list workspaces")
+	}
+	return names, e.workspace, nil
+}
+
+// NewWorkspace создает workspace с пустым state и переключается на него
+func (e *Engine) NewWorkspace(configFile, name string) error {
+	if err := backend.ValidateWorkspaceName(name); err != nil {
+		return errors.WrapError(err, errors.CodeConfig, "Invalid workspace name")
+	}
+	if err := e.loadBackend(configFile, true); err != nil {
+		return err
+	}
+
+	manager, err := e.workspaceStateManager(name)
+	if err != nil {
+		return err
+	}
+	exists, err := workspaceExists(manager, name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.NewError(errors.CodeBackend, fmt.Sprintf("Workspace %q already exists", name))
+	}
+
+	// Пустой state с lineage отличает созданный workspace от несуществующего
+	empty, err := manager.Load()
+	if err != nil {
+		return errors.WrapError(err, errors.CodeState, "Failed to read workspace state")
+	}
+	if err := manager.Save(empty); err != nil {
+		return errors.WrapError(err, errors.CodeState, "Failed to create workspace state")
+	}
+
+	if err := backend.SelectWorkspace(name); err != nil {
+		return errors.WrapError(err, errors.CodeBackend, "Failed to select workspace")
+	}
+	e.logger.Info("Created and switched to workspace %q! State is stored in %s", name, manager.Backend())
+	return nil
+}
+
+// SelectWorkspace переключается на существующий workspace
+func (e *Engine) SelectWorkspace(configFile, name string) error {
+	if err := backend.ValidateWorkspaceName(name); err != nil {
+		return errors.WrapError(err, errors.CodeConfig, "Invalid workspace name")
+	}
+	if err := e.loadBackend(configFile, true); err != nil {
+		return err
+	}
+	if name == e.workspace {
+		e.logger.Info("Already on workspace %q", name)
+		return nil
+	}
+
+	manager, err := e.workspaceStateManager(name)
+	if err != nil {
+		return err
+	}
+	exists, err := workspaceExists(manager, name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.NewError(errors.CodeBackend, fmt.Sprintf("Workspace %q doesn't exist, create it with workspace new", name))
+	}
+
+	if err := backend.SelectWorkspace(name); err != nil {
+		return errors.WrapError(err, errors.CodeBackend, "Failed to select workspace")
+	}
+	e.logger.Info("Switched to workspace %q", name)
+	return nil
+}
+
+// DeleteWorkspace удаляет state workspace. Workspace с ресурсами удаляется только с force:
+// сами ресурсы при этом остаются и больше не управляются.
+func (e *Engine) DeleteWorkspace(configFile, name string, force bool) error {
+	if err := backend.ValidateWorkspaceName(name); err != nil {
+		return errors.WrapError(err, errors.CodeConfig, "Invalid workspace name")
+	}
+	if err := e.loadBackend(configFile, true); err != nil {
+		return err
+	}
+	if name == config.DefaultWorkspace {
+		return errors.NewError(errors.CodeBackend, "The default workspace cannot be deleted")
+	}
+	if name == e.workspace {
+		return errors.NewError(errors.CodeBackend, fmt.Sprintf("Workspace %q is currently selected, switch to another workspace before deleting it", name))
+	}
+
+	workspaceManager, err := e.workspaceManager()
+	if err != nil {
+		return err
+	}
+	manager, err := e.workspaceStateManager(name)
+	if err != nil {
+		return err
+	}
+	exists, err := workspaceExists(manager, name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.NewError(errors.CodeBackend, fmt.Sprintf("Workspace %q doesn't exist", name))
+	}
+
+	if err := e.checkWorkspaceEmpty(manager, name, force); err != nil {
+		return err
+	}
+
+	if err := workspaceManager.DeleteWorkspace(context.Background(), name); err != nil {
+		return errors.WrapError(err, errors.CodeBackend, "Failed to delete workspace")
+	}
+	e.logger.Info("Deleted workspace %q!", name)
+	return nil
+}
+
+// checkWorkspaceEmpty под блокировкой проверяет, что в state workspace нет ресурсов
+func (e *Engine) checkWorkspaceEmpty(manager *state.StateManager, name string, force bool) error {
+	if e.options.Lock {
+		lock, err := manager.Lock(context.Background(), "workspace delete", e.options.LockTimeout)
+		if err != nil {
+			return errors.WrapError(err, errors.CodeStateLock, "Failed to acquire state lock")
+		}
+		defer lock.Unlock()
+	}
+
+	current, err := manager.Load()
+	if err != nil {
+		return errors.WrapError(err, errors.CodeState, "Failed to read workspace state")
+	}
+	if len(current.Resources) == 0 {
+		return nil
+	}
+	if !force {
+		return errors.NewError(errors.CodeState, fmt.Sprintf(
+			"Workspace %q still manages %d resources: destroy them first or use -force to forget them", name, len(current.Resources)))
+	}
+	e.logger.Warn("Deleting workspace %q with %d resources, they will no longer be managed", name, len(current.Resources))
+	return nil
+}
+
+// workspaceStateManager state указанного workspace в настроенном бэкенде
+func (e *Engine) workspaceStateManager(name string) (*state.StateManager, error) {
+	b, err := backend.New(e.config.Backend, name)
+	if err != nil {
+		return nil, errors.WrapError(err, errors.CodeBackend, "Failed to configure backend")
+	}
+	return state.NewStateManager(b, e.encryption), nil
+}
+
+func (e *Engine) workspaceManager() (state.WorkspaceManager, error) {
+	manager, ok := e.stateManager.Backend().(state.WorkspaceManager)
+	if !ok {
+		return nil, errors.NewError(errors.CodeBackend, fmt.Sprintf("Backend %s does not support workspaces", e.stateManager.Backend()))
+	}
+	return manager, nil
+}
+
+// workspaceExists workspace существует, если это default или у него есть state
+func workspaceExists(manager *state.StateManager, name string) (bool, error) {
+	if name == config.DefaultWorkspace {
+		return true, nil
+	}
+	data, err := manager.Backend().Read(context.Background())
+	if err != nil {
+		return false, errors.WrapError(err, errors.CodeState, "Failed to read workspace state")
+	}
+	return data != nil, nil
+}
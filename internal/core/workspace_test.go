@@ -0,0 +1,146 @@
+package core
+
+import (
+	stderrors "errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/backend"
+	"github.com/Artemka007/derraform/internal/errors"
+	"github.com/Artemka007/derraform/internal/state"
+)
+
+func TestWorkspaceLifecycle(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(backend.WorkspaceEnv, "")
+
+	engine := newTestEngine(t)
+	assertEngineWorkspaces(t, []string{"default"}, "default")
+
+	if err := engine.NewWorkspace(defaultTestConfig, "staging"); err != nil {
+		t.Fatalf("workspace new: %v", err)
+	}
+	assertEngineWorkspaces(t, []string{"default", "staging"}, "staging")
+
+	// Новый workspace получает пустой state со своим lineage
+	stagingFile := filepath.Join("terraform.tfstate.d", "staging", state.DefaultStateFile)
+	data, err := os.ReadFile(stagingFile)
+	if err != nil {
+		t.Fatalf("staging state: %v", err)
+	}
+	created, err := state.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Lineage == "" || len(created.Resources) != 0 {
+		t.Errorf("staging state = %+v, want empty state with lineage", created)
+	}
+
+	err = newTestEngine(t).NewWorkspace(defaultTestConfig, "staging")
+	assertErrorCode(t, err, errors.CodeBackend, `Workspace "staging" already exists`)
+	err = newTestEngine(t).NewWorkspace(defaultTestConfig, "../prod")
+	assertErrorCode(t, err, errors.CodeConfig, "Invalid workspace name")
+	err = newTestEngine(t).SelectWorkspace(defaultTestConfig, "prod")
+	assertErrorCode(t, err, errors.CodeBackend, `Workspace "prod" doesn't exist, create it with workspace new`)
+
+	err = newTestEngine(t).DeleteWorkspace(defaultTestConfig, "staging", false)
+	assertErrorCode(t, err, errors.CodeBackend, `Workspace "staging" is currently selected`)
+	err = newTestEngine(t).DeleteWorkspace(defaultTestConfig, "default", false)
+	assertErrorCode(t, err, errors.CodeBackend, "The default workspace cannot be deleted")
+
+	if err := newTestEngine(t).SelectWorkspace(defaultTestConfig, "default"); err != nil {
+		t.Fatalf("workspace select: %v", err)
+	}
+	assertEngineWorkspaces(t, []string{"default", "staging"}, "default")
+
+	err = newTestEngine(t).DeleteWorkspace(defaultTestConfig, "prod", false)
+	assertErrorCode(t, err, errors.CodeBackend, `Workspace "prod" doesn't exist`)
+	if err := newTestEngine(t).DeleteWorkspace(defaultTestConfig, "staging", false); err != nil {
+		t.Fatalf("workspace delete: %v", err)
+	}
+	assertEngineWorkspaces(t, []string{"default"}, "default")
+}
+
+func TestDeleteWorkspaceWithResources(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(backend.WorkspaceEnv, "")
+
+	if err := newTestEngine(t).NewWorkspace(defaultTestConfig, "staging"); err != nil {
+		t.Fatal(err)
+	}
+	engine := newTestEngine(t)
+	if err := engine.loadBackend(defaultTestConfig, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.stateManager.SaveResourceState("docker_network", "app", &state.ResourceState{ID: "n1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := newTestEngine(t).SelectWorkspace(defaultTestConfig, "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := newTestEngine(t).DeleteWorkspace(defaultTestConfig, "staging", false)
+	assertErrorCode(t, err, errors.CodeState, `Workspace "staging" still manages 1 resources`)
+	assertEngineWorkspaces(t, []string{"default", "staging"}, "default")
+
+	if err := newTestEngine(t).DeleteWorkspace(defaultTestConfig, "staging", true); err != nil {
+		t.Fatalf("workspace delete -force: %v", err)
+	}
+	assertEngineWorkspaces(t, []string{"default"}, "default")
+}
+
+func TestWorkspaceFromEnvironment(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(backend.WorkspaceEnv, "")
+	if err := newTestEngine(t).NewWorkspace(defaultTestConfig, "staging"); err != nil {
+		t.Fatal(err)
+	}
+	if err := newTestEngine(t).NewWorkspace(defaultTestConfig, "prod"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(backend.WorkspaceEnv, "staging")
+	assertEngineWorkspaces(t, []string{"default", "prod", "staging"}, "staging")
+
+	engine := newTestEngine(t)
+	if err := engine.loadBackend(defaultTestConfig, true); err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join("terraform.tfstate.d", "staging", state.DefaultStateFile); engine.stateManager.Backend().String() != want {
+		t.Errorf("state = %s, want %s", engine.stateManager.Backend(), want)
+	}
+
+	err := newTestEngine(t).SelectWorkspace(defaultTestConfig, "default")
+	if err == nil || !strings.Contains(err.Error(), "overridden by TF_WORKSPACE=staging") {
+		t.Errorf("error = %v, want TF_WORKSPACE override error", err)
+	}
+}
+
+func assertEngineWorkspaces(t *testing.T, want []string, current string) {
+	t.Helper()
+	names, selected, err := newTestEngine(t).Workspaces(defaultTestConfig)
+	if err != nil {
+		t.Fatalf("workspace list: %v", err)
+	}
+	if !reflect.DeepEqual(names, want) || selected != current {
+		t.Errorf("workspaces = %v (current %s), want %v (current %s)", names, selected, want, current)
+	}
+}
+
+// assertErrorCode проверяет код ошибки движка и начало ее сообщения
+func assertErrorCode(t *testing.T, err error, code errors.Code, message string) {
+	t.Helper()
+	var terraErr *errors.TerraformError
+	if !stderrors.As(err, &terraErr) {
+		t.Fatalf("error = %v, want %s: %s", err, code, message)
+	}
+	if terraErr.Code != code {
+		t.Errorf("code = %s, want %s (%v)", terraErr.Code, code, err)
+	}
+	if !strings.Contains(err.Error(), message) {
+		t.Errorf("error = %v, want it to contain %q", err, message)
+	}
+}
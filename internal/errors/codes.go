@@ -0,0 +1,80 @@
+package errors
+
+import "strings"
+
+// Code код ошибки из каталога. Описание каждого кода - в docs/errors.md.
+type Code string
+
+const (
+	CodeConfig      Code = "CONFIG_ERROR"
+	CodeBackend     Code = "BACKEND_ERROR"
+	CodeState       Code = "STATE_ERROR"
+	CodeStateLock   Code = "STATE_LOCK_ERROR"
+	CodeResource    Code = "RESOURCE_ERROR"
+	CodeImport      Code = "IMPORT_ERROR"
+	CodeApply       Code = "APPLY_ERROR"
+	CodeDestroy     Code = "DESTROY_ERROR"
+	CodeInterrupted Code = "INTERRUPTED"
+)
+
+// docsBaseURL страница с описанием кодов ошибок, якорь - код в нижнем регистре
+const docsBaseURL = "https://github.com/Artemka007/derraform/blob/main/docs/errors.md"
+
+// CodeInfo описание кода ошибки
+type CodeInfo struct {
+	Summary string
+	// Hint что обычно помогает исправить ошибку
+	Hint string
+}
+
+var catalog = map[Code]CodeInfo{
+	CodeConfig: {
+		Summary: "The configuration or a command-line argument is invalid",
+		Hint:    "Fix the configuration or argument named in the message and run the command again.",
+	},
+	CodeBackend: {
+		Summary: "The state backend could not be configured or reached",
+		Hint:    "Check the backend block and credentials, then run init (use -reconfigure or -migrate-state after changing it).",
+	},
+	CodeState: {
+		Summary: "The state could not be read, decrypted or written",
+		Hint:    "Inspect the state with state pull; restore a backup written next to the state if it is damaged.",
+	},
+	CodeStateLock: {
+		Summary: "The state is locked by another operation",
+		Hint:    "Wait for the other operation or retry with -lock-timeout; use force-unlock only if that process is gone.",
+	},
+	CodeResource: {
+		Summary: "A provider failed to plan, create, update or delete a resource",
+		Hint:    "Read the provider error; objects created before the failure are kept in state, tainted ones are recreated by the next apply.",
+	},
+	CodeImport: {
+		Summary: "An existing object could not be imported",
+		Hint:    "Check the import ID and that a resource block exists for the address, or use -generate-config-out.",
+	},
+	CodeApply: {
+		Summary: "Some resources failed to apply",
+		Hint:    "Successful resources are saved in state; fix the errors listed above and run apply again.",
+	},
+	CodeDestroy: {
+		Summary: "Some resources failed to be destroyed",
+		Hint:    "Destroyed resources are removed from state; fix the errors listed above and run destroy again.",
+	},
+	CodeInterrupted: {
+		Summary: "The operation was interrupted",
+		Hint:    "Resources processed before the interrupt are saved in state; run the command again to finish.",
+	},
+}
+
+// Info описание кода, для неизвестного кода - пустое
+func (c Code) Info() CodeInfo {
+	return catalog[c]
+}
+
+// DocsURL ссылка на описание кода ошибки
+func (c Code) DocsURL() string {
+	if _, known := catalog[c]; !known {
+		return ""
+	}
+	return docsBaseURL + "#" + strings.ToLower(string(c))
+}
@@ -1,86 +1,118 @@
 package errors
 
 import (
-    "fmt"
-    "runtime"
-    "strings"
+	stderrors "errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
 )
 
 type TerraformError struct {
-    Code     string
-    Message  string
-    Resource string
-    Cause    error
-    Stack    []string
+	Code     Code
+	Message  string
+	Resource string
+	// DependsOn адреса ресурсов, от которых зависит Resource
+	DependsOn []string
+	// Subject место в конфигурации, к которому относится ошибка
+	Subject *hcl.Range
+	Cause   error
+	// Stack стек Go в момент создания ошибки, показывается только в режиме отладки
+	Stack []string
 }
 
 func (e *TerraformError) Error() string {
-    var sb strings.Builder
-    sb.WriteString(fmt.Sprintf("[%s] %s", e.Code, e.Message))
-    
-    if e.Resource != "" {
-        sb.WriteString(fmt.Sprintf(" (resource: %s)", e.Resource))
-    }
-    
-    if e.Cause != nil {
-        sb.WriteString(fmt.Sprintf("\nCaused by: %v", e.Cause))
-    }
-    
-    if len(e.Stack) > 0 {
-        sb.WriteString("\nStack trace:")
-        for _, frame := range e.Stack {
-            sb.WriteString("\n  " + frame)
-        }
-    }
-    
-    return sb.String()
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("[%s] %s", e.Code, e.Message))
+
+	if e.Resource != "" {
+		sb.WriteString(fmt.Sprintf(" (resource: %s)", e.Resource))
+	}
+
+	if e.Cause != nil {
+		sb.WriteString(fmt.Sprintf("\nCaused by: %v", e.Cause))
+	}
+
+	return sb.String()
 }
 
-func NewError(code, message string) *TerraformError {
-    return &TerraformError{
-        Code:    code,
-        Message: message,
-        Stack:   captureStack(2), // Пропускаем 2 кадра
-    }
+// Unwrap открывает причину для errors.Is и errors.As, например errdefs.IsNotFound
+func (e *TerraformError) Unwrap() error {
+	return e.Cause
 }
 
-func WrapError(err error, code, message string) *TerraformError {
-    terraErr, ok := err.(*TerraformError)
-    if ok {
-        terraErr.Message = message + ": " + terraErr.Message
-        return terraErr
-    }
-    
-    return &TerraformError{
-        Code:    code,
-        Message: message,
-        Cause:   err,
-        Stack:   captureStack(2),
-    }
+// Hint подсказка, как исправить ошибку, из каталога кодов
+func (e *TerraformError) Hint() string {
+	return e.Code.Info().Hint
+}
+
+// DocsURL ссылка на описание кода ошибки
+func (e *TerraformError) DocsURL() string {
+	return e.Code.DocsURL()
+}
+
+func NewError(code Code, message string) *TerraformError {
+	return &TerraformError{
+		Code:    code,
+		Message: message,
+		Stack:   captureStack(2), // Пропускаем 2 кадра
+	}
+}
+
+// WrapError добавляет к ошибке контекст. Если err уже TerraformError, создается копия:
+// сохраняются ее более точный код, ресурс и стек, а сообщение дополняется спереди.
+// Исходная ошибка не изменяется.
+func WrapError(err error, code Code, message string) *TerraformError {
+	if terraErr, ok := err.(*TerraformError); ok {
+		wrapped := *terraErr
+		wrapped.Message = message + ": " + terraErr.Message
+		return &wrapped
+	}
+
+	return &TerraformError{
+		Code:    code,
+		Message: message,
+		Cause:   err,
+		Stack:   captureStack(2),
+	}
+}
+
+// Join объединяет несколько ошибок, как errors.Join. ErrorReporter показывает их списком.
+func Join(errs ...error) error {
+	return stderrors.Join(errs...)
+}
+
+// Joined возвращает ошибки, объединенные Join, или nil, если err - одна ошибка
+func Joined(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return nil
 }
 
 func ResourceError(resource, message string, cause error) *TerraformError {
-    return &TerraformError{
-        Code:     "RESOURCE_ERROR",
-        Message:  message,
-        Resource: resource,
-        Cause:    cause,
-        Stack:    captureStack(2),
-    }
+	return &TerraformError{
+		Code:     CodeResource,
+		Message:  message,
+		Resource: resource,
+		Cause:    cause,
+		Stack:    captureStack(2),
+	}
 }
 
 func captureStack(skip int) []string {
-    var stack []string
-    for i := skip; i < 10; i++ { // Берем 10 кадров
-        pc, file, line, ok := runtime.Caller(i)
-        if !ok {
-            break
-        }
-        
-        fn := runtime.FuncForPC(pc)
-        if fn != nil {
-            stack = append(stack, fmt.Sprintf("%s:%d %s", file, line, fn.Name()))
-        }
-    }
-    return stack
-}
\ No newline at end of file
+	var stack []string
+	for i := skip; i < 10; i++ { // Берем 10 кадров
+		pc, file, line, ok := runtime.Caller(i)
+		if !ok {
+			break
+		}
+
+		fn := runtime.FuncForPC(pc)
+		if fn != nil {
+			stack = append(stack, fmt.Sprintf("%s:%d %s", file, line, fn.Name()))
+		}
+	}
+	return stack
+}
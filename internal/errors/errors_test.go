@@ -0,0 +1,181 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// allCodes коды каталога, новый код нужно добавить и сюда
+var allCodes = []Code{
+	CodeConfig, CodeBackend, CodeState, CodeStateLock, CodeResource,
+	CodeImport, CodeApply, CodeDestroy, CodeInterrupted,
+}
+
+func TestTerraformErrorError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *TerraformError
+		want string
+	}{
+		{"message", NewError(CodeConfig, "Invalid address"), "[CONFIG_ERROR] Invalid address"},
+		{
+			"resource and cause",
+			ResourceError("docker_container.web", "Failed to apply resource", stderrors.New("port is allocated")),
+			"[RESOURCE_ERROR] Failed to apply resource (resource: docker_container.web)\nCaused by: port is allocated",
+		},
+		{
+			"nested cause",
+			WrapError(fmt.Errorf("read: %w", fs.ErrNotExist), CodeState, "Failed to load state"),
+			"[STATE_ERROR] Failed to load state\nCaused by: read: file does not exist",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.err.Error(); got != test.want {
+				t.Errorf("Error() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWrapError(t *testing.T) {
+	cause := fmt.Errorf("stat: %w", fs.ErrNotExist)
+	resourceErr := ResourceError("docker_network.app", "Failed to destroy resource", cause)
+	resourceErr.DependsOn = []string{"docker_container.web"}
+
+	tests := []struct {
+		name         string
+		err          error
+		code         Code
+		wantCode     Code
+		wantMessage  string
+		wantResource string
+	}{
+		{"plain error", cause, CodeState, CodeState, "Failed to save state", ""},
+		{"keeps the more precise code", resourceErr, CodeState, CodeResource, "Failed to save state: Failed to destroy resource", "docker_network.app"},
+		{"nil", nil, CodeApply, CodeApply, "Failed to save state", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wrapped := WrapError(test.err, test.code, "Failed to save state")
+			if wrapped.Code != test.wantCode || wrapped.Message != test.wantMessage || wrapped.Resource != test.wantResource {
+				t.Errorf("WrapError = %s %q %q, want %s %q %q",
+					wrapped.Code, wrapped.Message, wrapped.Resource, test.wantCode, test.wantMessage, test.wantResource)
+			}
+			if len(wrapped.Stack) == 0 {
+				t.Error("wrapped error has no stack")
+			}
+			if test.err != nil && !stderrors.Is(wrapped, fs.ErrNotExist) {
+				t.Error("errors.Is does not reach the cause")
+			}
+		})
+	}
+
+	// Исходная ошибка не изменяется, копия сохраняет ее поля
+	if resourceErr.Message != "Failed to destroy resource" {
+		t.Errorf("original message = %q, want it unchanged", resourceErr.Message)
+	}
+	wrapped := WrapError(resourceErr, CodeDestroy, "Destroy failed")
+	if !reflect.DeepEqual(wrapped.DependsOn, resourceErr.DependsOn) || !reflect.DeepEqual(wrapped.Stack, resourceErr.Stack) {
+		t.Error("wrapped copy lost DependsOn or Stack")
+	}
+}
+
+type lockHeldError struct{ holder string }
+
+func (e *lockHeldError) Error() string { return "lock is held by " + e.holder }
+
+func TestIsAs(t *testing.T) {
+	held := &lockHeldError{holder: "ci"}
+	err := fmt.Errorf("apply: %w", WrapError(
+		WrapError(fmt.Errorf("lock: %w", held), CodeStateLock, "Failed to acquire state lock"),
+		CodeApply, "Apply failed"))
+
+	var terraErr *TerraformError
+	if !stderrors.As(err, &terraErr) || terraErr.Code != CodeStateLock {
+		t.Errorf("As TerraformError = %v, want the STATE_LOCK_ERROR", terraErr)
+	}
+	var lockErr *lockHeldError
+	if !stderrors.As(err, &lockErr) || lockErr.holder != "ci" {
+		t.Errorf("As lockHeldError = %v, want the original cause", lockErr)
+	}
+	if !stderrors.Is(err, held) {
+		t.Error("errors.Is does not reach the original cause")
+	}
+	if stderrors.Is(NewError(CodeConfig, "no cause"), held) {
+		t.Error("errors.Is matched an error without a cause")
+	}
+}
+
+func TestJoined(t *testing.T) {
+	first := ResourceError("docker_container.web", "Failed to apply resource", os.ErrPermission)
+	second := ResourceError("docker_container.db", "Failed to apply resource", os.ErrDeadlineExceeded)
+
+	tests := []struct {
+		name string
+		err  error
+		want []error
+	}{
+		{"joined", Join(first, second), []error{first, second}},
+		{"nil errors are dropped", Join(nil, first, nil), []error{first}},
+		{"single error", first, nil},
+		{"nil", nil, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Joined(test.err); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Joined = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if Join() != nil {
+		t.Error("Join() of no errors is not nil")
+	}
+	if !stderrors.Is(Join(first, second), os.ErrDeadlineExceeded) {
+		t.Error("errors.Is does not search joined errors")
+	}
+}
+
+func TestCodeCatalog(t *testing.T) {
+	docs, err := os.ReadFile("../../docs/errors.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	anchor := regexp.MustCompile(`#([a-z_]+)$`)
+
+	for _, code := range allCodes {
+		t.Run(string(code), func(t *testing.T) {
+			info := code.Info()
+			if info.Summary == "" || info.Hint == "" {
+				t.Errorf("Info() = %+v, want summary and hint", info)
+			}
+			if got := NewError(code, "boom").Hint(); got != info.Hint {
+				t.Errorf("Hint() = %q, want %q", got, info.Hint)
+			}
+
+			url := NewError(code, "boom").DocsURL()
+			match := anchor.FindStringSubmatch(url)
+			if !strings.HasPrefix(url, docsBaseURL) || match == nil || match[1] != strings.ToLower(string(code)) {
+				t.Errorf("DocsURL() = %q", url)
+			}
+			if !strings.Contains(string(docs), "\n## "+string(code)+"\n") {
+				t.Errorf("docs/errors.md has no section for %s", code)
+			}
+		})
+	}
+
+	unknown := Code("UNKNOWN")
+	if unknown.DocsURL() != "" || unknown.Info() != (CodeInfo{}) {
+		t.Errorf("unknown code has info %+v and url %q", unknown.Info(), unknown.DocsURL())
+	}
+}
@@ -0,0 +1,81 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"syscall"
+)
+
+// Интерфейсы классов ошибок Docker API (github.com/docker/docker/errdefs),
+// проверяются без зависимости от клиента Docker
+type (
+	unavailableError interface{ Unavailable() }
+	systemError      interface{ System() }
+	conflictError    interface{ Conflict() }
+	notFoundError    interface{ NotFound() }
+)
+
+// transientMessages фрагменты текста временных ошибок, которые приходят без типа,
+// например в потоке сообщений docker pull
+var transientMessages = []string{
+	"connection reset by peer",
+	"broken pipe",
+	"i/o timeout",
+	"tls handshake timeout",
+	"unexpected eof",
+	"500 internal server error",
+	"502 bad gateway",
+	"503 service unavailable",
+	"504 gateway timeout",
+}
+
+// networkNotFound сеть, созданная только что, может быть еще не видна демону
+var networkNotFound = regexp.MustCompile(`network [^ ]+ not found`)
+
+// IsNetworkNotFound сообщает, что демон не нашел сеть. Для подключения контейнера
+// к только что созданной сети это временная ошибка: сеть появится после события демона.
+// Удаление и inspect такую ошибку не повторяют (см. IsRetryable).
+func IsNetworkNotFound(err error) bool {
+	return err != nil && networkNotFound.MatchString(strings.ToLower(err.Error()))
+}
+
+// IsRetryable сообщает, что ошибка временная и операцию стоит повторить: сбой соединения,
+// ответ 5xx, конфликт с операцией в процессе. Отмену контекста и NotFound повторять
+// нельзя: отсутствующий объект от повтора не появится.
+func IsRetryable(err error) bool {
+	if err == nil || stderrors.Is(err, context.Canceled) || stderrors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	message := strings.ToLower(err.Error())
+	var (
+		unavailable unavailableError
+		system      systemError
+		conflict    conflictError
+		notFound    notFoundError
+		netErr      net.Error
+	)
+	switch {
+	case stderrors.As(err, &unavailable), stderrors.As(err, &system):
+		return true
+	case stderrors.As(err, &conflict):
+		return strings.Contains(message, "in progress")
+	case stderrors.As(err, &notFound):
+		return false
+	case stderrors.Is(err, syscall.ECONNRESET), stderrors.Is(err, syscall.EPIPE), stderrors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	case stderrors.As(err, &netErr) && netErr.Timeout():
+		return true
+	}
+
+	for _, fragment := range transientMessages {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
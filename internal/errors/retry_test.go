@@ -0,0 +1,71 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+)
+
+type notFound struct{ error }
+
+func (notFound) NotFound() {}
+
+type unavailable struct{ error }
+
+func (unavailable) Unavailable() {}
+
+type conflict struct{ error }
+
+func (conflict) Conflict() {}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"canceled", fmt.Errorf("pull: %w", context.Canceled), false},
+		{"connection reset", fmt.Errorf("remove: %w", syscall.ECONNRESET), true},
+		{"unexpected eof", io.ErrUnexpectedEOF, true},
+		{"unavailable", unavailable{stderrors.New("daemon is restarting")}, true},
+		{"conflict in progress", conflict{stderrors.New("removal of container is already in progress")}, true},
+		{"conflict", conflict{stderrors.New("name is already in use")}, false},
+		{"network not found", notFound{stderrors.New("network frontnd not found")}, false},
+		{"untyped network not found", stderrors.New("Error response from daemon: network frontnd not found"), false},
+		{"bad gateway message", stderrors.New("received unexpected HTTP status: 502 Bad Gateway"), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := IsRetryable(test.err); got != test.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", test.err, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsNetworkNotFound(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"typed", notFound{stderrors.New("network frontnd not found")}, true},
+		{"untyped", stderrors.New("Error response from daemon: network 3f2a9c not found"), true},
+		{"container not found", notFound{stderrors.New("No such container: web")}, false},
+		{"volume not found", notFound{stderrors.New("get data: no such volume")}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := IsNetworkNotFound(test.err); got != test.want {
+				t.Errorf("IsNetworkNotFound(%v) = %v, want %v", test.err, got, test.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package logging
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/fatih/color"
@@ -12,14 +16,131 @@ import (
 type LogLevel int
 
 const (
-	DEBUG LogLevel = iota
+	TRACE LogLevel = iota
+	DEBUG
 	INFO
 	WARN
 	ERROR
 	FATAL
+	// OFF отключает лог подсистемы
+	OFF
 )
 
+var levelNames = map[LogLevel]string{
+	TRACE: "TRACE",
+	DEBUG: "DEBUG",
+	INFO:  "INFO",
+	WARN:  "WARN",
+	ERROR: "ERROR",
+	FATAL: "FATAL",
+	OFF:   "OFF",
+}
+
+func (l LogLevel) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+// ParseLevel разбирает уровень из TF_LOG или --log-level, регистр не важен
+func ParseLevel(value string) (LogLevel, error) {
+	name := strings.ToUpper(strings.TrimSpace(value))
+	if name == "WARNING" {
+		name = "WARN"
+	}
+	for level, levelName := range levelNames {
+		if levelName == name && level != FATAL {
+			return level, nil
+		}
+	}
+	return INFO, fmt.Errorf("invalid log level %q: expected TRACE, DEBUG, INFO, WARN, ERROR or OFF", value)
+}
+
+// Подсистемы, для которых уровень можно задать отдельно: TF_LOG_CORE, TF_LOG_PROVIDER, TF_LOG_STATE
+const (
+	SubsystemCore     = "core"
+	SubsystemProvider = "provider"
+	SubsystemState    = "state"
+)
+
+// Format формат записей лога
+type Format string
+
+const (
+	// FormatText строки для человека: [время] LEVEL: сообщение
+	FormatText Format = "text"
+	// FormatJSON по одному JSON объекту на строку с полями @timestamp, @level, @module, @message
+	FormatJSON Format = "json"
+)
+
+// ParseFormat разбирает формат из TF_LOG_FORMAT или --log-format
+func ParseFormat(value string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(value))) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return FormatText, fmt.Errorf("invalid log format %q: expected text or json", value)
+}
+
+// Переменные окружения, которые читает ConfigFromEnv
+const (
+	EnvLevel  = "TF_LOG"
+	EnvPath   = "TF_LOG_PATH"
+	EnvFormat = "TF_LOG_FORMAT"
+)
+
+// Config настройки логирования
+type Config struct {
+	// Level уровень по умолчанию для всех подсистем
+	Level LogLevel
+	// Levels уровни отдельных подсистем, заменяют Level
+	Levels map[string]LogLevel
+	Format Format
+	// Path файл, в который дописываются записи. В консоль тогда попадают только
+	// сообщения для пользователя (INFO и выше), отладочные пишутся только в файл.
+	Path string
+	// Output куда пишутся записи для пользователя, по умолчанию stdout
+	Output io.Writer
+}
+
+// ConfigFromEnv читает настройки из TF_LOG, TF_LOG_<ПОДСИСТЕМА>, TF_LOG_PATH и TF_LOG_FORMAT.
+// Как и в Terraform, TF_LOG=JSON включает уровень TRACE в формате JSON.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{Level: INFO, Levels: make(map[string]LogLevel), Path: os.Getenv(EnvPath)}
+
+	var err error
+	if cfg.Format, err = ParseFormat(os.Getenv(EnvFormat)); err != nil {
+		return cfg, fmt.Errorf("%s: %w", EnvFormat, err)
+	}
+
+	if value := os.Getenv(EnvLevel); strings.EqualFold(value, "json") {
+		cfg.Level, cfg.Format = TRACE, FormatJSON
+	} else if value != "" {
+		if cfg.Level, err = ParseLevel(value); err != nil {
+			return cfg, fmt.Errorf("%s: %w", EnvLevel, err)
+		}
+	}
+
+	for _, subsystem := range []string{SubsystemCore, SubsystemProvider, SubsystemState} {
+		name := EnvLevel + "_" + strings.ToUpper(subsystem)
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		level, err := ParseLevel(value)
+		if err != nil {
+			return cfg, fmt.Errorf("%s: %w", name, err)
+		}
+		cfg.Levels[subsystem] = level
+	}
+	return cfg, nil
+}
+
 var (
+	traceColor = color.New(color.FgHiBlack)
 	debugColor = color.New(color.FgHiBlue)
 	infoColor  = color.New(color.FgHiGreen)
 	warnColor  = color.New(color.FgHiYellow)
@@ -27,60 +148,250 @@ var (
 	fatalColor = color.New(color.FgHiRed, color.Bold)
 )
 
-type Logger struct {
-	level  LogLevel
+var levelColors = map[LogLevel]*color.Color{
+	TRACE: traceColor,
+	DEBUG: debugColor,
+	INFO:  infoColor,
+	WARN:  warnColor,
+	ERROR: errorColor,
+	FATAL: fatalColor,
+}
+
+// output место, куда пишутся записи: консоль или файл TF_LOG_PATH
+type output struct {
+	mu     sync.Mutex
 	writer io.Writer
+	format Format
+	// minLevel записи ниже этого уровня сюда не пишутся, даже если подсистема их пропускает
+	minLevel LogLevel
+	colored  bool
+}
+
+// Field дополнительное поле записи: адрес ресурса, операция, длительность
+type Field struct {
+	Key   string
+	Value interface{}
 }
 
+// Logger общий лог движка. Logger, полученные через Named и With, пишут туда же,
+// что и исходный, и могут использоваться из нескольких горутин.
+type Logger struct {
+	outputs []*output
+	config  Config
+	// closer файл TF_LOG_PATH, закрывается Close исходного логгера
+	closer io.Closer
+
+	level     LogLevel
+	subsystem string
+	fields    []Field
+}
+
+// NewLogger создает логгер, который пишет в stdout текстом
 func NewLogger(level LogLevel) *Logger {
-	return &Logger{
-		level:  level,
-		writer: os.Stdout,
+	logger, _ := New(Config{Level: level})
+	return logger
+}
+
+// New создает логгер по настройкам. Файл TF_LOG_PATH открывается на дозапись.
+func New(cfg Config) (*Logger, error) {
+	if cfg.Format == "" {
+		cfg.Format = FormatText
+	}
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
 	}
+
+	console := &output{writer: cfg.Output, format: cfg.Format, minLevel: TRACE, colored: cfg.Format == FormatText}
+	logger := &Logger{outputs: []*output{console}, config: cfg, level: cfg.Level}
+
+	if cfg.Path != "" {
+		file, err := os.OpenFile(cfg.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file %s: %w", cfg.Path, err)
+		}
+		console.minLevel = INFO
+		logger.outputs = append(logger.outputs, &output{writer: file, format: cfg.Format, minLevel: TRACE})
+		logger.closer = file
+	}
+	return logger, nil
+}
+
+// Close закрывает файл лога, если он открыт
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// Named возвращает логгер подсистемы с ее собственным уровнем
+func (l *Logger) Named(subsystem string) *Logger {
+	child := *l
+	child.subsystem = subsystem
+	child.closer = nil
+	if level, ok := l.config.Levels[subsystem]; ok {
+		child.level = level
+	} else {
+		child.level = l.config.Level
+	}
+	return &child
+}
+
+// With возвращает логгер, который добавляет поле ко всем записям
+func (l *Logger) With(key string, value interface{}) *Logger {
+	child := *l
+	child.closer = nil
+	child.fields = append(append([]Field(nil), l.fields...), Field{Key: key, Value: value})
+	return &child
 }
 
-func (l *Logger) log(level LogLevel, levelStr, msg string, args ...interface{}) {
-	if level < l.level {
+// Enabled сообщает, попадут ли в лог записи этого уровня
+func (l *Logger) Enabled(level LogLevel) bool {
+	return level >= l.level && l.level != OFF
+}
+
+func (l *Logger) log(level LogLevel, msg string, args ...interface{}) {
+	if !l.Enabled(level) {
 		return
 	}
+	l.write(time.Now(), level, Redact(fmt.Sprintf(msg, args...)), l.fields)
+}
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	message := fmt.Sprintf(msg, args...)
+func (l *Logger) write(timestamp time.Time, level LogLevel, message string, fields []Field) {
+	for _, out := range l.outputs {
+		if level < out.minLevel {
+			continue
+		}
 
-	var output string
-	switch level {
-	case DEBUG:
-		output = debugColor.Sprintf("[%s] DEBUG: %s", timestamp, message)
-	case INFO:
-		output = infoColor.Sprintf("[%s] INFO: %s", timestamp, message)
-	case WARN:
-		output = warnColor.Sprintf("[%s] WARN: %s", timestamp, message)
-	case ERROR:
-		output = errorColor.Sprintf("[%s] ERROR: %s", timestamp, message)
-	case FATAL:
-		output = fatalColor.Sprintf("[%s] FATAL: %s", timestamp, message)
+		var line string
+		if out.format == FormatJSON {
+			line = l.jsonLine(timestamp, level, message, fields)
+		} else {
+			line = l.textLine(out, timestamp, level, message, fields)
+		}
+
+		out.mu.Lock()
+		fmt.Fprintln(out.writer, line)
+		out.mu.Unlock()
 	}
+}
+
+// textLine [время] LEVEL: сообщение. Подсистема и поля показываются, только когда
+// отладочные записи идут в консоль или запись идет в файл: обычному выводу они не нужны.
+func (l *Logger) textLine(out *output, timestamp time.Time, level LogLevel, message string, fields []Field) string {
+	detailed := !out.colored || (l.level < INFO && out.minLevel < INFO)
+	if detailed && l.subsystem != "" {
+		message = l.subsystem + ": " + message
+	}
+	if detailed {
+		for _, field := range fields {
+			message += fmt.Sprintf(" %s=%v", field.Key, fieldValue(field.Value))
+		}
+	}
+
+	line := fmt.Sprintf("[%s] %s: %s", timestamp.Format("2006-01-02 15:04:05"), level, message)
+	if out.colored {
+		return levelColors[level].Sprint(line)
+	}
+	return line
+}
+
+func (l *Logger) jsonLine(timestamp time.Time, level LogLevel, message string, fields []Field) string {
+	entry := make(map[string]interface{}, len(fields)+4)
+	for _, field := range fields {
+		entry[field.Key] = fieldValue(field.Value)
+	}
+	entry["@timestamp"] = timestamp.Format(time.RFC3339Nano)
+	entry["@level"] = strings.ToLower(level.String())
+	entry["@message"] = message
+	if l.subsystem != "" {
+		entry["@module"] = l.subsystem
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"@level": "error", "@message": "failed to encode log entry: " + err.Error()})
+	}
+	return string(data)
+}
+
+// fieldValue значение поля для записи: длительности пишутся строкой (1.5s), строки - без sensitive значений
+func fieldValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case time.Duration:
+		return v.String()
+	case string:
+		return Redact(v)
+	case error:
+		return Redact(v.Error())
+	}
+	return value
+}
+
+// Relay повторяет запись, которую плагин записал в stderr в формате JSON, с ее уровнем
+// и полями. Возвращает false, если строка - не запись лога.
+func (l *Logger) Relay(line string) bool {
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		return false
+	}
+	levelName, _ := entry["@level"].(string)
+	message, ok := entry["@message"].(string)
+	if !ok {
+		return false
+	}
+	level, err := ParseLevel(levelName)
+	if err != nil {
+		level = DEBUG
+	}
+	if !l.Enabled(level) {
+		return true
+	}
+
+	timestamp := time.Now()
+	if value, ok := entry["@timestamp"].(string); ok {
+		if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			timestamp = parsed
+		}
+	}
+
+	keys := make([]string, 0, len(entry))
+	for key := range entry {
+		if !strings.HasPrefix(key, "@") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	fields := append([]Field(nil), l.fields...)
+	for _, key := range keys {
+		fields = append(fields, Field{Key: key, Value: entry[key]})
+	}
+
+	l.write(timestamp, level, Redact(message), fields)
+	return true
+}
 
-	fmt.Fprintln(l.writer, output)
+func (l *Logger) Trace(msg string, args ...interface{}) {
+	l.log(TRACE, msg, args...)
 }
 
 func (l *Logger) Debug(msg string, args ...interface{}) {
-	l.log(DEBUG, "DEBUG", msg, args...)
+	l.log(DEBUG, msg, args...)
 }
 
 func (l *Logger) Info(msg string, args ...interface{}) {
-	l.log(INFO, "INFO", msg, args...)
+	l.log(INFO, msg, args...)
 }
 
 func (l *Logger) Warn(msg string, args ...interface{}) {
-	l.log(WARN, "WARN", msg, args...)
+	l.log(WARN, msg, args...)
 }
 
 func (l *Logger) Error(msg string, args ...interface{}) {
-	l.log(ERROR, "ERROR", msg, args...)
+	l.log(ERROR, msg, args...)
 }
 
 func (l *Logger) Fatal(msg string, args ...interface{}) {
-	l.log(FATAL, "FATAL", msg, args...)
+	l.log(FATAL, msg, args...)
 	os.Exit(1)
 }
@@ -0,0 +1,302 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		value string
+		want  LogLevel
+		err   bool
+	}{
+		{"trace", TRACE, false},
+		{" Debug ", DEBUG, false},
+		{"INFO", INFO, false},
+		{"warning", WARN, false},
+		{"error", ERROR, false},
+		{"off", OFF, false},
+		{"fatal", INFO, true},
+		{"verbose", INFO, true},
+		{"", INFO, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := ParseLevel(test.value)
+			if (err != nil) != test.err || got != test.want {
+				t.Errorf("ParseLevel(%q) = %s, %v, want %s (error %v)", test.value, got, err, test.want, test.err)
+			}
+		})
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want Config
+		err  string
+	}{
+		{
+			name: "defaults",
+			want: Config{Level: INFO, Levels: map[string]LogLevel{}, Format: FormatText},
+		},
+		{
+			name: "level, format and path",
+			env:  map[string]string{EnvLevel: "debug", EnvFormat: "JSON", EnvPath: "derraform.log"},
+			want: Config{Level: DEBUG, Levels: map[string]LogLevel{}, Format: FormatJSON, Path: "derraform.log"},
+		},
+		{
+			name: "TF_LOG=json",
+			env:  map[string]string{EnvLevel: "json"},
+			want: Config{Level: TRACE, Levels: map[string]LogLevel{}, Format: FormatJSON},
+		},
+		{
+			name: "subsystem levels",
+			env:  map[string]string{"TF_LOG_PROVIDER": "trace", "TF_LOG_STATE": "off"},
+			want: Config{Level: INFO, Levels: map[string]LogLevel{SubsystemProvider: TRACE, SubsystemState: OFF}, Format: FormatText},
+		},
+		{name: "invalid level", env: map[string]string{EnvLevel: "loud"}, err: `TF_LOG: invalid log level "loud"`},
+		{name: "invalid subsystem level", env: map[string]string{"TF_LOG_CORE": "loud"}, err: `TF_LOG_CORE: invalid log level "loud"`},
+		{name: "invalid format", env: map[string]string{EnvFormat: "xml"}, err: `TF_LOG_FORMAT: invalid log format "xml"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, name := range []string{EnvLevel, EnvPath, EnvFormat, "TF_LOG_CORE", "TF_LOG_PROVIDER", "TF_LOG_STATE"} {
+				t.Setenv(name, test.env[name])
+			}
+
+			got, err := ConfigFromEnv()
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ConfigFromEnv() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+// decodeLines разбирает записи в формате JSON
+func decodeLines(t *testing.T, data []byte) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLoggerLevels(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		// want сообщения, попавшие в лог
+		want []string
+	}{
+		{
+			name:   "default level",
+			config: Config{Level: INFO},
+			want:   []string{"core info", "provider info", "provider warn", "state info"},
+		},
+		{
+			name:   "subsystem level",
+			config: Config{Level: WARN, Levels: map[string]LogLevel{SubsystemProvider: TRACE}},
+			want:   []string{"provider trace", "provider debug", "provider info", "provider warn"},
+		},
+		{
+			name:   "subsystem off",
+			config: Config{Level: DEBUG, Levels: map[string]LogLevel{SubsystemProvider: OFF, SubsystemState: ERROR}},
+			want:   []string{"core debug", "core info"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			test.config.Format, test.config.Output = FormatJSON, &out
+			logger, err := New(test.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			core := logger.Named(SubsystemCore)
+			core.Debug("core debug")
+			core.Info("core info")
+			provider := logger.Named(SubsystemProvider)
+			provider.Trace("provider trace")
+			provider.Debug("provider debug")
+			provider.Info("provider info")
+			provider.Warn("provider warn")
+			logger.Named(SubsystemState).Info("state info")
+
+			var got []string
+			for _, entry := range decodeLines(t, out.Bytes()) {
+				got = append(got, entry["@message"].(string))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("messages = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoggerJSON(t *testing.T) {
+	resetSensitive(t)
+	AddSensitive("s3cr3t-token")
+
+	var out bytes.Buffer
+	logger, err := New(Config{Level: INFO, Format: FormatJSON, Output: &out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Named(SubsystemCore).
+		With("address", "docker_container.web").
+		With("duration", 1500*time.Millisecond).
+		With("token", "s3cr3t-token").
+		Info("Applied %s with %s", "docker_container.web", "s3cr3t-token")
+
+	entries := decodeLines(t, out.Bytes())
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if _, err := time.Parse(time.RFC3339Nano, entry["@timestamp"].(string)); err != nil {
+		t.Errorf("@timestamp: %v", err)
+	}
+	delete(entry, "@timestamp")
+	want := map[string]interface{}{
+		"@level":   "info",
+		"@module":  "core",
+		"@message": "Applied docker_container.web with (sensitive value)",
+		"address":  "docker_container.web",
+		"duration": "1.5s",
+		"token":    "(sensitive value)",
+	}
+	if !reflect.DeepEqual(entry, want) {
+		t.Errorf("entry = %v, want %v", entry, want)
+	}
+}
+
+func TestLoggerPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "derraform.log")
+	if err := os.WriteFile(path, []byte("previous run\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var console bytes.Buffer
+	logger, err := New(Config{Level: TRACE, Path: path, Output: &console})
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := logger.Named(SubsystemState).With("workspace", "prod")
+	state.Debug("Acquired state lock")
+	state.Info("State saved")
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// В консоль попадают только сообщения для пользователя, без подсистемы и полей
+	if got := console.String(); strings.Contains(got, "Acquired state lock") || !strings.Contains(got, "INFO: State saved\n") {
+		t.Errorf("console:\n%s", got)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || lines[0] != "previous run" ||
+		!strings.HasSuffix(lines[1], "DEBUG: state: Acquired state lock workspace=prod") ||
+		!strings.HasSuffix(lines[2], "INFO: state: State saved workspace=prod") {
+		t.Errorf("log file:\n%s", data)
+	}
+
+	if _, err := New(Config{Path: filepath.Join(t.TempDir(), "missing", "derraform.log")}); err == nil {
+		t.Error("New with a path in a missing directory succeeded")
+	}
+}
+
+func TestRelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		level   LogLevel
+		relayed bool
+		want    map[string]interface{}
+	}{
+		{
+			name:    "entry with fields",
+			line:    `{"@level":"warn","@message":"Retrying pull","@timestamp":"2026-10-19T10:00:00Z","attempt":2}`,
+			level:   INFO,
+			relayed: true,
+			want:    map[string]interface{}{"@level": "warn", "@message": "Retrying pull", "@module": "provider", "@timestamp": "2026-10-19T10:00:00Z", "attempt": float64(2), "plugin": "docker"},
+		},
+		{
+			name:    "unknown level is debug",
+			line:    `{"@level":"verbose","@message":"Connected"}`,
+			level:   DEBUG,
+			relayed: true,
+			want:    map[string]interface{}{"@level": "debug", "@message": "Connected", "@module": "provider", "plugin": "docker"},
+		},
+		{
+			name:    "below the level",
+			line:    `{"@level":"debug","@message":"Connected"}`,
+			level:   INFO,
+			relayed: true,
+		},
+		{name: "plain text", line: "panic: runtime error", level: INFO},
+		{name: "json without message", line: `{"@level":"info"}`, level: INFO},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			logger, err := New(Config{Level: test.level, Format: FormatJSON, Output: &out})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := logger.Named(SubsystemProvider).With("plugin", "docker").Relay(test.line); got != test.relayed {
+				t.Errorf("Relay = %v, want %v", got, test.relayed)
+			}
+			entries := decodeLines(t, out.Bytes())
+			if test.want == nil {
+				if len(entries) != 0 {
+					t.Errorf("entries = %v, want none", entries)
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			if _, hasTimestamp := test.want["@timestamp"]; !hasTimestamp {
+				delete(entries[0], "@timestamp")
+			}
+			if !reflect.DeepEqual(entries[0], test.want) {
+				t.Errorf("entry = %v, want %v", entries[0], test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SensitivePlaceholder показывается вместо sensitive значений
+const SensitivePlaceholder = "(sensitive value)"
+
+// shortSensitiveLength значения короче этого вырезаются только целым словом: такие
+// строки (числа, флаги) часто встречаются внутри других слов и значений
+const shortSensitiveLength = 4
+
+var (
+	sensitiveMu     sync.RWMutex
+	sensitiveValues []string
+)
+
+// AddSensitive запоминает значения, которые нужно вырезать из логов и сообщений об ошибках
+func AddSensitive(values ...string) {
+	sensitiveMu.Lock()
+	defer sensitiveMu.Unlock()
+
+	for _, value := range values {
+		if value == "" || containsString(sensitiveValues, value) {
+			continue
+		}
+		sensitiveValues = append(sensitiveValues, value)
+	}
+	// Длинные значения заменяются первыми, чтобы не оставить хвост значения,
+	// которое содержит более короткое
+	sort.Slice(sensitiveValues, func(i, j int) bool {
+		return len(sensitiveValues[i]) > len(sensitiveValues[j])
+	})
+}
+
+// Redact заменяет известные sensitive значения на (sensitive value)
+func Redact(s string) string {
+	sensitiveMu.RLock()
+	defer sensitiveMu.RUnlock()
+
+	for _, value := range sensitiveValues {
+		if len(value) < shortSensitiveLength {
+			s = replaceWords(s, value)
+		} else {
+			s = strings.ReplaceAll(s, value, SensitivePlaceholder)
+		}
+	}
+	return s
+}
+
+// replaceWords заменяет value, только когда оно не окружено буквами и цифрами:
+// короткий пароль "ab1" вырезается из "password=ab1", но не из "tab1"
+func replaceWords(s, value string) string {
+	var b strings.Builder
+	written := 0
+	for i := 0; i <= len(s)-len(value); {
+		found := strings.Index(s[i:], value)
+		if found < 0 {
+			break
+		}
+		start, end := i+found, i+found+len(value)
+		if !wordRune(lastRune(s[:start])) && !wordRune(firstRune(s[end:])) {
+			b.WriteString(s[written:start])
+			b.WriteString(SensitivePlaceholder)
+			written, i = end, end
+		} else {
+			i = start + 1
+		}
+	}
+	if written == 0 {
+		return s
+	}
+	b.WriteString(s[written:])
+	return b.String()
+}
+
+func lastRune(s string) rune {
+	r, size := utf8.DecodeLastRuneInString(s)
+	if size == 0 {
+		return -1
+	}
+	return r
+}
+
+func firstRune(s string) rune {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return -1
+	}
+	return r
+}
+
+func wordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
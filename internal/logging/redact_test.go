@@ -0,0 +1,44 @@
+package logging
+
+import "testing"
+
+// resetSensitive очищает общий список sensitive значений после теста
+func resetSensitive(t *testing.T) {
+	t.Cleanup(func() {
+		sensitiveMu.Lock()
+		sensitiveValues = nil
+		sensitiveMu.Unlock()
+	})
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name      string
+		sensitive []string
+		in        string
+		want      string
+	}{
+		{"long value inside text", []string{"s3cr3t-token"}, "auth=Bearer s3cr3t-token;", "auth=Bearer (sensitive value);"},
+		{"long value inside a word", []string{"hunter22"}, "xhunter22x", "x(sensitive value)x"},
+		{"short password", []string{"pw1"}, "PASSWORD=pw1", "PASSWORD=(sensitive value)"},
+		{"single character", []string{"7"}, "pin 7 accepted", "pin (sensitive value) accepted"},
+		{"short value in quotes", []string{"ab"}, `env "ab" and 'ab'`, `env "(sensitive value)" and '(sensitive value)'`},
+		{"short value inside a word", []string{"ab1"}, "tab1 ab12 ab1", "tab1 ab12 (sensitive value)"},
+		{"short value at both ends", []string{"42"}, "42 of 420 and 42", "(sensitive value) of 420 and (sensitive value)"},
+		{"short value in json", []string{"xy"}, `{"token":"xy"}`, `{"token":"(sensitive value)"}`},
+		{"unicode neighbours", []string{"ab"}, "éab ab", "éab (sensitive value)"},
+		{"longer value first", []string{"abc", "abcdef"}, "abcdef abc", "(sensitive value) (sensitive value)"},
+		{"empty value is ignored", []string{""}, "nothing to hide", "nothing to hide"},
+		{"no match", []string{"secret-value"}, "plain message", "plain message"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resetSensitive(t)
+			AddSensitive(test.sensitive...)
+			if got := Redact(test.in); got != test.want {
+				t.Errorf("Redact(%q) = %q, want %q", test.in, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// HandshakeTimeout сколько ждать строку рукопожатия от плагина
+	HandshakeTimeout = 10 * time.Second
+
+	// stderrTailLines сколько последних строк stderr показывать при падении плагина
+	stderrTailLines = 20
+)
+
+// Client запущенный процесс плагина. Реализует providers.Provider,
+// поэтому движок работает с ним так же, как со встроенным провайдером.
+type Client struct {
+	name      string
+	cmd       *exec.Cmd
+	conn      *grpc.ClientConn
+	socketDir string
+	logger    *logging.Logger
+
+	exited  chan struct{}
+	waitErr error
+	stderr  *tailBuffer
+
+	schema    schemaResponse
+	resources map[string]providers.Resource
+}
+
+// Launch запускает бинарник плагина, выполняет рукопожатие и получает схему
+func Launch(ctx context.Context, name, path string, logger *logging.Logger) (*Client, error) {
+	socketDir, err := os.MkdirTemp("", "derraform-plugin-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	client := &Client{
+		name:      name,
+		socketDir: socketDir,
+		logger:    logger,
+		exited:    make(chan struct{}),
+		stderr:    &tailBuffer{limit: stderrTailLines},
+	}
+
+	handshake := make(chan string, 1)
+	var once sync.Once
+
+	cmd := exec.Command(path)
+	cmd.Env = append(os.Environ(),
+		MagicCookieKey+"="+MagicCookieValue,
+		protocolVersionsKey+"="+strconv.Itoa(ProtocolVersion),
+		socketDirKey+"="+socketDir,
+	)
+	// Первая строка stdout - рукопожатие. Остальной вывод плагина идет в лог:
+	// stdout движка занят выводом для пользователя или событиями -json
+	cmd.Stdout = &lineWriter{onLine: func(line string) {
+		handled := false
+		once.Do(func() {
+			handshake <- line
+			handled = true
+		})
+		if !handled {
+			logger.Debug("[provider %s] %s", name, line)
+		}
+	}}
+	cmd.Stderr = &lineWriter{onLine: func(line string) {
+		client.stderr.add(line)
+		// Записи лога плагина приходят в JSON и повторяются с их уровнем, остальное - отладочный вывод
+		if !logger.Relay(line) {
+			logger.Debug("[provider %s] %s", name, line)
+		}
+	}}
+	client.cmd = cmd
+
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(socketDir)
+		return nil, fmt.Errorf("failed to start provider plugin %s: %w", name, err)
+	}
+	go func() {
+		client.waitErr = cmd.Wait()
+		close(client.exited)
+	}()
+
+	logger.Debug("Started provider plugin %s (pid %d)", name, cmd.Process.Pid)
+
+	var line string
+	select {
+	case line = <-handshake:
+	case <-client.exited:
+		client.Close()
+		return nil, client.crashError("exited before handshake")
+	case <-time.After(HandshakeTimeout):
+		client.Close()
+		return nil, fmt.Errorf("provider plugin %s did not complete handshake within %s", name, HandshakeTimeout)
+	case <-ctx.Done():
+		client.Close()
+		return nil, ctx.Err()
+	}
+
+	address, err := parseHandshake(line)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("provider plugin %s: %w", name, err)
+	}
+
+	client.conn, err = grpc.NewClient("unix://"+address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype(codecName)),
+	)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to provider plugin %s: %w", name, err)
+	}
+
+	if err := client.call(ctx, "GetSchema", &emptyMessage{}, &client.schema); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to get schema from provider plugin %s: %w", name, err)
+	}
+
+	client.resources = make(map[string]providers.Resource, len(client.schema.Resources))
+	for typeName, schema := range client.schema.Resources {
+		client.resources[typeName] = &remoteResource{client: client, typeName: typeName, schema: schema}
+	}
+
+	return client, nil
+}
+
+// parseHandshake разбирает строку "<версия>|unix|<адрес>"
+func parseHandshake(line string) (string, error) {
+	parts := strings.Split(strings.TrimSpace(line), "|")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("invalid handshake %q", line)
+	}
+
+	version, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid handshake %q", line)
+	}
+	if version != ProtocolVersion {
+		return "", fmt.Errorf("incompatible protocol version %d, expected %d", version, ProtocolVersion)
+	}
+	if parts[1] != "unix" {
+		return "", fmt.Errorf("unsupported network %q", parts[1])
+	}
+	return parts[2], nil
+}
+
+func (c *Client) Schema() providers.Schema {
+	return c.schema.Provider
+}
+
+func (c *Client) Configure(ctx context.Context, cfg config.Provider) error {
+	wire, err := encodeProvider(cfg)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, "Configure", &configureRequest{Config: wire}, &emptyMessage{})
+}
+
+func (c *Client) Resources() map[string]providers.Resource {
+	return c.resources
+}
+
+// Close останавливает процесс плагина и удаляет его сокет
+func (c *Client) Close() error {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+
+	select {
+	case <-c.exited:
+	default:
+		c.cmd.Process.Kill()
+		<-c.exited
+	}
+
+	return os.RemoveAll(c.socketDir)
+}
+
+// call выполняет RPC и переводит ошибки gRPC обратно в обычные ошибки.
+// Если процесс плагина упал, ошибка содержит хвост его stderr.
+func (c *Client) call(ctx context.Context, method string, req, resp any) error {
+	err := c.conn.Invoke(ctx, "/"+serviceName+"/"+method, req, resp)
+	if err == nil {
+		return nil
+	}
+
+	st := status.Convert(err)
+	if st.Code() == codes.Unavailable {
+		// Соединение обрывается раньше, чем мы узнаем о завершении процесса
+		select {
+		case <-c.exited:
+			return c.crashError("crashed")
+		case <-time.After(time.Second):
+		}
+	}
+	if st.Code() == codes.Canceled || st.Code() == codes.DeadlineExceeded {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+	}
+	return errors.New(st.Message())
+}
+
+func (c *Client) crashError(what string) error {
+	msg := fmt.Sprintf("provider plugin %s %s", c.name, what)
+	if c.waitErr != nil {
+		msg += fmt.Sprintf(" (%v)", c.waitErr)
+	}
+	if tail := c.stderr.String(); tail != "" {
+		msg += ":\n" + tail
+	}
+	return errors.New(msg)
+}
+
+// remoteResource ресурс, реализованный в процессе плагина
+type remoteResource struct {
+	client   *Client
+	typeName string
+	schema   providers.Schema
+}
+
+func (r *remoteResource) Schema() providers.Schema {
+	return r.schema
+}
+
+func (r *remoteResource) Plan(ctx context.Context, cfg config.Resource, prior *state.ResourceState) (*providers.PlannedChange, error) {
+	wire, err := encodeResource(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp planResponse
+	if err := r.client.call(ctx, "PlanResourceChange", &planRequest{TypeName: r.typeName, Config: wire, Prior: prior}, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Change == nil {
+		return nil, fmt.Errorf("provider plugin %s returned an empty plan for %s", r.client.name, r.typeName)
+	}
+	return resp.Change, nil
+}
+
+func (r *remoteResource) Apply(ctx context.Context, cfg config.Resource, prior *state.ResourceState, change *providers.PlannedChange) (*state.ResourceState, error) {
+	wire, err := encodeResource(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp applyResponse
+	req := &applyRequest{TypeName: r.typeName, Config: wire, Prior: prior, Change: change}
+	if err := r.client.call(ctx, "ApplyResourceChange", req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return resp.State, errors.New(resp.Error)
+	}
+	return resp.State, nil
+}
+
+func (r *remoteResource) Read(ctx context.Context, current *state.ResourceState) (*state.ResourceState, error) {
+	var resp stateResponse
+	if err := r.client.call(ctx, "ReadResource", &readRequest{TypeName: r.typeName, Current: current}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.State, nil
+}
+
+func (r *remoteResource) Delete(ctx context.Context, current *state.ResourceState) error {
+	return r.client.call(ctx, "DeleteResource", &deleteRequest{TypeName: r.typeName, Current: current}, &emptyMessage{})
+}
+
+func (r *remoteResource) Import(ctx context.Context, id string) (*state.ResourceState, error) {
+	var resp stateResponse
+	if err := r.client.call(ctx, "ImportResource", &importRequest{TypeName: r.typeName, ID: id}, &resp); err != nil {
+		return nil, err
+	}
+	if resp.State == nil {
+		return nil, fmt.Errorf("resource %s with id %q not found", r.typeName, id)
+	}
+	return resp.State, nil
+}
+
+func (r *remoteResource) UpgradeState(ctx context.Context, version int, attributes map[string]interface{}) (map[string]interface{}, error) {
+	var resp upgradeStateResponse
+	req := &upgradeStateRequest{TypeName: r.typeName, Version: version, Attributes: attributes}
+	if err := r.client.call(ctx, "UpgradeResourceState", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Attributes, nil
+}
+
+// lineWriter режет вывод процесса на строки
+type lineWriter struct {
+	mu     sync.Mutex
+	buf    []byte
+	onLine func(string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.onLine(strings.TrimRight(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// tailBuffer хранит последние строки вывода
+type tailBuffer struct {
+	mu    sync.Mutex
+	lines []string
+	limit int
+}
+
+func (t *tailBuffer) add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.lines = append(t.lines, line)
+	if len(t.lines) > t.limit {
+		t.lines = t.lines[len(t.lines)-t.limit:]
+	}
+}
+
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.Join(t.lines, "\n")
+}
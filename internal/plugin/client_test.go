@@ -0,0 +1,341 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// testPluginModeKey переключает тестовый бинарник в режим плагина:
+// Launch запускает os.Args[0], и TestMain вместо тестов вызывает Serve
+const testPluginModeKey = "DERRAFORM_TEST_PLUGIN_MODE"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(testPluginModeKey) {
+	case "":
+		os.Exit(m.Run())
+	case "serve":
+		Serve(func() providers.Provider { return &testProvider{} })
+	case "exit":
+		fmt.Fprintln(os.Stderr, "failed to load configuration")
+		os.Exit(3)
+	case "version":
+		fmt.Printf("%d|unix|/tmp/plugin.sock\n", ProtocolVersion+1)
+		select {}
+	}
+	os.Exit(0)
+}
+
+// testProvider провайдер с одним ресурсом test_thing
+type testProvider struct {
+	host string
+}
+
+func (p *testProvider) Schema() providers.Schema {
+	return providers.Schema{Attributes: map[string]*providers.Attribute{
+		"host": {Type: cty.String, Optional: true},
+	}}
+}
+
+func (p *testProvider) Configure(ctx context.Context, cfg config.Provider) error {
+	host, err := providers.StringAttribute(cfg.Attributes, "host")
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		return errors.New("missing required attribute 'host'")
+	}
+	p.host = host
+	return nil
+}
+
+func (p *testProvider) Resources() map[string]providers.Resource {
+	return map[string]providers.Resource{"test_thing": &testResource{provider: p}}
+}
+
+// testResource поведение выбирается атрибутом mode или ID объекта
+type testResource struct {
+	provider *testProvider
+}
+
+func (r *testResource) Schema() providers.Schema {
+	return providers.Schema{Version: 2, Attributes: map[string]*providers.Attribute{
+		"mode":     {Type: cty.String, Optional: true},
+		"password": {Type: cty.String, Optional: true, Sensitive: true},
+	}}
+}
+
+func (r *testResource) Plan(ctx context.Context, cfg config.Resource, prior *state.ResourceState) (*providers.PlannedChange, error) {
+	mode, _ := providers.StringAttribute(cfg.Attributes, "mode")
+	switch mode {
+	case "panic":
+		panic("index out of range")
+	case "crash":
+		fmt.Fprintln(os.Stderr, "fatal error: out of memory")
+		os.Exit(2)
+	case "empty":
+		return nil, nil
+	}
+	reason := fmt.Sprintf("host=%s name=%s blocks=%d", r.provider.host, cfg.Name, len(cfg.Blocks))
+	return &providers.PlannedChange{Action: providers.ActionCreate, Reason: reason}, nil
+}
+
+func (r *testResource) Apply(ctx context.Context, cfg config.Resource, prior *state.ResourceState, change *providers.PlannedChange) (*state.ResourceState, error) {
+	created := &state.ResourceState{Type: cfg.Type, ID: "thing-1", Attributes: map[string]interface{}{"reason": change.Reason}}
+	if mode, _ := providers.StringAttribute(cfg.Attributes, "mode"); mode == "unhealthy" {
+		return created, errors.New("container is unhealthy")
+	}
+	return created, nil
+}
+
+func (r *testResource) Read(ctx context.Context, current *state.ResourceState) (*state.ResourceState, error) {
+	if current.ID == "gone" {
+		return nil, nil
+	}
+	return current, nil
+}
+
+func (r *testResource) Delete(ctx context.Context, current *state.ResourceState) error {
+	if current.ID == "in-use" {
+		return errors.New("volume is in use")
+	}
+	return nil
+}
+
+func (r *testResource) Import(ctx context.Context, id string) (*state.ResourceState, error) {
+	if id == "missing" {
+		return nil, nil
+	}
+	return &state.ResourceState{Type: "test_thing", ID: id}, nil
+}
+
+// launchTestPlugin запускает тестовый бинарник как плагин в режиме mode
+func launchTestPlugin(t *testing.T, mode string) (*Client, error) {
+	t.Helper()
+	t.Setenv(testPluginModeKey, mode)
+	client, err := Launch(context.Background(), "test", os.Args[0], logging.NewLogger(logging.OFF))
+	if client != nil {
+		t.Cleanup(func() { client.Close() })
+	}
+	return client, err
+}
+
+func TestParseHandshake(t *testing.T) {
+	tests := []struct {
+		line    string
+		address string
+		err     string
+	}{
+		{line: "1|unix|/tmp/derraform-plugin-1/plugin.sock\n", address: "/tmp/derraform-plugin-1/plugin.sock"},
+		{line: "1|unix|/tmp/plugin.sock\r\n", address: "/tmp/plugin.sock"},
+		{line: "2|unix|/tmp/plugin.sock", err: "incompatible protocol version 2, expected 1"},
+		{line: "1|tcp|127.0.0.1:1234", err: `unsupported network "tcp"`},
+		{line: "one|unix|/tmp/plugin.sock", err: "invalid handshake"},
+		{line: "Listening on /tmp/plugin.sock", err: "invalid handshake"},
+	}
+
+	for _, test := range tests {
+		t.Run(strings.TrimSpace(test.line), func(t *testing.T) {
+			address, err := parseHandshake(test.line)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if address != test.address {
+				t.Errorf("address = %q, want %q", address, test.address)
+			}
+		})
+	}
+}
+
+func TestSupportsProtocol(t *testing.T) {
+	tests := []struct {
+		offered string
+		want    bool
+	}{
+		{"1", true},
+		{"2, 1", true},
+		{"2,3", false},
+		{"", false},
+		{"v1", false},
+	}
+
+	for _, test := range tests {
+		if got := supportsProtocol(test.offered); got != test.want {
+			t.Errorf("supportsProtocol(%q) = %v, want %v", test.offered, got, test.want)
+		}
+	}
+}
+
+func TestServeRequiresEngine(t *testing.T) {
+	tests := []struct {
+		name string
+		env  []string
+		err  string
+	}{
+		{
+			name: "without magic cookie",
+			err:  "It is started by derraform and should not be run directly",
+		},
+		{
+			name: "unsupported protocol",
+			env:  []string{MagicCookieKey + "=" + MagicCookieValue, protocolVersionsKey + "=2,3"},
+			err:  `Unsupported plugin protocol: derraform offers versions "2,3", plugin supports 1`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0])
+			cmd.Env = append(os.Environ(), append(test.env, testPluginModeKey+"=serve")...)
+			output, err := cmd.CombinedOutput()
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+				t.Fatalf("plugin exited with %v, want exit status 1", err)
+			}
+			if !strings.Contains(string(output), test.err) {
+				t.Errorf("output = %q, want it to contain %q", output, test.err)
+			}
+		})
+	}
+}
+
+func TestLaunchHandshakeErrors(t *testing.T) {
+	tests := []struct {
+		mode string
+		err  string
+	}{
+		{mode: "exit", err: "provider plugin test exited before handshake (exit status 3):\nfailed to load configuration"},
+		{mode: "version", err: "provider plugin test: incompatible protocol version 2, expected 1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mode, func(t *testing.T) {
+			_, err := launchTestPlugin(t, test.mode)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("error = %v, want it to contain %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestLaunch(t *testing.T) {
+	client, err := launchTestPlugin(t, "serve")
+	if err != nil {
+		t.Fatalf("Launch: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, exists := client.Schema().Attributes["host"]; !exists {
+		t.Errorf("provider schema = %+v, want the host attribute", client.Schema())
+	}
+	resource, exists := client.Resources()["test_thing"]
+	if !exists {
+		t.Fatalf("resources = %v, want test_thing", client.Resources())
+	}
+	schema := resource.Schema()
+	if schema.Version != 2 || schema.Attributes["password"] == nil || !schema.Attributes["password"].Sensitive {
+		t.Errorf("resource schema = %+v, want version 2 with sensitive password", schema)
+	}
+
+	if err := client.Configure(ctx, config.Provider{Name: "test"}); err == nil || err.Error() != "missing required attribute 'host'" {
+		t.Errorf("Configure error = %v, want the provider error unchanged", err)
+	}
+	if err := client.Configure(ctx, config.Provider{Name: "test", Attributes: map[string]cty.Value{"host": cty.StringVal("unix:///run/docker.sock")}}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+
+	cfg := config.Resource{
+		Type:   "test_thing",
+		Name:   "web",
+		Blocks: []config.Block{{Type: "port", Attributes: map[string]cty.Value{"internal": cty.NumberIntVal(80)}}},
+	}
+	change, err := resource.Plan(ctx, cfg, nil)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if want := "host=unix:///run/docker.sock name=web blocks=1"; change.Action != providers.ActionCreate || change.Reason != want {
+		t.Errorf("change = %+v, want create with reason %q", change, want)
+	}
+
+	created, err := resource.Apply(ctx, cfg, nil, change)
+	if err != nil || created == nil || created.ID != "thing-1" {
+		t.Errorf("Apply = %+v, %v", created, err)
+	}
+	// Apply возвращает и состояние, и ошибку: объект создан, но не готов
+	cfg.Attributes = map[string]cty.Value{"mode": cty.StringVal("unhealthy")}
+	created, err = resource.Apply(ctx, cfg, nil, change)
+	if err == nil || err.Error() != "container is unhealthy" || created == nil || created.ID != "thing-1" {
+		t.Errorf("Apply = %+v, %v, want state together with the error", created, err)
+	}
+
+	if current, err := resource.Read(ctx, &state.ResourceState{Type: "test_thing", ID: "gone"}); err != nil || current != nil {
+		t.Errorf("Read of a deleted object = %+v, %v, want nil state", current, err)
+	}
+	if err := resource.Delete(ctx, &state.ResourceState{Type: "test_thing", ID: "in-use"}); err == nil || err.Error() != "volume is in use" {
+		t.Errorf("Delete error = %v", err)
+	}
+	if _, err := resource.Import(ctx, "missing"); err == nil || err.Error() != `resource test_thing with id "missing" not found` {
+		t.Errorf("Import error = %v", err)
+	}
+	upgrader := resource.(providers.StateUpgrader)
+	if _, err := upgrader.UpgradeState(ctx, 1, nil); err == nil || !strings.Contains(err.Error(), "resource type test_thing does not support state upgrades") {
+		t.Errorf("UpgradeState error = %v", err)
+	}
+
+	cfg.Attributes = map[string]cty.Value{"mode": cty.StringVal("empty")}
+	if _, err := resource.Plan(ctx, cfg, nil); err == nil || !strings.Contains(err.Error(), "returned an empty plan for test_thing") {
+		t.Errorf("Plan error = %v, want an empty plan error", err)
+	}
+
+	// Паника в ресурсе становится ошибкой вызова, плагин продолжает работать
+	cfg.Attributes = map[string]cty.Value{"mode": cty.StringVal("panic")}
+	if _, err := resource.Plan(ctx, cfg, nil); err == nil || err.Error() != "provider panicked: index out of range" {
+		t.Errorf("Plan error = %v, want a panic error", err)
+	}
+	cfg.Attributes = nil
+	if _, err := resource.Plan(ctx, cfg, nil); err != nil {
+		t.Errorf("Plan after panic: %v", err)
+	}
+}
+
+func TestLaunchPluginCrash(t *testing.T) {
+	client, err := launchTestPlugin(t, "serve")
+	if err != nil {
+		t.Fatalf("Launch: %v", err)
+	}
+	resource := client.Resources()["test_thing"]
+
+	cfg := config.Resource{Type: "test_thing", Name: "web", Attributes: map[string]cty.Value{"mode": cty.StringVal("crash")}}
+	_, err = resource.Plan(context.Background(), cfg, nil)
+	want := "provider plugin test crashed (exit status 2):\nfatal error: out of memory"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("error = %v, want it to contain %q", err, want)
+	}
+}
+
+func TestLaunchCanceled(t *testing.T) {
+	t.Setenv(testPluginModeKey, "serve")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Launch(ctx, "test", os.Args[0], logging.NewLogger(logging.OFF))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+}
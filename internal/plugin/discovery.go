@@ -0,0 +1,171 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	// DefaultPluginDir локальный каталог с бинарниками провайдеров
+	DefaultPluginDir = "terraform.d/plugins"
+
+	// LockFileName файл с контрольными суммами установленных провайдеров
+	LockFileName = ".terraform.lock.hcl"
+
+	// BinaryPrefix префикс имени бинарника: derraform-provider-<name>
+	BinaryPrefix = "derraform-provider-"
+)
+
+// Plugin найденный в каталоге бинарник провайдера
+type Plugin struct {
+	Name string
+	Path string
+	Hash string
+}
+
+// Discover ищет в каталоге исполняемые файлы derraform-provider-<name>
+func Discover(dir string) ([]Plugin, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read plugin directory %s: %w", dir, err)
+	}
+
+	var plugins []Plugin
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(fileName, BinaryPrefix) {
+			continue
+		}
+
+		path := filepath.Join(dir, fileName)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode()&0111 == 0 {
+			continue
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(fileName, BinaryPrefix), ".exe")
+		if name == "" || strings.ContainsAny(name, "._") {
+			return nil, fmt.Errorf("invalid provider plugin name %q", fileName)
+		}
+
+		hash, err := Checksum(path)
+		if err != nil {
+			return nil, err
+		}
+		plugins = append(plugins, Plugin{Name: name, Path: path, Hash: hash})
+	}
+
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins, nil
+}
+
+// Checksum возвращает контрольную сумму файла в формате sha256:<hex>
+func Checksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// LockedProvider запись о провайдере в lock файле
+type LockedProvider struct {
+	Path string
+	Hash string
+}
+
+// LockFile содержимое .terraform.lock.hcl
+type LockFile struct {
+	Providers map[string]LockedProvider
+}
+
+type lockFileHCL struct {
+	Providers []lockedProviderHCL `hcl:"provider,block"`
+}
+
+type lockedProviderHCL struct {
+	Name string `hcl:"name,label"`
+	Path string `hcl:"path"`
+	Hash string `hcl:"hash"`
+}
+
+// ReadLockFile читает lock файл. Отсутствующий файл - пустой lock.
+func ReadLockFile(path string) (*LockFile, error) {
+	lockFile := &LockFile{Providers: make(map[string]LockedProvider)}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return lockFile, nil
+		}
+		return nil, err
+	}
+
+	var raw lockFileHCL
+	if err := hclsimple.Decode(path, src, nil, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for _, provider := range raw.Providers {
+		lockFile.Providers[provider.Name] = LockedProvider{Path: provider.Path, Hash: provider.Hash}
+	}
+	return lockFile, nil
+}
+
+// Save записывает lock файл
+func (l *LockFile) Save(path string) error {
+	file := hclwrite.NewEmptyFile()
+	body := file.Body()
+
+	names := make([]string, 0, len(l.Providers))
+	for name := range l.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		if i > 0 {
+			body.AppendNewline()
+		}
+		provider := l.Providers[name]
+		block := body.AppendNewBlock("provider", []string{name})
+		block.Body().SetAttributeValue("path", cty.StringVal(provider.Path))
+		block.Body().SetAttributeValue("hash", cty.StringVal(provider.Hash))
+	}
+
+	header := "# This file is maintained automatically by \"init\".\n# Manual edits may be lost in future updates.\n\n"
+	return os.WriteFile(path, append([]byte(header), file.Bytes()...), 0644)
+}
+
+// Verify проверяет, что бинарник не изменился с момента init
+func (p LockedProvider) Verify() error {
+	hash, err := Checksum(p.Path)
+	if err != nil {
+		return err
+	}
+	if hash != p.Hash {
+		return fmt.Errorf("checksum mismatch for %s: lock file has %s, got %s", p.Path, p.Hash, hash)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Package plugin запускает провайдеры как отдельные процессы и общается с ними по gRPC.
+//
+// Движок запускает бинарник провайдера с magic cookie в окружении, провайдер
+// открывает Unix сокет и печатает в stdout строку рукопожатия
+// "<версия протокола>|unix|<путь к сокету>". Дальше все вызовы идут по gRPC,
+// сообщения кодируются в JSON, а значения cty - в msgpack.
+package plugin
+
+import (
+	"encoding/json"
+
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"google.golang.org/grpc/encoding"
+)
+
+// ProtocolVersion версия протокола между движком и провайдером.
+// Увеличивается при любом несовместимом изменении сообщений.
+const ProtocolVersion = 1
+
+const (
+	// MagicCookieKey и MagicCookieValue защищают от случайного запуска плагина руками
+	MagicCookieKey   = "DERRAFORM_PLUGIN_MAGIC_COOKIE"
+	MagicCookieValue = "c8a3e5f1d0b94a7e9f2b6d4c1e8a7b35"
+
+	// protocolVersionsKey версии протокола, которые поддерживает движок
+	protocolVersionsKey = "DERRAFORM_PLUGIN_PROTOCOL_VERSIONS"
+	// socketDirKey каталог, в котором плагин создает сокет
+	socketDirKey = "DERRAFORM_PLUGIN_SOCKET_DIR"
+)
+
+const (
+	serviceName = "derraform.plugin.Provider"
+	codecName   = "json"
+)
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// jsonCodec кодирует gRPC сообщения в JSON, чтобы обойтись без protoc
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return codecName
+}
+
+// Сообщения протокола
+
+type emptyMessage struct{}
+
+type schemaResponse struct {
+	Provider  providers.Schema            `json:"provider"`
+	Resources map[string]providers.Schema `json:"resources"`
+}
+
+type configureRequest struct {
+	Config wireProvider `json:"config"`
+}
+
+type planRequest struct {
+	TypeName string               `json:"type_name"`
+	Config   wireResource         `json:"config"`
+	Prior    *state.ResourceState `json:"prior,omitempty"`
+}
+
+type planResponse struct {
+	Change *providers.PlannedChange `json:"change"`
+}
+
+type applyRequest struct {
+	TypeName string                   `json:"type_name"`
+	Config   wireResource             `json:"config"`
+	Prior    *state.ResourceState     `json:"prior,omitempty"`
+	Change   *providers.PlannedChange `json:"change"`
+}
+
+// applyResponse передает ошибку отдельно от gRPC статуса,
+// потому что Apply может вернуть и состояние, и ошибку
+type applyResponse struct {
+	State *state.ResourceState `json:"state,omitempty"`
+	Error string               `json:"error,omitempty"`
+}
+
+type readRequest struct {
+	TypeName string               `json:"type_name"`
+	Current  *state.ResourceState `json:"current"`
+}
+
+type stateResponse struct {
+	State *state.ResourceState `json:"state,omitempty"`
+}
+
+type deleteRequest struct {
+	TypeName string               `json:"type_name"`
+	Current  *state.ResourceState `json:"current"`
+}
+
+type importRequest struct {
+	TypeName string `json:"type_name"`
+	ID       string `json:"id"`
+}
+
+type upgradeStateRequest struct {
+	TypeName   string                 `json:"type_name"`
+	Version    int                    `json:"version"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+type upgradeStateResponse struct {
+	Attributes map[string]interface{} `json:"attributes"`
+}
@@ -0,0 +1,245 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/Artemka007/derraform/internal/providers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Serve запускает провайдер как плагин. Вызывается из main бинарника провайдера:
+//
+//	func main() {
+//		plugin.Serve(myprovider.NewProvider)
+//	}
+func Serve(factory providers.Factory) {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		fmt.Fprintln(os.Stderr, "This binary is a derraform provider plugin. It is started by derraform and should not be run directly.")
+		os.Exit(1)
+	}
+
+	if !supportsProtocol(os.Getenv(protocolVersionsKey)) {
+		fmt.Fprintf(os.Stderr, "Unsupported plugin protocol: derraform offers versions %q, plugin supports %d\n",
+			os.Getenv(protocolVersionsKey), ProtocolVersion)
+		os.Exit(1)
+	}
+
+	dir := os.Getenv(socketDirKey)
+	if dir == "" {
+		var err error
+		if dir, err = os.MkdirTemp("", "derraform-plugin-"); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create socket directory: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	address := filepath.Join(dir, "plugin.sock")
+	listener, err := net.Listen("unix", address)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to listen on %s: %v\n", address, err)
+		os.Exit(1)
+	}
+
+	// Лог плагина пишется в stderr в JSON: движок повторяет записи в своем логе,
+	// поэтому уровни фильтрует он, а плагин пишет все
+	provider := factory()
+	if setter, ok := provider.(providers.LoggerSetter); ok {
+		logger, _ := logging.New(logging.Config{Level: logging.TRACE, Format: logging.FormatJSON, Output: os.Stderr})
+		setter.SetLogger(logger)
+	}
+
+	server := grpc.NewServer()
+	server.RegisterService(&serviceDesc, &providerServer{provider: provider})
+
+	// Ctrl+C получает вся группа процессов, а остановкой плагина управляет движок
+	signal.Ignore(os.Interrupt)
+
+	// Рукопожатие: первая строка stdout
+	fmt.Printf("%d|unix|%s\n", ProtocolVersion, address)
+
+	if err := server.Serve(listener); err != nil {
+		fmt.Fprintf(os.Stderr, "Plugin server stopped: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// supportsProtocol проверяет, что движок предлагает нашу версию протокола
+func supportsProtocol(offered string) bool {
+	for _, version := range strings.Split(offered, ",") {
+		if v, err := strconv.Atoi(strings.TrimSpace(version)); err == nil && v == ProtocolVersion {
+			return true
+		}
+	}
+	return false
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "GetSchema", Handler: unaryHandler((*providerServer).getSchema)},
+		{MethodName: "Configure", Handler: unaryHandler((*providerServer).configure)},
+		{MethodName: "PlanResourceChange", Handler: unaryHandler((*providerServer).plan)},
+		{MethodName: "ApplyResourceChange", Handler: unaryHandler((*providerServer).apply)},
+		{MethodName: "ReadResource", Handler: unaryHandler((*providerServer).read)},
+		{MethodName: "DeleteResource", Handler: unaryHandler((*providerServer).delete)},
+		{MethodName: "ImportResource", Handler: unaryHandler((*providerServer).importResource)},
+		{MethodName: "UpgradeResourceState", Handler: unaryHandler((*providerServer).upgradeState)},
+	},
+}
+
+// unaryHandler декодирует запрос и превращает панику провайдера в ошибку вызова,
+// чтобы один упавший ресурс не ронял весь процесс плагина
+func unaryHandler[Req, Resp any](fn func(*providerServer, context.Context, *Req) (*Resp, error)) grpc.MethodHandler {
+	return func(srv any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (resp any, err error) {
+		req := new(Req)
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Fprintf(os.Stderr, "panic: %v\n\n%s", r, debug.Stack())
+				resp, err = nil, status.Errorf(codes.Internal, "provider panicked: %v", r)
+			}
+		}()
+
+		return fn(srv.(*providerServer), ctx, req)
+	}
+}
+
+// providerServer обслуживает вызовы движка поверх обычного providers.Provider
+type providerServer struct {
+	provider providers.Provider
+}
+
+func (s *providerServer) resource(typeName string) (providers.Resource, error) {
+	impl, exists := s.provider.Resources()[typeName]
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "unknown resource type: %s", typeName)
+	}
+	return impl, nil
+}
+
+func (s *providerServer) getSchema(ctx context.Context, req *emptyMessage) (*schemaResponse, error) {
+	resp := &schemaResponse{
+		Provider:  s.provider.Schema(),
+		Resources: make(map[string]providers.Schema),
+	}
+	for typeName, impl := range s.provider.Resources() {
+		resp.Resources[typeName] = impl.Schema()
+	}
+	return resp, nil
+}
+
+func (s *providerServer) configure(ctx context.Context, req *configureRequest) (*emptyMessage, error) {
+	cfg, err := decodeProvider(req.Config)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.provider.Configure(ctx, cfg); err != nil {
+		return nil, err
+	}
+	return &emptyMessage{}, nil
+}
+
+func (s *providerServer) plan(ctx context.Context, req *planRequest) (*planResponse, error) {
+	impl, err := s.resource(req.TypeName)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := decodeResource(req.Config)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	change, err := impl.Plan(ctx, cfg, req.Prior)
+	if err != nil {
+		return nil, err
+	}
+	return &planResponse{Change: change}, nil
+}
+
+func (s *providerServer) apply(ctx context.Context, req *applyRequest) (*applyResponse, error) {
+	impl, err := s.resource(req.TypeName)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := decodeResource(req.Config)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	newState, err := impl.Apply(ctx, cfg, req.Prior, req.Change)
+	resp := &applyResponse{State: newState}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return resp, nil
+}
+
+func (s *providerServer) read(ctx context.Context, req *readRequest) (*stateResponse, error) {
+	impl, err := s.resource(req.TypeName)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := impl.Read(ctx, req.Current)
+	if err != nil {
+		return nil, err
+	}
+	return &stateResponse{State: current}, nil
+}
+
+func (s *providerServer) delete(ctx context.Context, req *deleteRequest) (*emptyMessage, error) {
+	impl, err := s.resource(req.TypeName)
+	if err != nil {
+		return nil, err
+	}
+	if err := impl.Delete(ctx, req.Current); err != nil {
+		return nil, err
+	}
+	return &emptyMessage{}, nil
+}
+
+func (s *providerServer) importResource(ctx context.Context, req *importRequest) (*stateResponse, error) {
+	impl, err := s.resource(req.TypeName)
+	if err != nil {
+		return nil, err
+	}
+
+	imported, err := impl.Import(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &stateResponse{State: imported}, nil
+}
+
+func (s *providerServer) upgradeState(ctx context.Context, req *upgradeStateRequest) (*upgradeStateResponse, error) {
+	impl, err := s.resource(req.TypeName)
+	if err != nil {
+		return nil, err
+	}
+
+	upgrader, ok := impl.(providers.StateUpgrader)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "resource type %s does not support state upgrades", req.TypeName)
+	}
+
+	attributes, err := upgrader.UpgradeState(ctx, req.Version, req.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	return &upgradeStateResponse{Attributes: attributes}, nil
+}
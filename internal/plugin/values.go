@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/zclconf/go-cty/cty"
+	ctymsgpack "github.com/zclconf/go-cty/cty/msgpack"
+)
+
+// Конфигурация передается плагину как дерево блоков, где каждое значение
+// закодировано в msgpack вместе с типом (cty.DynamicPseudoType)
+
+type wireBody struct {
+	Attributes map[string][]byte `json:"attributes,omitempty"`
+	Blocks     []wireBlock       `json:"blocks,omitempty"`
+}
+
+type wireBlock struct {
+	Type   string   `json:"type"`
+	Labels []string `json:"labels,omitempty"`
+	Body   wireBody `json:"body"`
+}
+
+type wireResource struct {
+	Type     string   `json:"type"`
+	Name     string   `json:"name"`
+	Provider string   `json:"provider,omitempty"`
+	Dir      string   `json:"dir,omitempty"`
+	Body     wireBody `json:"body"`
+}
+
+type wireProvider struct {
+	Name string   `json:"name"`
+	Body wireBody `json:"body"`
+}
+
+func encodeResource(resource config.Resource) (wireResource, error) {
+	body, err := encodeBody(resource.Attributes, resource.Blocks)
+	if err != nil {
+		return wireResource{}, err
+	}
+	return wireResource{
+		Type:     resource.Type,
+		Name:     resource.Name,
+		Provider: resource.Provider,
+		Dir:      resource.Dir,
+		Body:     body,
+	}, nil
+}
+
+func decodeResource(wire wireResource) (config.Resource, error) {
+	attributes, blocks, err := decodeBody(wire.Body)
+	if err != nil {
+		return config.Resource{}, err
+	}
+	return config.Resource{
+		Type:       wire.Type,
+		Name:       wire.Name,
+		Provider:   wire.Provider,
+		Dir:        wire.Dir,
+		Attributes: attributes,
+		Blocks:     blocks,
+	}, nil
+}
+
+func encodeProvider(provider config.Provider) (wireProvider, error) {
+	body, err := encodeBody(provider.Attributes, provider.Blocks)
+	if err != nil {
+		return wireProvider{}, err
+	}
+	return wireProvider{Name: provider.Name, Body: body}, nil
+}
+
+func decodeProvider(wire wireProvider) (config.Provider, error) {
+	attributes, blocks, err := decodeBody(wire.Body)
+	if err != nil {
+		return config.Provider{}, err
+	}
+	return config.Provider{Name: wire.Name, Attributes: attributes, Blocks: blocks}, nil
+}
+
+func encodeBody(attributes map[string]cty.Value, blocks []config.Block) (wireBody, error) {
+	body := wireBody{Attributes: make(map[string][]byte, len(attributes))}
+
+	for name, value := range attributes {
+		encoded, err := ctymsgpack.Marshal(value, cty.DynamicPseudoType)
+		if err != nil {
+			return body, fmt.Errorf("failed to encode attribute %s: %w", name, err)
+		}
+		body.Attributes[name] = encoded
+	}
+
+	for _, block := range blocks {
+		nested, err := encodeBody(block.Attributes, block.Blocks)
+		if err != nil {
+			return body, fmt.Errorf("block %s: %w", block.Type, err)
+		}
+		body.Blocks = append(body.Blocks, wireBlock{Type: block.Type, Labels: block.Labels, Body: nested})
+	}
+
+	return body, nil
+}
+
+func decodeBody(body wireBody) (map[string]cty.Value, []config.Block, error) {
+	attributes := make(map[string]cty.Value, len(body.Attributes))
+	for name, encoded := range body.Attributes {
+		value, err := ctymsgpack.Unmarshal(encoded, cty.DynamicPseudoType)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode attribute %s: %w", name, err)
+		}
+		attributes[name] = value
+	}
+
+	var blocks []config.Block
+	for _, wire := range body.Blocks {
+		nestedAttributes, nestedBlocks, err := decodeBody(wire.Body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("block %s: %w", wire.Type, err)
+		}
+		blocks = append(blocks, config.Block{
+			Type:       wire.Type,
+			Labels:     wire.Labels,
+			Attributes: nestedAttributes,
+			Blocks:     nestedBlocks,
+		})
+	}
+
+	return attributes, blocks, nil
+}
@@ -0,0 +1,146 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestResourceWireRoundTrip(t *testing.T) {
+	resource := config.Resource{
+		Type:     "docker_container",
+		Name:     "web",
+		Provider: "docker.remote",
+		Dir:      "modules/web",
+		Attributes: map[string]cty.Value{
+			"image":    cty.StringVal("nginx:1.27"),
+			"restart":  cty.NullVal(cty.String),
+			"memory":   cty.NumberIntVal(512),
+			"ratio":    cty.NumberFloatVal(0.75),
+			"rm":       cty.True,
+			"command":  cty.ListVal([]cty.Value{cty.StringVal("nginx"), cty.StringVal("-g")}),
+			"labels":   cty.MapVal(map[string]cty.Value{"app": cty.StringVal("web")}),
+			"env":      cty.SetVal([]cty.Value{cty.StringVal("A=1"), cty.StringVal("B=2")}),
+			"settings": cty.ObjectVal(map[string]cty.Value{"debug": cty.False, "level": cty.NumberIntVal(3)}),
+			"id":       cty.UnknownVal(cty.String),
+		},
+		Blocks: []config.Block{
+			{Type: "ports", Attributes: map[string]cty.Value{"internal": cty.NumberIntVal(80)}},
+			{
+				Type:   "upload",
+				Labels: []string{"config"},
+				Blocks: []config.Block{{Type: "source", Attributes: map[string]cty.Value{"path": cty.StringVal("nginx.conf")}}},
+			},
+		},
+	}
+
+	wire, err := encodeResource(resource)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	// Сообщение проходит через jsonCodec
+	data, err := jsonCodec{}.Marshal(wire)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received wireResource
+	if err := (jsonCodec{}).Unmarshal(data, &received); err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeResource(received)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if got.Type != resource.Type || got.Name != resource.Name || got.Provider != resource.Provider || got.Dir != resource.Dir {
+		t.Errorf("resource = %s.%s (%s, %s), want %s.%s (%s, %s)",
+			got.Type, got.Name, got.Provider, got.Dir, resource.Type, resource.Name, resource.Provider, resource.Dir)
+	}
+	assertAttributes(t, "resource", got.Attributes, resource.Attributes)
+
+	if len(got.Blocks) != 2 {
+		t.Fatalf("blocks = %+v, want 2", got.Blocks)
+	}
+	assertAttributes(t, "ports", got.Blocks[0].Attributes, resource.Blocks[0].Attributes)
+	upload := got.Blocks[1]
+	if upload.Type != "upload" || len(upload.Labels) != 1 || upload.Labels[0] != "config" || len(upload.Blocks) != 1 {
+		t.Fatalf("upload block = %+v", upload)
+	}
+	assertAttributes(t, "upload.source", upload.Blocks[0].Attributes, resource.Blocks[1].Blocks[0].Attributes)
+}
+
+func TestProviderWireRoundTrip(t *testing.T) {
+	provider := config.Provider{
+		Name:       "docker",
+		Attributes: map[string]cty.Value{"host": cty.StringVal("ssh://deploy@build.internal")},
+		Blocks: []config.Block{{Type: "registry_auth", Attributes: map[string]cty.Value{
+			"address": cty.StringVal("ghcr.io"),
+		}}},
+	}
+
+	wire, err := encodeProvider(provider)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	got, err := decodeProvider(wire)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Name != "docker" || len(got.Blocks) != 1 || got.Blocks[0].Type != "registry_auth" {
+		t.Fatalf("provider = %+v", got)
+	}
+	assertAttributes(t, "provider", got.Attributes, provider.Attributes)
+	assertAttributes(t, "registry_auth", got.Blocks[0].Attributes, provider.Blocks[0].Attributes)
+}
+
+func TestDecodeBodyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  string
+	}{
+		{
+			name: "invalid attribute",
+			body: `{"attributes": {"image": "bm90IG1zZ3BhY2s="}}`,
+			err:  "failed to decode attribute image",
+		},
+		{
+			name: "invalid nested attribute",
+			body: `{"blocks": [{"type": "ports", "body": {"attributes": {"internal": "bm90IG1zZ3BhY2s="}}}]}`,
+			err:  "block ports: failed to decode attribute internal",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body wireBody
+			if err := json.Unmarshal([]byte(test.body), &body); err != nil {
+				t.Fatal(err)
+			}
+			_, _, err := decodeBody(body)
+			if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+				t.Fatalf("error = %v, want it to start with %q", err, test.err)
+			}
+		})
+	}
+}
+
+func assertAttributes(t *testing.T, what string, got, want map[string]cty.Value) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s attributes = %v, want %v", what, got, want)
+	}
+	for name, value := range want {
+		decoded, exists := got[name]
+		if !exists {
+			t.Errorf("%s attribute %s is missing", what, name)
+			continue
+		}
+		if !decoded.RawEquals(value) {
+			t.Errorf("%s attribute %s = %#v, want %#v", what, name, decoded, value)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// internal/providers/attributes.go
+package providers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// StringAttribute возвращает строковый атрибут или пустую строку, если его нет
+func StringAttribute(attributes map[string]cty.Value, name string) (string, error) {
+	val, exists := attributes[name]
+	if !exists || val.IsNull() {
+		return "", nil
+	}
+	if val.Type() != cty.String {
+		return "", fmt.Errorf("attribute '%s' must be a string", name)
+	}
+	return val.AsString(), nil
+}
+
+// BoolAttribute возвращает булев атрибут или false, если его нет
+func BoolAttribute(attributes map[string]cty.Value, name string) (bool, error) {
+	val, exists := attributes[name]
+	if !exists || val.IsNull() {
+		return false, nil
+	}
+	if val.Type() != cty.Bool {
+		return false, fmt.Errorf("attribute '%s' must be a bool", name)
+	}
+	return val.True(), nil
+}
+
+// IntAttribute возвращает целочисленный атрибут или 0, если его нет
+func IntAttribute(attributes map[string]cty.Value, name string) (int, error) {
+	val, exists := attributes[name]
+	if !exists || val.IsNull() {
+		return 0, nil
+	}
+	if val.Type() != cty.Number {
+		return 0, fmt.Errorf("attribute '%s' must be a number", name)
+	}
+	number, _ := val.AsBigFloat().Int64()
+	return int(number), nil
+}
+
+// DurationAttribute разбирает строку длительности вида "30s" или "1m30s"
+func DurationAttribute(attributes map[string]cty.Value, name string) (time.Duration, error) {
+	val, exists := attributes[name]
+	if !exists || val.IsNull() {
+		return 0, nil
+	}
+	if val.Type() != cty.String {
+		return 0, fmt.Errorf("attribute '%s' must be a duration string like \"30s\"", name)
+	}
+	duration, err := time.ParseDuration(val.AsString())
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration for '%s': %w", name, err)
+	}
+	return duration, nil
+}
+
+// StringMapAttribute возвращает map строк или nil, если атрибута нет
+func StringMapAttribute(attributes map[string]cty.Value, name string) (map[string]string, error) {
+	val, exists := attributes[name]
+	if !exists || val.IsNull() {
+		return nil, nil
+	}
+	if !val.Type().IsObjectType() && !val.Type().IsMapType() {
+		return nil, fmt.Errorf("attribute '%s' must be a map of strings", name)
+	}
+
+	result := make(map[string]string)
+	for key, item := range val.AsValueMap() {
+		if item.Type() != cty.String {
+			return nil, fmt.Errorf("attribute '%s.%s' must be a string", name, key)
+		}
+		result[key] = item.AsString()
+	}
+	return result, nil
+}
+
+// StringListAttribute возвращает список строк или nil, если атрибута нет
+func StringListAttribute(attributes map[string]cty.Value, name string) ([]string, error) {
+	val, exists := attributes[name]
+	if !exists || val.IsNull() {
+		return nil, nil
+	}
+	if !val.Type().IsListType() && !val.Type().IsTupleType() && !val.Type().IsSetType() {
+		return nil, fmt.Errorf("attribute '%s' must be a list of strings", name)
+	}
+
+	var result []string
+	for _, item := range val.AsValueSlice() {
+		if item.Type() != cty.String {
+			return nil, fmt.Errorf("attribute '%s' must be a list of strings", name)
+		}
+		result = append(result, item.AsString())
+	}
+	return result, nil
+}
@@ -0,0 +1,278 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/docker/docker/api/types/build"
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+)
+
+// ImageConfig конфигурация ресурса docker_image
+type ImageConfig struct {
+	Name  string
+	Build *BuildConfig
+}
+
+// BuildConfig параметры сборки образа из Dockerfile
+type BuildConfig struct {
+	Context    string
+	Dockerfile string
+	BuildArgs  map[string]string
+	Target     string
+	Labels     map[string]string
+	Platform   string
+	NoCache    bool
+}
+
+// builtImageLabel метка образов, собранных ресурсом docker_image. Контейнеры с таким
+// образом не делают pull: образа нет в registry.
+const builtImageLabel = "io.derraform.built"
+
+// ContextHash вычисляет хеш содержимого контекста сборки с учетом .dockerignore
+// и параметров сборки. Изменение хеша означает, что образ нужно пересобрать.
+func ContextHash(config *BuildConfig) (string, error) {
+	hash := sha256.New()
+
+	// Параметры сборки тоже влияют на результат
+	fmt.Fprintf(hash, "dockerfile=%s\ntarget=%s\nplatform=%s\n", config.dockerfile(), config.Target, config.Platform)
+	for _, key := range sortedKeys(config.BuildArgs) {
+		fmt.Fprintf(hash, "arg:%s=%s\n", key, config.BuildArgs[key])
+	}
+	for _, key := range sortedKeys(config.Labels) {
+		fmt.Fprintf(hash, "label:%s=%s\n", key, config.Labels[key])
+	}
+
+	err := walkBuildContext(config, func(rel, abs string, info fs.FileInfo) error {
+		fmt.Fprintf(hash, "%s %o\n", rel, info.Mode())
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(abs)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(hash, "-> %s\n", target)
+		case info.Mode().IsRegular():
+			file, err := os.Open(abs)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			if _, err := io.Copy(hash, file); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash build context: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// BuildImage собирает образ из контекста и тегирует его именем ресурса.
+// Вывод сборки передается в логгер.
+func (d *DockerClient) BuildImage(ctx context.Context, config *ImageConfig) (string, error) {
+	d.logger.Info("Building image %s from %s", config.Name, config.Build.Context)
+
+	// Контекст упаковывается по мере отправки, закрытие останавливает упаковку,
+	// если сборка завершилась раньше, чем прочитан весь архив
+	buildContext := contextTar(config.Build)
+	defer buildContext.Close()
+
+	buildArgs := make(map[string]*string, len(config.Build.BuildArgs))
+	for key, value := range config.Build.BuildArgs {
+		value := value
+		buildArgs[key] = &value
+	}
+
+	resp, err := d.cli.ImageBuild(ctx, buildContext, build.ImageBuildOptions{
+		Tags:        []string{config.Name},
+		Dockerfile:  config.Build.dockerfile(),
+		BuildArgs:   buildArgs,
+		Target:      config.Build.Target,
+		Labels:      buildLabels(config.Build.Labels),
+		Platform:    config.Build.Platform,
+		NoCache:     config.Build.NoCache,
+		Remove:      true,
+		ForceRemove: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to build image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := d.streamJSONMessages(resp.Body, config.Name); err != nil {
+		return "", fmt.Errorf("failed to build image: %w", err)
+	}
+
+	imageID, err := d.ImageID(ctx, config.Name)
+	if err != nil {
+		return "", err
+	}
+
+	d.logger.Info("Image %s built successfully with ID: %s", config.Name, shortID(imageID))
+	return imageID, nil
+}
+
+// buildLabels метки собранного образа: метки из конфигурации и builtImageLabel
+func buildLabels(labels map[string]string) map[string]string {
+	result := make(map[string]string, len(labels)+1)
+	for key, value := range labels {
+		result[key] = value
+	}
+	result[builtImageLabel] = "true"
+	return result
+}
+
+func (b *BuildConfig) dockerfile() string {
+	if b.Dockerfile == "" {
+		return "Dockerfile"
+	}
+	return b.Dockerfile
+}
+
+// contextTar упаковывает контекст сборки в tar архив. Архив пишется в горутине
+// прямо в возвращаемый поток и не держится в памяти целиком; ошибка обхода
+// контекста возвращается из Read.
+func contextTar(config *BuildConfig) io.ReadCloser {
+	reader, writer := io.Pipe()
+
+	go func() {
+		tw := tar.NewWriter(writer)
+		err := walkBuildContext(config, func(rel, abs string, info fs.FileInfo) error {
+			var link string
+			if info.Mode()&fs.ModeSymlink != 0 {
+				target, err := os.Readlink(abs)
+				if err != nil {
+					return err
+				}
+				link = target
+			}
+
+			header, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			header.Name = rel
+			if info.IsDir() {
+				header.Name += "/"
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			file, err := os.Open(abs)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			_, err = io.Copy(tw, file)
+			return err
+		})
+		if err == nil {
+			err = tw.Close()
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to archive build context: %w", err)
+		}
+		writer.CloseWithError(err)
+	}()
+
+	return reader
+}
+
+// walkBuildContext обходит файлы контекста в детерминированном порядке,
+// пропуская исключенные через .dockerignore. Dockerfile и .dockerignore
+// всегда попадают в контекст, как и в docker build.
+func walkBuildContext(config *BuildConfig, fn func(rel, abs string, info fs.FileInfo) error) error {
+	root, err := filepath.Abs(config.Context)
+	if err != nil {
+		return err
+	}
+
+	matcher, err := loadDockerignore(root)
+	if err != nil {
+		return err
+	}
+
+	alwaysInclude := map[string]bool{
+		filepath.ToSlash(filepath.Clean(config.dockerfile())): true,
+		".dockerignore": true,
+	}
+
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if matcher != nil && !alwaysInclude[rel] {
+			excluded, err := matcher.MatchesOrParentMatches(rel)
+			if err != nil {
+				return err
+			}
+			if excluded {
+				// В исключенную директорию можно не заходить, если нет шаблонов "!"
+				if entry.IsDir() && !matcher.Exclusions() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return fn(rel, path, info)
+	})
+}
+
+// loadDockerignore читает шаблоны из .dockerignore в корне контекста
+func loadDockerignore(root string) (*patternmatcher.PatternMatcher, error) {
+	file, err := os.Open(filepath.Join(root, ".dockerignore"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	patterns, err := ignorefile.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .dockerignore: %w", err)
+	}
+	return patternmatcher.New(patterns)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
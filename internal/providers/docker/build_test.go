@@ -0,0 +1,74 @@
+package docker
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestContextTarStreamsContext(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Dockerfile":     "FROM scratch\n",
+		".dockerignore":  "ignored.txt\n",
+		"app/main.go":    "package main\n",
+		"ignored.txt":    "secret\n",
+		"app/static.txt": "asset\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archive := contextTar(&BuildConfig{Context: dir})
+	defer archive.Close()
+
+	got := make(map[string]string)
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[header.Name] = string(content)
+	}
+
+	for name, content := range files {
+		if name == "ignored.txt" {
+			if _, exists := got[name]; exists {
+				t.Errorf("%s is excluded by .dockerignore but was archived", name)
+			}
+			continue
+		}
+		if got[name] != content {
+			t.Errorf("%s = %q, want %q", name, got[name], content)
+		}
+	}
+}
+
+func TestContextTarReturnsWalkError(t *testing.T) {
+	archive := contextTar(&BuildConfig{Context: filepath.Join(t.TempDir(), "missing")})
+	defer archive.Close()
+
+	if _, err := io.ReadAll(archive); err == nil {
+		t.Fatal("expected an error for a missing build context")
+	}
+}
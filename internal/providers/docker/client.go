@@ -2,26 +2,39 @@ package docker
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/Artemka007/derraform/internal/logging"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
 )
 
 type DockerClient struct {
-	cli    *client.Client
-	logger *logging.Logger
+	cli          *client.Client
+	logger       *logging.Logger
+	registryAuth []RegistryAuth
 }
 
-func NewDockerClient() (*DockerClient, error) {
-	cli, err := client.NewClientWithOpts(client.FromEnv)
-	logger := logging.NewLogger(logging.DEBUG)
+// NewDockerClient создает клиент Docker API, сообщения пишутся в logger
+func NewDockerClient(config ClientConfig, logger *logging.Logger) (*DockerClient, error) {
+	opts, err := config.clientOpts()
 	if err != nil {
 		return nil, err
 	}
-	return &DockerClient{cli: cli, logger: logger}, nil
+
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &DockerClient{cli: cli, logger: logger, registryAuth: config.RegistryAuth}, nil
 }
 
 // internal/providers/docker/client.go
@@ -34,10 +47,6 @@ type NetworkConfig struct {
 
 // CreateNetwork создает Docker сеть
 func (d *DockerClient) CreateNetwork(ctx context.Context, config *NetworkConfig) (string, error) {
-	if d.logger == nil {
-		d.logger = logging.NewLogger(logging.INFO)
-	}
-
 	d.logger.Info("Creating network: %s", config.Name)
 
 	resp, err := d.cli.NetworkCreate(ctx, config.Name, network.CreateOptions{
@@ -53,13 +62,12 @@ func (d *DockerClient) CreateNetwork(ctx context.Context, config *NetworkConfig)
 
 // DestroyNetwork удаляет Docker сеть
 func (d *DockerClient) DestroyNetwork(ctx context.Context, networkID string) error {
-	if d.logger == nil {
-		d.logger = logging.NewLogger(logging.INFO)
-	}
-
 	d.logger.Info("Destroying network: %s", networkID[:12])
 
-	if err := d.cli.NetworkRemove(ctx, networkID); err != nil {
+	err := retryRemove(ctx, d, "Removing network", func() error {
+		return d.cli.NetworkRemove(ctx, networkID)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to remove network: %w", err)
 	}
 
@@ -67,11 +75,70 @@ func (d *DockerClient) DestroyNetwork(ctx context.Context, networkID string) err
 	return nil
 }
 
-func (d *DockerClient) DestroyContainer(ctx context.Context, containerID string) error {
-	if d.logger == nil {
-		d.logger = logging.NewLogger(logging.INFO)
+// InspectNetwork возвращает данные docker network inspect
+func (d *DockerClient) InspectNetwork(ctx context.Context, networkID string) (network.Inspect, error) {
+	info, err := withRetry(ctx, d, "Inspecting network", func() (network.Inspect, error) {
+		return d.cli.NetworkInspect(ctx, networkID, network.InspectOptions{})
+	})
+	if err != nil {
+		return info, fmt.Errorf("failed to inspect network: %w", err)
+	}
+	return info, nil
+}
+
+// VolumeConfig конфигурация для создания тома
+type VolumeConfig struct {
+	Name       string
+	Driver     string
+	DriverOpts map[string]string
+	Labels     map[string]string
+}
+
+// CreateVolume создает Docker том
+func (d *DockerClient) CreateVolume(ctx context.Context, config *VolumeConfig) (volume.Volume, error) {
+	d.logger.Info("Creating volume: %s", config.Name)
+
+	vol, err := d.cli.VolumeCreate(ctx, volume.CreateOptions{
+		Name:       config.Name,
+		Driver:     config.Driver,
+		DriverOpts: config.DriverOpts,
+		Labels:     config.Labels,
+	})
+	if err != nil {
+		return vol, fmt.Errorf("failed to create volume: %w", err)
 	}
 
+	d.logger.Info("Volume %s created successfully", vol.Name)
+	return vol, nil
+}
+
+// InspectVolume возвращает данные docker volume inspect
+func (d *DockerClient) InspectVolume(ctx context.Context, name string) (volume.Volume, error) {
+	vol, err := withRetry(ctx, d, "Inspecting volume", func() (volume.Volume, error) {
+		return d.cli.VolumeInspect(ctx, name)
+	})
+	if err != nil {
+		return vol, fmt.Errorf("failed to inspect volume: %w", err)
+	}
+	return vol, nil
+}
+
+// DestroyVolume удаляет Docker том
+func (d *DockerClient) DestroyVolume(ctx context.Context, name string) error {
+	d.logger.Info("Destroying volume: %s", name)
+
+	err := retryRemove(ctx, d, "Removing volume "+name, func() error {
+		return d.cli.VolumeRemove(ctx, name, false)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove volume: %w", err)
+	}
+
+	d.logger.Info("Volume %s destroyed successfully", name)
+	return nil
+}
+
+func (d *DockerClient) DestroyContainer(ctx context.Context, containerID string) error {
 	d.logger.Info("Destroying container: %s", containerID[:12])
 
 	// Останавливаем контейнер
@@ -86,11 +153,14 @@ func (d *DockerClient) DestroyContainer(ctx context.Context, containerID string)
 
 	// Удаляем контейнер
 	d.logger.Debug("Removing container: %s", containerID[:12])
-	if err := d.cli.ContainerRemove(ctx, containerID, container.RemoveOptions{
-		Force:         true,  // Принудительное удаление
-		RemoveVolumes: true,  // Удаляем связанные тома
-		RemoveLinks:   false, // Не удаляем линки
-	}); err != nil {
+	err := retryRemove(ctx, d, "Removing container", func() error {
+		return d.cli.ContainerRemove(ctx, containerID, container.RemoveOptions{
+			Force:         true,  // Принудительное удаление
+			RemoveVolumes: true,  // Удаляем связанные тома
+			RemoveLinks:   false, // Не удаляем линки
+		})
+	})
+	if err != nil {
 		d.logger.Error("Failed to remove container %s: %v", containerID[:12], err)
 		return fmt.Errorf("failed to remove container: %w", err)
 	}
@@ -98,3 +168,140 @@ func (d *DockerClient) DestroyContainer(ctx context.Context, containerID string)
 	d.logger.Info("Container destroyed successfully: %s", containerID[:12])
 	return nil
 }
+
+// PullImage скачивает образ и дожидается окончания загрузки
+func (d *DockerClient) PullImage(ctx context.Context, ref string) error {
+	d.logger.Info("Pulling image: %s", ref)
+
+	encodedAuth, err := d.encodedAuthFor(ref)
+	if err != nil {
+		return err
+	}
+
+	// Ошибка registry может прийти и в ответе, и в потоке сообщений: повторяем pull целиком
+	err = retryCall(ctx, d, "Pulling image "+ref, func() error {
+		reader, err := d.cli.ImagePull(ctx, ref, image.PullOptions{RegistryAuth: encodedAuth})
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		return d.streamJSONMessages(reader, ref)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pull image: %w", err)
+	}
+	return nil
+}
+
+// pullContainerImage готовит образ для контейнера. Образ, собранный ресурсом docker_image,
+// берется локально: в registry его нет. Остальные скачиваются при каждом создании
+// контейнера, чтобы изменяемый тег вроде nginx:latest указывал на свежий образ.
+func (d *DockerClient) pullContainerImage(ctx context.Context, ref string) error {
+	info, err := d.InspectImage(ctx, ref)
+	if err == nil && info.Config != nil && info.Config.Labels[builtImageLabel] != "" {
+		d.logger.Debug("Using image %s built by docker_image: %s", ref, shortID(info.ID))
+		return nil
+	}
+	if err != nil && !errdefs.IsNotFound(err) {
+		return err
+	}
+	return d.PullImage(ctx, ref)
+}
+
+// EnsureImage скачивает образ, только если его нет локально.
+// Локально собранные образы в registry отсутствуют, поэтому pull для них не делаем.
+func (d *DockerClient) EnsureImage(ctx context.Context, ref string) (string, error) {
+	imageID, err := d.ImageID(ctx, ref)
+	if err == nil {
+		d.logger.Debug("Image %s found locally: %s", ref, shortID(imageID))
+		return imageID, nil
+	}
+	if !errdefs.IsNotFound(err) {
+		return "", err
+	}
+
+	if err := d.PullImage(ctx, ref); err != nil {
+		return "", err
+	}
+	return d.ImageID(ctx, ref)
+}
+
+// ImageID возвращает ID локального образа
+func (d *DockerClient) ImageID(ctx context.Context, ref string) (string, error) {
+	info, err := d.InspectImage(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	return info.ID, nil
+}
+
+// InspectImage возвращает данные docker image inspect
+func (d *DockerClient) InspectImage(ctx context.Context, ref string) (image.InspectResponse, error) {
+	info, err := withRetry(ctx, d, "Inspecting image "+ref, func() (image.InspectResponse, error) {
+		return d.cli.ImageInspect(ctx, ref)
+	})
+	if err != nil {
+		return info, fmt.Errorf("failed to inspect image %s: %w", ref, err)
+	}
+	return info, nil
+}
+
+// DestroyImage удаляет локальный образ
+func (d *DockerClient) DestroyImage(ctx context.Context, ref string) error {
+	d.logger.Info("Destroying image: %s", ref)
+
+	err := retryCall(ctx, d, "Removing image "+ref, func() error {
+		_, err := d.cli.ImageRemove(ctx, ref, image.RemoveOptions{PruneChildren: true})
+		return err
+	})
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to remove image: %w", err)
+	}
+
+	d.logger.Info("Image %s destroyed successfully", ref)
+	return nil
+}
+
+// streamJSONMessages передает поток сообщений Docker API (build, pull, push)
+// в логгер и возвращает ошибку, если она пришла в потоке
+func (d *DockerClient) streamJSONMessages(reader io.Reader, prefix string) error {
+	decoder := json.NewDecoder(reader)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		if msg.Error != nil {
+			return msg.Error
+		}
+
+		if stream := strings.TrimRight(msg.Stream, "\n"); stream != "" {
+			for _, line := range strings.Split(stream, "\n") {
+				d.logger.Info("[%s] %s", prefix, line)
+			}
+		}
+		if msg.Status != "" {
+			if msg.ID != "" {
+				d.logger.Debug("[%s] %s: %s", prefix, msg.ID, msg.Status)
+			} else {
+				d.logger.Debug("[%s] %s", prefix, msg.Status)
+			}
+		}
+	}
+}
+
+// shortID сокращает ID Docker объекта для логов
+func shortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
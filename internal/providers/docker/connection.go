@@ -0,0 +1,181 @@
+package docker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// ClientConfig настройки подключения к Docker daemon из блока provider "docker".
+// Пустые поля берутся из окружения (DOCKER_HOST, DOCKER_CERT_PATH и т.д.).
+type ClientConfig struct {
+	Host         string
+	CertPath     string
+	CAMaterial   string
+	CertMaterial string
+	KeyMaterial  string
+	SSHOpts      []string
+	APIVersion   string
+	RegistryAuth []RegistryAuth
+}
+
+// clientOpts собирает опции Docker клиента
+func (c ClientConfig) clientOpts() ([]client.Opt, error) {
+	opts := []client.Opt{client.FromEnv}
+
+	if c.CAMaterial != "" || c.CertMaterial != "" || c.KeyMaterial != "" {
+		tlsConfig, err := c.tlsConfigFromMaterial()
+		if err != nil {
+			return nil, err
+		}
+		// HTTP клиент должен быть задан до хоста, чтобы хост настроил именно его транспорт
+		opts = append(opts, client.WithHTTPClient(&http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		}))
+	}
+
+	if c.Host != "" {
+		hostURL, err := url.Parse(c.Host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid docker host %s: %w", c.Host, err)
+		}
+
+		if hostURL.Scheme == "ssh" {
+			// Адрес фиктивный: соединение устанавливается через ssh
+			opts = append(opts,
+				client.WithHost("http://docker.example.com"),
+				client.WithDialContext(sshDialer(hostURL, c.SSHOpts)),
+			)
+		} else {
+			opts = append(opts, client.WithHost(c.Host))
+		}
+	}
+
+	if c.CertPath != "" {
+		opts = append(opts, client.WithTLSClientConfig(
+			filepath.Join(c.CertPath, "ca.pem"),
+			filepath.Join(c.CertPath, "cert.pem"),
+			filepath.Join(c.CertPath, "key.pem"),
+		))
+	}
+
+	if c.APIVersion != "" {
+		opts = append(opts, client.WithVersion(c.APIVersion))
+	} else {
+		opts = append(opts, client.WithAPIVersionNegotiation())
+	}
+
+	return opts, nil
+}
+
+// tlsConfigFromMaterial строит TLS конфигурацию из PEM содержимого
+func (c ClientConfig) tlsConfigFromMaterial() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if c.CAMaterial != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(c.CAMaterial)) {
+			return nil, fmt.Errorf("failed to parse ca_material")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (c.CertMaterial == "") != (c.KeyMaterial == "") {
+		return nil, fmt.Errorf("'cert_material' and 'key_material' must be set together")
+	}
+	if c.CertMaterial != "" {
+		cert, err := tls.X509KeyPair([]byte(c.CertMaterial), []byte(c.KeyMaterial))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// sshDialer подключается к удаленному daemon через "docker system dial-stdio",
+// так же как это делает docker CLI для ssh:// хостов
+func sshDialer(hostURL *url.URL, sshOpts []string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		args := append([]string{}, sshOpts...)
+		if port := hostURL.Port(); port != "" {
+			args = append(args, "-p", port)
+		}
+
+		target := hostURL.Hostname()
+		if hostURL.User != nil {
+			target = hostURL.User.Username() + "@" + target
+		}
+		args = append(args, "--", target, "docker", "system", "dial-stdio")
+
+		return newCommandConn(ctx, "ssh", args...)
+	}
+}
+
+// commandConn net.Conn поверх stdin/stdout внешнего процесса
+type commandConn struct {
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	stdout    io.ReadCloser
+	closeOnce sync.Once
+}
+
+func newCommandConn(ctx context.Context, name string, args ...string) (net.Conn, error) {
+	// Процесс не привязываем к ctx запроса: соединение переиспользуется транспортом
+	cmd := exec.Command(name, args...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", name, err)
+	}
+
+	return &commandConn{cmd: cmd, stdin: stdin, stdout: stdout}, nil
+}
+
+func (c *commandConn) Read(p []byte) (int, error)  { return c.stdout.Read(p) }
+func (c *commandConn) Write(p []byte) (int, error) { return c.stdin.Write(p) }
+
+func (c *commandConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.stdin.Close()
+		c.stdout.Close()
+		if c.cmd.Process != nil {
+			c.cmd.Process.Kill()
+		}
+		c.cmd.Wait()
+	})
+	return nil
+}
+
+func (c *commandConn) LocalAddr() net.Addr  { return dummyAddr{} }
+func (c *commandConn) RemoteAddr() net.Addr { return dummyAddr{} }
+
+// Дедлайны для процесса не поддерживаются
+func (c *commandConn) SetDeadline(t time.Time) error      { return nil }
+func (c *commandConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *commandConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type dummyAddr struct{}
+
+func (dummyAddr) Network() string { return "command" }
+func (dummyAddr) String() string  { return "command" }
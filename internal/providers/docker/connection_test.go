@@ -0,0 +1,338 @@
+package docker
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// testCertificate PEM сертификат и ключ, выпущенные тестовым CA
+type testCertificate struct {
+	certPEM string
+	keyPEM  string
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+}
+
+// issueCertificate выпускает сертификат; при parent == nil сертификат самоподписанный CA
+func issueCertificate(t *testing.T, name string, parent *testCertificate, usage x509.ExtKeyUsage) *testCertificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCertificate{
+		certPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		keyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+		cert:    cert,
+		key:     key,
+	}
+}
+
+// clearDockerEnv убирает настройки docker CLI, которые client.FromEnv применил бы в тесте
+func clearDockerEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{"DOCKER_HOST", "DOCKER_CERT_PATH", "DOCKER_TLS_VERIFY", "DOCKER_API_VERSION"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestProviderToClientConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes map[string]cty.Value
+		blocks     []config.Block
+		want       ClientConfig
+		err        string
+	}{
+		{
+			name: "empty block uses environment",
+		},
+		{
+			name: "tcp host with cert_path",
+			attributes: map[string]cty.Value{
+				"host":        cty.StringVal("tcp://docker.internal:2376"),
+				"cert_path":   cty.StringVal("/etc/docker/certs"),
+				"api_version": cty.StringVal("1.45"),
+			},
+			want: ClientConfig{Host: "tcp://docker.internal:2376", CertPath: "/etc/docker/certs", APIVersion: "1.45"},
+		},
+		{
+			name: "ssh host with options",
+			attributes: map[string]cty.Value{
+				"host":     cty.StringVal("ssh://deploy@build.internal:2222"),
+				"ssh_opts": stringList("-o", "StrictHostKeyChecking=no"),
+			},
+			want: ClientConfig{Host: "ssh://deploy@build.internal:2222", SSHOpts: []string{"-o", "StrictHostKeyChecking=no"}},
+		},
+		{
+			name: "registry_auth blocks",
+			blocks: []config.Block{
+				{Type: "registry_auth", Attributes: map[string]cty.Value{
+					"address": cty.StringVal("ghcr.io"), "username": cty.StringVal("bot"), "password": cty.StringVal("pat"),
+				}},
+				{Type: "registry_auth", Attributes: map[string]cty.Value{
+					"address": cty.StringVal("docker.io"), "config_file": cty.StringVal("~/.docker/ci.json"),
+				}},
+			},
+			want: ClientConfig{RegistryAuth: []RegistryAuth{
+				{Address: "ghcr.io", Username: "bot", Password: "pat"},
+				{Address: "docker.io", ConfigFile: "~/.docker/ci.json"},
+			}},
+		},
+		{
+			name: "cert_path and ca_material",
+			attributes: map[string]cty.Value{
+				"cert_path":   cty.StringVal("/etc/docker/certs"),
+				"ca_material": cty.StringVal("-----BEGIN CERTIFICATE-----"),
+			},
+			err: "'cert_path' and 'ca_material' are mutually exclusive",
+		},
+		{
+			name:       "ssh_opts without ssh host",
+			attributes: map[string]cty.Value{"host": cty.StringVal("tcp://docker.internal:2376"), "ssh_opts": stringList("-v")},
+			err:        "'ssh_opts' requires an ssh:// host",
+		},
+		{
+			name:   "registry_auth without address",
+			blocks: []config.Block{{Type: "registry_auth", Attributes: map[string]cty.Value{"username": cty.StringVal("bot")}}},
+			err:    "invalid registry_auth block: missing required attribute 'address'",
+		},
+		{
+			name: "registry_auth username without password",
+			blocks: []config.Block{{Type: "registry_auth", Attributes: map[string]cty.Value{
+				"address": cty.StringVal("ghcr.io"), "username": cty.StringVal("bot"),
+			}}},
+			err: "'username' and 'password' must be set together",
+		},
+		{
+			name: "registry_auth username and config_file",
+			blocks: []config.Block{{Type: "registry_auth", Attributes: map[string]cty.Value{
+				"address": cty.StringVal("ghcr.io"), "username": cty.StringVal("bot"), "password": cty.StringVal("pat"),
+				"config_file": cty.StringVal("~/.docker/config.json"),
+			}}},
+			err: "'username' and 'config_file' are mutually exclusive",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := providerToClientConfig(config.Provider{Name: "docker", Attributes: test.attributes, Blocks: test.blocks})
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Host != test.want.Host || got.CertPath != test.want.CertPath || got.APIVersion != test.want.APIVersion ||
+				strings.Join(got.SSHOpts, " ") != strings.Join(test.want.SSHOpts, " ") {
+				t.Errorf("config = %+v, want %+v", got, test.want)
+			}
+			if len(got.RegistryAuth) != len(test.want.RegistryAuth) {
+				t.Fatalf("registry_auth = %+v, want %+v", got.RegistryAuth, test.want.RegistryAuth)
+			}
+			for i := range got.RegistryAuth {
+				if got.RegistryAuth[i] != test.want.RegistryAuth[i] {
+					t.Errorf("registry_auth[%d] = %+v, want %+v", i, got.RegistryAuth[i], test.want.RegistryAuth[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTLSConfigFromMaterial(t *testing.T) {
+	ca := issueCertificate(t, "test ca", nil, 0)
+	clientCert := issueCertificate(t, "client", ca, x509.ExtKeyUsageClientAuth)
+	other := issueCertificate(t, "other", ca, x509.ExtKeyUsageClientAuth)
+
+	tests := []struct {
+		name   string
+		config ClientConfig
+		certs  int
+		err    string
+	}{
+		{name: "ca only", config: ClientConfig{CAMaterial: ca.certPEM}},
+		{name: "client certificate", config: ClientConfig{CAMaterial: ca.certPEM, CertMaterial: clientCert.certPEM, KeyMaterial: clientCert.keyPEM}, certs: 1},
+		{name: "invalid ca", config: ClientConfig{CAMaterial: "not a certificate"}, err: "failed to parse ca_material"},
+		{name: "cert without key", config: ClientConfig{CertMaterial: clientCert.certPEM}, err: "'cert_material' and 'key_material' must be set together"},
+		{name: "key without cert", config: ClientConfig{KeyMaterial: clientCert.keyPEM}, err: "'cert_material' and 'key_material' must be set together"},
+		{name: "key of another certificate", config: ClientConfig{CertMaterial: clientCert.certPEM, KeyMaterial: other.keyPEM}, err: "failed to parse client certificate"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.config.tlsConfigFromMaterial()
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.MinVersion != tls.VersionTLS12 {
+				t.Errorf("MinVersion = %x, want TLS 1.2", got.MinVersion)
+			}
+			if test.config.CAMaterial != "" && got.RootCAs == nil {
+				t.Error("RootCAs is not set from ca_material")
+			}
+			if len(got.Certificates) != test.certs {
+				t.Errorf("certificates = %d, want %d", len(got.Certificates), test.certs)
+			}
+		})
+	}
+}
+
+func TestDockerClientTLSMaterial(t *testing.T) {
+	clearDockerEnv(t)
+	ca := issueCertificate(t, "test ca", nil, 0)
+	serverCert := issueCertificate(t, "127.0.0.1", ca, x509.ExtKeyUsageServerAuth)
+	clientCert := issueCertificate(t, "client", ca, x509.ExtKeyUsageClientAuth)
+
+	serverPair, err := tls.X509KeyPair([]byte(serverCert.certPEM), []byte(serverCert.keyPEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	var peer string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			peer = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		w.Header().Set("Api-Version", fakeAPIVersion)
+		w.Write([]byte("OK"))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverPair},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	d, err := NewDockerClient(ClientConfig{
+		Host:         "tcp://" + strings.TrimPrefix(server.URL, "https://"),
+		CAMaterial:   ca.certPEM,
+		CertMaterial: clientCert.certPEM,
+		KeyMaterial:  clientCert.keyPEM,
+		APIVersion:   fakeAPIVersion,
+	}, logging.NewLogger(logging.OFF))
+	if err != nil {
+		t.Fatalf("NewDockerClient: %v", err)
+	}
+	if _, err := d.cli.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if peer != "client" {
+		t.Errorf("client certificate = %q, want the one from cert_material", peer)
+	}
+}
+
+func TestSSHDialer(t *testing.T) {
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" > " + argsFile + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "ssh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tests := []struct {
+		name string
+		host string
+		opts []string
+		want []string
+	}{
+		{
+			name: "user and port",
+			host: "ssh://deploy@build.internal:2222",
+			opts: []string{"-o", "StrictHostKeyChecking=no"},
+			want: []string{"-o", "StrictHostKeyChecking=no", "-p", "2222", "--", "deploy@build.internal", "docker", "system", "dial-stdio"},
+		},
+		{
+			name: "host only",
+			host: "ssh://build.internal",
+			want: []string{"--", "build.internal", "docker", "system", "dial-stdio"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hostURL, err := url.Parse(test.host)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn, err := sshDialer(hostURL, test.opts)(context.Background(), "tcp", "docker.example.com:80")
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			// stdout закрывается, когда ssh завершился и аргументы уже записаны
+			io.ReadAll(conn)
+			conn.Close()
+
+			data, err := os.ReadFile(argsFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+			if strings.Join(got, " ") != strings.Join(test.want, " ") {
+				t.Errorf("ssh args = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package docker
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/logging"
+)
+
+// fakeAPIVersion версия API, под которой клиент обращается к fakeDaemon
+const fakeAPIVersion = "1.45"
+
+// fakeDaemon HTTP сервер с частью Docker Engine API. Обработчики регистрируются
+// в mux без префикса версии: "GET /containers/{id}/json".
+type fakeDaemon struct {
+	mux *http.ServeMux
+
+	mu       sync.Mutex
+	requests []string
+}
+
+// newFakeDaemon запускает fakeDaemon и возвращает клиент, подключенный к нему
+func newFakeDaemon(t *testing.T) (*fakeDaemon, *DockerClient) {
+	t.Helper()
+	daemon := &fakeDaemon{mux: http.NewServeMux()}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		daemon.mu.Lock()
+		daemon.requests = append(daemon.requests, r.Method+" "+r.URL.Path)
+		daemon.mu.Unlock()
+
+		r.URL.Path = strings.TrimPrefix(r.URL.Path, "/v"+fakeAPIVersion)
+		daemon.mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	d, err := NewDockerClient(ClientConfig{
+		Host:       "tcp://" + strings.TrimPrefix(server.URL, "http://"),
+		APIVersion: fakeAPIVersion,
+	}, logging.NewLogger(logging.OFF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return daemon, d
+}
+
+// handle регистрирует обработчик, который отвечает JSON значением
+func (f *fakeDaemon) handle(pattern string, respond func(r *http.Request) (int, interface{})) {
+	f.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		status, body := respond(r)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if body != nil {
+			json.NewEncoder(w).Encode(body)
+		}
+	})
+}
+
+// count сколько раз сервер получил запрос "METHOD /path" (путь без версии)
+func (f *fakeDaemon) count(request string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	method, path, _ := strings.Cut(request, " ")
+	n := 0
+	for _, got := range f.requests {
+		if got == method+" /v"+fakeAPIVersion+path {
+			n++
+		}
+	}
+	return n
+}
+
+// apiError тело ответа Docker API с ошибкой
+func apiError(message string) map[string]string {
+	return map[string]string{"message": message}
+}
+
+// multiplexed кадр потока логов контейнера без TTY (stdcopy): stream 1 - stdout, 2 - stderr
+func multiplexed(stream byte, data string) []byte {
+	frame := make([]byte, 8, 8+len(data))
+	frame[0] = stream
+	binary.BigEndian.PutUint32(frame[4:], uint32(len(data)))
+	return append(frame, data...)
+}
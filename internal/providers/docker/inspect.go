@@ -0,0 +1,274 @@
+package docker
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+	dockerspec "github.com/moby/docker-image-spec/specs-go/v1"
+)
+
+// defaultNetwork сеть, в которую Docker подключает контейнер без явных сетей
+const defaultNetwork = "bridge"
+
+// inspectToContainerConfig восстанавливает конфигурацию контейнера по docker inspect.
+// Значения, унаследованные от образа (env, command, healthcheck), не включаются:
+// их нет в конфигурации, которая создала бы такой же контейнер.
+func inspectToContainerConfig(info container.InspectResponse, img image.InspectResponse) *ContainerConfig {
+	containerConfig := &ContainerConfig{
+		Name: strings.TrimPrefix(info.Name, "/"),
+	}
+
+	var imageConfig dockerspec.DockerOCIImageConfig
+	if img.Config != nil {
+		imageConfig = *img.Config
+	}
+
+	if info.Config != nil {
+		containerConfig.Image = info.Config.Image
+		containerConfig.Env = ownEnv(info.Config.Env, imageConfig.Env)
+		if !reflect.DeepEqual([]string(info.Config.Cmd), imageConfig.Cmd) {
+			containerConfig.Command = info.Config.Cmd
+		}
+		if health := info.Config.Healthcheck; health != nil && !reflect.DeepEqual(health, imageConfig.Healthcheck) {
+			containerConfig.HealthCheck = &HealthCheck{
+				Test:        health.Test,
+				Interval:    health.Interval,
+				Timeout:     health.Timeout,
+				StartPeriod: health.StartPeriod,
+				Retries:     health.Retries,
+			}
+			if len(health.Test) == 1 && health.Test[0] == "NONE" {
+				containerConfig.HealthCheck = &HealthCheck{Disable: true}
+			}
+		}
+	}
+
+	if info.HostConfig != nil {
+		for port, bindings := range info.HostConfig.PortBindings {
+			if containerConfig.Ports == nil {
+				containerConfig.Ports = make(map[string]string)
+			}
+			hostPort := ""
+			if len(bindings) > 0 {
+				hostPort = bindings[0].HostPort
+			}
+			containerConfig.Ports[string(port)] = hostPort
+		}
+	}
+
+	for _, endpoint := range networkEndpoints(info) {
+		containerConfig.Networks = append(containerConfig.Networks, NetworkAttachment{Name: endpoint.NetworkName})
+	}
+	if len(containerConfig.Networks) == 1 && containerConfig.Networks[0].Name == defaultNetwork {
+		containerConfig.Networks = nil
+	}
+
+	for _, mountPoint := range info.Mounts {
+		switch mountPoint.Type {
+		case mount.TypeVolume:
+			containerConfig.Volumes = append(containerConfig.Volumes, VolumeMount{
+				Source: mountPoint.Name, Target: mountPoint.Destination, ReadOnly: !mountPoint.RW, Named: true,
+			})
+		case mount.TypeBind:
+			containerConfig.Volumes = append(containerConfig.Volumes, VolumeMount{
+				Source: mountPoint.Source, Target: mountPoint.Destination, ReadOnly: !mountPoint.RW,
+			})
+		}
+	}
+	sort.Slice(containerConfig.Volumes, func(i, j int) bool {
+		return containerConfig.Volumes[i].Target < containerConfig.Volumes[j].Target
+	})
+
+	return containerConfig
+}
+
+// ownEnv оставляет переменные окружения контейнера, которых нет в образе с тем же значением
+func ownEnv(containerEnv, imageEnv []string) map[string]string {
+	inherited := make(map[string]bool, len(imageEnv))
+	for _, entry := range imageEnv {
+		inherited[entry] = true
+	}
+
+	var env map[string]string
+	for _, entry := range containerEnv {
+		if inherited[entry] {
+			continue
+		}
+		key, value, _ := strings.Cut(entry, "=")
+		if env == nil {
+			env = make(map[string]string)
+		}
+		env[key] = value
+	}
+	return env
+}
+
+// containerAttributes атрибуты state, соответствующие конфигурации контейнера.
+// Раскладка совпадает со схемой ресурса, чтобы по ним можно было сгенерировать HCL.
+func containerAttributes(containerConfig *ContainerConfig) map[string]interface{} {
+	attributes := map[string]interface{}{
+		"name":  containerConfig.Name,
+		"image": containerConfig.Image,
+	}
+
+	if len(containerConfig.Ports) > 0 {
+		ports := make(map[string]interface{}, len(containerConfig.Ports))
+		for internal, external := range containerConfig.Ports {
+			ports[strings.TrimSuffix(internal, "/tcp")] = external
+		}
+		attributes["ports"] = ports
+	}
+	if len(containerConfig.Env) > 0 {
+		env := make(map[string]interface{}, len(containerConfig.Env))
+		for key, value := range containerConfig.Env {
+			env[key] = value
+		}
+		attributes["env"] = env
+	}
+	if len(containerConfig.Networks) > 0 {
+		networks := make([]interface{}, 0, len(containerConfig.Networks))
+		for _, attachment := range containerConfig.Networks {
+			networks = append(networks, attachment.Name)
+		}
+		attributes["networks"] = networks
+	}
+	if len(containerConfig.Command) > 0 {
+		command := make([]interface{}, 0, len(containerConfig.Command))
+		for _, arg := range containerConfig.Command {
+			command = append(command, arg)
+		}
+		attributes["command"] = command
+	}
+
+	if health := containerConfig.HealthCheck; health != nil {
+		block := map[string]interface{}{}
+		if health.Disable {
+			block["disable"] = true
+		} else {
+			test := make([]interface{}, 0, len(health.Test))
+			for _, arg := range health.Test {
+				test = append(test, arg)
+			}
+			block["test"] = test
+			if health.Interval > 0 {
+				block["interval"] = health.Interval.String()
+			}
+			if health.Timeout > 0 {
+				block["timeout"] = health.Timeout.String()
+			}
+			if health.StartPeriod > 0 {
+				block["start_period"] = health.StartPeriod.String()
+			}
+			if health.Retries > 0 {
+				block["retries"] = health.Retries
+			}
+		}
+		attributes["healthcheck"] = []interface{}{block}
+	}
+
+	if len(containerConfig.Volumes) > 0 {
+		volumes := make([]interface{}, 0, len(containerConfig.Volumes))
+		for _, volume := range containerConfig.Volumes {
+			block := map[string]interface{}{
+				"container_path": volume.Target,
+				"read_only":      volume.ReadOnly,
+			}
+			if volume.Named {
+				block["volume_name"] = volume.Source
+			} else {
+				block["host_path"] = volume.Source
+			}
+			volumes = append(volumes, block)
+		}
+		attributes["volumes"] = volumes
+	}
+
+	return attributes
+}
+
+// containerDiff перечисляет параметры, которыми конфигурация desired отличается
+// от контейнера actual. Порт на хосте, не заданный в конфигурации, Docker выбирает сам.
+func containerDiff(desired, actual *ContainerConfig) []string {
+	var changed []string
+
+	if desired.Name != actual.Name {
+		changed = append(changed, "name")
+	}
+	if desired.Image != actual.Image {
+		changed = append(changed, "image")
+	}
+	if !equalPorts(desired.Ports, actual.Ports) {
+		changed = append(changed, "ports")
+	}
+	if !equalStringMaps(desired.Env, actual.Env) {
+		changed = append(changed, "env")
+	}
+	if !reflect.DeepEqual(networkNames(desired.Networks), networkNames(actual.Networks)) {
+		changed = append(changed, "networks")
+	}
+	if len(desired.Command) > 0 && !reflect.DeepEqual(desired.Command, actual.Command) {
+		changed = append(changed, "command")
+	}
+	if !equalVolumes(desired.Volumes, actual.Volumes) {
+		changed = append(changed, "volumes")
+	}
+	if desired.HealthCheck != nil && !reflect.DeepEqual(desired.HealthCheck, actual.HealthCheck) {
+		changed = append(changed, "healthcheck")
+	}
+
+	return changed
+}
+
+func equalPorts(desired, actual map[string]string) bool {
+	if len(desired) != len(actual) {
+		return false
+	}
+	for internal, external := range desired {
+		if !strings.Contains(internal, "/") {
+			internal += "/tcp"
+		}
+		hostPort, exists := actual[internal]
+		if !exists || (external != "" && external != hostPort) {
+			return false
+		}
+	}
+	return true
+}
+
+func networkNames(attachments []NetworkAttachment) []string {
+	names := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		names = append(names, attachment.Name)
+	}
+	sort.Strings(names)
+	if len(names) == 1 && names[0] == defaultNetwork {
+		return []string{}
+	}
+	return names
+}
+
+func equalVolumes(desired, actual []VolumeMount) bool {
+	if len(desired) != len(actual) {
+		return false
+	}
+	byTarget := make(map[string]VolumeMount, len(actual))
+	for _, volume := range actual {
+		byTarget[volume.Target] = volume
+	}
+	for _, volume := range desired {
+		if byTarget[volume.Target] != volume {
+			return false
+		}
+	}
+	return true
+}
+
+// diffReason описание отличий для плана
+func diffReason(changed []string) string {
+	return fmt.Sprintf("%s differ from the configuration", strings.Join(changed, ", "))
+}
@@ -0,0 +1,202 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func init() {
+	providers.Register("docker", NewProvider)
+}
+
+// Provider реализация providers.Provider для Docker
+type Provider struct {
+	client    *DockerClient
+	logger    *logging.Logger
+	resources map[string]providers.Resource
+}
+
+// NewProvider создает неконфигурированный Docker провайдер
+func NewProvider() providers.Provider {
+	p := &Provider{logger: logging.NewLogger(logging.INFO)}
+	p.resources = map[string]providers.Resource{
+		"docker_container":      &containerResource{provider: p},
+		"docker_network":        &networkResource{provider: p},
+		"docker_volume":         &volumeResource{provider: p},
+		"docker_image":          &imageResource{provider: p},
+		"docker_registry_image": &registryImageResource{provider: p},
+	}
+	return p
+}
+
+func (p *Provider) Schema() providers.Schema {
+	return providers.Schema{
+		Attributes: map[string]*providers.Attribute{
+			"host":          {Type: cty.String, Optional: true, Description: "Docker daemon address, e.g. unix:///var/run/docker.sock or ssh://user@host"},
+			"cert_path":     {Type: cty.String, Optional: true, Description: "Directory with ca.pem, cert.pem and key.pem"},
+			"ca_material":   {Type: cty.String, Optional: true},
+			"cert_material": {Type: cty.String, Optional: true},
+			"key_material":  {Type: cty.String, Optional: true, Sensitive: true},
+			"ssh_opts":      {Type: cty.List(cty.String), Optional: true},
+			"api_version":   {Type: cty.String, Optional: true},
+		},
+		Blocks: map[string]*providers.NestedBlock{
+			"registry_auth": {Schema: providers.Schema{
+				Attributes: map[string]*providers.Attribute{
+					"address":     {Type: cty.String, Required: true},
+					"username":    {Type: cty.String, Optional: true},
+					"password":    {Type: cty.String, Optional: true, Sensitive: true},
+					"config_file": {Type: cty.String, Optional: true},
+				},
+			}},
+		},
+	}
+}
+
+// SetLogger задает лог движка, в который пишет Docker клиент
+func (p *Provider) SetLogger(logger *logging.Logger) {
+	p.logger = logger
+}
+
+// Configure создает Docker клиент по настройкам блока provider
+func (p *Provider) Configure(ctx context.Context, cfg config.Provider) error {
+	clientConfig, err := providerToClientConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewDockerClient(clientConfig, p.logger)
+	if err != nil {
+		return fmt.Errorf("failed to create Docker client: %w", err)
+	}
+	p.client = client
+	return nil
+}
+
+func (p *Provider) Resources() map[string]providers.Resource {
+	return p.resources
+}
+
+// providerToClientConfig преобразует блок provider "docker" в настройки клиента
+func providerToClientConfig(cfg config.Provider) (ClientConfig, error) {
+	clientConfig := ClientConfig{}
+
+	var err error
+	if clientConfig.Host, err = providers.StringAttribute(cfg.Attributes, "host"); err != nil {
+		return clientConfig, err
+	}
+	if clientConfig.CertPath, err = providers.StringAttribute(cfg.Attributes, "cert_path"); err != nil {
+		return clientConfig, err
+	}
+	if clientConfig.CAMaterial, err = providers.StringAttribute(cfg.Attributes, "ca_material"); err != nil {
+		return clientConfig, err
+	}
+	if clientConfig.CertMaterial, err = providers.StringAttribute(cfg.Attributes, "cert_material"); err != nil {
+		return clientConfig, err
+	}
+	if clientConfig.KeyMaterial, err = providers.StringAttribute(cfg.Attributes, "key_material"); err != nil {
+		return clientConfig, err
+	}
+	if clientConfig.SSHOpts, err = providers.StringListAttribute(cfg.Attributes, "ssh_opts"); err != nil {
+		return clientConfig, err
+	}
+	if clientConfig.APIVersion, err = providers.StringAttribute(cfg.Attributes, "api_version"); err != nil {
+		return clientConfig, err
+	}
+
+	if clientConfig.CertPath != "" && clientConfig.CAMaterial != "" {
+		return clientConfig, fmt.Errorf("'cert_path' and 'ca_material' are mutually exclusive")
+	}
+	if len(clientConfig.SSHOpts) > 0 && !strings.HasPrefix(clientConfig.Host, "ssh://") {
+		return clientConfig, fmt.Errorf("'ssh_opts' requires an ssh:// host")
+	}
+
+	for _, block := range cfg.BlocksOfType("registry_auth") {
+		auth, err := blockToRegistryAuth(block)
+		if err != nil {
+			return clientConfig, fmt.Errorf("invalid registry_auth block: %w", err)
+		}
+		clientConfig.RegistryAuth = append(clientConfig.RegistryAuth, auth)
+	}
+
+	return clientConfig, nil
+}
+
+// blockToRegistryAuth преобразует блок registry_auth в RegistryAuth
+func blockToRegistryAuth(block config.Block) (RegistryAuth, error) {
+	auth := RegistryAuth{}
+
+	var err error
+	if auth.Address, err = providers.StringAttribute(block.Attributes, "address"); err != nil {
+		return auth, err
+	}
+	if auth.Address == "" {
+		return auth, fmt.Errorf("missing required attribute 'address'")
+	}
+	if auth.Username, err = providers.StringAttribute(block.Attributes, "username"); err != nil {
+		return auth, err
+	}
+	if auth.Password, err = providers.StringAttribute(block.Attributes, "password"); err != nil {
+		return auth, err
+	}
+	if auth.ConfigFile, err = providers.StringAttribute(block.Attributes, "config_file"); err != nil {
+		return auth, err
+	}
+
+	if auth.Username != "" && auth.ConfigFile != "" {
+		return auth, fmt.Errorf("'username' and 'config_file' are mutually exclusive")
+	}
+	if (auth.Username == "") != (auth.Password == "") {
+		return auth, fmt.Errorf("'username' and 'password' must be set together")
+	}
+
+	return auth, nil
+}
+
+// nameAttribute возвращает атрибут name или имя ресурса из конфигурации
+func nameAttribute(cfg config.Resource) (string, error) {
+	name, err := providers.StringAttribute(cfg.Attributes, "name")
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return cfg.Name, nil
+	}
+	return name, nil
+}
+
+// stateString возвращает строковый атрибут из state
+func stateString(attributes map[string]interface{}, name string) string {
+	value, _ := attributes[name].(string)
+	return value
+}
+
+// stateStringMap возвращает map строк из state
+func stateStringMap(attributes map[string]interface{}, name string) map[string]string {
+	raw, _ := attributes[name].(map[string]interface{})
+	result := make(map[string]string, len(raw))
+	for key, value := range raw {
+		if str, ok := value.(string); ok {
+			result[key] = str
+		}
+	}
+	return result
+}
+
+func equalStringMaps(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, exists := b[key]; !exists || other != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,281 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+)
+
+// dockerHubAddress ключ Docker Hub в ~/.docker/config.json
+const dockerHubAddress = "https://index.docker.io/v1/"
+
+// RegistryAuth учетные данные для одного registry из блока registry_auth.
+// Задаются либо Username/Password, либо ConfigFile (по умолчанию ~/.docker/config.json).
+type RegistryAuth struct {
+	Address    string
+	Username   string
+	Password   string
+	ConfigFile string
+}
+
+// dockerConfigFile часть формата ~/.docker/config.json, нужная для авторизации
+type dockerConfigFile struct {
+	Auths       map[string]dockerConfigAuth `json:"auths"`
+	CredsStore  string                      `json:"credsStore"`
+	CredHelpers map[string]string           `json:"credHelpers"`
+}
+
+type dockerConfigAuth struct {
+	Auth          string `json:"auth"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	IdentityToken string `json:"identitytoken"`
+}
+
+// credentialHelperResponse ответ docker-credential-<helper> get
+type credentialHelperResponse struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// PushImage отправляет локальный образ в registry и возвращает его digest
+func (d *DockerClient) PushImage(ctx context.Context, ref string) (string, error) {
+	d.logger.Info("Pushing image: %s", ref)
+
+	encodedAuth, err := d.encodedAuthFor(ref)
+	if err != nil {
+		return "", err
+	}
+
+	// Уже загруженные слои registry пропускает, поэтому push можно повторить целиком
+	err = retryCall(ctx, d, "Pushing image "+ref, func() error {
+		reader, err := d.cli.ImagePush(ctx, ref, image.PushOptions{RegistryAuth: encodedAuth})
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		return d.streamJSONMessages(reader, ref)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to push image: %w", err)
+	}
+
+	digest, err := d.RegistryDigest(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	d.logger.Info("Image %s pushed successfully: %s", ref, digest)
+	return digest, nil
+}
+
+// RegistryDigest возвращает digest образа в registry
+func (d *DockerClient) RegistryDigest(ctx context.Context, ref string) (string, error) {
+	encodedAuth, err := d.encodedAuthFor(ref)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := withRetry(ctx, d, "Inspecting image "+ref+" in registry", func() (registry.DistributionInspect, error) {
+		return d.cli.DistributionInspect(ctx, ref, encodedAuth)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect image %s in registry: %w", ref, err)
+	}
+	return info.Descriptor.Digest.String(), nil
+}
+
+// encodedAuthFor возвращает значение заголовка X-Registry-Auth для образа.
+// Пустая строка означает анонимный доступ.
+func (d *DockerClient) encodedAuthFor(ref string) (string, error) {
+	address, err := registryAddress(ref)
+	if err != nil {
+		return "", err
+	}
+
+	for _, auth := range d.registryAuth {
+		if normalizeRegistryAddress(auth.Address) != address {
+			continue
+		}
+
+		authConfig, err := resolveRegistryAuth(auth)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve credentials for registry %s: %w", auth.Address, err)
+		}
+
+		d.logger.Debug("Using credentials of %s for registry %s", authConfig.Username, address)
+		return registry.EncodeAuthConfig(authConfig)
+	}
+
+	return "", nil
+}
+
+// resolveRegistryAuth получает учетные данные из блока или из docker config
+func resolveRegistryAuth(auth RegistryAuth) (registry.AuthConfig, error) {
+	if auth.Username != "" {
+		return registry.AuthConfig{
+			Username:      auth.Username,
+			Password:      auth.Password,
+			ServerAddress: auth.Address,
+		}, nil
+	}
+
+	configPath := auth.ConfigFile
+	if configPath == "" {
+		configPath = defaultDockerConfigPath()
+	}
+	configPath, err := expandHome(configPath)
+	if err != nil {
+		return registry.AuthConfig{}, err
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return registry.AuthConfig{}, fmt.Errorf("failed to read docker config %s: %w", configPath, err)
+	}
+
+	var dockerConfig dockerConfigFile
+	if err := json.Unmarshal(data, &dockerConfig); err != nil {
+		return registry.AuthConfig{}, fmt.Errorf("failed to parse docker config %s: %w", configPath, err)
+	}
+
+	address := normalizeRegistryAddress(auth.Address)
+
+	// Credential helper для конкретного registry имеет приоритет над общим credsStore
+	for key, helper := range dockerConfig.CredHelpers {
+		if normalizeRegistryAddress(key) == address {
+			return credentialsFromHelper(helper, key)
+		}
+	}
+
+	for key, entry := range dockerConfig.Auths {
+		if normalizeRegistryAddress(key) != address {
+			continue
+		}
+		authConfig, err := decodeConfigAuth(entry)
+		if err != nil {
+			return registry.AuthConfig{}, err
+		}
+		// Если в auths нет секрета, данные лежат в credsStore
+		if authConfig.Username != "" || authConfig.IdentityToken != "" || dockerConfig.CredsStore == "" {
+			authConfig.ServerAddress = key
+			return authConfig, nil
+		}
+		return credentialsFromHelper(dockerConfig.CredsStore, key)
+	}
+
+	if dockerConfig.CredsStore != "" {
+		serverURL := auth.Address
+		if address == "docker.io" {
+			serverURL = dockerHubAddress
+		}
+		return credentialsFromHelper(dockerConfig.CredsStore, serverURL)
+	}
+
+	return registry.AuthConfig{}, fmt.Errorf("no credentials for %s in %s", auth.Address, configPath)
+}
+
+// decodeConfigAuth разбирает запись из секции auths
+func decodeConfigAuth(entry dockerConfigAuth) (registry.AuthConfig, error) {
+	authConfig := registry.AuthConfig{
+		Username:      entry.Username,
+		Password:      entry.Password,
+		IdentityToken: entry.IdentityToken,
+	}
+	if entry.Auth == "" {
+		return authConfig, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+	if err != nil {
+		return authConfig, fmt.Errorf("invalid auth entry: %w", err)
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return authConfig, fmt.Errorf("invalid auth entry: expected username:password")
+	}
+	authConfig.Username = username
+	authConfig.Password = password
+	return authConfig, nil
+}
+
+// credentialsFromHelper вызывает docker-credential-<helper> get
+func credentialsFromHelper(helper, serverURL string) (registry.AuthConfig, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return registry.AuthConfig{}, fmt.Errorf("credential helper %s failed: %w: %s",
+			helper, err, strings.TrimSpace(stdout.String()+stderr.String()))
+	}
+
+	var resp credentialHelperResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return registry.AuthConfig{}, fmt.Errorf("invalid response from credential helper %s: %w", helper, err)
+	}
+
+	// Так helper'ы возвращают identity token вместо пароля
+	if resp.Username == "<token>" {
+		return registry.AuthConfig{IdentityToken: resp.Secret, ServerAddress: serverURL}, nil
+	}
+	return registry.AuthConfig{
+		Username:      resp.Username,
+		Password:      resp.Secret,
+		ServerAddress: serverURL,
+	}, nil
+}
+
+// registryAddress возвращает адрес registry для ссылки на образ
+func registryAddress(ref string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %s: %w", ref, err)
+	}
+	return normalizeRegistryAddress(reference.Domain(named)), nil
+}
+
+// normalizeRegistryAddress приводит адрес registry к виду host[:port],
+// все варианты адреса Docker Hub сводятся к docker.io
+func normalizeRegistryAddress(address string) string {
+	address = strings.TrimPrefix(address, "https://")
+	address = strings.TrimPrefix(address, "http://")
+	address, _, _ = strings.Cut(address, "/")
+
+	switch address {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return "docker.io"
+	}
+	return address
+}
+
+func defaultDockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	return filepath.Join("~", ".docker", "config.json")
+}
+
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
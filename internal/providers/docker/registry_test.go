@@ -0,0 +1,271 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/docker/docker/api/types/registry"
+)
+
+// fakeCredentialHelpers кладет в PATH скрипты docker-credential-<name>.
+// Helper возвращает свое имя как Username и полученный адрес как Secret.
+func fakeCredentialHelpers(t *testing.T, names ...string) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		script := "#!/bin/sh\nread url\nprintf '{\"ServerURL\":\"%s\",\"Username\":\"" + name + "\",\"Secret\":\"%s\"}' \"$url\" \"$url\"\n"
+		if name == "token" {
+			script = "#!/bin/sh\nread url\nprintf '{\"ServerURL\":\"%s\",\"Username\":\"<token>\",\"Secret\":\"identity\"}' \"$url\"\n"
+		}
+		if name == "broken" {
+			script = "#!/bin/sh\necho 'credentials not found in native keychain'\nexit 1\n"
+		}
+		if err := os.WriteFile(filepath.Join(dir, "docker-credential-"+name), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// writeDockerConfig сохраняет config.json и возвращает путь к нему
+func writeDockerConfig(t *testing.T, config string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func basicAuth(username, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+}
+
+func TestNormalizeRegistryAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"docker.io", "docker.io"},
+		{"https://index.docker.io/v1/", "docker.io"},
+		{"registry-1.docker.io", "docker.io"},
+		{"registry.hub.docker.com", "docker.io"},
+		{"ghcr.io", "ghcr.io"},
+		{"https://ghcr.io/v2/", "ghcr.io"},
+		{"http://localhost:5000", "localhost:5000"},
+		{"registry.example.com:5000/team", "registry.example.com:5000"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			if got := normalizeRegistryAddress(test.address); got != test.want {
+				t.Errorf("normalizeRegistryAddress(%q) = %q, want %q", test.address, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistryAddress(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+		err  string
+	}{
+		{ref: "nginx", want: "docker.io"},
+		{ref: "library/nginx:1.27", want: "docker.io"},
+		{ref: "ghcr.io/acme/app:v1", want: "ghcr.io"},
+		{ref: "localhost:5000/app", want: "localhost:5000"},
+		{ref: "Invalid Reference", err: "invalid image reference"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ref, func(t *testing.T) {
+			got, err := registryAddress(test.ref)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("registryAddress(%q) = %q, want %q", test.ref, got, test.want)
+			}
+		})
+	}
+}
+
+func TestResolveRegistryAuth(t *testing.T) {
+	fakeCredentialHelpers(t, "store", "ecr", "token", "broken")
+
+	tests := []struct {
+		name   string
+		auth   RegistryAuth
+		config string
+		want   registry.AuthConfig
+		err    string
+	}{
+		{
+			name: "username and password from the block",
+			auth: RegistryAuth{Address: "ghcr.io", Username: "bot", Password: "pat"},
+			want: registry.AuthConfig{Username: "bot", Password: "pat", ServerAddress: "ghcr.io"},
+		},
+		{
+			name:   "auths entry",
+			auth:   RegistryAuth{Address: "ghcr.io"},
+			config: `{"auths": {"https://ghcr.io": {"auth": "` + basicAuth("bot", "pa:ss") + `"}}}`,
+			want:   registry.AuthConfig{Username: "bot", Password: "pa:ss", ServerAddress: "https://ghcr.io"},
+		},
+		{
+			name:   "docker hub entry",
+			auth:   RegistryAuth{Address: "docker.io"},
+			config: `{"auths": {"https://index.docker.io/v1/": {"username": "me", "password": "secret"}}}`,
+			want:   registry.AuthConfig{Username: "me", Password: "secret", ServerAddress: dockerHubAddress},
+		},
+		{
+			name:   "identity token entry",
+			auth:   RegistryAuth{Address: "registry.example.com"},
+			config: `{"auths": {"registry.example.com": {"identitytoken": "refresh"}}, "credsStore": "store"}`,
+			want:   registry.AuthConfig{IdentityToken: "refresh", ServerAddress: "registry.example.com"},
+		},
+		{
+			name:   "credHelpers before auths and credsStore",
+			auth:   RegistryAuth{Address: "123456789012.dkr.ecr.eu-west-1.amazonaws.com"},
+			config: `{"auths": {"123456789012.dkr.ecr.eu-west-1.amazonaws.com": {"auth": "` + basicAuth("old", "old") + `"}}, "credsStore": "store", "credHelpers": {"123456789012.dkr.ecr.eu-west-1.amazonaws.com": "ecr"}}`,
+			want:   registry.AuthConfig{Username: "ecr", Password: "123456789012.dkr.ecr.eu-west-1.amazonaws.com", ServerAddress: "123456789012.dkr.ecr.eu-west-1.amazonaws.com"},
+		},
+		{
+			name:   "empty auths entry uses credsStore",
+			auth:   RegistryAuth{Address: "ghcr.io"},
+			config: `{"auths": {"ghcr.io": {}}, "credsStore": "store"}`,
+			want:   registry.AuthConfig{Username: "store", Password: "ghcr.io", ServerAddress: "ghcr.io"},
+		},
+		{
+			name:   "credsStore for docker hub",
+			auth:   RegistryAuth{Address: "docker.io"},
+			config: `{"credsStore": "store"}`,
+			want:   registry.AuthConfig{Username: "store", Password: dockerHubAddress, ServerAddress: dockerHubAddress},
+		},
+		{
+			name:   "helper returns identity token",
+			auth:   RegistryAuth{Address: "ghcr.io"},
+			config: `{"credHelpers": {"ghcr.io": "token"}}`,
+			want:   registry.AuthConfig{IdentityToken: "identity", ServerAddress: "ghcr.io"},
+		},
+		{
+			name:   "helper fails",
+			auth:   RegistryAuth{Address: "ghcr.io"},
+			config: `{"credHelpers": {"ghcr.io": "broken"}}`,
+			err:    "credential helper broken failed: exit status 1: credentials not found in native keychain",
+		},
+		{
+			name:   "no credentials",
+			auth:   RegistryAuth{Address: "ghcr.io"},
+			config: `{"auths": {"docker.io": {"auth": "` + basicAuth("me", "secret") + `"}}}`,
+			err:    "no credentials for ghcr.io",
+		},
+		{
+			name:   "invalid auth entry",
+			auth:   RegistryAuth{Address: "ghcr.io"},
+			config: `{"auths": {"ghcr.io": {"auth": "` + base64.StdEncoding.EncodeToString([]byte("token-only")) + `"}}}`,
+			err:    "invalid auth entry: expected username:password",
+		},
+		{
+			name:   "invalid config",
+			auth:   RegistryAuth{Address: "ghcr.io"},
+			config: `{"auths": [`,
+			err:    "failed to parse docker config",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			auth := test.auth
+			if test.config != "" {
+				auth.ConfigFile = writeDockerConfig(t, test.config)
+			}
+			got, err := resolveRegistryAuth(auth)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("auth = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestResolveRegistryAuthDockerConfigEnv(t *testing.T) {
+	dir := t.TempDir()
+	config := `{"auths": {"ghcr.io": {"auth": "` + basicAuth("bot", "pat") + `"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DOCKER_CONFIG", dir)
+
+	got, err := resolveRegistryAuth(RegistryAuth{Address: "ghcr.io"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Username != "bot" || got.Password != "pat" {
+		t.Errorf("auth = %+v, want credentials from $DOCKER_CONFIG", got)
+	}
+}
+
+func TestEncodedAuthFor(t *testing.T) {
+	d := &DockerClient{
+		logger: logging.NewLogger(logging.OFF),
+		registryAuth: []RegistryAuth{
+			{Address: "https://index.docker.io/v1/", Username: "hub", Password: "hub-secret"},
+			{Address: "ghcr.io", Username: "bot", Password: "pat"},
+		},
+	}
+
+	tests := []struct {
+		ref      string
+		username string
+	}{
+		{ref: "nginx:1.27", username: "hub"},
+		{ref: "ghcr.io/acme/app:v1", username: "bot"},
+		{ref: "quay.io/acme/app:v1", username: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ref, func(t *testing.T) {
+			encoded, err := d.encodedAuthFor(test.ref)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.username == "" {
+				if encoded != "" {
+					t.Errorf("auth = %q, want anonymous access", encoded)
+				}
+				return
+			}
+			data, err := base64.URLEncoding.DecodeString(encoded)
+			if err != nil {
+				t.Fatalf("invalid X-Registry-Auth %q: %v", encoded, err)
+			}
+			var got registry.AuthConfig
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Username != test.username {
+				t.Errorf("username = %q, want %q", got.Username, test.username)
+			}
+		})
+	}
+}
@@ -0,0 +1,457 @@
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// containerResource ресурс docker_container
+type containerResource struct {
+	provider *Provider
+}
+
+func (r *containerResource) Schema() providers.Schema {
+	return providers.Schema{
+		Attributes: map[string]*providers.Attribute{
+			"name":         {Type: cty.String, Optional: true, Description: "Container name, defaults to the resource name", Unique: true},
+			"image":        {Type: cty.String, Required: true},
+			"ports":        {Type: cty.Map(cty.String), Optional: true, Description: "Map of container port to host port"},
+			"env":          {Type: cty.Map(cty.String), Optional: true, Sensitive: true},
+			"networks":     {Type: cty.List(cty.String), Optional: true},
+			"command":      {Type: cty.List(cty.String), Optional: true},
+			"wait":         {Type: cty.Bool, Optional: true, Description: "Wait until the container is healthy or running"},
+			"wait_timeout": {Type: cty.String, Optional: true},
+			"image_id":     {Type: cty.String, Computed: true},
+			"network_data": {Type: cty.List(cty.Map(cty.String)), Computed: true},
+		},
+		Blocks: map[string]*providers.NestedBlock{
+			"healthcheck": {MaxItems: 1, Schema: providers.Schema{
+				Attributes: map[string]*providers.Attribute{
+					"test":         {Type: cty.List(cty.String), Optional: true},
+					"interval":     {Type: cty.String, Optional: true},
+					"timeout":      {Type: cty.String, Optional: true},
+					"start_period": {Type: cty.String, Optional: true},
+					"retries":      {Type: cty.Number, Optional: true},
+					"disable":      {Type: cty.Bool, Optional: true},
+				},
+			}},
+			"networks_advanced": {Schema: providers.Schema{
+				Attributes: map[string]*providers.Attribute{
+					"name":         {Type: cty.String, Required: true},
+					"aliases":      {Type: cty.List(cty.String), Optional: true},
+					"links":        {Type: cty.List(cty.String), Optional: true},
+					"ipv4_address": {Type: cty.String, Optional: true},
+					"ipv6_address": {Type: cty.String, Optional: true},
+				},
+			}},
+			"ports": {Schema: providers.Schema{
+				Attributes: map[string]*providers.Attribute{
+					"internal": {Type: cty.Number, Required: true},
+					"external": {Type: cty.Number, Optional: true},
+					"protocol": {Type: cty.String, Optional: true},
+				},
+			}},
+			"volumes": {Schema: providers.Schema{
+				Attributes: map[string]*providers.Attribute{
+					"volume_name":    {Type: cty.String, Optional: true},
+					"host_path":      {Type: cty.String, Optional: true},
+					"container_path": {Type: cty.String, Required: true},
+					"read_only":      {Type: cty.Bool, Optional: true},
+				},
+			}},
+		},
+	}
+}
+
+// Plan пересоздает контейнер, если изменилась конфигурация или образ (например, после пересборки)
+func (r *containerResource) Plan(ctx context.Context, cfg config.Resource, prior *state.ResourceState) (*providers.PlannedChange, error) {
+	containerConfig, err := resourceToContainerConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if prior == nil {
+		return &providers.PlannedChange{Action: providers.ActionCreate}, nil
+	}
+
+	if hash := stateString(prior.Attributes, "config_hash"); hash != "" && hash != containerConfigHash(containerConfig) {
+		return &providers.PlannedChange{Action: providers.ActionReplace, Reason: "configuration changed"}, nil
+	}
+
+	// У импортированного контейнера нет хеша конфигурации, сравниваем ее с самим контейнером
+	if stateString(prior.Attributes, "config_hash") == "" {
+		_, actual, err := r.inspect(ctx, prior.ID)
+		if err != nil {
+			if errdefs.IsNotFound(err) {
+				return &providers.PlannedChange{Action: providers.ActionCreate, Reason: "container no longer exists"}, nil
+			}
+			return nil, err
+		}
+		if changed := containerDiff(containerConfig, actual); len(changed) > 0 {
+			return &providers.PlannedChange{Action: providers.ActionReplace, Reason: diffReason(changed)}, nil
+		}
+	}
+
+	imageID, err := r.provider.client.ImageID(ctx, containerConfig.Image)
+	if err != nil && !errdefs.IsNotFound(err) {
+		return nil, err
+	}
+	if imageID != stateString(prior.Attributes, "image_id") {
+		return &providers.PlannedChange{Action: providers.ActionReplace, Reason: "image changed"}, nil
+	}
+
+	return &providers.PlannedChange{Action: providers.ActionNoop}, nil
+}
+
+func (r *containerResource) Apply(ctx context.Context, cfg config.Resource, prior *state.ResourceState, change *providers.PlannedChange) (*state.ResourceState, error) {
+	containerConfig, err := resourceToContainerConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	client := r.provider.client
+	containerID, createErr := client.CreateContainer(ctx, containerConfig)
+	if containerID == "" {
+		return nil, fmt.Errorf("failed to create container: %w", createErr)
+	}
+
+	newState, err := r.readState(ctx, containerID)
+	if err != nil {
+		client.logger.Warn("Failed to read container %s after create: %v", containerConfig.Name, err)
+		newState = &state.ResourceState{
+			ID: containerID,
+			Attributes: map[string]interface{}{
+				"id":    containerID,
+				"name":  containerConfig.Name,
+				"image": containerConfig.Image,
+			},
+		}
+	}
+	newState.Attributes["config_hash"] = containerConfigHash(containerConfig)
+
+	// Контейнер создан частично: возвращаем состояние, чтобы его можно было удалить
+	if createErr != nil {
+		return newState, fmt.Errorf("failed to create container: %w", createErr)
+	}
+
+	// Ждем готовности, чтобы зависимые ресурсы создавались после нее.
+	// Состояние возвращаем и при ошибке: контейнер уже существует.
+	if containerConfig.Wait {
+		if err := client.WaitForContainer(ctx, containerID, containerConfig.WaitTimeout); err != nil {
+			return newState, fmt.Errorf("container %s is not ready: %w", containerConfig.Name, err)
+		}
+	}
+
+	return newState, nil
+}
+
+func (r *containerResource) Read(ctx context.Context, current *state.ResourceState) (*state.ResourceState, error) {
+	newState, err := r.readState(ctx, current.ID)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if hash := stateString(current.Attributes, "config_hash"); hash != "" {
+		newState.Attributes["config_hash"] = hash
+	}
+	return newState, nil
+}
+
+func (r *containerResource) Delete(ctx context.Context, current *state.ResourceState) error {
+	return r.provider.client.DestroyContainer(ctx, current.ID)
+}
+
+func (r *containerResource) Import(ctx context.Context, id string) (*state.ResourceState, error) {
+	return r.readState(ctx, id)
+}
+
+// inspect возвращает данные docker inspect и восстановленную по ним конфигурацию контейнера
+func (r *containerResource) inspect(ctx context.Context, containerID string) (container.InspectResponse, *ContainerConfig, error) {
+	info, err := r.provider.client.InspectContainer(ctx, containerID)
+	if err != nil {
+		return info, nil, err
+	}
+	// Без образа не отличить его значения по умолчанию от заданных, но конфигурация все равно нужна
+	img, err := r.provider.client.InspectImage(ctx, info.Image)
+	if err != nil && !errdefs.IsNotFound(err) {
+		return info, nil, err
+	}
+	return info, inspectToContainerConfig(info, img), nil
+}
+
+// readState строит состояние по данным docker inspect
+func (r *containerResource) readState(ctx context.Context, containerID string) (*state.ResourceState, error) {
+	info, containerConfig, err := r.inspect(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Сохраняем адреса во всех сетях, чтобы на них можно было ссылаться
+	networkData := make([]interface{}, 0)
+	for _, endpoint := range networkEndpoints(info) {
+		networkData = append(networkData, map[string]interface{}{
+			"network_name": endpoint.NetworkName,
+			"ip_address":   endpoint.IPAddress,
+			"ipv6_address": endpoint.IPv6Address,
+			"gateway":      endpoint.Gateway,
+			"mac_address":  endpoint.MacAddress,
+		})
+	}
+
+	attributes := containerAttributes(containerConfig)
+	attributes["id"] = info.ID
+	attributes["image_id"] = info.Image
+	attributes["network_data"] = networkData
+
+	return &state.ResourceState{ID: info.ID, Attributes: attributes}, nil
+}
+
+// containerConfigHash хеш конфигурации контейнера для обнаружения изменений.
+// Параметры ожидания не влияют на сам контейнер и в хеш не входят.
+func containerConfigHash(containerConfig *ContainerConfig) string {
+	hashed := *containerConfig
+	hashed.Wait = false
+	hashed.WaitTimeout = 0
+
+	data, _ := json.Marshal(hashed)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func resourceToContainerConfig(resource config.Resource) (*ContainerConfig, error) {
+	name, err := nameAttribute(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ContainerConfig{
+		Name: name,
+	}
+
+	// Извлекаем image (обязательный атрибут)
+	if config.Image, err = providers.StringAttribute(resource.Attributes, "image"); err != nil {
+		return nil, err
+	}
+	if config.Image == "" {
+		return nil, fmt.Errorf("missing required attribute 'image'")
+	}
+
+	// Обрабатываем порты
+	if config.Ports, err = providers.StringMapAttribute(resource.Attributes, "ports"); err != nil {
+		return nil, err
+	}
+	for _, block := range resource.BlocksOfType("ports") {
+		internal, external, err := blockToPortBinding(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ports block: %w", err)
+		}
+		if config.Ports == nil {
+			config.Ports = make(map[string]string)
+		}
+		config.Ports[internal] = external
+	}
+
+	// Обрабатываем environment variables
+	if config.Env, err = providers.StringMapAttribute(resource.Attributes, "env"); err != nil {
+		return nil, err
+	}
+
+	// Обрабатываем сети
+	networks, err := providers.StringListAttribute(resource.Attributes, "networks")
+	if err != nil {
+		return nil, err
+	}
+	for _, networkName := range networks {
+		config.Networks = append(config.Networks, NetworkAttachment{Name: networkName})
+	}
+
+	// Обрабатываем расширенные настройки сетей
+	for _, block := range resource.BlocksOfType("networks_advanced") {
+		attachment, err := blockToNetworkAttachment(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid networks_advanced block: %w", err)
+		}
+		for _, existing := range config.Networks {
+			if existing.Name == attachment.Name {
+				return nil, fmt.Errorf("network %s is attached more than once", attachment.Name)
+			}
+		}
+		config.Networks = append(config.Networks, attachment)
+	}
+
+	// Обрабатываем тома
+	for _, block := range resource.BlocksOfType("volumes") {
+		volume, err := blockToVolumeMount(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid volumes block: %w", err)
+		}
+		config.Volumes = append(config.Volumes, volume)
+	}
+
+	// Обрабатываем команду
+	if config.Command, err = providers.StringListAttribute(resource.Attributes, "command"); err != nil {
+		return nil, err
+	}
+
+	// Обрабатываем healthcheck
+	if healthBlocks := resource.BlocksOfType("healthcheck"); len(healthBlocks) > 0 {
+		healthCheck, err := blockToHealthCheck(healthBlocks[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid healthcheck block: %w", err)
+		}
+		config.HealthCheck = healthCheck
+	}
+
+	// Обрабатываем ожидание готовности
+	if config.Wait, err = providers.BoolAttribute(resource.Attributes, "wait"); err != nil {
+		return nil, err
+	}
+	if config.WaitTimeout, err = providers.DurationAttribute(resource.Attributes, "wait_timeout"); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// blockToPortBinding преобразует блок ports в пару "порт/протокол" -> порт на хосте
+func blockToPortBinding(block config.Block) (string, string, error) {
+	internal, err := providers.IntAttribute(block.Attributes, "internal")
+	if err != nil {
+		return "", "", err
+	}
+	if internal == 0 {
+		return "", "", fmt.Errorf("missing required attribute 'internal'")
+	}
+
+	external, err := providers.IntAttribute(block.Attributes, "external")
+	if err != nil {
+		return "", "", err
+	}
+
+	protocol, err := providers.StringAttribute(block.Attributes, "protocol")
+	if err != nil {
+		return "", "", err
+	}
+	if protocol == "" {
+		protocol = "tcp"
+	}
+
+	// Без external Docker выберет свободный порт на хосте
+	hostPort := ""
+	if external != 0 {
+		hostPort = strconv.Itoa(external)
+	}
+
+	return strconv.Itoa(internal) + "/" + protocol, hostPort, nil
+}
+
+// blockToVolumeMount преобразует блок volumes в VolumeMount
+func blockToVolumeMount(block config.Block) (VolumeMount, error) {
+	volume := VolumeMount{}
+
+	var err error
+	if volume.Target, err = providers.StringAttribute(block.Attributes, "container_path"); err != nil {
+		return volume, err
+	}
+	if volume.Target == "" {
+		return volume, fmt.Errorf("missing required attribute 'container_path'")
+	}
+
+	volumeName, err := providers.StringAttribute(block.Attributes, "volume_name")
+	if err != nil {
+		return volume, err
+	}
+	hostPath, err := providers.StringAttribute(block.Attributes, "host_path")
+	if err != nil {
+		return volume, err
+	}
+
+	switch {
+	case volumeName != "" && hostPath != "":
+		return volume, fmt.Errorf("'volume_name' and 'host_path' are mutually exclusive")
+	case volumeName != "":
+		volume.Source = volumeName
+		volume.Named = true
+	case hostPath != "":
+		volume.Source = hostPath
+	default:
+		return volume, fmt.Errorf("one of 'volume_name' or 'host_path' is required")
+	}
+
+	if volume.ReadOnly, err = providers.BoolAttribute(block.Attributes, "read_only"); err != nil {
+		return volume, err
+	}
+
+	return volume, nil
+}
+
+// blockToNetworkAttachment преобразует блок networks_advanced в NetworkAttachment
+func blockToNetworkAttachment(block config.Block) (NetworkAttachment, error) {
+	attachment := NetworkAttachment{}
+
+	var err error
+	if attachment.Name, err = providers.StringAttribute(block.Attributes, "name"); err != nil {
+		return attachment, err
+	}
+	if attachment.Name == "" {
+		return attachment, fmt.Errorf("missing required string attribute 'name'")
+	}
+	if attachment.Aliases, err = providers.StringListAttribute(block.Attributes, "aliases"); err != nil {
+		return attachment, err
+	}
+	if attachment.Links, err = providers.StringListAttribute(block.Attributes, "links"); err != nil {
+		return attachment, err
+	}
+	if attachment.IPv4Address, err = providers.StringAttribute(block.Attributes, "ipv4_address"); err != nil {
+		return attachment, err
+	}
+	if attachment.IPv6Address, err = providers.StringAttribute(block.Attributes, "ipv6_address"); err != nil {
+		return attachment, err
+	}
+
+	return attachment, nil
+}
+
+// blockToHealthCheck преобразует блок healthcheck в HealthCheck
+func blockToHealthCheck(block config.Block) (*HealthCheck, error) {
+	healthCheck := &HealthCheck{}
+
+	var err error
+	if healthCheck.Test, err = providers.StringListAttribute(block.Attributes, "test"); err != nil {
+		return nil, err
+	}
+	if healthCheck.Interval, err = providers.DurationAttribute(block.Attributes, "interval"); err != nil {
+		return nil, err
+	}
+	if healthCheck.Timeout, err = providers.DurationAttribute(block.Attributes, "timeout"); err != nil {
+		return nil, err
+	}
+	if healthCheck.StartPeriod, err = providers.DurationAttribute(block.Attributes, "start_period"); err != nil {
+		return nil, err
+	}
+	if healthCheck.Retries, err = providers.IntAttribute(block.Attributes, "retries"); err != nil {
+		return nil, err
+	}
+	if healthCheck.Disable, err = providers.BoolAttribute(block.Attributes, "disable"); err != nil {
+		return nil, err
+	}
+
+	if len(healthCheck.Test) == 0 && !healthCheck.Disable {
+		return nil, fmt.Errorf("missing required attribute 'test'")
+	}
+
+	return healthCheck, nil
+}
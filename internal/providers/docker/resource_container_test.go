@@ -0,0 +1,122 @@
+package docker
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func stringList(values ...string) cty.Value {
+	items := make([]cty.Value, len(values))
+	for i, value := range values {
+		items[i] = cty.StringVal(value)
+	}
+	return cty.ListVal(items)
+}
+
+func networksAdvanced(attributes map[string]cty.Value) config.Block {
+	return config.Block{Type: "networks_advanced", Attributes: attributes}
+}
+
+func TestResourceToContainerConfigNetworks(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes map[string]cty.Value
+		blocks     []config.Block
+		want       []NetworkAttachment
+		err        string
+	}{
+		{
+			name:       "networks list",
+			attributes: map[string]cty.Value{"networks": stringList("frontend", "backend")},
+			want:       []NetworkAttachment{{Name: "frontend"}, {Name: "backend"}},
+		},
+		{
+			name: "networks_advanced",
+			blocks: []config.Block{
+				networksAdvanced(map[string]cty.Value{
+					"name":         cty.StringVal("frontend"),
+					"aliases":      stringList("web", "www"),
+					"ipv4_address": cty.StringVal("172.28.0.10"),
+				}),
+				networksAdvanced(map[string]cty.Value{
+					"name":         cty.StringVal("backend"),
+					"links":        stringList("db:database"),
+					"ipv6_address": cty.StringVal("fd00::10"),
+				}),
+			},
+			want: []NetworkAttachment{
+				{Name: "frontend", Aliases: []string{"web", "www"}, IPv4Address: "172.28.0.10"},
+				{Name: "backend", Links: []string{"db:database"}, IPv6Address: "fd00::10"},
+			},
+		},
+		{
+			name:       "list and blocks together",
+			attributes: map[string]cty.Value{"networks": stringList("metrics")},
+			blocks:     []config.Block{networksAdvanced(map[string]cty.Value{"name": cty.StringVal("frontend"), "aliases": stringList("web")})},
+			want:       []NetworkAttachment{{Name: "metrics"}, {Name: "frontend", Aliases: []string{"web"}}},
+		},
+		{
+			name:       "network attached twice",
+			attributes: map[string]cty.Value{"networks": stringList("frontend")},
+			blocks:     []config.Block{networksAdvanced(map[string]cty.Value{"name": cty.StringVal("frontend")})},
+			err:        "network frontend is attached more than once",
+		},
+		{
+			name:   "missing name",
+			blocks: []config.Block{networksAdvanced(map[string]cty.Value{"aliases": stringList("web")})},
+			err:    "invalid networks_advanced block: missing required string attribute 'name'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attributes := map[string]cty.Value{"image": cty.StringVal("nginx")}
+			for name, value := range test.attributes {
+				attributes[name] = value
+			}
+			got, err := resourceToContainerConfig(config.Resource{Type: "docker_container", Name: "web", Attributes: attributes, Blocks: test.blocks})
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got.Networks, test.want) {
+				t.Errorf("networks = %+v, want %+v", got.Networks, test.want)
+			}
+		})
+	}
+}
+
+func TestContainerDiffNetworks(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired []NetworkAttachment
+		actual  []NetworkAttachment
+		changed bool
+	}{
+		{name: "same networks in another order", desired: []NetworkAttachment{{Name: "a"}, {Name: "b"}}, actual: []NetworkAttachment{{Name: "b"}, {Name: "a"}}},
+		{name: "aliases are not compared", desired: []NetworkAttachment{{Name: "a", Aliases: []string{"web"}}}, actual: []NetworkAttachment{{Name: "a"}}},
+		{name: "default bridge", desired: nil, actual: []NetworkAttachment{{Name: defaultNetwork}}},
+		{name: "network added", desired: []NetworkAttachment{{Name: "a"}, {Name: "b"}}, actual: []NetworkAttachment{{Name: "a"}}, changed: true},
+		{name: "network replaced", desired: []NetworkAttachment{{Name: "b"}}, actual: []NetworkAttachment{{Name: "a"}}, changed: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			desired := &ContainerConfig{Name: "web", Image: "nginx", Networks: test.desired}
+			actual := &ContainerConfig{Name: "web", Image: "nginx", Networks: test.actual}
+			changed := containerDiff(desired, actual)
+			if got := len(changed) > 0; got != test.changed {
+				t.Errorf("containerDiff = %v, want changed %v", changed, test.changed)
+			}
+		})
+	}
+}
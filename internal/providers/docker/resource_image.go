@@ -0,0 +1,219 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/docker/docker/errdefs"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// imageResource ресурс docker_image: скачивает образ или собирает его из Dockerfile
+type imageResource struct {
+	provider *Provider
+}
+
+func (r *imageResource) Schema() providers.Schema {
+	return providers.Schema{
+		Attributes: map[string]*providers.Attribute{
+			"name":         {Type: cty.String, Required: true, Description: "Image reference, e.g. nginx:alpine"},
+			"context_hash": {Type: cty.String, Computed: true},
+		},
+		Blocks: map[string]*providers.NestedBlock{
+			"build": {MaxItems: 1, Schema: providers.Schema{
+				Attributes: map[string]*providers.Attribute{
+					"context":    {Type: cty.String, Required: true},
+					"dockerfile": {Type: cty.String, Optional: true},
+					"build_args": {Type: cty.Map(cty.String), Optional: true},
+					"target":     {Type: cty.String, Optional: true},
+					"labels":     {Type: cty.Map(cty.String), Optional: true},
+					"platform":   {Type: cty.String, Optional: true},
+					"no_cache":   {Type: cty.Bool, Optional: true},
+				},
+			}},
+		},
+	}
+}
+
+// Plan пересобирает образ, только если изменился контекст или образ пропал локально
+func (r *imageResource) Plan(ctx context.Context, cfg config.Resource, prior *state.ResourceState) (*providers.PlannedChange, error) {
+	imageConfig, err := resourceToImageConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if prior == nil {
+		return &providers.PlannedChange{Action: providers.ActionCreate}, nil
+	}
+
+	if stateString(prior.Attributes, "name") != imageConfig.Name {
+		return &providers.PlannedChange{Action: providers.ActionReplace, Reason: "name changed"}, nil
+	}
+
+	currentID, err := r.provider.client.ImageID(ctx, imageConfig.Name)
+	if err != nil && !errdefs.IsNotFound(err) {
+		return nil, err
+	}
+	if currentID != prior.ID {
+		return &providers.PlannedChange{Action: providers.ActionUpdate, Reason: "image missing or changed locally"}, nil
+	}
+
+	if imageConfig.Build != nil {
+		contextHash, err := ContextHash(imageConfig.Build)
+		if err != nil {
+			return nil, err
+		}
+		if stateString(prior.Attributes, "context_hash") != contextHash {
+			return &providers.PlannedChange{Action: providers.ActionUpdate, Reason: "build context changed"}, nil
+		}
+	}
+
+	return &providers.PlannedChange{Action: providers.ActionNoop}, nil
+}
+
+func (r *imageResource) Apply(ctx context.Context, cfg config.Resource, prior *state.ResourceState, change *providers.PlannedChange) (*state.ResourceState, error) {
+	imageConfig, err := resourceToImageConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	client := r.provider.client
+	attributes := map[string]interface{}{
+		"name": imageConfig.Name,
+	}
+
+	var imageID string
+	if imageConfig.Build != nil {
+		contextHash, err := ContextHash(imageConfig.Build)
+		if err != nil {
+			return nil, err
+		}
+		attributes["context_hash"] = contextHash
+
+		imageID, err = client.BuildImage(ctx, imageConfig)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		imageID, err = client.EnsureImage(ctx, imageConfig.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	attributes["id"] = imageID
+
+	return &state.ResourceState{ID: imageID, Attributes: attributes}, nil
+}
+
+func (r *imageResource) Read(ctx context.Context, current *state.ResourceState) (*state.ResourceState, error) {
+	imageID, err := r.provider.client.ImageID(ctx, current.ID)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	attributes := make(map[string]interface{}, len(current.Attributes))
+	for key, value := range current.Attributes {
+		attributes[key] = value
+	}
+	attributes["id"] = imageID
+
+	return &state.ResourceState{ID: imageID, Attributes: attributes}, nil
+}
+
+func (r *imageResource) Delete(ctx context.Context, current *state.ResourceState) error {
+	return r.provider.client.DestroyImage(ctx, current.ID)
+}
+
+// Import принимает ссылку на образ (nginx:alpine) или его ID
+func (r *imageResource) Import(ctx context.Context, id string) (*state.ResourceState, error) {
+	info, err := r.provider.client.InspectImage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// По ID образа восстанавливаем ссылку, которую нужно указать в name
+	name := id
+	if strings.HasPrefix(info.ID, id) || strings.HasPrefix(info.ID, "sha256:"+id) {
+		if len(info.RepoTags) > 0 {
+			name = info.RepoTags[0]
+		}
+	}
+
+	return &state.ResourceState{
+		ID: info.ID,
+		Attributes: map[string]interface{}{
+			"id":   info.ID,
+			"name": name,
+		},
+	}, nil
+}
+
+// resourceToImageConfig преобразует Resource в ImageConfig
+func resourceToImageConfig(resource config.Resource) (*ImageConfig, error) {
+	name, err := providers.StringAttribute(resource.Attributes, "name")
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, fmt.Errorf("missing required attribute 'name'")
+	}
+
+	imageConfig := &ImageConfig{Name: name}
+
+	buildBlocks := resource.BlocksOfType("build")
+	if len(buildBlocks) == 0 {
+		return imageConfig, nil
+	}
+
+	build, err := blockToBuildConfig(buildBlocks[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid build block: %w", err)
+	}
+	if !filepath.IsAbs(build.Context) {
+		build.Context = filepath.Join(resource.Dir, build.Context)
+	}
+	imageConfig.Build = build
+
+	return imageConfig, nil
+}
+
+// blockToBuildConfig преобразует блок build в BuildConfig
+func blockToBuildConfig(block config.Block) (*BuildConfig, error) {
+	build := &BuildConfig{}
+
+	var err error
+	if build.Context, err = providers.StringAttribute(block.Attributes, "context"); err != nil {
+		return nil, err
+	}
+	if build.Context == "" {
+		return nil, fmt.Errorf("missing required attribute 'context'")
+	}
+	if build.Dockerfile, err = providers.StringAttribute(block.Attributes, "dockerfile"); err != nil {
+		return nil, err
+	}
+	if build.Target, err = providers.StringAttribute(block.Attributes, "target"); err != nil {
+		return nil, err
+	}
+	if build.Platform, err = providers.StringAttribute(block.Attributes, "platform"); err != nil {
+		return nil, err
+	}
+	if build.BuildArgs, err = providers.StringMapAttribute(block.Attributes, "build_args"); err != nil {
+		return nil, err
+	}
+	if build.Labels, err = providers.StringMapAttribute(block.Attributes, "labels"); err != nil {
+		return nil, err
+	}
+	if build.NoCache, err = providers.BoolAttribute(block.Attributes, "no_cache"); err != nil {
+		return nil, err
+	}
+
+	return build, nil
+}
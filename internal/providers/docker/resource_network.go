@@ -0,0 +1,125 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// networkResource ресурс docker_network
+type networkResource struct {
+	provider *Provider
+}
+
+func (r *networkResource) Schema() providers.Schema {
+	return providers.Schema{
+		Attributes: map[string]*providers.Attribute{
+			"name":   {Type: cty.String, Optional: true, Description: "Network name, defaults to the resource name", Unique: true},
+			"driver": {Type: cty.String, Optional: true, Description: "Network driver, bridge by default"},
+		},
+	}
+}
+
+// Plan пересоздает сеть при смене имени или драйвера, Docker не умеет менять их на месте
+func (r *networkResource) Plan(ctx context.Context, cfg config.Resource, prior *state.ResourceState) (*providers.PlannedChange, error) {
+	networkConfig, err := resourceToNetworkConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if prior == nil {
+		return &providers.PlannedChange{Action: providers.ActionCreate}, nil
+	}
+
+	if stateString(prior.Attributes, "name") != networkConfig.Name {
+		return &providers.PlannedChange{Action: providers.ActionReplace, Reason: "name changed"}, nil
+	}
+	if driver := stateString(prior.Attributes, "driver"); driver != "" && driver != networkConfig.Driver {
+		return &providers.PlannedChange{Action: providers.ActionReplace, Reason: "driver changed"}, nil
+	}
+
+	return &providers.PlannedChange{Action: providers.ActionNoop}, nil
+}
+
+func (r *networkResource) Apply(ctx context.Context, cfg config.Resource, prior *state.ResourceState, change *providers.PlannedChange) (*state.ResourceState, error) {
+	networkConfig, err := resourceToNetworkConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Создаем сеть
+	networkID, err := r.provider.client.CreateNetwork(ctx, networkConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create network: %w", err)
+	}
+
+	return &state.ResourceState{
+		ID: networkID,
+		Attributes: map[string]interface{}{
+			"id":     networkID,
+			"name":   networkConfig.Name,
+			"driver": networkConfig.Driver,
+		},
+	}, nil
+}
+
+func (r *networkResource) Read(ctx context.Context, current *state.ResourceState) (*state.ResourceState, error) {
+	newState, err := r.Import(ctx, current.ID)
+	if err != nil && errdefs.IsNotFound(err) {
+		return nil, nil
+	}
+	return newState, err
+}
+
+func (r *networkResource) Delete(ctx context.Context, current *state.ResourceState) error {
+	return r.provider.client.DestroyNetwork(ctx, current.ID)
+}
+
+func (r *networkResource) Import(ctx context.Context, id string) (*state.ResourceState, error) {
+	info, err := r.provider.client.InspectNetwork(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return networkState(info), nil
+}
+
+func networkState(info network.Inspect) *state.ResourceState {
+	return &state.ResourceState{
+		ID: info.ID,
+		Attributes: map[string]interface{}{
+			"id":     info.ID,
+			"name":   info.Name,
+			"driver": info.Driver,
+		},
+	}
+}
+
+// resourceToNetworkConfig преобразует Resource в NetworkConfig
+func resourceToNetworkConfig(resource config.Resource) (*NetworkConfig, error) {
+	name, err := nameAttribute(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &NetworkConfig{
+		Name:   name,
+		Driver: "bridge", // Значение по умолчанию
+	}
+
+	// Извлекаем driver если есть
+	driver, err := providers.StringAttribute(resource.Attributes, "driver")
+	if err != nil {
+		return nil, err
+	}
+	if driver != "" {
+		config.Driver = driver
+	}
+
+	return config, nil
+}
@@ -0,0 +1,129 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/docker/docker/errdefs"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// registryImageResource ресурс docker_registry_image: отправляет локальный образ в registry
+type registryImageResource struct {
+	provider *Provider
+}
+
+func (r *registryImageResource) Schema() providers.Schema {
+	return providers.Schema{
+		Attributes: map[string]*providers.Attribute{
+			"name":          {Type: cty.String, Required: true, Description: "Full image reference including the registry, e.g. localhost:5000/app:1.0"},
+			"image_id":      {Type: cty.String, Computed: true},
+			"sha256_digest": {Type: cty.String, Computed: true},
+		},
+	}
+}
+
+// Plan повторно отправляет образ, только если локальный образ изменился
+func (r *registryImageResource) Plan(ctx context.Context, cfg config.Resource, prior *state.ResourceState) (*providers.PlannedChange, error) {
+	name, err := providers.StringAttribute(cfg.Attributes, "name")
+	if err != nil {
+		return nil, err
+	}
+
+	if prior == nil {
+		return &providers.PlannedChange{Action: providers.ActionCreate}, nil
+	}
+	if stateString(prior.Attributes, "name") != name {
+		return &providers.PlannedChange{Action: providers.ActionReplace, Reason: "name changed"}, nil
+	}
+
+	localID, err := r.provider.client.ImageID(ctx, name)
+	if err != nil && !errdefs.IsNotFound(err) {
+		return nil, err
+	}
+	if localID != stateString(prior.Attributes, "image_id") {
+		return &providers.PlannedChange{Action: providers.ActionUpdate, Reason: "local image changed"}, nil
+	}
+
+	return &providers.PlannedChange{Action: providers.ActionNoop}, nil
+}
+
+func (r *registryImageResource) Apply(ctx context.Context, cfg config.Resource, prior *state.ResourceState, change *providers.PlannedChange) (*state.ResourceState, error) {
+	name, err := providers.StringAttribute(cfg.Attributes, "name")
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, fmt.Errorf("missing required attribute 'name'")
+	}
+
+	client := r.provider.client
+	localID, err := client.ImageID(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("image %s must exist locally, build or tag it with docker_image first: %w", name, err)
+	}
+
+	digest, err := client.PushImage(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state.ResourceState{
+		ID: digest,
+		Attributes: map[string]interface{}{
+			"id":            digest,
+			"name":          name,
+			"image_id":      localID,
+			"sha256_digest": digest,
+		},
+	}, nil
+}
+
+func (r *registryImageResource) Read(ctx context.Context, current *state.ResourceState) (*state.ResourceState, error) {
+	name := stateString(current.Attributes, "name")
+	digest, err := r.provider.client.RegistryDigest(ctx, name)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	attributes := make(map[string]interface{}, len(current.Attributes))
+	for key, value := range current.Attributes {
+		attributes[key] = value
+	}
+	attributes["id"] = digest
+	attributes["sha256_digest"] = digest
+
+	return &state.ResourceState{ID: digest, Attributes: attributes}, nil
+}
+
+// Delete оставляет образ в registry: Docker API не умеет удалять из registry,
+// поэтому ресурс только убирается из state
+func (r *registryImageResource) Delete(ctx context.Context, current *state.ResourceState) error {
+	r.provider.client.logger.Warn("Image %s is kept in the registry", stateString(current.Attributes, "name"))
+	return nil
+}
+
+// Import принимает полную ссылку на образ в registry
+func (r *registryImageResource) Import(ctx context.Context, id string) (*state.ResourceState, error) {
+	digest, err := r.provider.client.RegistryDigest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := map[string]interface{}{
+		"id":            digest,
+		"name":          id,
+		"sha256_digest": digest,
+	}
+	if localID, err := r.provider.client.ImageID(ctx, id); err == nil {
+		attributes["image_id"] = localID
+	}
+
+	return &state.ResourceState{ID: digest, Attributes: attributes}, nil
+}
@@ -0,0 +1,131 @@
+package docker
+
+import (
+	"context"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/state"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/errdefs"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// volumeResource ресурс docker_volume
+type volumeResource struct {
+	provider *Provider
+}
+
+func (r *volumeResource) Schema() providers.Schema {
+	return providers.Schema{
+		Attributes: map[string]*providers.Attribute{
+			"name":        {Type: cty.String, Optional: true, Description: "Volume name, defaults to the resource name", Unique: true},
+			"driver":      {Type: cty.String, Optional: true},
+			"driver_opts": {Type: cty.Map(cty.String), Optional: true},
+			"labels":      {Type: cty.Map(cty.String), Optional: true},
+			"mountpoint":  {Type: cty.String, Computed: true},
+		},
+	}
+}
+
+// Plan пересоздает том при любом изменении: Docker не обновляет тома на месте
+func (r *volumeResource) Plan(ctx context.Context, cfg config.Resource, prior *state.ResourceState) (*providers.PlannedChange, error) {
+	volumeConfig, err := resourceToVolumeConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if prior == nil {
+		return &providers.PlannedChange{Action: providers.ActionCreate}, nil
+	}
+
+	if stateString(prior.Attributes, "name") != volumeConfig.Name {
+		return &providers.PlannedChange{Action: providers.ActionReplace, Reason: "name changed"}, nil
+	}
+	if volumeConfig.Driver != "" && stateString(prior.Attributes, "driver") != volumeConfig.Driver {
+		return &providers.PlannedChange{Action: providers.ActionReplace, Reason: "driver changed"}, nil
+	}
+	if !equalStringMaps(stateStringMap(prior.Attributes, "driver_opts"), volumeConfig.DriverOpts) {
+		return &providers.PlannedChange{Action: providers.ActionReplace, Reason: "driver_opts changed"}, nil
+	}
+
+	return &providers.PlannedChange{Action: providers.ActionNoop}, nil
+}
+
+func (r *volumeResource) Apply(ctx context.Context, cfg config.Resource, prior *state.ResourceState, change *providers.PlannedChange) (*state.ResourceState, error) {
+	volumeConfig, err := resourceToVolumeConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	vol, err := r.provider.client.CreateVolume(ctx, volumeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return volumeState(vol), nil
+}
+
+func (r *volumeResource) Read(ctx context.Context, current *state.ResourceState) (*state.ResourceState, error) {
+	newState, err := r.Import(ctx, current.ID)
+	if err != nil && errdefs.IsNotFound(err) {
+		return nil, nil
+	}
+	return newState, err
+}
+
+func (r *volumeResource) Delete(ctx context.Context, current *state.ResourceState) error {
+	return r.provider.client.DestroyVolume(ctx, current.ID)
+}
+
+func (r *volumeResource) Import(ctx context.Context, id string) (*state.ResourceState, error) {
+	vol, err := r.provider.client.InspectVolume(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return volumeState(vol), nil
+}
+
+// volumeState строит состояние тома. ID тома в Docker это его имя.
+func volumeState(vol volume.Volume) *state.ResourceState {
+	driverOpts := make(map[string]interface{}, len(vol.Options))
+	for key, value := range vol.Options {
+		driverOpts[key] = value
+	}
+	labels := make(map[string]interface{}, len(vol.Labels))
+	for key, value := range vol.Labels {
+		labels[key] = value
+	}
+
+	return &state.ResourceState{
+		ID: vol.Name,
+		Attributes: map[string]interface{}{
+			"id":          vol.Name,
+			"name":        vol.Name,
+			"driver":      vol.Driver,
+			"driver_opts": driverOpts,
+			"labels":      labels,
+			"mountpoint":  vol.Mountpoint,
+		},
+	}
+}
+
+// resourceToVolumeConfig преобразует Resource в VolumeConfig
+func resourceToVolumeConfig(resource config.Resource) (*VolumeConfig, error) {
+	name, err := nameAttribute(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &VolumeConfig{Name: name}
+	if config.Driver, err = providers.StringAttribute(resource.Attributes, "driver"); err != nil {
+		return nil, err
+	}
+	if config.DriverOpts, err = providers.StringMapAttribute(resource.Attributes, "driver_opts"); err != nil {
+		return nil, err
+	}
+	if config.Labels, err = providers.StringMapAttribute(resource.Attributes, "labels"); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
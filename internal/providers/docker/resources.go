@@ -3,10 +3,11 @@ package docker
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/go-connections/nat"
@@ -17,40 +18,64 @@ type ContainerConfig struct {
 	Image       string
 	Ports       map[string]string
 	Env         map[string]string
-	Networks    []string
+	Networks    []NetworkAttachment
 	Volumes     []VolumeMount
 	HealthCheck *HealthCheck
 	Command     []string // Добавляем поле Command
+	Wait        bool
+	WaitTimeout time.Duration
+}
+
+// NetworkAttachment настройки подключения контейнера к одной сети
+type NetworkAttachment struct {
+	Name        string
+	Aliases     []string
+	Links       []string
+	IPv4Address string
+	IPv6Address string
+}
+
+// NetworkEndpoint фактические параметры подключения контейнера к сети
+type NetworkEndpoint struct {
+	NetworkName string
+	IPAddress   string
+	IPv6Address string
+	Gateway     string
+	MacAddress  string
 }
 
 type VolumeMount struct {
 	Source   string
 	Target   string
 	ReadOnly bool
+	// Named Source это имя Docker тома, а не путь на хосте
+	Named bool
 }
 
 type HealthCheck struct {
-	Test     []string
-	Interval time.Duration
-	Timeout  time.Duration
-	Retries  int
+	Test        []string
+	Interval    time.Duration
+	Timeout     time.Duration
+	StartPeriod time.Duration
+	Retries     int
+	Disable     bool
 }
 
 func (d *DockerClient) CreateContainer(ctx context.Context, config *ContainerConfig) (string, error) {
 	// Pull image
-	d.logger.Info("Pulling image: %s", config.Image)
-	reader, err := d.cli.ImagePull(ctx, config.Image, image.PullOptions{})
-	if err != nil {
-		return "", fmt.Errorf("failed to pull image: %w", err)
+	if err := d.pullContainerImage(ctx, config.Image); err != nil {
+		return "", err
 	}
-	defer reader.Close()
 
 	// Parse port bindings
 	portBindings := make(nat.PortMap)
 	exposedPorts := make(nat.PortSet)
 
 	for internal, external := range config.Ports {
-		port := nat.Port(internal + "/tcp")
+		if !strings.Contains(internal, "/") {
+			internal += "/tcp"
+		}
+		port := nat.Port(internal)
 		exposedPorts[port] = struct{}{}
 		portBindings[port] = []nat.PortBinding{
 			{
@@ -69,8 +94,12 @@ func (d *DockerClient) CreateContainer(ctx context.Context, config *ContainerCon
 	// Prepare volume mounts
 	var mounts []mount.Mount
 	for _, vol := range config.Volumes {
+		mountType := mount.TypeBind
+		if vol.Named {
+			mountType = mount.TypeVolume
+		}
 		mounts = append(mounts, mount.Mount{
-			Type:     mount.TypeBind,
+			Type:     mountType,
 			Source:   vol.Source,
 			Target:   vol.Target,
 			ReadOnly: vol.ReadOnly,
@@ -81,10 +110,25 @@ func (d *DockerClient) CreateContainer(ctx context.Context, config *ContainerCon
 	var healthConfig *container.HealthConfig
 	if config.HealthCheck != nil {
 		healthConfig = &container.HealthConfig{
-			Test:     config.HealthCheck.Test,
-			Interval: config.HealthCheck.Interval,
-			Timeout:  config.HealthCheck.Timeout,
-			Retries:  config.HealthCheck.Retries,
+			Test:        config.HealthCheck.Test,
+			Interval:    config.HealthCheck.Interval,
+			Timeout:     config.HealthCheck.Timeout,
+			StartPeriod: config.HealthCheck.StartPeriod,
+			Retries:     config.HealthCheck.Retries,
+		}
+		// Docker отключает healthcheck образа через специальный тест NONE
+		if config.HealthCheck.Disable {
+			healthConfig = &container.HealthConfig{Test: []string{"NONE"}}
+		}
+	}
+
+	// Первую сеть подключаем при создании, чтобы контейнер
+	// не оказался в сети bridge по умолчанию
+	networkingConfig := &network.NetworkingConfig{}
+	if len(config.Networks) > 0 {
+		first := config.Networks[0]
+		networkingConfig.EndpointsConfig = map[string]*network.EndpointSettings{
+			first.Name: endpointSettings(first),
 		}
 	}
 
@@ -92,6 +136,7 @@ func (d *DockerClient) CreateContainer(ctx context.Context, config *ContainerCon
 	resp, err := d.cli.ContainerCreate(ctx,
 		&container.Config{
 			Image:        config.Image,
+			Cmd:          config.Command,
 			Env:          envVars,
 			ExposedPorts: exposedPorts,
 			Healthcheck:  healthConfig,
@@ -103,7 +148,7 @@ func (d *DockerClient) CreateContainer(ctx context.Context, config *ContainerCon
 				Name: "unless-stopped",
 			},
 		},
-		&network.NetworkingConfig{},
+		networkingConfig,
 		nil,
 		config.Name,
 	)
@@ -111,18 +156,85 @@ func (d *DockerClient) CreateContainer(ctx context.Context, config *ContainerCon
 		return "", fmt.Errorf("failed to create container: %w", err)
 	}
 
-	// Connect to networks
-	for _, networkName := range config.Networks {
-		if err := d.cli.NetworkConnect(ctx, networkName, resp.ID, nil); err != nil {
-			d.logger.Warn("Failed to connect container to network %s: %v", networkName, err)
+	// Дальше контейнер уже существует: при ошибках ID возвращается вместе с ней
+
+	// Connect to remaining networks
+	for i := 1; i < len(config.Networks); i++ {
+		attachment := config.Networks[i]
+		err := retryConnect(ctx, d, "Connecting container to network "+attachment.Name, func() error {
+			return d.cli.NetworkConnect(ctx, attachment.Name, resp.ID, endpointSettings(attachment))
+		})
+		if err != nil {
+			return resp.ID, fmt.Errorf("failed to connect container to network %s: %w", attachment.Name, err)
 		}
 	}
 
 	// Start container
-	if err := d.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		return "", fmt.Errorf("failed to start container: %w", err)
+	err = retryCall(ctx, d, "Starting container "+config.Name, func() error {
+		return d.cli.ContainerStart(ctx, resp.ID, container.StartOptions{})
+	})
+	if err != nil {
+		return resp.ID, fmt.Errorf("failed to start container: %w", err)
 	}
 
 	d.logger.Info("Container %s created successfully with ID: %s", config.Name, resp.ID[:12])
 	return resp.ID, nil
 }
+
+// endpointSettings собирает настройки endpoint для подключения к сети
+func endpointSettings(attachment NetworkAttachment) *network.EndpointSettings {
+	settings := &network.EndpointSettings{
+		Aliases: attachment.Aliases,
+		Links:   attachment.Links,
+	}
+	if attachment.IPv4Address != "" || attachment.IPv6Address != "" {
+		settings.IPAMConfig = &network.EndpointIPAMConfig{
+			IPv4Address: attachment.IPv4Address,
+			IPv6Address: attachment.IPv6Address,
+		}
+	}
+	return settings
+}
+
+// InspectContainer возвращает данные docker inspect для контейнера
+func (d *DockerClient) InspectContainer(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	info, err := withRetry(ctx, d, "Inspecting container", func() (container.InspectResponse, error) {
+		return d.cli.ContainerInspect(ctx, containerID)
+	})
+	if err != nil {
+		return info, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	return info, nil
+}
+
+// ContainerNetworks возвращает адреса контейнера во всех подключенных сетях
+func (d *DockerClient) ContainerNetworks(ctx context.Context, containerID string) ([]NetworkEndpoint, error) {
+	info, err := d.InspectContainer(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	return networkEndpoints(info), nil
+}
+
+// networkEndpoints извлекает адреса контейнера из данных inspect
+func networkEndpoints(info container.InspectResponse) []NetworkEndpoint {
+	var endpoints []NetworkEndpoint
+	if info.NetworkSettings == nil {
+		return endpoints
+	}
+
+	for name, settings := range info.NetworkSettings.Networks {
+		endpoints = append(endpoints, NetworkEndpoint{
+			NetworkName: name,
+			IPAddress:   settings.IPAddress,
+			IPv6Address: settings.GlobalIPv6Address,
+			Gateway:     settings.Gateway,
+			MacAddress:  settings.MacAddress,
+		})
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].NetworkName < endpoints[j].NetworkName
+	})
+
+	return endpoints
+}
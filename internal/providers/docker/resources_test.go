@@ -0,0 +1,181 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createRequest тело POST /containers/create, которое проверяют тесты
+type createRequest struct {
+	Image       string
+	Healthcheck *struct {
+		Test        []string
+		Interval    time.Duration
+		Timeout     time.Duration
+		StartPeriod time.Duration
+		Retries     int
+	}
+	NetworkingConfig struct {
+		EndpointsConfig map[string]*endpointRequest
+	}
+}
+
+// endpointRequest настройки подключения к сети в запросах create и connect
+type endpointRequest struct {
+	Aliases    []string
+	Links      []string
+	IPAMConfig *struct {
+		IPv4Address string
+		IPv6Address string
+	}
+}
+
+// fakeContainerAPI регистрирует обработчики создания и запуска контейнера.
+// Образ считается собранным docker_image, поэтому pull не выполняется.
+func fakeContainerAPI(t *testing.T, daemon *fakeDaemon) *createRequest {
+	t.Helper()
+	created := &createRequest{}
+	daemon.handle("GET /images/{name...}", func(r *http.Request) (int, interface{}) {
+		return http.StatusOK, map[string]interface{}{
+			"Id":     "sha256:5d4e1b2c",
+			"Config": map[string]interface{}{"Labels": map[string]string{builtImageLabel: "true"}},
+		}
+	})
+	daemon.handle("POST /containers/create", func(r *http.Request) (int, interface{}) {
+		if err := json.NewDecoder(r.Body).Decode(created); err != nil {
+			t.Errorf("invalid create request: %v", err)
+		}
+		return http.StatusCreated, map[string]interface{}{"Id": testContainerID}
+	})
+	daemon.handle("POST /containers/{id}/start", func(r *http.Request) (int, interface{}) {
+		return http.StatusNoContent, nil
+	})
+	return created
+}
+
+func TestCreateContainerHealthcheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		health *HealthCheck
+		want   []string
+	}{
+		{name: "image default", health: nil, want: nil},
+		{name: "custom", health: &HealthCheck{Test: []string{"CMD", "true"}, Interval: 5 * time.Second, Retries: 3}, want: []string{"CMD", "true"}},
+		{name: "disabled", health: &HealthCheck{Test: []string{"CMD", "true"}, Disable: true}, want: []string{"NONE"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			daemon, d := newFakeDaemon(t)
+			created := fakeContainerAPI(t, daemon)
+
+			id, err := d.CreateContainer(context.Background(), &ContainerConfig{Name: "web", Image: "app:local", HealthCheck: test.health})
+			if err != nil {
+				t.Fatalf("CreateContainer: %v", err)
+			}
+			if id != testContainerID {
+				t.Errorf("id = %s, want %s", id, testContainerID)
+			}
+			if daemon.count("POST /images/create") != 0 {
+				t.Error("image built by docker_image was pulled")
+			}
+
+			if test.want == nil {
+				if created.Healthcheck != nil {
+					t.Errorf("healthcheck = %+v, want the image default", created.Healthcheck)
+				}
+				return
+			}
+			if created.Healthcheck == nil || !reflect.DeepEqual(created.Healthcheck.Test, test.want) {
+				t.Fatalf("healthcheck = %+v, want test %v", created.Healthcheck, test.want)
+			}
+			if !test.health.Disable && (created.Healthcheck.Interval != test.health.Interval || created.Healthcheck.Retries != test.health.Retries) {
+				t.Errorf("healthcheck = %+v, want %+v", created.Healthcheck, test.health)
+			}
+		})
+	}
+}
+
+func TestCreateContainerNetworks(t *testing.T) {
+	daemon, d := newFakeDaemon(t)
+	created := fakeContainerAPI(t, daemon)
+
+	connected := make(map[string]*endpointRequest)
+	daemon.handle("POST /networks/{name}/connect", func(r *http.Request) (int, interface{}) {
+		var body struct {
+			Container      string
+			EndpointConfig *endpointRequest
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid connect request: %v", err)
+		}
+		if body.Container != testContainerID {
+			t.Errorf("connected container %s, want %s", body.Container, testContainerID)
+		}
+		connected[r.PathValue("name")] = body.EndpointConfig
+		return http.StatusOK, nil
+	})
+
+	_, err := d.CreateContainer(context.Background(), &ContainerConfig{
+		Name:  "web",
+		Image: "app:local",
+		Networks: []NetworkAttachment{
+			{Name: "frontend", Aliases: []string{"web", "www"}, IPv4Address: "172.28.0.10"},
+			{Name: "backend", Links: []string{"db:database"}, IPv6Address: "fd00::10"},
+			{Name: "metrics"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateContainer: %v", err)
+	}
+
+	// Первая сеть подключается при создании, чтобы контейнер не попал в bridge
+	endpoints := created.NetworkingConfig.EndpointsConfig
+	if len(endpoints) != 1 || endpoints["frontend"] == nil {
+		t.Fatalf("networks on create = %v, want only frontend", endpoints)
+	}
+	frontend := endpoints["frontend"]
+	if !reflect.DeepEqual(frontend.Aliases, []string{"web", "www"}) || frontend.IPAMConfig == nil || frontend.IPAMConfig.IPv4Address != "172.28.0.10" {
+		t.Errorf("frontend endpoint = %+v", frontend)
+	}
+
+	if len(connected) != 2 {
+		t.Fatalf("connected networks = %v, want backend and metrics", connected)
+	}
+	backend := connected["backend"]
+	if backend == nil || !reflect.DeepEqual(backend.Links, []string{"db:database"}) || backend.IPAMConfig == nil || backend.IPAMConfig.IPv6Address != "fd00::10" {
+		t.Errorf("backend endpoint = %+v", backend)
+	}
+	if metrics, exists := connected["metrics"]; !exists || metrics == nil || metrics.IPAMConfig != nil {
+		t.Errorf("metrics endpoint = %+v, want no static address", metrics)
+	}
+}
+
+func TestCreateContainerNetworkConnectFails(t *testing.T) {
+	daemon, d := newFakeDaemon(t)
+	fakeContainerAPI(t, daemon)
+	daemon.handle("POST /networks/{name}/connect", func(r *http.Request) (int, interface{}) {
+		return http.StatusBadRequest, apiError("invalid IPv4 address")
+	})
+
+	id, err := d.CreateContainer(context.Background(), &ContainerConfig{
+		Name:     "web",
+		Image:    "app:local",
+		Networks: []NetworkAttachment{{Name: "frontend"}, {Name: "backend", IPv4Address: "10.0.0.300"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to connect container to network backend") {
+		t.Fatalf("error = %v, want a connect error", err)
+	}
+	// Контейнер уже создан: ID возвращается, чтобы движок сохранил его как tainted
+	if id != testContainerID {
+		t.Errorf("id = %q, want the created container", id)
+	}
+	if daemon.count("POST /containers/"+testContainerID+"/start") != 0 {
+		t.Error("container was started after a failed network connect")
+	}
+}
@@ -0,0 +1,82 @@
+package docker
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Artemka007/derraform/internal/errors"
+	"github.com/docker/docker/errdefs"
+)
+
+const (
+	// retryAttempts сколько раз выполнять операцию, включая первую попытку
+	retryAttempts = 4
+	// Задержка перед повтором растет вдвое с каждой попыткой, но не выше retryMaxDelay
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 8 * time.Second
+)
+
+// withRetry выполняет операцию Docker API и повторяет ее при временных ошибках
+// (errors.IsRetryable) с экспоненциальной задержкой со случайным разбросом.
+// Повторять можно только идемпотентные операции.
+func withRetry[T any](ctx context.Context, d *DockerClient, operation string, fn func() (T, error)) (T, error) {
+	return retryWhile(ctx, d, operation, errors.IsRetryable, fn)
+}
+
+// retryWhile повторяет операцию, пока retryable считает ошибку временной
+func retryWhile[T any](ctx context.Context, d *DockerClient, operation string, retryable func(error) bool, fn func() (T, error)) (T, error) {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		result, err := fn()
+		if err == nil || attempt == retryAttempts || !retryable(err) || ctx.Err() != nil {
+			return result, err
+		}
+
+		// Половина задержки фиксирована, вторая случайна: одновременные повторы расходятся
+		wait := delay/2 + rand.N(delay/2+1)
+		d.logger.Warn("%s failed (attempt %d of %d), retrying in %s: %v", operation, attempt, retryAttempts, wait.Round(time.Millisecond), err)
+
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(wait):
+		}
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+// retryCall withRetry для операций без результата
+func retryCall(ctx context.Context, d *DockerClient, operation string, fn func() error) error {
+	_, err := withRetry(ctx, d, operation, func() (struct{}, error) {
+		return struct{}{}, fn()
+	})
+	return err
+}
+
+// retryRemove retryCall для удаления объекта. NotFound на повторной попытке считается
+// успехом: значит, предыдущая попытка удалила объект, но ответ до нас не дошел.
+func retryRemove(ctx context.Context, d *DockerClient, operation string, fn func() error) error {
+	attempt := 0
+	return retryCall(ctx, d, operation, func() error {
+		attempt++
+		err := fn()
+		if attempt > 1 && errdefs.IsNotFound(err) {
+			d.logger.Debug("%s: object is already removed by a previous attempt", operation)
+			return nil
+		}
+		return err
+	})
+}
+
+// retryConnect retryCall для подключения контейнера к сети. Кроме временных ошибок
+// повторяется "network not found": сеть, созданная в этом же apply, может быть еще не видна демону.
+func retryConnect(ctx context.Context, d *DockerClient, operation string, fn func() error) error {
+	retryable := func(err error) bool {
+		return errors.IsRetryable(err) || errors.IsNetworkNotFound(err)
+	}
+	_, err := retryWhile(ctx, d, operation, retryable, func() (struct{}, error) {
+		return struct{}{}, fn()
+	})
+	return err
+}
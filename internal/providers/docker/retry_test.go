@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/docker/docker/errdefs"
+)
+
+func TestRetryRemove(t *testing.T) {
+	d := &DockerClient{logger: logging.NewLogger(logging.OFF)}
+	gone := errdefs.NotFound(errors.New("no such container"))
+	reset := syscall.ECONNRESET
+
+	tests := []struct {
+		name     string
+		results  []error
+		wantErr  bool
+		attempts int
+	}{
+		{name: "first attempt succeeds", results: []error{nil}, attempts: 1},
+		{name: "not found after lost response", results: []error{reset, gone}, attempts: 2},
+		{name: "not found on first attempt", results: []error{gone}, wantErr: true, attempts: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			err := retryRemove(context.Background(), d, "Removing container", func() error {
+				err := test.results[attempts]
+				attempts++
+				return err
+			})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, test.wantErr)
+			}
+			if attempts != test.attempts {
+				t.Errorf("attempts = %d, want %d", attempts, test.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryConnect(t *testing.T) {
+	d := &DockerClient{logger: logging.NewLogger(logging.OFF)}
+	networkMissing := errdefs.NotFound(errors.New("network backend not found"))
+	containerMissing := errdefs.NotFound(errors.New("No such container: 3f2a9c"))
+
+	tests := []struct {
+		name     string
+		results  []error
+		wantErr  bool
+		attempts int
+	}{
+		{name: "network appears on retry", results: []error{networkMissing, nil}, attempts: 2},
+		{name: "network never appears", results: []error{networkMissing, networkMissing, networkMissing, networkMissing}, wantErr: true, attempts: retryAttempts},
+		{name: "missing container is final", results: []error{containerMissing}, wantErr: true, attempts: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			err := retryConnect(context.Background(), d, "Connecting container to network backend", func() error {
+				err := test.results[attempts]
+				attempts++
+				return err
+			})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, test.wantErr)
+			}
+			if attempts != test.attempts {
+				t.Errorf("attempts = %d, want %d", attempts, test.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryRemoveNetworkNotFound(t *testing.T) {
+	d := &DockerClient{logger: logging.NewLogger(logging.OFF)}
+	attempts := 0
+	err := retryRemove(context.Background(), d, "Removing network", func() error {
+		attempts++
+		return errdefs.NotFound(errors.New("network backend not found"))
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("retryRemove = %v after %d attempts, want NotFound after 1 attempt", err, attempts)
+	}
+}
@@ -0,0 +1,125 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	// DefaultWaitTimeout время ожидания готовности контейнера по умолчанию
+	DefaultWaitTimeout = 60 * time.Second
+
+	waitPollInterval = time.Second
+	waitLogTail      = "50"
+)
+
+// WaitForContainer блокируется, пока контейнер не станет healthy.
+// Если healthcheck не задан, достаточно состояния running.
+func (d *DockerClient) WaitForContainer(ctx context.Context, containerID string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultWaitTimeout
+	}
+
+	d.logger.Info("Waiting for container %s to become ready (timeout %s)", containerID[:12], timeout)
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	for {
+		ready, err := d.containerReady(waitCtx, containerID)
+		if err != nil {
+			return d.withContainerLogs(ctx, containerID, err)
+		}
+		if ready {
+			d.logger.Info("Container %s is ready", containerID[:12])
+			return nil
+		}
+
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return fmt.Errorf("interrupted while waiting for container to become ready: %w", ctx.Err())
+			}
+			return d.withContainerLogs(ctx, containerID,
+				fmt.Errorf("timed out after %s waiting for container to become ready", timeout))
+		case <-ticker.C:
+		}
+	}
+}
+
+// containerReady проверяет состояние контейнера. Возвращает ошибку,
+// если контейнер уже не сможет стать готовым.
+func (d *DockerClient) containerReady(ctx context.Context, containerID string) (bool, error) {
+	info, err := d.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	state := info.State
+	if state == nil {
+		return false, nil
+	}
+
+	if state.Status == container.StateExited || state.Status == container.StateDead {
+		return false, fmt.Errorf("container exited with code %d", state.ExitCode)
+	}
+
+	if state.Health == nil || state.Health.Status == container.NoHealthcheck {
+		return state.Running, nil
+	}
+
+	switch state.Health.Status {
+	case container.Healthy:
+		return true, nil
+	case container.Unhealthy:
+		return false, fmt.Errorf("container is unhealthy")
+	default:
+		d.logger.Debug("Container %s health status: %s", containerID[:12], state.Health.Status)
+		return false, nil
+	}
+}
+
+// withContainerLogs дополняет ошибку последними строками логов контейнера
+func (d *DockerClient) withContainerLogs(ctx context.Context, containerID string, cause error) error {
+	logs, err := d.ContainerLogs(ctx, containerID)
+	if err != nil {
+		d.logger.Warn("Failed to read logs of container %s: %v", containerID[:12], err)
+		return cause
+	}
+	if logs == "" {
+		return cause
+	}
+	return fmt.Errorf("%w\nLast container logs:\n%s", cause, logs)
+}
+
+// ContainerLogs возвращает последние строки stdout/stderr контейнера
+func (d *DockerClient) ContainerLogs(ctx context.Context, containerID string) (string, error) {
+	reader, err := d.cli.ContainerLogs(ctx, containerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       waitLogTail,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, reader); err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(stdout.String()+stderr.String(), "\n"), nil
+}
@@ -0,0 +1,191 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const testContainerID = "3f2a9c1b7d4e5f60718293a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4"
+
+func containerState(status, health string, exitCode int) map[string]interface{} {
+	state := map[string]interface{}{
+		"Status":   status,
+		"Running":  status == "running",
+		"ExitCode": exitCode,
+	}
+	if health != "" {
+		state["Health"] = map[string]interface{}{"Status": health}
+	}
+	return map[string]interface{}{"Id": testContainerID, "State": state}
+}
+
+func TestWaitForContainer(t *testing.T) {
+	tests := []struct {
+		name    string
+		states  []map[string]interface{}
+		timeout time.Duration
+		err     string
+		logs    bool
+	}{
+		{
+			name:   "healthy",
+			states: []map[string]interface{}{containerState("running", "healthy", 0)},
+		},
+		{
+			name:   "running without healthcheck",
+			states: []map[string]interface{}{containerState("running", "", 0)},
+		},
+		{
+			name:   "running with disabled healthcheck",
+			states: []map[string]interface{}{containerState("running", "none", 0)},
+		},
+		{
+			name: "becomes healthy",
+			states: []map[string]interface{}{
+				containerState("running", "starting", 0),
+				containerState("running", "healthy", 0),
+			},
+		},
+		{
+			name:   "unhealthy",
+			states: []map[string]interface{}{containerState("running", "unhealthy", 0)},
+			err:    "container is unhealthy",
+			logs:   true,
+		},
+		{
+			name:   "exited",
+			states: []map[string]interface{}{containerState("exited", "", 3)},
+			err:    "container exited with code 3",
+			logs:   true,
+		},
+		{
+			name:    "timeout",
+			states:  []map[string]interface{}{containerState("running", "starting", 0)},
+			timeout: 100 * time.Millisecond,
+			err:     "timed out after 100ms waiting for container to become ready",
+			logs:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			daemon, d := newFakeDaemon(t)
+			var polls atomic.Int32
+			daemon.handle("GET /containers/{id}/json", func(r *http.Request) (int, interface{}) {
+				n := int(polls.Add(1)) - 1
+				return http.StatusOK, test.states[min(n, len(test.states)-1)]
+			})
+			daemon.mux.HandleFunc("GET /containers/{id}/logs", func(w http.ResponseWriter, r *http.Request) {
+				w.Write(multiplexed(1, "listening on :8080\n"))
+				w.Write(multiplexed(2, "fatal: database is not reachable\n"))
+			})
+
+			timeout := test.timeout
+			if timeout == 0 {
+				timeout = 10 * time.Second
+			}
+			err := d.WaitForContainer(context.Background(), testContainerID, timeout)
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if want := len(test.states); int(polls.Load()) != want {
+					t.Errorf("container inspected %d times, want %d", polls.Load(), want)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("error = %v, want it to contain %q", err, test.err)
+			}
+			if test.logs && !strings.Contains(err.Error(), "Last container logs:\nlistening on :8080\nfatal: database is not reachable") {
+				t.Errorf("error does not include container logs: %v", err)
+			}
+		})
+	}
+}
+
+func TestWaitForContainerInterrupted(t *testing.T) {
+	daemon, d := newFakeDaemon(t)
+	daemon.handle("GET /containers/{id}/json", func(r *http.Request) (int, interface{}) {
+		return http.StatusOK, containerState("running", "starting", 0)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := d.WaitForContainer(ctx, testContainerID, time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "interrupted while waiting") {
+		t.Fatalf("error = %v, want an interruption error", err)
+	}
+}
+
+func TestBlockToHealthCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes map[string]cty.Value
+		want       HealthCheck
+		err        string
+	}{
+		{
+			name: "full",
+			attributes: map[string]cty.Value{
+				"test":         cty.ListVal([]cty.Value{cty.StringVal("CMD"), cty.StringVal("curl"), cty.StringVal("-f"), cty.StringVal("http://localhost/")}),
+				"interval":     cty.StringVal("5s"),
+				"timeout":      cty.StringVal("2s"),
+				"start_period": cty.StringVal("1m"),
+				"retries":      cty.NumberIntVal(3),
+			},
+			want: HealthCheck{
+				Test:        []string{"CMD", "curl", "-f", "http://localhost/"},
+				Interval:    5 * time.Second,
+				Timeout:     2 * time.Second,
+				StartPeriod: time.Minute,
+				Retries:     3,
+			},
+		},
+		{
+			name:       "disable without test",
+			attributes: map[string]cty.Value{"disable": cty.True},
+			want:       HealthCheck{Disable: true},
+		},
+		{
+			name:       "missing test",
+			attributes: map[string]cty.Value{"interval": cty.StringVal("5s")},
+			err:        "missing required attribute 'test'",
+		},
+		{
+			name: "invalid interval",
+			attributes: map[string]cty.Value{
+				"test":     cty.ListVal([]cty.Value{cty.StringVal("CMD-SHELL"), cty.StringVal("true")}),
+				"interval": cty.StringVal("often"),
+			},
+			err: "interval",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := blockToHealthCheck(config.Block{Type: "healthcheck", Attributes: test.attributes})
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(got.Test, " ") != strings.Join(test.want.Test, " ") ||
+				got.Interval != test.want.Interval || got.Timeout != test.want.Timeout ||
+				got.StartPeriod != test.want.StartPeriod || got.Retries != test.want.Retries || got.Disable != test.want.Disable {
+				t.Errorf("health check = %+v, want %+v", *got, test.want)
+			}
+		})
+	}
+}
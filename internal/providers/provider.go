@@ -0,0 +1,120 @@
+// internal/providers/provider.go
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/Artemka007/derraform/internal/state"
+)
+
+// Provider набор типов ресурсов, работающих с одной системой (Docker и т.п.).
+// Для каждого блока provider (включая alias) создается отдельный экземпляр.
+type Provider interface {
+	// Schema описывает атрибуты блока provider
+	Schema() Schema
+	// Configure применяет настройки блока provider. Вызывается один раз до работы с ресурсами.
+	Configure(ctx context.Context, cfg config.Provider) error
+	// Resources возвращает реализации ресурсов по имени типа
+	Resources() map[string]Resource
+}
+
+// Resource жизненный цикл одного типа ресурса
+type Resource interface {
+	// Schema описывает атрибуты и вложенные блоки ресурса
+	Schema() Schema
+	// Plan сравнивает конфигурацию с сохраненным состоянием (prior == nil, если ресурса нет)
+	Plan(ctx context.Context, cfg config.Resource, prior *state.ResourceState) (*PlannedChange, error)
+	// Apply создает или обновляет объект. Может вернуть состояние вместе с ошибкой,
+	// если объект уже создан, но не доведен до готовности.
+	Apply(ctx context.Context, cfg config.Resource, prior *state.ResourceState, change *PlannedChange) (*state.ResourceState, error)
+	// Read читает актуальное состояние объекта. Возвращает nil, если объект удален вне нас.
+	Read(ctx context.Context, current *state.ResourceState) (*state.ResourceState, error)
+	// Delete удаляет объект
+	Delete(ctx context.Context, current *state.ResourceState) error
+	// Import читает существующий объект по его ID
+	Import(ctx context.Context, id string) (*state.ResourceState, error)
+}
+
+// StateUpgrader реализуют ресурсы, у которых менялась раскладка атрибутов в state.
+// Движок вызывает UpgradeState, если schema_version записи меньше Schema().Version.
+type StateUpgrader interface {
+	// UpgradeState переводит атрибуты из версии схемы version в текущую
+	UpgradeState(ctx context.Context, version int, attributes map[string]interface{}) (map[string]interface{}, error)
+}
+
+// LoggerSetter реализуют провайдеры, которые пишут в общий лог движка.
+// Движок вызывает SetLogger сразу после создания провайдера, до Configure.
+type LoggerSetter interface {
+	SetLogger(logger *logging.Logger)
+}
+
+// Action тип изменения ресурса
+type Action string
+
+const (
+	ActionNoop    Action = "no-op"
+	ActionCreate  Action = "create"
+	ActionUpdate  Action = "update"
+	ActionReplace Action = "replace"
+	ActionDelete  Action = "delete"
+)
+
+// PlannedChange результат планирования одного ресурса
+type PlannedChange struct {
+	Action Action
+	// Reason поясняет, почему нужна замена или обновление
+	Reason string
+}
+
+// Factory создает неконфигурированный экземпляр провайдера
+type Factory func() Provider
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register регистрирует провайдер. Обычно вызывается из init() пакета провайдера.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("provider %s is already registered", name))
+	}
+	registry[name] = factory
+}
+
+// Lookup возвращает фабрику зарегистрированного провайдера
+func Lookup(name string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	factory, exists := registry[name]
+	return factory, exists
+}
+
+// Registered возвращает имена всех зарегистрированных провайдеров
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProviderName возвращает имя провайдера по типу ресурса: docker_container -> docker
+func ProviderName(resourceType string) string {
+	name, _, _ := strings.Cut(resourceType, "_")
+	return name
+}
@@ -0,0 +1,123 @@
+// internal/providers/schema.go
+package providers
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Schema описание атрибутов и вложенных блоков ресурса или провайдера
+type Schema struct {
+	// Version версия схемы ресурса. Увеличивается, когда меняется раскладка
+	// атрибутов в state; старые записи переводит StateUpgrader.
+	Version    int
+	Attributes map[string]*Attribute
+	Blocks     map[string]*NestedBlock
+}
+
+// Attribute описание одного атрибута
+type Attribute struct {
+	Type        cty.Type
+	Description string
+	Required    bool
+	Optional    bool
+	// Computed атрибут заполняется провайдером и не задается в конфигурации
+	Computed bool
+	// Sensitive значение атрибута не должно попадать в вывод
+	Sensitive bool
+	// Unique значение не может повторяться у двух объектов провайдера (имя контейнера).
+	// Замена с create_before_destroy при неизменном значении невозможна.
+	Unique bool
+}
+
+// NestedBlock описание вложенного блока
+type NestedBlock struct {
+	Schema
+	MinItems int
+	// MaxItems 0 означает отсутствие ограничения
+	MaxItems int
+}
+
+// metaArguments обрабатываются движком и не входят в схему ресурса
+var metaArguments = map[string]bool{
+	"depends_on": true,
+	"count":      true,
+	"for_each":   true,
+	"lifecycle":  true,
+	"provider":   true,
+}
+
+// ValidateResource проверяет конфигурацию ресурса по схеме
+func ValidateResource(schema Schema, cfg config.Resource) error {
+	return validateBody(schema, cfg.Attributes, cfg.Blocks, true)
+}
+
+// ValidateProvider проверяет блок provider по схеме
+func ValidateProvider(schema Schema, cfg config.Provider) error {
+	attributes := make(map[string]cty.Value, len(cfg.Attributes))
+	for name, value := range cfg.Attributes {
+		if name != "alias" {
+			attributes[name] = value
+		}
+	}
+	return validateBody(schema, attributes, cfg.Blocks, false)
+}
+
+func validateBody(schema Schema, attributes map[string]cty.Value, blocks []config.Block, allowMeta bool) error {
+	for _, name := range sortedNames(attributes) {
+		if allowMeta && metaArguments[name] {
+			continue
+		}
+		attr, exists := schema.Attributes[name]
+		if !exists {
+			return fmt.Errorf("unsupported attribute '%s'", name)
+		}
+		if attr.Computed && !attr.Optional && !attr.Required {
+			return fmt.Errorf("attribute '%s' is computed and cannot be set", name)
+		}
+	}
+
+	for name, attr := range schema.Attributes {
+		if _, exists := attributes[name]; attr.Required && !exists {
+			return fmt.Errorf("missing required attribute '%s'", name)
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, block := range blocks {
+		if allowMeta && metaArguments[block.Type] {
+			continue
+		}
+		nested, exists := schema.Blocks[block.Type]
+		if !exists {
+			return fmt.Errorf("unsupported block '%s'", block.Type)
+		}
+		if err := validateBody(nested.Schema, block.Attributes, block.Blocks, false); err != nil {
+			return fmt.Errorf("invalid %s block: %w", block.Type, err)
+		}
+		counts[block.Type]++
+	}
+
+	for name, nested := range schema.Blocks {
+		if counts[name] < nested.MinItems {
+			return fmt.Errorf("at least %d '%s' block(s) required", nested.MinItems, name)
+		}
+		if nested.MaxItems > 0 && counts[name] > nested.MaxItems {
+			return fmt.Errorf("at most %d '%s' block(s) allowed", nested.MaxItems, name)
+		}
+	}
+
+	return nil
+}
+
+func sortedNames(attributes map[string]cty.Value) []string {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
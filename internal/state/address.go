@@ -0,0 +1,100 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Address адрес записи state: docker_container.web, docker_container.web[0]
+// (экземпляр count) или docker_container.web["blue"] (экземпляр for_each)
+type Address struct {
+	Type string
+	Name string
+	// Key ключ экземпляра в каноническом виде, например [0] или ["blue"].
+	// Пустая строка - адрес ресурса без индекса.
+	Key string
+}
+
+// ParseAddress разбирает адрес ресурса в синтаксисе HCL
+func ParseAddress(s string) (Address, error) {
+	traversal, diags := hclsyntax.ParseTraversalAbs([]byte(s), "", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return Address{}, fmt.Errorf("invalid resource address %q: %s", s, diags.Error())
+	}
+	if len(traversal) < 2 || len(traversal) > 3 {
+		return Address{}, fmt.Errorf("invalid resource address %q: expected TYPE.NAME, TYPE.NAME[INDEX] or TYPE.NAME[\"KEY\"]", s)
+	}
+
+	root, _ := traversal[0].(hcl.TraverseRoot)
+	name, ok := traversal[1].(hcl.TraverseAttr)
+	if !ok {
+		return Address{}, fmt.Errorf("invalid resource address %q: expected TYPE.NAME", s)
+	}
+	address := Address{Type: root.Name, Name: name.Name}
+
+	if len(traversal) == 3 {
+		index, ok := traversal[2].(hcl.TraverseIndex)
+		if !ok {
+			return Address{}, fmt.Errorf("invalid resource address %q: only an index may follow TYPE.NAME", s)
+		}
+		key, err := instanceKey(index.Key)
+		if err != nil {
+			return Address{}, fmt.Errorf("invalid resource address %q: %w", s, err)
+		}
+		address.Key = key
+	}
+	return address, nil
+}
+
+// instanceKey приводит индекс к каноническому виду: целое число или строка в кавычках
+func instanceKey(key cty.Value) (string, error) {
+	if key.IsNull() || !key.IsKnown() {
+		return "", fmt.Errorf("index must be a number or a string")
+	}
+	switch key.Type() {
+	case cty.Number:
+		index, accuracy := key.AsBigFloat().Int64()
+		if accuracy != 0 || index < 0 {
+			return "", fmt.Errorf("count index must be a non-negative integer")
+		}
+		return fmt.Sprintf("[%d]", index), nil
+	case cty.String:
+		return "[" + string(hclwrite.TokensForValue(key).Bytes()) + "]", nil
+	default:
+		return "", fmt.Errorf("index must be a number or a string")
+	}
+}
+
+func (a Address) String() string {
+	return a.Type + "." + a.Name + a.Key
+}
+
+// Resource адрес ресурса без индекса экземпляра
+func (a Address) Resource() Address {
+	return Address{Type: a.Type, Name: a.Name}
+}
+
+// Matches сообщает, относится ли запись other к этому адресу. Адрес без индекса
+// охватывает и сам ресурс, и все его экземпляры.
+func (a Address) Matches(other Address) bool {
+	if a.Key != "" {
+		return a == other
+	}
+	return a == other.Resource()
+}
+
+// Addresses разбирает ключи записей state. Ключи, которые не являются адресами,
+// пропускаются: такие записи можно только удалить вручную.
+func (s *State) Addresses() map[string]Address {
+	addresses := make(map[string]Address, len(s.Resources))
+	for key := range s.Resources {
+		if address, err := ParseAddress(key); err == nil {
+			addresses[key] = address
+		}
+	}
+	return addresses
+}
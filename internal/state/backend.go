@@ -0,0 +1,41 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Backend хранилище state: локальный файл, HTTP сервер, S3 и т.п.
+// Serial, lineage и формат файла обрабатывает StateManager, бэкенд хранит байты.
+type Backend interface {
+	// String описывает, где хранится state: terraform.tfstate, s3://bucket/key
+	fmt.Stringer
+
+	// Read возвращает содержимое state или nil, если state еще нет
+	Read(ctx context.Context) ([]byte, error)
+	// Write заменяет state целиком
+	Write(ctx context.Context, data []byte) error
+	// Lock берет блокировку. Если она занята, возвращает *LockError.
+	// stale - блокировка, оставленная упавшим процессом и замененная новой,
+	// если бэкенд умеет это определять.
+	Lock(ctx context.Context, info LockInfo) (stale *LockInfo, err error)
+	// Unlock снимает блокировку с указанным ID, в том числе чужую (force-unlock)
+	Unlock(ctx context.Context, id string) error
+}
+
+// LockInspector реализуют бэкенды, которые умеют показать текущую блокировку
+type LockInspector interface {
+	LockInfo(ctx context.Context) (*LockInfo, error)
+}
+
+// ErrWorkspacesNotListable бэкенд хранит state разных workspace, но не умеет их перечислить
+var ErrWorkspacesNotListable = errors.New("backend cannot list workspaces")
+
+// WorkspaceManager реализуют бэкенды, которые хранят state нескольких workspace
+type WorkspaceManager interface {
+	// Workspaces возвращает имена workspace с сохраненным state, всегда включая default
+	Workspaces(ctx context.Context) ([]string, error)
+	// DeleteWorkspace удаляет state workspace вместе с его блокировкой
+	DeleteWorkspace(ctx context.Context, name string) error
+}
@@ -0,0 +1,106 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Filter возвращает отсортированные адреса записей, которые подходят под любой
+// из адресов (все записи, если адресов нет) и, если id не пуст, имеют такой ID
+func (s *State) Filter(addresses []Address, id string) []string {
+	var result []string
+	for key, address := range s.Addresses() {
+		if id != "" && s.Resources[key].ID != id {
+			continue
+		}
+		if len(addresses) > 0 && !matchesAny(addresses, address) {
+			continue
+		}
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func matchesAny(addresses []Address, address Address) bool {
+	for _, filter := range addresses {
+		if filter.Matches(address) {
+			return true
+		}
+	}
+	return false
+}
+
+// Move переименовывает записи src в dst и возвращает пары старый -> новый адрес.
+// Адрес без индекса переносит ресурс вместе со всеми экземплярами, сохраняя их ключи,
+// а адрес с индексом позволяет перенести ресурс в экземпляр count/for_each и обратно.
+func (s *State) Move(src, dst Address) ([][2]string, error) {
+	if src.Type != dst.Type {
+		return nil, fmt.Errorf("cannot move %s to %s: resource type cannot be changed", src, dst)
+	}
+
+	var moves [][2]string
+	for _, key := range s.Filter([]Address{src}, "") {
+		address, _ := ParseAddress(key)
+		target := dst
+		if src.Key == "" && address.Key != "" {
+			if dst.Key != "" {
+				return nil, fmt.Errorf("cannot move the instances of %s into the single instance %s", src, dst)
+			}
+			target.Key = address.Key
+		}
+		moves = append(moves, [2]string{key, target.String()})
+	}
+	if len(moves) == 0 {
+		return nil, fmt.Errorf("no resource in state matches %s", src)
+	}
+
+	// Проверяем все цели до изменения, чтобы не оставить state наполовину перенесенным
+	for _, move := range moves {
+		if _, exists := s.Resources[move[1]]; exists && move[0] != move[1] {
+			return nil, fmt.Errorf("cannot move %s to %s: the destination already exists in state", move[0], move[1])
+		}
+	}
+
+	moved := make(map[string]ResourceState, len(moves))
+	for _, move := range moves {
+		moved[move[1]] = s.Resources[move[0]]
+		delete(s.Resources, move[0])
+	}
+	for key, resourceState := range moved {
+		s.Resources[key] = resourceState
+	}
+	return moves, nil
+}
+
+// Remove удаляет записи, подходящие под адреса, и возвращает их отсортированный список
+func (s *State) Remove(addresses []Address) ([]string, error) {
+	removed := s.Filter(addresses, "")
+	if len(removed) == 0 {
+		return nil, fmt.Errorf("no resource in state matches the given addresses")
+	}
+	for _, key := range removed {
+		delete(s.Resources, key)
+	}
+	return removed, nil
+}
+
+// ReplaceProvider переназначает записи провайдера from на провайдер to.
+// defaultProvider вычисляет провайдер записи без явного provider по типу ресурса.
+func (s *State) ReplaceProvider(from, to string, defaultProvider func(resourceType string) string) []string {
+	var replaced []string
+	for key, resourceState := range s.Resources {
+		current := resourceState.Provider
+		if current == "" {
+			current = defaultProvider(resourceState.Type)
+		}
+		if current != from {
+			continue
+		}
+		resourceState.Provider = to
+		s.Resources[key] = resourceState
+		replaced = append(replaced, key)
+	}
+	sort.Strings(replaced)
+	return replaced
+}
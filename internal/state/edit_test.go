@@ -0,0 +1,359 @@
+package state
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// newEditState state с записями по адресам, ID записи совпадает с адресом
+func newEditState(addresses ...string) *State {
+	s := &State{Resources: make(map[string]ResourceState)}
+	for _, address := range addresses {
+		resourceType, _, _ := strings.Cut(address, ".")
+		s.Resources[address] = ResourceState{Type: resourceType, ID: address}
+	}
+	return s
+}
+
+func mustParseAddress(t *testing.T, s string) Address {
+	t.Helper()
+	address, err := ParseAddress(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return address
+}
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Address
+		err   string
+	}{
+		{input: "docker_container.web", want: Address{Type: "docker_container", Name: "web"}},
+		{input: "docker_container.web[0]", want: Address{Type: "docker_container", Name: "web", Key: "[0]"}},
+		{input: "docker_container.web[12]", want: Address{Type: "docker_container", Name: "web", Key: "[12]"}},
+		{input: `docker_container.web["blue"]`, want: Address{Type: "docker_container", Name: "web", Key: `["blue"]`}},
+		{input: `docker_container.web["a \"b\""]`, want: Address{Type: "docker_container", Name: "web", Key: `["a \"b\""]`}},
+		{input: "docker_container", err: "expected TYPE.NAME"},
+		{input: "docker_container.web.name", err: "only an index may follow TYPE.NAME"},
+		{input: "docker_container.web[0][1]", err: "expected TYPE.NAME, TYPE.NAME[INDEX]"},
+		{input: "docker_container[0]", err: "expected TYPE.NAME"},
+		{input: "docker_container.web[-1]", err: "invalid resource address"},
+		{input: "docker_container.web[1.5]", err: "count index must be a non-negative integer"},
+		{input: "docker_container.web[true]", err: "Index brackets must contain either a literal number or a literal string"},
+		{input: "docker container.web", err: "invalid resource address"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := ParseAddress(test.input)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("address = %+v, want %+v", got, test.want)
+			}
+			if got.String() != test.input {
+				t.Errorf("String() = %s, want %s", got, test.input)
+			}
+		})
+	}
+}
+
+func TestAddressMatches(t *testing.T) {
+	tests := []struct {
+		filter  string
+		address string
+		want    bool
+	}{
+		{"docker_container.web", "docker_container.web", true},
+		{"docker_container.web", "docker_container.web[0]", true},
+		{"docker_container.web", `docker_container.web["blue"]`, true},
+		{"docker_container.web[0]", "docker_container.web[0]", true},
+		{"docker_container.web[0]", "docker_container.web[1]", false},
+		{"docker_container.web[0]", "docker_container.web", false},
+		{"docker_container.web", "docker_container.website", false},
+		{"docker_container.web", "docker_image.web", false},
+	}
+
+	for _, test := range tests {
+		filter := mustParseAddress(t, test.filter)
+		if got := filter.Matches(mustParseAddress(t, test.address)); got != test.want {
+			t.Errorf("%s matches %s = %v, want %v", test.filter, test.address, got, test.want)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	s := newEditState("docker_container.web[0]", "docker_container.web[1]", "docker_container.db", "docker_network.app")
+	// Ключ, который не разбирается как адрес, не попадает в выборку
+	s.Resources["not an address"] = ResourceState{ID: "x"}
+
+	tests := []struct {
+		name    string
+		filters []string
+		id      string
+		want    []string
+	}{
+		{name: "all", want: []string{"docker_container.db", "docker_container.web[0]", "docker_container.web[1]", "docker_network.app"}},
+		{name: "resource", filters: []string{"docker_container.web"}, want: []string{"docker_container.web[0]", "docker_container.web[1]"}},
+		{name: "instance", filters: []string{"docker_container.web[1]"}, want: []string{"docker_container.web[1]"}},
+		{name: "several", filters: []string{"docker_network.app", "docker_container.db"}, want: []string{"docker_container.db", "docker_network.app"}},
+		{name: "id", id: "docker_container.web[0]", want: []string{"docker_container.web[0]"}},
+		{name: "id and other address", filters: []string{"docker_container.db"}, id: "docker_container.web[0]"},
+		{name: "no match", filters: []string{"docker_volume.data"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var filters []Address
+			for _, filter := range test.filters {
+				filters = append(filters, mustParseAddress(t, filter))
+			}
+			if got := s.Filter(filters, test.id); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Filter = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMove(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    []string
+		src, dst string
+		// want адреса после переноса: новый адрес -> ID (исходный адрес)
+		want map[string]string
+		err  string
+	}{
+		{
+			name:  "rename",
+			state: []string{"docker_container.web", "docker_container.db"},
+			src:   "docker_container.web", dst: "docker_container.frontend",
+			want: map[string]string{"docker_container.frontend": "docker_container.web", "docker_container.db": "docker_container.db"},
+		},
+		{
+			name:  "rename with instances",
+			state: []string{"docker_container.web[0]", `docker_container.web["blue"]`},
+			src:   "docker_container.web", dst: "docker_container.frontend",
+			want: map[string]string{"docker_container.frontend[0]": "docker_container.web[0]", `docker_container.frontend["blue"]`: `docker_container.web["blue"]`},
+		},
+		{
+			name:  "single resource into count",
+			state: []string{"docker_container.web"},
+			src:   "docker_container.web", dst: "docker_container.web[0]",
+			want: map[string]string{"docker_container.web[0]": "docker_container.web"},
+		},
+		{
+			name:  "instance out of for_each",
+			state: []string{`docker_container.web["blue"]`, `docker_container.web["green"]`},
+			src:   `docker_container.web["blue"]`, dst: "docker_container.blue",
+			want: map[string]string{"docker_container.blue": `docker_container.web["blue"]`, `docker_container.web["green"]`: `docker_container.web["green"]`},
+		},
+		{
+			name:  "count to for_each key",
+			state: []string{"docker_container.web[0]"},
+			src:   "docker_container.web[0]", dst: `docker_container.web["blue"]`,
+			want: map[string]string{`docker_container.web["blue"]`: "docker_container.web[0]"},
+		},
+		{
+			name:  "same address",
+			state: []string{"docker_container.web"},
+			src:   "docker_container.web", dst: "docker_container.web",
+			want: map[string]string{"docker_container.web": "docker_container.web"},
+		},
+		{
+			name:  "type change",
+			state: []string{"docker_container.web"},
+			src:   "docker_container.web", dst: "docker_image.web",
+			err: "resource type cannot be changed",
+		},
+		{
+			name:  "destination exists",
+			state: []string{"docker_container.web", "docker_container.frontend"},
+			src:   "docker_container.web", dst: "docker_container.frontend",
+			err: "cannot move docker_container.web to docker_container.frontend: the destination already exists in state",
+		},
+		{
+			name:  "one instance of several destinations exists",
+			state: []string{"docker_container.web[0]", "docker_container.web[1]", "docker_container.frontend[1]"},
+			src:   "docker_container.web", dst: "docker_container.frontend",
+			err: "cannot move docker_container.web[1] to docker_container.frontend[1]",
+		},
+		{
+			name:  "instances into a single instance",
+			state: []string{"docker_container.web[0]", "docker_container.web[1]"},
+			src:   "docker_container.web", dst: "docker_container.frontend[0]",
+			err: "cannot move the instances of docker_container.web into the single instance docker_container.frontend[0]",
+		},
+		{
+			name:  "no match",
+			state: []string{"docker_container.web"},
+			src:   "docker_container.db", dst: "docker_container.database",
+			err: "no resource in state matches docker_container.db",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newEditState(test.state...)
+			_, err := s.Move(mustParseAddress(t, test.src), mustParseAddress(t, test.dst))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				// Отклоненный перенос не меняет state даже частично
+				if !reflect.DeepEqual(s, newEditState(test.state...)) {
+					t.Errorf("state changed after a failed move: %v", s.Resources)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := make(map[string]string, len(s.Resources))
+			for address, resourceState := range s.Resources {
+				got[address] = resourceState.ID
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("state after move = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRemove(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses []string
+		removed   []string
+		left      []string
+		err       string
+	}{
+		{
+			name:      "resource with instances",
+			addresses: []string{"docker_container.web"},
+			removed:   []string{"docker_container.web[0]", "docker_container.web[1]"},
+			left:      []string{"docker_container.db", "docker_network.app"},
+		},
+		{
+			name:      "instance and resource",
+			addresses: []string{"docker_container.web[1]", "docker_network.app"},
+			removed:   []string{"docker_container.web[1]", "docker_network.app"},
+			left:      []string{"docker_container.db", "docker_container.web[0]"},
+		},
+		{
+			name:      "no match",
+			addresses: []string{"docker_volume.data"},
+			left:      []string{"docker_container.db", "docker_container.web[0]", "docker_container.web[1]", "docker_network.app"},
+			err:       "no resource in state matches the given addresses",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newEditState("docker_container.web[0]", "docker_container.web[1]", "docker_container.db", "docker_network.app")
+			var addresses []Address
+			for _, address := range test.addresses {
+				addresses = append(addresses, mustParseAddress(t, address))
+			}
+
+			removed, err := s.Remove(addresses)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(removed, test.removed) {
+				t.Errorf("removed = %v, want %v", removed, test.removed)
+			}
+
+			var left []string
+			for address := range s.Resources {
+				left = append(left, address)
+			}
+			sort.Strings(left)
+			if !reflect.DeepEqual(left, test.left) {
+				t.Errorf("left = %v, want %v", left, test.left)
+			}
+		})
+	}
+}
+
+func TestReplaceProvider(t *testing.T) {
+	defaultProvider := func(resourceType string) string {
+		name, _, _ := strings.Cut(resourceType, "_")
+		return name
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		replaced []string
+		// providers провайдеры записей после замены
+		providers map[string]string
+	}{
+		{
+			name: "default provider to alias",
+			from: "docker", to: "docker.edge",
+			replaced: []string{"docker_container.web", "docker_network.app"},
+			providers: map[string]string{
+				"docker_container.web": "docker.edge", "docker_network.app": "docker.edge",
+				"docker_container.db": "docker.remote", "kubernetes_pod.api": "",
+			},
+		},
+		{
+			name: "alias to default provider",
+			from: "docker.remote", to: "docker",
+			replaced: []string{"docker_container.db"},
+			providers: map[string]string{
+				"docker_container.web": "", "docker_network.app": "docker",
+				"docker_container.db": "docker", "kubernetes_pod.api": "",
+			},
+		},
+		{
+			name: "unused provider",
+			from: "docker.edge", to: "docker",
+			providers: map[string]string{
+				"docker_container.web": "", "docker_network.app": "docker",
+				"docker_container.db": "docker.remote", "kubernetes_pod.api": "",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newEditState("docker_container.web", "docker_network.app", "docker_container.db", "kubernetes_pod.api")
+			setProvider(s, "docker_network.app", "docker")
+			setProvider(s, "docker_container.db", "docker.remote")
+
+			replaced := s.ReplaceProvider(test.from, test.to, defaultProvider)
+			if !reflect.DeepEqual(replaced, test.replaced) {
+				t.Errorf("replaced = %v, want %v", replaced, test.replaced)
+			}
+			for address, provider := range test.providers {
+				if got := s.Resources[address].Provider; got != provider {
+					t.Errorf("%s provider = %q, want %q", address, got, provider)
+				}
+			}
+		})
+	}
+}
+
+func setProvider(s *State, address, provider string) {
+	resourceState := s.Resources[address]
+	resourceState.Provider = provider
+	s.Resources[address] = resourceState
+}
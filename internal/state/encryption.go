@@ -0,0 +1,294 @@
+package state
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	// encryptionFormat версия формата зашифрованного файла
+	encryptionFormat = 1
+	methodAESGCM     = "aes256-gcm"
+
+	kdfPBKDF2 = "pbkdf2-sha256"
+	kdfNone   = "none"
+	// pbkdf2Iterations рекомендация OWASP для PBKDF2-HMAC-SHA256
+	pbkdf2Iterations = 600000
+
+	keySize  = 32
+	saltSize = 16
+
+	// MinPassphraseLength минимальная длина пароля для ключа state
+	MinPassphraseLength = 16
+)
+
+// errKeyMismatch ключ не подходит к файлу: другой тип ключа или неверный ключ
+var errKeyMismatch = errors.New("key does not match")
+
+// envelope содержимое зашифрованного файла. Заголовок хранится открыто
+// и участвует в аутентификации как additional data GCM.
+type envelope struct {
+	Encryption *envelopeHeader `json:"encryption"`
+	// Payload nonce и шифротекст
+	Payload []byte `json:"payload"`
+}
+
+type envelopeHeader struct {
+	Version       int    `json:"version"`
+	Method        string `json:"method"`
+	KeyDerivation string `json:"key_derivation"`
+	Salt          []byte `json:"salt,omitempty"`
+	Iterations    int    `json:"iterations,omitempty"`
+}
+
+// Key ключ шифрования: пароль, из которого ключ выводится через PBKDF2,
+// готовый 256-битный ключ или отсутствие шифрования
+type Key struct {
+	name        string
+	passphrase  []byte
+	raw         []byte
+	unencrypted bool
+
+	// Ключ, выведенный из пароля, кешируется: PBKDF2 намеренно медленный,
+	// а state записывается после каждого ресурса
+	mu      sync.Mutex
+	salt    []byte
+	derived map[string][]byte
+}
+
+// NewPassphraseKey ключ из пароля
+func NewPassphraseKey(name, passphrase string) (*Key, error) {
+	if len(passphrase) < MinPassphraseLength {
+		return nil, fmt.Errorf("passphrase must be at least %d characters long", MinPassphraseLength)
+	}
+	return &Key{name: name, passphrase: []byte(passphrase), derived: make(map[string][]byte)}, nil
+}
+
+// NewRawKey готовый ключ AES-256
+func NewRawKey(name string, key []byte) (*Key, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes long, got %d", keySize, len(key))
+	}
+	return &Key{name: name, raw: key}, nil
+}
+
+// NewUnencryptedKey "ключ", с которым state хранится открыто. Основной - чтобы
+// расшифровать state, запасной - чтобы прочитать еще не зашифрованный state.
+func NewUnencryptedKey() *Key {
+	return &Key{name: "unencrypted", unencrypted: true}
+}
+
+func (k *Key) String() string {
+	return k.name
+}
+
+// writeKey возвращает ключ и заголовок для записи
+func (k *Key) writeKey() ([]byte, *envelopeHeader, error) {
+	header := &envelopeHeader{Version: encryptionFormat, Method: methodAESGCM, KeyDerivation: kdfNone}
+	if k.raw != nil {
+		return k.raw, header, nil
+	}
+
+	k.mu.Lock()
+	if k.salt == nil {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			k.mu.Unlock()
+			return nil, nil, err
+		}
+		k.salt = salt
+	}
+	salt := k.salt
+	k.mu.Unlock()
+
+	header.KeyDerivation = kdfPBKDF2
+	header.Salt = salt
+	header.Iterations = pbkdf2Iterations
+	key, err := k.readKey(header)
+	return key, header, err
+}
+
+// readKey возвращает ключ для файла с заголовком header
+func (k *Key) readKey(header *envelopeHeader) ([]byte, error) {
+	switch header.KeyDerivation {
+	case kdfNone:
+		if k.raw == nil {
+			return nil, errKeyMismatch
+		}
+		return k.raw, nil
+	case kdfPBKDF2:
+		if k.passphrase == nil {
+			return nil, errKeyMismatch
+		}
+		if header.Iterations <= 0 || len(header.Salt) == 0 {
+			return nil, fmt.Errorf("invalid %s parameters", kdfPBKDF2)
+		}
+
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		cacheKey := fmt.Sprintf("%x:%d", header.Salt, header.Iterations)
+		if key, exists := k.derived[cacheKey]; exists {
+			return key, nil
+		}
+		key, err := pbkdf2.Key(sha256.New, string(k.passphrase), header.Salt, header.Iterations, keySize)
+		if err != nil {
+			return nil, err
+		}
+		k.derived[cacheKey] = key
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key derivation %q", header.KeyDerivation)
+	}
+}
+
+// Encryption шифрует state основным ключом. Запасные ключи используются только
+// для чтения: так меняют ключ и переводят существующий state на шифрование.
+type Encryption struct {
+	primary   *Key
+	fallbacks []*Key
+
+	// OnFallback вызывается, когда state прочитан запасным ключом
+	OnFallback func(key *Key)
+}
+
+// NewEncryption создает шифрование с основным ключом primary
+func NewEncryption(primary *Key, fallbacks ...*Key) *Encryption {
+	return &Encryption{primary: primary, fallbacks: fallbacks}
+}
+
+// IsEncrypted сообщает, зашифровано ли содержимое файла
+func IsEncrypted(data []byte) bool {
+	var probe struct {
+		Encryption json.RawMessage `json:"encryption"`
+	}
+	return json.Unmarshal(data, &probe) == nil && len(probe.Encryption) > 0
+}
+
+// Encrypt шифрует данные основным ключом. nil Encryption возвращает данные как есть.
+func (enc *Encryption) Encrypt(data []byte) ([]byte, error) {
+	if enc == nil || enc.primary.unencrypted {
+		return data, nil
+	}
+
+	key, header, err := enc.primary.writeKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	additional, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	payload := aead.Seal(nonce, nonce, data, additional)
+
+	return json.MarshalIndent(envelope{Encryption: header, Payload: payload}, "", "  ")
+}
+
+// Decrypt расшифровывает данные основным или запасным ключом.
+// Незашифрованные данные принимаются, только если среди ключей есть unencrypted.
+func (enc *Encryption) Decrypt(data []byte) ([]byte, error) {
+	encrypted := IsEncrypted(data)
+	if enc == nil {
+		if encrypted {
+			return nil, fmt.Errorf("state is encrypted, but no encryption is configured in the terraform block")
+		}
+		return data, nil
+	}
+
+	if !encrypted {
+		for _, key := range enc.keys() {
+			if key.unencrypted {
+				enc.usedKey(key)
+				return data, nil
+			}
+		}
+		return nil, fmt.Errorf("state is not encrypted: add fallback { unencrypted = true } to the encryption block to encrypt the existing state")
+	}
+
+	var sealed envelope
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, fmt.Errorf("invalid encrypted state: %w", err)
+	}
+	header := sealed.Encryption
+	if header == nil {
+		return nil, fmt.Errorf("invalid encrypted state: missing encryption header")
+	}
+	if header.Version != encryptionFormat || header.Method != methodAESGCM {
+		return nil, fmt.Errorf("unsupported state encryption: version %d, method %q", header.Version, header.Method)
+	}
+	additional, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range enc.keys() {
+		if key.unencrypted {
+			continue
+		}
+		plain, err := openPayload(key, header, sealed.Payload, additional)
+		if errors.Is(err, errKeyMismatch) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		enc.usedKey(key)
+		return plain, nil
+	}
+	return nil, fmt.Errorf("failed to decrypt state: none of the configured keys match")
+}
+
+func (enc *Encryption) keys() []*Key {
+	return append([]*Key{enc.primary}, enc.fallbacks...)
+}
+
+func (enc *Encryption) usedKey(key *Key) {
+	if key != enc.primary && enc.OnFallback != nil {
+		enc.OnFallback(key)
+	}
+}
+
+func openPayload(key *Key, header *envelopeHeader, payload, additional []byte) ([]byte, error) {
+	secret, err := key.readKey(header)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) < aead.NonceSize() {
+		return nil, fmt.Errorf("invalid encrypted state: payload is too short")
+	}
+
+	nonce, ciphertext := payload[:aead.NonceSize()], payload[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, additional)
+	if err != nil {
+		// GCM не отличает неверный ключ от поврежденных данных
+		return nil, errKeyMismatch
+	}
+	return plain, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,243 @@
+package state
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var testState = []byte(`{"version": 4, "serial": 3, "resources": []}`)
+
+func passphraseKey(t *testing.T, name, passphrase string) *Key {
+	t.Helper()
+	key, err := NewPassphraseKey(name, passphrase)
+	if err != nil {
+		t.Fatalf("NewPassphraseKey: %v", err)
+	}
+	return key
+}
+
+func rawKey(t *testing.T, name string, fill byte) *Key {
+	t.Helper()
+	key, err := NewRawKey(name, bytes.Repeat([]byte{fill}, keySize))
+	if err != nil {
+		t.Fatalf("NewRawKey: %v", err)
+	}
+	return key
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		key  *Key
+	}{
+		{"passphrase", passphraseKey(t, "main", "correct horse battery staple")},
+		{"raw", rawKey(t, "main", 0x42)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			enc := NewEncryption(test.key)
+			sealed, err := enc.Encrypt(testState)
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if !IsEncrypted(sealed) {
+				t.Fatalf("IsEncrypted(%s) = false", sealed)
+			}
+			if bytes.Contains(sealed, []byte("resources")) {
+				t.Fatalf("encrypted state contains plaintext: %s", sealed)
+			}
+
+			plain, err := enc.Decrypt(sealed)
+			if err != nil {
+				t.Fatalf("Decrypt: %v", err)
+			}
+			if !bytes.Equal(plain, testState) {
+				t.Errorf("Decrypt = %s, want %s", plain, testState)
+			}
+		})
+	}
+}
+
+func TestEncryptionDecryptErrors(t *testing.T) {
+	primary := passphraseKey(t, "main", "correct horse battery staple")
+	sealed, err := NewEncryption(primary).Encrypt(testState)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(sealed, &envelope); err != nil {
+		t.Fatalf("invalid envelope: %v", err)
+	}
+	var payload []byte
+	if err := json.Unmarshal(envelope["payload"], &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	payload[len(payload)-1] ^= 0xff
+	tamperedPayload := withField(t, envelope, "payload", payload)
+
+	var header map[string]interface{}
+	if err := json.Unmarshal(envelope["encryption"], &header); err != nil {
+		t.Fatalf("invalid header: %v", err)
+	}
+	header["iterations"] = header["iterations"].(float64) + 1
+	tamperedHeader := withField(t, envelope, "encryption", header)
+
+	tests := []struct {
+		name string
+		enc  *Encryption
+		data []byte
+		want string
+	}{
+		{"wrong passphrase", NewEncryption(passphraseKey(t, "other", "a completely different phrase")), sealed, "none of the configured keys match"},
+		{"raw key for passphrase state", NewEncryption(rawKey(t, "raw", 0x42)), sealed, "none of the configured keys match"},
+		{"tampered payload", NewEncryption(primary), tamperedPayload, "none of the configured keys match"},
+		{"tampered header", NewEncryption(primary), tamperedHeader, "none of the configured keys match"},
+		{"plain state without fallback", NewEncryption(primary), testState, "state is not encrypted"},
+		{"encrypted state without encryption", nil, sealed, "no encryption is configured"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.enc.Decrypt(test.data)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Decrypt error = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+// withField возвращает конверт, в котором поле name заменено на value
+func withField(t *testing.T, envelope map[string]json.RawMessage, name string, value interface{}) []byte {
+	t.Helper()
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("marshal %s: %v", name, err)
+	}
+	changed := make(map[string]json.RawMessage, len(envelope))
+	for key, field := range envelope {
+		changed[key] = field
+	}
+	changed[name] = encoded
+	data, err := json.Marshal(changed)
+	if err != nil {
+		t.Fatalf("marshal envelope: %v", err)
+	}
+	return data
+}
+
+func TestEncryptionFallbackAndRekey(t *testing.T) {
+	oldKey := passphraseKey(t, "old", "correct horse battery staple")
+	newKey := rawKey(t, "new", 0x17)
+
+	tests := []struct {
+		name     string
+		data     func(t *testing.T) []byte
+		fallback *Key
+	}{
+		{
+			name: "rotate key",
+			data: func(t *testing.T) []byte {
+				sealed, err := NewEncryption(oldKey).Encrypt(testState)
+				if err != nil {
+					t.Fatalf("Encrypt: %v", err)
+				}
+				return sealed
+			},
+			fallback: oldKey,
+		},
+		{
+			name:     "encrypt plain state",
+			data:     func(t *testing.T) []byte { return testState },
+			fallback: NewUnencryptedKey(),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var used *Key
+			enc := NewEncryption(newKey, test.fallback)
+			enc.OnFallback = func(key *Key) { used = key }
+
+			plain, err := enc.Decrypt(test.data(t))
+			if err != nil {
+				t.Fatalf("Decrypt with fallback: %v", err)
+			}
+			if !bytes.Equal(plain, testState) {
+				t.Fatalf("Decrypt = %s, want %s", plain, testState)
+			}
+			if used != test.fallback {
+				t.Fatalf("OnFallback called with %v, want %v", used, test.fallback)
+			}
+
+			rekeyed, err := enc.Encrypt(plain)
+			if err != nil {
+				t.Fatalf("Encrypt with new key: %v", err)
+			}
+
+			used = nil
+			if _, err := enc.Decrypt(rekeyed); err != nil {
+				t.Fatalf("Decrypt rekeyed state: %v", err)
+			}
+			if used != nil {
+				t.Errorf("rekeyed state was read with fallback key %v", used)
+			}
+			if _, err := NewEncryption(test.fallback).Decrypt(rekeyed); err == nil {
+				t.Error("rekeyed state can still be read with the old key")
+			}
+		})
+	}
+}
+
+func TestPassphraseKeyDerivation(t *testing.T) {
+	header := &envelopeHeader{
+		Version:       encryptionFormat,
+		Method:        methodAESGCM,
+		KeyDerivation: kdfPBKDF2,
+		Salt:          bytes.Repeat([]byte{1}, saltSize),
+		Iterations:    1000,
+	}
+
+	first, err := passphraseKey(t, "a", "correct horse battery staple").readKey(header)
+	if err != nil {
+		t.Fatalf("readKey: %v", err)
+	}
+	if len(first) != keySize {
+		t.Fatalf("derived key is %d bytes, want %d", len(first), keySize)
+	}
+	second, err := passphraseKey(t, "b", "correct horse battery staple").readKey(header)
+	if err != nil {
+		t.Fatalf("readKey: %v", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("same passphrase and salt derived different keys")
+	}
+
+	otherSalt := *header
+	otherSalt.Salt = bytes.Repeat([]byte{2}, saltSize)
+	third, err := passphraseKey(t, "c", "correct horse battery staple").readKey(&otherSalt)
+	if err != nil {
+		t.Fatalf("readKey: %v", err)
+	}
+	if bytes.Equal(first, third) {
+		t.Error("different salts derived the same key")
+	}
+
+	// RFC 7914, раздел 11: PBKDF2-HMAC-SHA256, P="passwd", S="salt", c=1, первые 32 байта
+	vector := &Key{passphrase: []byte("passwd"), derived: make(map[string][]byte)}
+	derived, err := vector.readKey(&envelopeHeader{KeyDerivation: kdfPBKDF2, Salt: []byte("salt"), Iterations: 1})
+	if err != nil {
+		t.Fatalf("readKey: %v", err)
+	}
+	if want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc"; hex.EncodeToString(derived) != want {
+		t.Errorf("PBKDF2 key = %x, want %s", derived, want)
+	}
+
+	if _, err := NewPassphraseKey("short", "too short"); err == nil {
+		t.Error("NewPassphraseKey accepted a passphrase shorter than MinPassphraseLength")
+	}
+}
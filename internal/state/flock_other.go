@@ -0,0 +1,20 @@
+//go:build !unix
+
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var errLockHeld = errors.New("lock is held by another process")
+
+// На платформах без flock блокировка недоступна, используйте -lock=false
+func flock(file *os.File) error {
+	return fmt.Errorf("state locking is not supported on this platform: %w", errors.ErrUnsupported)
+}
+
+func funlock(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,274 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultStateFile файл state локального бэкенда по умолчанию
+const DefaultStateFile = "terraform.tfstate"
+
+// LocalBackend хранит state в локальном файле. Запись атомарная, перед первой
+// записью за запуск предыдущий state копируется в .backup. Блокировка держится
+// через flock на файле .<state>.lock.info, поэтому ядро снимает ее само, если процесс упал.
+type LocalBackend struct {
+	filename string
+
+	mu sync.Mutex
+	// backedUp копия предыдущего state делается один раз за запуск
+	backedUp bool
+	// held блокировки, взятые этим процессом, по ID
+	held map[string]*os.File
+}
+
+func NewLocalBackend(filename string) *LocalBackend {
+	if filename == "" {
+		filename = DefaultStateFile
+	}
+	return &LocalBackend{filename: filename, held: make(map[string]*os.File)}
+}
+
+func (b *LocalBackend) String() string {
+	return b.filename
+}
+
+// Filename путь к файлу state
+func (b *LocalBackend) Filename() string {
+	return b.filename
+}
+
+func (b *LocalBackend) Read(ctx context.Context) ([]byte, error) {
+	data, err := os.ReadFile(b.filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (b *LocalBackend) Write(ctx context.Context, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Создаем директорию если нужно
+	if err := os.MkdirAll(filepath.Dir(b.filename), 0755); err != nil {
+		return err
+	}
+
+	if !b.backedUp {
+		if err := b.backup(); err != nil {
+			return fmt.Errorf("failed to back up state: %w", err)
+		}
+		b.backedUp = true
+	}
+
+	return writeFileAtomic(b.filename, data, 0644)
+}
+
+// BackupPath путь к копии предыдущего state
+func (b *LocalBackend) BackupPath() string {
+	return b.filename + ".backup"
+}
+
+func (b *LocalBackend) backup() error {
+	data, err := os.ReadFile(b.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return writeFileAtomic(b.BackupPath(), data, 0644)
+}
+
+// lockPath путь к файлу блокировки: .terraform.tfstate.lock.info рядом со state
+func (b *LocalBackend) lockPath() string {
+	return filepath.Join(filepath.Dir(b.filename), "."+filepath.Base(b.filename)+".lock.info")
+}
+
+func (b *LocalBackend) Lock(ctx context.Context, info LockInfo) (*LockInfo, error) {
+	path := b.lockPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	file, err := tryLock(path)
+	if err == errLockHeld {
+		held, _ := readLockInfo(path)
+		return nil, &LockError{Info: held, Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Непустой файл при свободном flock значит, что прошлый держатель не снял блокировку
+	stale, _ := decodeLockInfo(file)
+
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err == nil {
+		if err = file.Truncate(0); err == nil {
+			_, err = file.WriteAt(data, 0)
+		}
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if err != nil {
+		funlock(file)
+		file.Close()
+		return nil, fmt.Errorf("failed to write lock info: %w", err)
+	}
+
+	b.mu.Lock()
+	b.held[info.ID] = file
+	b.mu.Unlock()
+
+	return stale, nil
+}
+
+// Unlock снимает свою блокировку или, для force-unlock, удаляет чужой файл блокировки
+func (b *LocalBackend) Unlock(ctx context.Context, id string) error {
+	path := b.lockPath()
+
+	b.mu.Lock()
+	file, held := b.held[id]
+	delete(b.held, id)
+	b.mu.Unlock()
+
+	if held {
+		// После force-unlock по этому пути может лежать уже чужая блокировка
+		var err error
+		opened, statErr := file.Stat()
+		current, currentErr := os.Stat(path)
+		if statErr == nil && currentErr == nil && os.SameFile(opened, current) {
+			if err = os.Remove(path); os.IsNotExist(err) {
+				err = nil
+			}
+		}
+		funlock(file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+
+	info, err := readLockInfo(path)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return fmt.Errorf("state is not locked")
+	}
+	if info.ID != id {
+		return fmt.Errorf("lock ID %q does not match the existing lock (%s)", id, info)
+	}
+	return os.Remove(path)
+}
+
+func (b *LocalBackend) LockInfo(ctx context.Context) (*LockInfo, error) {
+	return readLockInfo(b.lockPath())
+}
+
+// tryLock открывает файл блокировки и пытается взять на нем flock
+func tryLock(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := flock(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// Пока мы ждали flock, держатель мог удалить файл при разблокировке
+	// или force-unlock. Тогда блокировка на старом inode ничего не защищает.
+	opened, err := file.Stat()
+	if err != nil {
+		funlock(file)
+		file.Close()
+		return nil, err
+	}
+	current, err := os.Stat(path)
+	if err != nil || !os.SameFile(opened, current) {
+		funlock(file)
+		file.Close()
+		return nil, errLockHeld
+	}
+
+	return file, nil
+}
+
+func readLockInfo(path string) (*LockInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	return decodeLockInfo(file)
+}
+
+// decodeLockInfo читает информацию о блокировке, пустой файл - nil
+func decodeLockInfo(file *os.File) (*LockInfo, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if stat.Size() == 0 {
+		return nil, nil
+	}
+
+	data := make([]byte, stat.Size())
+	if _, err := file.ReadAt(data, 0); err != nil {
+		return nil, err
+	}
+
+	var info LockInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("invalid lock file %s: %w", file.Name(), err)
+	}
+	return &info, nil
+}
+
+// writeFileAtomic пишет файл так, что при падении на диске остается либо старое,
+// либо новое содержимое целиком
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(filename)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, filename); err != nil {
+		return err
+	}
+
+	// fsync каталога, чтобы сам rename пережил падение
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package state
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+)
+
+// lockRetryInterval как часто повторять попытку взять занятую блокировку
+const lockRetryInterval = 500 * time.Millisecond
+
+// LockInfo информация о блокировке: кто, когда и для какой операции взял state
+type LockInfo struct {
+	ID        string    `json:"id"`
+	Operation string    `json:"operation"`
+	Who       string    `json:"who"`
+	PID       int       `json:"pid"`
+	Created   time.Time `json:"created"`
+	Path      string    `json:"path"`
+}
+
+func (i *LockInfo) String() string {
+	return fmt.Sprintf("ID: %s, operation: %s, who: %s (pid %d), created: %s",
+		i.ID, i.Operation, i.Who, i.PID, i.Created.Format(time.RFC3339))
+}
+
+// LockError state уже заблокирован другим процессом
+type LockError struct {
+	Info *LockInfo
+	Err  error
+}
+
+func (e *LockError) Error() string {
+	if e.Info == nil {
+		return fmt.Sprintf("state is locked: %v", e.Err)
+	}
+	return fmt.Sprintf("state is locked by another process (%s): %v", e.Info, e.Err)
+}
+
+func (e *LockError) Unwrap() error {
+	return e.Err
+}
+
+// Lock взятая блокировка state
+type Lock struct {
+	Info LockInfo
+	// Stale блокировка, оставленная упавшим процессом и замененная этой
+	Stale *LockInfo
+
+	backend Backend
+}
+
+// Lock блокирует state для операции. Если state занят, ждет до timeout (0 - не ждать).
+func (sm *StateManager) Lock(ctx context.Context, operation string, timeout time.Duration) (*Lock, error) {
+	info := newLockInfo(operation, sm.backend.String())
+
+	deadline := time.Now().Add(timeout)
+	for {
+		stale, err := sm.backend.Lock(ctx, info)
+		if err == nil {
+			return &Lock{Info: info, Stale: stale, backend: sm.backend}, nil
+		}
+
+		var lockErr *LockError
+		if !errors.As(err, &lockErr) {
+			return nil, fmt.Errorf("failed to lock state: %w", err)
+		}
+		if !time.Now().Before(deadline) {
+			if timeout > 0 {
+				lockErr.Err = fmt.Errorf("timed out after %s: %w", timeout, lockErr.Err)
+			}
+			return nil, lockErr
+		}
+
+		select {
+		case <-ctx.Done():
+			lockErr.Err = ctx.Err()
+			return nil, lockErr
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// Unlock снимает блокировку
+func (l *Lock) Unlock() error {
+	return l.backend.Unlock(context.Background(), l.Info.ID)
+}
+
+// LockInfo возвращает текущую блокировку или nil, если state не заблокирован
+// или бэкенд не умеет ее показать
+func (sm *StateManager) LockInfo() (*LockInfo, error) {
+	inspector, ok := sm.backend.(LockInspector)
+	if !ok {
+		return nil, nil
+	}
+	return inspector.LockInfo(context.Background())
+}
+
+// ForceUnlock снимает блокировку с указанным ID, не дожидаясь ее держателя
+func (sm *StateManager) ForceUnlock(id string) error {
+	return sm.backend.Unlock(context.Background(), id)
+}
+
+func newLockInfo(operation, path string) LockInfo {
+	who := "unknown"
+	if current, err := user.Current(); err == nil {
+		who = current.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		who += "@" + host
+	}
+
+	return LockInfo{
+		ID:        newUUID(),
+		Operation: operation,
+		Who:       who,
+		PID:       os.Getpid(),
+		Created:   time.Now().UTC(),
+		Path:      path,
+	}
+}
+
+// newUUID генерирует случайный UUID версии 4
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,231 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestBackends два бэкенда одного файла state: как два процесса derraform
+func newTestBackends(t *testing.T) (*LocalBackend, *LocalBackend) {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), DefaultStateFile)
+	return NewLocalBackend(filename), NewLocalBackend(filename)
+}
+
+func TestLocalBackendLock(t *testing.T) {
+	first, second := newTestBackends(t)
+	ctx := context.Background()
+
+	info := newLockInfo("apply", first.String())
+	stale, err := first.Lock(ctx, info)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	if stale != nil {
+		t.Errorf("stale = %+v, want nil for a fresh lock", stale)
+	}
+
+	_, err = second.Lock(ctx, newLockInfo("plan", second.String()))
+	var lockErr *LockError
+	if !errors.As(err, &lockErr) {
+		t.Fatalf("error = %v, want *LockError", err)
+	}
+	if lockErr.Info == nil || lockErr.Info.ID != info.ID || lockErr.Info.Operation != "apply" {
+		t.Errorf("lock info = %+v, want the holder %s", lockErr.Info, info.ID)
+	}
+
+	held, err := second.LockInfo(ctx)
+	if err != nil || held == nil || held.ID != info.ID {
+		t.Errorf("LockInfo = %+v, %v, want %s", held, err, info.ID)
+	}
+
+	if err := first.Unlock(ctx, info.ID); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if _, err := os.Stat(first.lockPath()); !os.IsNotExist(err) {
+		t.Errorf("lock file is left after unlock: %v", err)
+	}
+	if held, _ := second.LockInfo(ctx); held != nil {
+		t.Errorf("LockInfo after unlock = %+v, want nil", held)
+	}
+
+	next := newLockInfo("plan", second.String())
+	if _, err := second.Lock(ctx, next); err != nil {
+		t.Fatalf("Lock after unlock: %v", err)
+	}
+	if err := second.Unlock(ctx, next.ID); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+}
+
+func TestLocalBackendStaleLock(t *testing.T) {
+	backend, _ := newTestBackends(t)
+	ctx := context.Background()
+
+	// Файл блокировки без flock остается от процесса, который упал посреди apply
+	crashed := newLockInfo("apply", backend.String())
+	crashed.PID = 4242
+	data, err := json.Marshal(crashed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(backend.lockPath(), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info := newLockInfo("apply", backend.String())
+	stale, err := backend.Lock(ctx, info)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	if stale == nil || stale.ID != crashed.ID || stale.PID != 4242 {
+		t.Errorf("stale = %+v, want the lock of the crashed process", stale)
+	}
+	held, err := backend.LockInfo(ctx)
+	if err != nil || held == nil || held.ID != info.ID {
+		t.Errorf("LockInfo = %+v, %v, want the new lock %s", held, err, info.ID)
+	}
+	if err := backend.Unlock(ctx, info.ID); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+}
+
+func TestForceUnlock(t *testing.T) {
+	holder, other := newTestBackends(t)
+	ctx := context.Background()
+	manager := NewStateManager(other, nil)
+
+	if err := manager.ForceUnlock("0000"); err == nil || err.Error() != "state is not locked" {
+		t.Errorf("ForceUnlock without lock = %v, want state is not locked", err)
+	}
+
+	info := newLockInfo("apply", holder.String())
+	if _, err := holder.Lock(ctx, info); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+
+	err := manager.ForceUnlock("0000")
+	if err == nil || !strings.Contains(err.Error(), `lock ID "0000" does not match the existing lock (ID: `+info.ID) {
+		t.Errorf("ForceUnlock with wrong ID = %v", err)
+	}
+
+	if err := manager.ForceUnlock(info.ID); err != nil {
+		t.Fatalf("ForceUnlock: %v", err)
+	}
+	// Держатель все еще держит flock на удаленном файле, это не мешает новой блокировке
+	lock, err := manager.Lock(ctx, "plan", 0)
+	if err != nil {
+		t.Fatalf("Lock after force-unlock: %v", err)
+	}
+	// Снятие старой блокировки не трогает новую
+	if err := holder.Unlock(ctx, info.ID); err != nil {
+		t.Errorf("Unlock of a force-unlocked lock = %v, want nil", err)
+	}
+	if held, _ := manager.LockInfo(); held == nil || held.ID != lock.Info.ID {
+		t.Errorf("LockInfo = %+v, want %s", held, lock.Info.ID)
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+}
+
+func TestStateManagerLockWait(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		release  time.Duration
+		cancel   bool
+		err      string
+		canceled bool
+	}{
+		{name: "no wait", err: "state is locked by another process (ID: "},
+		{name: "timeout", timeout: 100 * time.Millisecond, err: "timed out after 100ms: lock is held by another process"},
+		{name: "released while waiting", timeout: 5 * time.Second, release: 200 * time.Millisecond},
+		{name: "interrupted", timeout: 5 * time.Second, cancel: true, canceled: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			holder, other := newTestBackends(t)
+			held, err := NewStateManager(holder, nil).Lock(context.Background(), "apply", 0)
+			if err != nil {
+				t.Fatalf("Lock: %v", err)
+			}
+			defer held.Unlock()
+
+			if test.release > 0 {
+				time.AfterFunc(test.release, func() { held.Unlock() })
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if test.cancel {
+				time.AfterFunc(100*time.Millisecond, cancel)
+			}
+
+			lock, err := NewStateManager(other, nil).Lock(ctx, "plan", test.timeout)
+			if test.err == "" && !test.canceled {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				lock.Unlock()
+				return
+			}
+
+			var lockErr *LockError
+			if !errors.As(err, &lockErr) {
+				t.Fatalf("error = %v, want *LockError", err)
+			}
+			if lockErr.Info == nil || lockErr.Info.ID != held.Info.ID {
+				t.Errorf("lock info = %+v, want the holder %s", lockErr.Info, held.Info.ID)
+			}
+			if test.canceled && !errors.Is(err, context.Canceled) {
+				t.Errorf("error = %v, want context.Canceled", err)
+			}
+			if test.err != "" && !strings.Contains(err.Error(), test.err) {
+				t.Errorf("error = %v, want it to contain %q", err, test.err)
+			}
+		})
+	}
+}
+
+// failingLockBackend бэкенд, у которого блокировка не работает совсем
+type failingLockBackend struct {
+	LocalBackend
+}
+
+func (b *failingLockBackend) Lock(ctx context.Context, info LockInfo) (*LockInfo, error) {
+	return nil, errors.New("access denied")
+}
+
+func TestStateManagerLockBackendError(t *testing.T) {
+	backend := &failingLockBackend{LocalBackend: *NewLocalBackend(filepath.Join(t.TempDir(), DefaultStateFile))}
+
+	_, err := NewStateManager(backend, nil).Lock(context.Background(), "apply", time.Minute)
+	if err == nil || err.Error() != "failed to lock state: access denied" {
+		t.Fatalf("error = %v, want the backend error without waiting", err)
+	}
+	var lockErr *LockError
+	if errors.As(err, &lockErr) {
+		t.Error("backend error is reported as a held lock")
+	}
+}
+
+func TestNewLockInfo(t *testing.T) {
+	info := newLockInfo("apply", "terraform.tfstate")
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(info.ID) {
+		t.Errorf("ID = %q, want a version 4 UUID", info.ID)
+	}
+	if info.PID != os.Getpid() || info.Operation != "apply" || info.Path != "terraform.tfstate" || info.Who == "" {
+		t.Errorf("info = %+v", info)
+	}
+	if other := newLockInfo("apply", "terraform.tfstate"); other.ID == info.ID {
+		t.Error("lock IDs are not unique")
+	}
+}
@@ -1,81 +1,190 @@
 package state
 
 import (
+	"context"
 	"encoding/json"
-	"os"
-	"path/filepath"
+	"fmt"
+
+	"github.com/Artemka007/derraform/internal/version"
 )
 
+// StateVersion версия формата файла state. При изменении формата
+// добавьте миграцию в stateUpgraders (upgrade.go).
+const StateVersion = 2
+
 type State struct {
+	Version int `json:"version"`
+	// TerraformVersion версия derraform, которая последней записала state
+	TerraformVersion string `json:"terraform_version"`
+	// Serial увеличивается при каждой записи
+	Serial uint64 `json:"serial"`
+	// Lineage UUID, назначаемый при создании state. Не меняется, пока state не удален,
+	// и позволяет отличить один state от другого с тем же serial.
+	Lineage   string                   `json:"lineage"`
 	Resources map[string]ResourceState `json:"resources"`
 }
 
 type ResourceState struct {
-	Type       string                 `json:"type"`
-	ID         string                 `json:"id"`
-	Attributes map[string]interface{} `json:"attributes"`
+	Type     string `json:"type"`
+	ID       string `json:"id"`
+	Provider string `json:"provider,omitempty"`
+	// SchemaVersion версия схемы ресурса, по которой записаны Attributes
+	SchemaVersion int                    `json:"schema_version"`
+	Attributes    map[string]interface{} `json:"attributes"`
+	// SensitiveAttributes атрибуты, значения которых не показываются в выводе
+	SensitiveAttributes []string `json:"sensitive_attributes,omitempty"`
+	// Tainted объект создан не полностью (например, контейнер не запустился)
+	// и будет пересоздан при следующем apply
+	Tainted bool `json:"tainted,omitempty"`
+	// Dependencies адреса ресурсов, от которых зависит этот: destroy удаляет его раньше них
+	Dependencies []string `json:"dependencies,omitempty"`
 }
 
+// StateManager читает и пишет state через бэкенд, отвечая за формат,
+// serial, lineage и шифрование
 type StateManager struct {
-	filename string
+	backend Backend
+	// encryption шифрование state, nil - state хранится открыто
+	encryption *Encryption
+}
+
+func NewStateManager(backend Backend, encryption *Encryption) *StateManager {
+	return &StateManager{backend: backend, encryption: encryption}
 }
 
-func NewStateManager(filename string) *StateManager {
-	return &StateManager{filename: filename}
+// Backend возвращает хранилище state
+func (sm *StateManager) Backend() Backend {
+	return sm.backend
 }
 
 func (sm *StateManager) Load() (*State, error) {
-	data, err := os.ReadFile(sm.filename)
+	data, err := sm.backend.Read(context.Background())
 	if err != nil {
-		if os.IsNotExist(err) {
-			return &State{
-				Resources: make(map[string]ResourceState),
-			}, nil
-		}
 		return nil, err
 	}
+	if data == nil {
+		return &State{
+			Resources: make(map[string]ResourceState),
+		}, nil
+	}
 
-	var state State
-	if err := json.Unmarshal(data, &state); err != nil {
+	return sm.Decode(data)
+}
+
+// Decode расшифровывает и разбирает содержимое файла state
+func (sm *StateManager) Decode(data []byte) (*State, error) {
+	plain, err := sm.encryption.Decrypt(data)
+	if err != nil {
 		return nil, err
 	}
+	return Parse(plain)
+}
 
-	return &state, nil
+// Parse разбирает содержимое файла state, обновляя формат старых версий
+func Parse(data []byte) (*State, error) {
+	state, err := upgradeState(data)
+	if err != nil {
+		return nil, err
+	}
+	if state.Resources == nil {
+		state.Resources = make(map[string]ResourceState)
+	}
+	return state, nil
 }
 
+// Save записывает state, увеличивая serial и назначая lineage новому state
 func (sm *StateManager) Save(state *State) error {
-	// Создаем директорию если нужно
-	dir := filepath.Dir(sm.filename)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
+	if state.Lineage == "" {
+		state.Lineage = newUUID()
 	}
+	state.Version = StateVersion
+	state.TerraformVersion = version.Version
+	state.Serial++
 
 	data, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(sm.filename, data, 0644)
+	if data, err = sm.encryption.Encrypt(data); err != nil {
+		return fmt.Errorf("failed to encrypt state: %w", err)
+	}
+	return sm.backend.Write(context.Background(), data)
+}
+
+// Push заменяет state целиком, например при state push.
+// State с другим lineage или меньшим serial принимается только с force.
+func (sm *StateManager) Push(state *State, force bool) error {
+	current, err := sm.Load()
+	if err != nil {
+		return err
+	}
+
+	if !force && current.Lineage != "" {
+		if state.Lineage != current.Lineage {
+			return fmt.Errorf("cannot push state with lineage %q over state with lineage %q: they are different states", state.Lineage, current.Lineage)
+		}
+		if state.Serial < current.Serial {
+			return fmt.Errorf("cannot push state with serial %d over newer state with serial %d", state.Serial, current.Serial)
+		}
+	}
+
+	// Save увеличит serial, так что записанный state будет новее обоих
+	if state.Serial < current.Serial {
+		state.Serial = current.Serial
+	}
+	return sm.Save(state)
 }
 
-func (sm *StateManager) SaveResourceState(resourceType, resourceName string, attributes map[string]interface{}) error {
+func (sm *StateManager) SaveResourceState(resourceType, resourceName string, resourceState *ResourceState) error {
 	state, err := sm.Load()
 	if err != nil {
 		return err
 	}
 
 	resourceID := resourceType + "." + resourceName
-	state.Resources[resourceID] = ResourceState{
-		Type:       resourceType,
-		ID:         attributes["id"].(string),
-		Attributes: attributes,
+	saved := *resourceState
+	saved.Type = resourceType
+	state.Resources[resourceID] = saved
+
+	return sm.Save(state)
+}
+
+// RemoveResourceState убирает ресурс из состояния
+func (sm *StateManager) RemoveResourceState(resourceType, resourceName string) error {
+	return sm.RemoveResource(resourceType + "." + resourceName)
+}
+
+// RemoveResource убирает запись состояния по ее адресу, например docker_container.web[0]
+func (sm *StateManager) RemoveResource(address string) error {
+	state, err := sm.Load()
+	if err != nil {
+		return err
 	}
 
+	delete(state.Resources, address)
 	return sm.Save(state)
 }
 
+// GetResourceState возвращает сохраненное состояние ресурса или nil, если его нет
+func (sm *StateManager) GetResourceState(resourceType, resourceName string) (*ResourceState, error) {
+	state, err := sm.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	resourceState, exists := state.Resources[resourceType+"."+resourceName]
+	if !exists {
+		return nil, nil
+	}
+	return &resourceState, nil
+}
+
+// Clear удаляет все ресурсы, сохраняя lineage
 func (sm *StateManager) Clear() error {
-	state := &State{
-		Resources: make(map[string]ResourceState),
+	state, err := sm.Load()
+	if err != nil {
+		return err
 	}
+	state.Resources = make(map[string]ResourceState)
 	return sm.Save(state)
 }
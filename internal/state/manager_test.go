@@ -0,0 +1,264 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/version"
+)
+
+// newTestManager StateManager над локальным файлом во временном каталоге
+func newTestManager(t *testing.T) (*StateManager, string) {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), DefaultStateFile)
+	return NewStateManager(NewLocalBackend(filename), nil), filename
+}
+
+// readStateFile разбирает файл state без StateManager, как его увидит другой процесс
+func readStateFile(t *testing.T, filename string) *State {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("invalid state file: %v", err)
+	}
+	return &state
+}
+
+func TestSaveSerialAndLineage(t *testing.T) {
+	manager, filename := newTestManager(t)
+
+	empty, err := manager.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if empty.Serial != 0 || empty.Lineage != "" || empty.Resources == nil {
+		t.Errorf("missing state = %+v, want empty state without lineage", empty)
+	}
+
+	if err := manager.SaveResourceState("docker_network", "app", &ResourceState{ID: "n1"}); err != nil {
+		t.Fatalf("SaveResourceState: %v", err)
+	}
+	first := readStateFile(t, filename)
+	if first.Version != StateVersion || first.TerraformVersion != version.Version || first.Serial != 1 {
+		t.Errorf("state = version %d, terraform_version %q, serial %d, want %d, %q, 1",
+			first.Version, first.TerraformVersion, first.Serial, StateVersion, version.Version)
+	}
+	if len(first.Lineage) != 36 {
+		t.Errorf("lineage = %q, want a UUID", first.Lineage)
+	}
+	if first.Resources["docker_network.app"].Type != "docker_network" {
+		t.Errorf("resources = %+v, want docker_network.app with its type", first.Resources)
+	}
+
+	steps := []struct {
+		name string
+		save func() error
+	}{
+		{"save resource", func() error {
+			return manager.SaveResourceState("docker_container", "web", &ResourceState{ID: "c1"})
+		}},
+		{"remove resource", func() error { return manager.RemoveResourceState("docker_container", "web") }},
+		{"clear", manager.Clear},
+	}
+	for i, step := range steps {
+		if err := step.save(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		got := readStateFile(t, filename)
+		if want := uint64(i + 2); got.Serial != want {
+			t.Errorf("%s: serial = %d, want %d", step.name, got.Serial, want)
+		}
+		if got.Lineage != first.Lineage {
+			t.Errorf("%s: lineage = %q, want %q", step.name, got.Lineage, first.Lineage)
+		}
+	}
+	if cleared := readStateFile(t, filename); cleared.Resources == nil || len(cleared.Resources) != 0 {
+		t.Errorf("resources after clear = %v, want an empty map", cleared.Resources)
+	}
+
+	// Новый state в другом файле получает свой lineage
+	other, otherFile := newTestManager(t)
+	if err := other.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if lineage := readStateFile(t, otherFile).Lineage; lineage == first.Lineage {
+		t.Error("two new states got the same lineage")
+	}
+}
+
+func TestPush(t *testing.T) {
+	tests := []struct {
+		name    string
+		current *State
+		pushed  State
+		force   bool
+		serial  uint64
+		lineage string
+		err     string
+	}{
+		{
+			name:    "no state yet",
+			pushed:  State{Serial: 4, Lineage: "b"},
+			serial:  5,
+			lineage: "b",
+		},
+		{
+			name:    "newer serial",
+			current: &State{Serial: 3, Lineage: "a"},
+			pushed:  State{Serial: 7, Lineage: "a"},
+			serial:  8,
+			lineage: "a",
+		},
+		{
+			name:    "same serial",
+			current: &State{Serial: 3, Lineage: "a"},
+			pushed:  State{Serial: 3, Lineage: "a"},
+			serial:  4,
+			lineage: "a",
+		},
+		{
+			name:    "older serial",
+			current: &State{Serial: 3, Lineage: "a"},
+			pushed:  State{Serial: 2, Lineage: "a"},
+			err:     "cannot push state with serial 2 over newer state with serial 3",
+		},
+		{
+			name:    "other lineage",
+			current: &State{Serial: 3, Lineage: "a"},
+			pushed:  State{Serial: 9, Lineage: "b"},
+			err:     `cannot push state with lineage "b" over state with lineage "a"`,
+		},
+		{
+			name:    "older serial with force",
+			current: &State{Serial: 3, Lineage: "a"},
+			pushed:  State{Serial: 1, Lineage: "b"},
+			force:   true,
+			serial:  4,
+			lineage: "b",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manager, filename := newTestManager(t)
+			if test.current != nil {
+				// Save увеличит serial, поэтому записываем на единицу меньше
+				current := *test.current
+				current.Serial--
+				if err := manager.Save(&current); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			pushed := test.pushed
+			pushed.Resources = map[string]ResourceState{"docker_volume.data": {Type: "docker_volume", ID: "data"}}
+			err := manager.Push(&pushed, test.force)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				if test.current != nil && readStateFile(t, filename).Serial != test.current.Serial {
+					t.Error("rejected push changed the state")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := readStateFile(t, filename)
+			if got.Serial != test.serial || got.Lineage != test.lineage {
+				t.Errorf("serial, lineage = %d, %q, want %d, %q", got.Serial, got.Lineage, test.serial, test.lineage)
+			}
+			if _, exists := got.Resources["docker_volume.data"]; !exists {
+				t.Errorf("resources = %v, want the pushed state", got.Resources)
+			}
+		})
+	}
+}
+
+func TestLocalBackendWrite(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "nested", DefaultStateFile)
+	ctx := context.Background()
+
+	backend := NewLocalBackend(filename)
+	for _, data := range []string{"first", "second", "third"} {
+		if err := backend.Write(ctx, []byte(data)); err != nil {
+			t.Fatalf("Write %s: %v", data, err)
+		}
+	}
+	assertFile(t, filename, "third")
+	// Первый запуск: предыдущего state не было, копировать нечего
+	if _, err := os.Stat(backend.BackupPath()); !os.IsNotExist(err) {
+		t.Errorf("backup of a new state exists: %v", err)
+	}
+
+	// Следующий запуск копирует state, который был до него, один раз
+	next := NewLocalBackend(filename)
+	for _, data := range []string{"fourth", "fifth"} {
+		if err := next.Write(ctx, []byte(data)); err != nil {
+			t.Fatalf("Write %s: %v", data, err)
+		}
+	}
+	assertFile(t, filename, "fifth")
+	assertFile(t, next.BackupPath(), "third")
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("state mode = %v, want 0644", info.Mode().Perm())
+	}
+
+	// Временные файлы записи не остаются в каталоге
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Errorf("temporary file %s is left after write", entry.Name())
+		}
+	}
+}
+
+func TestWriteFileAtomicFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	// Rename на место непустого каталога не выполнится
+	target := filepath.Join(dir, DefaultStateFile)
+	if err := os.MkdirAll(filepath.Join(target, "child"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(target, []byte("new"), 0644); err == nil {
+		t.Fatal("write over a directory succeeded")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want the temporary file removed", len(entries))
+	}
+}
+
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("%s = %q, want %q", filepath.Base(path), data, want)
+	}
+}
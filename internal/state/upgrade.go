@@ -0,0 +1,108 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// stateUpgraders цепочка миграций формата: stateUpgraders[N] переводит версию N в N+1.
+// Миграции работают с сырым JSON, чтобы старые файлы не зависели от текущих структур.
+var stateUpgraders = []func(raw map[string]interface{}) error{
+	upgradeV0ToV1,
+	upgradeV1ToV2,
+}
+
+// upgradeState приводит содержимое файла state к текущей версии формата
+func upgradeState(data []byte) (*State, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var raw map[string]interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	fileVersion, err := rawStateVersion(raw)
+	if err != nil {
+		return nil, err
+	}
+	if fileVersion > StateVersion {
+		return nil, fmt.Errorf("state format version %d is newer than supported version %d (written by derraform %v)",
+			fileVersion, StateVersion, raw["terraform_version"])
+	}
+
+	for v := fileVersion; v < StateVersion; v++ {
+		if err := stateUpgraders[v](raw); err != nil {
+			return nil, fmt.Errorf("failed to upgrade state from version %d to %d: %w", v, v+1, err)
+		}
+		raw["version"] = v + 1
+	}
+
+	upgraded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var state State
+	if err := json.Unmarshal(upgraded, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// rawStateVersion возвращает версию формата. Файлы без version - версия 0.
+func rawStateVersion(raw map[string]interface{}) (int, error) {
+	value, exists := raw["version"]
+	if !exists {
+		return 0, nil
+	}
+
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("invalid state version %v", value)
+	}
+	version, err := number.Int64()
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid state version %v", value)
+	}
+	return int(version), nil
+}
+
+// upgradeV0ToV1 v0 - исходный формат только с resources.
+// В v1 появились serial, lineage и версия derraform; lineage назначит первая запись.
+func upgradeV0ToV1(raw map[string]interface{}) error {
+	emptyResources(raw)
+	raw["serial"] = 0
+	raw["lineage"] = ""
+	return nil
+}
+
+// upgradeV1ToV2 в v2 у каждого ресурса есть schema_version - версия схемы провайдера,
+// по которой записаны атрибуты. Старые записи считаются версией 0.
+func upgradeV1ToV2(raw map[string]interface{}) error {
+	emptyResources(raw)
+	resources, ok := raw["resources"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid resources")
+	}
+
+	for id, value := range resources {
+		resource, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid resource %s", id)
+		}
+		if _, exists := resource["schema_version"]; !exists {
+			resource["schema_version"] = 0
+		}
+	}
+	return nil
+}
+
+// emptyResources заменяет отсутствующие и null resources пустым набором: так их
+// записывали старые версии для state без ресурсов
+func emptyResources(raw map[string]interface{}) {
+	if raw["resources"] == nil {
+		raw["resources"] = map[string]interface{}{}
+	}
+}
@@ -0,0 +1,105 @@
+package state
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseUpgrade(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		resources int
+		serial    uint64
+		lineage   string
+		schema    map[string]int
+		err       string
+	}{
+		{
+			name:      "v0 without version",
+			data:      `{"resources": {"docker_network.app": {"type": "docker_network", "id": "n1", "attributes": {}}}}`,
+			resources: 1,
+			schema:    map[string]int{"docker_network.app": 0},
+		},
+		{
+			name: "v0 with null resources",
+			data: `{"resources": null}`,
+		},
+		{
+			name: "v0 without resources",
+			data: `{}`,
+		},
+		{
+			name:   "v1 with null resources",
+			data:   `{"version": 1, "serial": 4, "lineage": "abc", "resources": null}`,
+			serial: 4, lineage: "abc",
+		},
+		{
+			name:      "v1 resource gets schema version 0",
+			data:      `{"version": 1, "serial": 2, "lineage": "abc", "resources": {"docker_container.web": {"type": "docker_container", "id": "c1"}}}`,
+			resources: 1, serial: 2, lineage: "abc",
+			schema: map[string]int{"docker_container.web": 0},
+		},
+		{
+			name:      "v2 keeps schema version",
+			data:      `{"version": 2, "serial": 7, "lineage": "abc", "resources": {"docker_container.web": {"type": "docker_container", "id": "c1", "schema_version": 3}}}`,
+			resources: 1, serial: 7, lineage: "abc",
+			schema: map[string]int{"docker_container.web": 3},
+		},
+		{
+			name:   "v2 with null resources",
+			data:   `{"version": 2, "serial": 1, "lineage": "abc", "resources": null}`,
+			serial: 1, lineage: "abc",
+		},
+		{
+			name: "newer version",
+			data: `{"version": 3, "terraform_version": "9.0.0", "resources": {}}`,
+			err:  "state format version 3 is newer than supported version 2 (written by derraform 9.0.0)",
+		},
+		{
+			name: "invalid version",
+			data: `{"version": "two"}`,
+			err:  "invalid state version",
+		},
+		{
+			name: "invalid resources",
+			data: `{"version": 1, "resources": []}`,
+			err:  "failed to upgrade state from version 1 to 2: invalid resources",
+		},
+		{
+			name: "invalid resource",
+			data: `{"version": 1, "resources": {"docker_container.web": "c1"}}`,
+			err:  "invalid resource docker_container.web",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state, err := Parse([]byte(test.data))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if state.Version != StateVersion {
+				t.Errorf("version = %d, want %d", state.Version, StateVersion)
+			}
+			if state.Resources == nil || len(state.Resources) != test.resources {
+				t.Errorf("resources = %v, want %d entries", state.Resources, test.resources)
+			}
+			if state.Serial != test.serial || state.Lineage != test.lineage {
+				t.Errorf("serial, lineage = %d, %q, want %d, %q", state.Serial, state.Lineage, test.serial, test.lineage)
+			}
+			for address, version := range test.schema {
+				if got := state.Resources[address].SchemaVersion; got != version {
+					t.Errorf("%s schema_version = %d, want %d", address, got, version)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package ui
+
+import (
+	"bytes"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Artemka007/derraform/internal/errors"
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/fatih/color"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// maxSnippetLines сколько строк исходного файла показывать для одного сообщения
+const maxSnippetLines = 5
+
+// Severity важность диагностики
+type Severity string
+
+const (
+	SeverityError   Severity = "Error"
+	SeverityWarning Severity = "Warning"
+)
+
+// Diagnostic сообщение об ошибке или предупреждение в том виде, в каком его видит
+// пользователь. Одна форма для ошибок конфигурации, планирования и применения.
+type Diagnostic struct {
+	Severity Severity
+	Summary  string
+	Detail   string
+	// Subject место в конфигурации: его строки показываются с выделенным выражением
+	Subject *hcl.Range
+	// Resource адрес ресурса, DependsOn - ресурсы, от которых он зависит
+	Resource  string
+	DependsOn []string
+	// Origin ошибка движка, если Summary и Detail взяты из диагностики HCL
+	Origin  string
+	Code    errors.Code
+	Hint    string
+	DocsURL string
+	Stack   []string
+}
+
+// Diagnostics превращает ошибку в диагностики. Ошибки, объединенные errors.Join,
+// и каждая из нескольких диагностик HCL становятся отдельными сообщениями.
+func Diagnostics(err error) []Diagnostic {
+	if joined := errors.Joined(err); joined != nil {
+		var diags []Diagnostic
+		for _, inner := range joined {
+			diags = append(diags, Diagnostics(inner)...)
+		}
+		return diags
+	}
+
+	terraErr := asTerraformError(err)
+	if joined := errors.Joined(terraErr.Cause); joined != nil {
+		diags := Diagnostics(terraErr.Cause)
+		outer := *terraErr
+		outer.Cause = nil
+		return append(diags, newDiagnostic(&outer, nil))
+	}
+
+	var hclDiags hcl.Diagnostics
+	if stderrors.As(terraErr.Cause, &hclDiags) && len(hclDiags) > 0 {
+		diags := make([]Diagnostic, 0, len(hclDiags))
+		for _, hclDiag := range hclDiags {
+			diags = append(diags, newDiagnostic(terraErr, hclDiag))
+		}
+		return diags
+	}
+	return []Diagnostic{newDiagnostic(terraErr, nil)}
+}
+
+func newDiagnostic(err *errors.TerraformError, hclDiag *hcl.Diagnostic) Diagnostic {
+	diag := Diagnostic{
+		Severity:  SeverityError,
+		Summary:   errorTitle(err),
+		Subject:   err.Subject,
+		Resource:  err.Resource,
+		DependsOn: err.DependsOn,
+		Code:      err.Code,
+		Hint:      err.Hint(),
+		DocsURL:   err.DocsURL(),
+		Stack:     err.Stack,
+	}
+	if err.Cause != nil {
+		diag.Detail = err.Cause.Error()
+	}
+
+	if hclDiag != nil {
+		diag.Summary = hclDiag.Summary
+		diag.Detail = hclDiag.Detail
+		diag.Origin = errorTitle(err)
+		if hclDiag.Subject != nil {
+			diag.Subject = hclDiag.Subject
+		}
+		if hclDiag.Severity == hcl.DiagWarning {
+			diag.Severity = SeverityWarning
+		}
+	}
+	return diag
+}
+
+// DiagnosticRenderer показывает диагностики в стиле Terraform: важность, краткое
+// описание, строки файла с выделенным выражением, адрес ресурса и подробности
+type DiagnosticRenderer struct {
+	out io.Writer
+	// ShowStack показывать стек Go, в котором возникла ошибка: только для отладки
+	ShowStack bool
+	sources   map[string][]byte
+}
+
+// NewDiagnosticRenderer создает рендерер, который пишет в out
+func NewDiagnosticRenderer(out io.Writer) *DiagnosticRenderer {
+	return &DiagnosticRenderer{out: out, sources: make(map[string][]byte)}
+}
+
+// Render показывает одну диагностику
+func (r *DiagnosticRenderer) Render(diag Diagnostic) {
+	frame := color.New(color.FgRed)
+	if diag.Severity == SeverityWarning {
+		frame = color.New(color.FgYellow)
+	}
+	bar := frame.Sprint("│")
+	line := func(format string, args ...interface{}) {
+		text := logging.Redact(fmt.Sprintf(format, args...))
+		if text == "" {
+			fmt.Fprintln(r.out, bar)
+			return
+		}
+		fmt.Fprintf(r.out, "%s %s\n", bar, text)
+	}
+
+	fmt.Fprintln(r.out, frame.Sprint("╷"))
+	line("%s %s", frame.Add(color.Bold).Sprintf("%s:", diag.Severity), color.New(color.Bold).Sprint(diag.Summary))
+
+	if diag.Subject != nil {
+		line("")
+		r.renderSnippet(line, diag)
+	}
+
+	if diag.Resource != "" {
+		line("")
+		if len(diag.DependsOn) > 0 {
+			line("Resource: %s (depends on %s)", diag.Resource, strings.Join(diag.DependsOn, ", "))
+		} else {
+			line("Resource: %s", diag.Resource)
+		}
+	}
+
+	if diag.Detail != "" {
+		line("")
+		for _, detailLine := range strings.Split(diag.Detail, "\n") {
+			line("%s", detailLine)
+		}
+	}
+
+	if diag.Origin != "" || diag.Hint != "" || diag.DocsURL != "" {
+		line("")
+	}
+	if diag.Origin != "" {
+		line("%s", diag.Origin)
+	}
+	if diag.Hint != "" {
+		line("%s %s", color.New(color.FgCyan).Sprint("Hint:"), diag.Hint)
+	}
+	if diag.DocsURL != "" {
+		line("See %s", diag.DocsURL)
+	}
+
+	if r.ShowStack && len(diag.Stack) > 0 {
+		line("")
+		line("Stack trace:")
+		for _, stackFrame := range diag.Stack {
+			line("  %s", stackFrame)
+		}
+	}
+	fmt.Fprintln(r.out, frame.Sprint("╵"))
+}
+
+// renderSnippet показывает расположение и строки файла, выражение выделяется подчеркиванием
+func (r *DiagnosticRenderer) renderSnippet(line func(string, ...interface{}), diag Diagnostic) {
+	subject := diag.Subject
+	location := fmt.Sprintf("on %s line %d", subject.Filename, subject.Start.Line)
+	if resourceType, name, ok := strings.Cut(diag.Resource, "."); ok {
+		location += fmt.Sprintf(", in resource %q %q", resourceType, name)
+	}
+	line("  %s:", location)
+
+	src := r.source(subject.Filename)
+	if src == nil {
+		return
+	}
+
+	highlight := color.New(color.Underline, color.Bold)
+	lineStart := 0
+	for number := 1; lineStart <= len(src); number++ {
+		lineEnd := bytes.IndexByte(src[lineStart:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(src)
+		} else {
+			lineEnd += lineStart
+		}
+
+		if number >= subject.Start.Line && number <= subject.End.Line && number < subject.Start.Line+maxSnippetLines {
+			text := src[lineStart:lineEnd]
+			from := clamp(subject.Start.Byte-lineStart, 0, len(text))
+			to := clamp(subject.End.Byte-lineStart, from, len(text))
+			line("  %4d: %s%s%s", number,
+				strings.TrimRight(string(text[:from]), "\r"),
+				highlight.Sprint(string(text[from:to])),
+				strings.TrimRight(string(text[to:]), "\r"))
+		}
+		if number >= subject.End.Line || lineEnd == len(src) {
+			return
+		}
+		lineStart = lineEnd + 1
+	}
+}
+
+// source содержимое файла конфигурации, nil - файл недоступен
+func (r *DiagnosticRenderer) source(filename string) []byte {
+	if src, cached := r.sources[filename]; cached {
+		return src
+	}
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		src = nil
+	}
+	r.sources[filename] = src
+	return src
+}
+
+func clamp(value, low, high int) int {
+	return max(low, min(value, high))
+}
@@ -0,0 +1,288 @@
+package ui
+
+import (
+	"bytes"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/errors"
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/fatih/color"
+	"github.com/hashicorp/hcl/v2"
+)
+
+const testConfig = `resource "docker_container" "web" {
+  image = "nginx:1.27"
+  ports = 80
+  env = {
+    A = "1"
+    B = "2"
+    C = "3"
+    D = "4"
+    E = "5"
+  }
+}
+`
+
+// disableColor отключает цвета, чтобы сравнивать вывод как текст
+func disableColor(t *testing.T) {
+	t.Helper()
+	noColor := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = noColor })
+}
+
+// subjectOf диапазон первого вхождения text в testConfig
+func subjectOf(t *testing.T, filename, text string) *hcl.Range {
+	t.Helper()
+	start := strings.Index(testConfig, text)
+	if start < 0 {
+		t.Fatalf("%q is not in the test configuration", text)
+	}
+	return &hcl.Range{
+		Filename: filename,
+		Start:    position(start),
+		End:      position(start + len(text)),
+	}
+}
+
+func position(offset int) hcl.Pos {
+	before := testConfig[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := offset - strings.LastIndex(before, "\n")
+	return hcl.Pos{Line: line, Column: column, Byte: offset}
+}
+
+func TestDiagnostics(t *testing.T) {
+	subject := &hcl.Range{Filename: "main.tf", Start: hcl.Pos{Line: 3, Column: 3}, End: hcl.Pos{Line: 3, Column: 13}}
+	hclErr := errors.WrapError(hcl.Diagnostics{
+		{Severity: hcl.DiagError, Summary: "Unsupported argument", Detail: `An argument named "port" is not expected here.`, Subject: subject},
+		{Severity: hcl.DiagWarning, Summary: "Deprecated attribute", Detail: "links is deprecated."},
+	}, errors.CodeConfig, "Failed to parse configuration file")
+
+	web := errors.ResourceError("docker_container.web", "Failed to apply resource", stderrors.New("port is allocated"))
+	web.DependsOn = []string{"docker_network.app"}
+	db := errors.ResourceError("docker_container.db", "Failed to apply resource", stderrors.New("image not found"))
+
+	tests := []struct {
+		name string
+		err  error
+		want []Diagnostic
+	}{
+		{
+			name: "plain error",
+			err:  stderrors.New("unexpected EOF"),
+			want: []Diagnostic{{Severity: SeverityError, Summary: "unexpected EOF"}},
+		},
+		{
+			name: "resource error",
+			err:  web,
+			want: []Diagnostic{{
+				Severity: SeverityError, Summary: "[RESOURCE_ERROR] Failed to apply resource", Detail: "port is allocated",
+				Resource: "docker_container.web", DependsOn: []string{"docker_network.app"},
+				Code: errors.CodeResource, Hint: errors.CodeResource.Info().Hint, DocsURL: errors.CodeResource.DocsURL(),
+			}},
+		},
+		{
+			name: "joined errors",
+			err:  errors.Join(web, db),
+			want: []Diagnostic{
+				{Severity: SeverityError, Summary: "[RESOURCE_ERROR] Failed to apply resource", Detail: "port is allocated", Resource: "docker_container.web", DependsOn: []string{"docker_network.app"}, Code: errors.CodeResource},
+				{Severity: SeverityError, Summary: "[RESOURCE_ERROR] Failed to apply resource", Detail: "image not found", Resource: "docker_container.db", Code: errors.CodeResource},
+			},
+		},
+		{
+			name: "summary after joined causes",
+			err:  errors.WrapError(errors.Join(web, db), errors.CodeApply, "Apply failed: 2 resources failed"),
+			want: []Diagnostic{
+				{Severity: SeverityError, Summary: "[RESOURCE_ERROR] Failed to apply resource", Detail: "port is allocated", Resource: "docker_container.web", DependsOn: []string{"docker_network.app"}, Code: errors.CodeResource},
+				{Severity: SeverityError, Summary: "[RESOURCE_ERROR] Failed to apply resource", Detail: "image not found", Resource: "docker_container.db", Code: errors.CodeResource},
+				{Severity: SeverityError, Summary: "[APPLY_ERROR] Apply failed: 2 resources failed", Code: errors.CodeApply},
+			},
+		},
+		{
+			name: "hcl diagnostics",
+			err:  hclErr,
+			want: []Diagnostic{
+				{Severity: SeverityError, Summary: "Unsupported argument", Detail: `An argument named "port" is not expected here.`, Subject: subject, Origin: "[CONFIG_ERROR] Failed to parse configuration file", Code: errors.CodeConfig},
+				{Severity: SeverityWarning, Summary: "Deprecated attribute", Detail: "links is deprecated.", Origin: "[CONFIG_ERROR] Failed to parse configuration file", Code: errors.CodeConfig},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Diagnostics(test.err)
+			if len(got) != len(test.want) {
+				t.Fatalf("got %d diagnostics, want %d: %+v", len(got), len(test.want), got)
+			}
+			for i := range got {
+				if got[i].Code != "" && test.want[i].Hint == "" {
+					// Подсказки и ссылки проверяются в первом случае, стек не сравнивается
+					got[i].Hint, got[i].DocsURL = "", ""
+				}
+				got[i].Stack = nil
+				if !reflect.DeepEqual(got[i], test.want[i]) {
+					t.Errorf("diagnostic %d = %+v, want %+v", i, got[i], test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDiagnosticRenderer(t *testing.T) {
+	disableColor(t)
+	filename := filepath.Join(t.TempDir(), "main.tf")
+	if err := os.WriteFile(filename, []byte(testConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		diag      Diagnostic
+		showStack bool
+		want      string
+	}{
+		{
+			name: "full diagnostic",
+			diag: Diagnostic{
+				Severity: SeverityError, Summary: "[RESOURCE_ERROR] Failed to apply resource",
+				Subject:  subjectOf(t, filename, "80"),
+				Resource: "docker_container.web", DependsOn: []string{"docker_network.app"},
+				Detail: "port is allocated\nstop the other container",
+				Hint:   "Read the provider error.", DocsURL: "https://example.com/errors#resource_error",
+			},
+			want: fmt.Sprintf(`╷
+│ Error: [RESOURCE_ERROR] Failed to apply resource
+│
+│   on %s line 3, in resource "docker_container" "web":
+│      3:   ports = 80
+│
+│ Resource: docker_container.web (depends on docker_network.app)
+│
+│ port is allocated
+│ stop the other container
+│
+│ Hint: Read the provider error.
+│ See https://example.com/errors#resource_error
+╵
+`, filename),
+		},
+		{
+			name: "warning from hcl",
+			diag: Diagnostic{
+				Severity: SeverityWarning, Summary: "Deprecated attribute", Detail: "image is deprecated.",
+				Subject: subjectOf(t, filename, `"nginx:1.27"`), Origin: "[CONFIG_ERROR] Failed to parse configuration file",
+			},
+			want: fmt.Sprintf(`╷
+│ Warning: Deprecated attribute
+│
+│   on %s line 2:
+│      2:   image = "nginx:1.27"
+│
+│ image is deprecated.
+│
+│ [CONFIG_ERROR] Failed to parse configuration file
+╵
+`, filename),
+		},
+		{
+			name: "long subject is cut",
+			diag: Diagnostic{Severity: SeverityError, Summary: "Invalid env", Subject: subjectOf(t, filename, testConfig[strings.Index(testConfig, "{\n    A"):strings.Index(testConfig, "  }")+3])},
+			want: fmt.Sprintf(`╷
+│ Error: Invalid env
+│
+│   on %s line 4:
+│      4:   env = {
+│      5:     A = "1"
+│      6:     B = "2"
+│      7:     C = "3"
+│      8:     D = "4"
+╵
+`, filename),
+		},
+		{
+			name: "missing file",
+			diag: Diagnostic{Severity: SeverityError, Summary: "Invalid value", Subject: &hcl.Range{Filename: "missing.tf", Start: hcl.Pos{Line: 7}, End: hcl.Pos{Line: 7}}},
+			want: `╷
+│ Error: Invalid value
+│
+│   on missing.tf line 7:
+╵
+`,
+		},
+		{
+			name:      "stack trace",
+			diag:      Diagnostic{Severity: SeverityError, Summary: "Failed", Stack: []string{"engine.go:12 core.Apply"}},
+			showStack: true,
+			want: `╷
+│ Error: Failed
+│
+│ Stack trace:
+│   engine.go:12 core.Apply
+╵
+`,
+		},
+		{
+			name: "stack trace is hidden",
+			diag: Diagnostic{Severity: SeverityError, Summary: "Failed", Stack: []string{"engine.go:12 core.Apply"}},
+			want: "╷\n│ Error: Failed\n╵\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			renderer := NewDiagnosticRenderer(&out)
+			renderer.ShowStack = test.showStack
+			renderer.Render(test.diag)
+			if out.String() != test.want {
+				t.Errorf("output:\n%s\nwant:\n%s", out.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestDiagnosticRendererRedacts(t *testing.T) {
+	disableColor(t)
+	logging.AddSensitive("s3cr3t-password")
+
+	var out bytes.Buffer
+	NewDiagnosticRenderer(&out).Render(Diagnostic{
+		Severity: SeverityError,
+		Summary:  "Failed to configure provider docker",
+		Detail:   "login with s3cr3t-password failed",
+	})
+	if strings.Contains(out.String(), "s3cr3t-password") {
+		t.Errorf("output contains a sensitive value:\n%s", out.String())
+	}
+}
+
+func TestPrintErrorShowsHintOnce(t *testing.T) {
+	disableColor(t)
+	var out bytes.Buffer
+	stderr := color.Error
+	color.Error = &out
+	t.Cleanup(func() { color.Error = stderr })
+
+	web := errors.ResourceError("docker_container.web", "Failed to apply resource", stderrors.New("port is allocated"))
+	db := errors.ResourceError("docker_container.db", "Failed to apply resource", stderrors.New("image not found"))
+	NewErrorReporter().PrintError(errors.WrapError(errors.Join(web, db), errors.CodeApply, "Apply failed"))
+
+	output := out.String()
+	for text, want := range map[string]int{
+		"Hint: " + errors.CodeResource.Info().Hint: 1,
+		"Hint: " + errors.CodeApply.Info().Hint:    1,
+		"See " + errors.CodeResource.DocsURL():     1,
+		"╷":                                        3,
+	} {
+		if got := strings.Count(output, text); got != want {
+			t.Errorf("%q appears %d times, want %d:\n%s", text, got, want, output)
+		}
+	}
+}
@@ -2,14 +2,16 @@ package ui
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/Artemka007/derraform/internal/errors"
+	"github.com/Artemka007/derraform/internal/logging"
 	"github.com/fatih/color"
 )
 
 type ErrorReporter struct {
 	errors []*errors.TerraformError
+	// Debug показывать стек Go, в котором возникла ошибка
+	Debug bool
 }
 
 func NewErrorReporter() *ErrorReporter {
@@ -20,6 +22,17 @@ func (e *ErrorReporter) AddError(err *errors.TerraformError) {
 	e.errors = append(e.errors, err)
 }
 
+// Add добавляет любую ошибку. Ошибки, объединенные errors.Join, добавляются по отдельности.
+func (e *ErrorReporter) Add(err error) {
+	if joined := errors.Joined(err); joined != nil {
+		for _, inner := range joined {
+			e.Add(inner)
+		}
+		return
+	}
+	e.AddError(asTerraformError(err))
+}
+
 func (e *ErrorReporter) HasErrors() bool {
 	return len(e.errors) > 0
 }
@@ -34,42 +47,52 @@ func (e *ErrorReporter) PrintSummary() {
 	fmt.Println()
 
 	for i, err := range e.errors {
-		color.Red("%d. [%s] %s", i+1, err.Code, err.Message)
+		color.Red("%d. [%s] %s", i+1, err.Code, logging.Redact(err.Message))
 		if err.Resource != "" {
 			fmt.Printf("   Resource: %s\n", err.Resource)
 		}
 		if err.Cause != nil {
-			fmt.Printf("   Details: %v\n", err.Cause)
+			fmt.Printf("   Details: %s\n", logging.Redact(err.Cause.Error()))
 		}
 		fmt.Println()
 	}
 }
 
-func (e *ErrorReporter) PrintDetailedError(err *errors.TerraformError) {
-	color.Red("╭─ Error: %s", err.Message)
-	color.Red("│ Code: %s", err.Code)
-
-	if err.Resource != "" {
-		color.Red("│ Resource: %s", err.Resource)
-	}
+// PrintError показывает в stderr ошибку, которой завершилась команда, в виде диагностик:
+// строки конфигурации, ресурс и его зависимости, подсказка и ссылка на описание кода.
+// Ошибки, объединенные errors.Join, показываются отдельными сообщениями, а подсказка
+// каждого кода - один раз.
+func (e *ErrorReporter) PrintError(err error) {
+	renderer := NewDiagnosticRenderer(color.Error)
+	renderer.ShowStack = e.Debug
 
-	if err.Cause != nil {
-		causeLines := strings.Split(err.Cause.Error(), "\n")
-		for i, line := range causeLines {
-			if i == 0 {
-				color.Red("│ Caused by: %s", line)
-			} else {
-				color.Red("│            %s", line)
-			}
+	seen := make(map[errors.Code]bool)
+	for _, diag := range Diagnostics(err) {
+		if diag.Code != "" && seen[diag.Code] {
+			diag.Hint, diag.DocsURL = "", ""
 		}
+		seen[diag.Code] = true
+		renderer.Render(diag)
 	}
+}
 
-	if len(err.Stack) > 0 {
-		color.Red("│ Stack trace:")
-		for _, frame := range err.Stack[:3] { // Показываем только 3 верхних кадра
-			color.Red("│   %s", frame)
-		}
+// PrintDetailedError показывает одну ошибку со всеми подробностями
+func (e *ErrorReporter) PrintDetailedError(err *errors.TerraformError) {
+	e.PrintError(err)
+}
+
+// asTerraformError приводит ошибку к TerraformError, не теряя текст обычных ошибок
+func asTerraformError(err error) *errors.TerraformError {
+	if terraErr, ok := err.(*errors.TerraformError); ok {
+		return terraErr
 	}
+	return &errors.TerraformError{Message: err.Error()}
+}
 
-	color.Red("╰─")
+func errorTitle(err *errors.TerraformError) string {
+	title := logging.Redact(err.Message)
+	if err.Code != "" {
+		title = fmt.Sprintf("[%s] %s", err.Code, title)
+	}
+	return title
 }
@@ -0,0 +1,358 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/core"
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/Artemka007/derraform/internal/version"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// JSONUIVersion версия схемы событий -json (docs/json-output.md). Минорная версия растет
+// при добавлении полей и событий, мажорная - при несовместимых изменениях.
+const JSONUIVersion = "1.0"
+
+// DefaultProgressInterval как часто повторяется apply_progress для долгой операции
+const DefaultProgressInterval = 10 * time.Second
+
+// JSONView пишет события plan и apply по одному JSON объекту на строку вместо
+// вывода для человека. Реализует core.Hooks.
+type JSONView struct {
+	mu  sync.Mutex
+	out io.Writer
+	// ProgressInterval период событий apply_progress
+	ProgressInterval time.Duration
+	// running операции, для которых идут apply_progress, ключ - адрес ресурса
+	running map[string]*runningApply
+}
+
+type runningApply struct {
+	started time.Time
+	stop    chan struct{}
+}
+
+var _ core.Hooks = (*JSONView)(nil)
+
+// NewJSONView создает вывод -json и сразу пишет событие version
+func NewJSONView(out io.Writer) *JSONView {
+	view := &JSONView{
+		out:              out,
+		ProgressInterval: DefaultProgressInterval,
+		running:          make(map[string]*runningApply),
+	}
+	view.emit("info", "version", fmt.Sprintf("derraform %s", version.Version), map[string]interface{}{
+		"derraform": version.Version,
+		"ui":        JSONUIVersion,
+	})
+	return view
+}
+
+// emit пишет одно событие. Известные sensitive значения вырезаются из сообщения и
+// строковых значений полей до кодирования: ключи и служебные значения события не трогаются.
+func (v *JSONView) emit(level, eventType, message string, fields map[string]interface{}) {
+	event := map[string]interface{}{
+		"@level":     level,
+		"@message":   logging.Redact(message),
+		"@module":    "derraform.ui",
+		"@timestamp": time.Now().Format(time.RFC3339Nano),
+		"type":       eventType,
+	}
+	for key, value := range fields {
+		event[key] = redactValue(value)
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		data, _ = json.Marshal(map[string]interface{}{
+			"@level":   "error",
+			"@message": "failed to encode event " + eventType + ": " + err.Error(),
+			"type":     "diagnostic",
+		})
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	fmt.Fprintln(v.out, string(data))
+}
+
+// redactValue заменяет sensitive значения во всех строках значения поля
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return logging.Redact(v)
+	case []string:
+		redacted := make([]string, len(v))
+		for i, item := range v {
+			redacted[i] = logging.Redact(item)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactValue(item)
+		}
+		return redacted
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			redacted[key] = redactValue(item)
+		}
+		return redacted
+	case json.RawMessage:
+		// Значения output уже закодированы: раскодируем, чтобы заменить строки, а не JSON текст
+		decoder := json.NewDecoder(bytes.NewReader(v))
+		decoder.UseNumber()
+		var decoded interface{}
+		if err := decoder.Decode(&decoded); err != nil {
+			return v
+		}
+		return redactValue(decoded)
+	}
+	return value
+}
+
+// jsonResource описание ресурса в событиях
+func jsonResource(address string) map[string]interface{} {
+	resourceType, name, _ := strings.Cut(address, ".")
+	return map[string]interface{}{
+		"addr":          address,
+		"resource_type": resourceType,
+		"resource_name": name,
+	}
+}
+
+// actionVerbs глагол операции в процессе и существительное по ее завершении
+var actionVerbs = map[providers.Action][2]string{
+	providers.ActionCreate:  {"Creating", "Creation"},
+	providers.ActionUpdate:  {"Modifying", "Modifications"},
+	providers.ActionReplace: {"Replacing", "Replacement"},
+	providers.ActionDelete:  {"Destroying", "Destruction"},
+}
+
+func (v *JSONView) PlannedChange(address string, change *providers.PlannedChange) {
+	fields := map[string]interface{}{
+		"resource": jsonResource(address),
+		"action":   string(change.Action),
+	}
+	if change.Reason != "" {
+		fields["reason"] = change.Reason
+	}
+	v.emit("info", "planned_change", fmt.Sprintf("%s: Plan to %s", address, change.Action), map[string]interface{}{
+		"change": fields,
+	})
+}
+
+func (v *JSONView) ApplyStart(address string, action providers.Action) {
+	operation := &runningApply{started: time.Now(), stop: make(chan struct{})}
+	v.mu.Lock()
+	v.running[address] = operation
+	v.mu.Unlock()
+
+	v.emit("info", "apply_start", fmt.Sprintf("%s: %s...", address, actionVerbs[action][0]), map[string]interface{}{
+		"hook": map[string]interface{}{
+			"resource": jsonResource(address),
+			"action":   string(action),
+		},
+	})
+
+	if v.ProgressInterval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(v.ProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-operation.stop:
+				return
+			case <-ticker.C:
+				elapsed := time.Since(operation.started).Round(time.Second)
+				v.emit("info", "apply_progress", fmt.Sprintf("%s: Still %s... [%s elapsed]", address, strings.ToLower(actionVerbs[action][0]), elapsed), map[string]interface{}{
+					"hook": map[string]interface{}{
+						"resource":        jsonResource(address),
+						"action":          string(action),
+						"elapsed_seconds": int(elapsed.Seconds()),
+					},
+				})
+			}
+		}
+	}()
+}
+
+// finish останавливает apply_progress операции и возвращает ее длительность
+func (v *JSONView) finish(address string) time.Duration {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	operation, exists := v.running[address]
+	if !exists {
+		return 0
+	}
+	delete(v.running, address)
+	close(operation.stop)
+	return time.Since(operation.started).Round(time.Second)
+}
+
+func (v *JSONView) ApplyComplete(address string, action providers.Action, id string) {
+	elapsed := v.finish(address)
+	hook := map[string]interface{}{
+		"resource":        jsonResource(address),
+		"action":          string(action),
+		"elapsed_seconds": int(elapsed.Seconds()),
+	}
+	message := fmt.Sprintf("%s: %s complete after %s", address, actionVerbs[action][1], elapsed)
+	if id != "" {
+		hook["id_key"] = "id"
+		hook["id_value"] = id
+		message += fmt.Sprintf(" [id=%s]", id)
+	}
+	v.emit("info", "apply_complete", message, map[string]interface{}{"hook": hook})
+}
+
+func (v *JSONView) ApplyErrored(address string, action providers.Action, err error) {
+	elapsed := v.finish(address)
+	v.emit("error", "apply_errored", fmt.Sprintf("%s: %s errored after %s", address, actionVerbs[action][1], elapsed), map[string]interface{}{
+		"hook": map[string]interface{}{
+			"resource":        jsonResource(address),
+			"action":          string(action),
+			"elapsed_seconds": int(elapsed.Seconds()),
+		},
+	})
+}
+
+// Outputs пишет значения output. Значения sensitive и еще не известные не передаются.
+func (v *JSONView) Outputs(outputs []config.Output) {
+	values := make(map[string]interface{}, len(outputs))
+	for _, output := range outputs {
+		sensitive := output.Sensitive || config.IsSensitive(output.Value)
+		entry := map[string]interface{}{"sensitive": sensitive}
+
+		value, _ := output.Value.UnmarkDeep()
+		if !sensitive && value.IsWhollyKnown() {
+			if data, err := ctyjson.Marshal(value, value.Type()); err == nil {
+				entry["value"] = json.RawMessage(data)
+			}
+			if data, err := ctyjson.MarshalType(value.Type()); err == nil {
+				entry["type"] = json.RawMessage(data)
+			}
+		}
+		values[output.Name] = entry
+	}
+
+	v.emit("info", "outputs", fmt.Sprintf("Outputs: %d", len(outputs)), map[string]interface{}{
+		"outputs": values,
+	})
+}
+
+func (v *JSONView) ChangeSummary(summary core.ChangeSummary) {
+	message := fmt.Sprintf("Apply complete! Resources: %d added, %d changed, %d destroyed.", summary.Add, summary.Change, summary.Remove)
+	if summary.Operation == "plan" {
+		message = fmt.Sprintf("Plan: %d to add, %d to change, %d to destroy.", summary.Add, summary.Change, summary.Remove)
+	}
+	if summary.Import > 0 {
+		message = strings.TrimSuffix(message, ".") + fmt.Sprintf(", %d to import.", summary.Import)
+	}
+
+	v.emit("info", "change_summary", message, map[string]interface{}{
+		"changes": map[string]interface{}{
+			"add":       summary.Add,
+			"change":    summary.Change,
+			"remove":    summary.Remove,
+			"import":    summary.Import,
+			"operation": summary.Operation,
+		},
+	})
+}
+
+// Error пишет ошибку команды событиями diagnostic, по одному на каждую диагностику
+func (v *JSONView) Error(err error) {
+	for _, diag := range Diagnostics(err) {
+		v.Diagnostic(diag)
+	}
+}
+
+// Diagnostic пишет одну диагностику
+func (v *JSONView) Diagnostic(diag Diagnostic) {
+	severity := strings.ToLower(string(diag.Severity))
+	fields := map[string]interface{}{
+		"severity": severity,
+		"summary":  diag.Summary,
+	}
+	if diag.Detail != "" {
+		fields["detail"] = diag.Detail
+	}
+	if diag.Resource != "" {
+		fields["address"] = diag.Resource
+	}
+	if len(diag.DependsOn) > 0 {
+		fields["depends_on"] = diag.DependsOn
+	}
+	if diag.Subject != nil {
+		fields["range"] = map[string]interface{}{
+			"filename": diag.Subject.Filename,
+			"start":    map[string]int{"line": diag.Subject.Start.Line, "column": diag.Subject.Start.Column, "byte": diag.Subject.Start.Byte},
+			"end":      map[string]int{"line": diag.Subject.End.Line, "column": diag.Subject.End.Column, "byte": diag.Subject.End.Byte},
+		}
+	}
+	if diag.Code != "" {
+		fields["code"] = string(diag.Code)
+	}
+	if diag.Hint != "" {
+		fields["hint"] = diag.Hint
+	}
+	if diag.DocsURL != "" {
+		fields["docs_url"] = diag.DocsURL
+	}
+
+	message := fmt.Sprintf("%s: %s", diag.Severity, diag.Summary)
+	v.emit(severity, "diagnostic", message, map[string]interface{}{"diagnostic": fields})
+}
+
+// LogOutput writer для лога в формате JSON в режиме -json. Предупреждения из лога
+// становятся событиями diagnostic с severity warning, остальные записи не выводятся:
+// для них есть TF_LOG_PATH.
+func (v *JSONView) LogOutput() io.Writer {
+	return &logEventWriter{view: v}
+}
+
+type logEventWriter struct {
+	view    *JSONView
+	mu      sync.Mutex
+	pending []byte
+}
+
+func (w *logEventWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending = append(w.pending, p...)
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end < 0 {
+			return len(p), nil
+		}
+		w.handle(w.pending[:end])
+		w.pending = w.pending[end+1:]
+	}
+}
+
+func (w *logEventWriter) handle(line []byte) {
+	var entry map[string]interface{}
+	if err := json.Unmarshal(line, &entry); err != nil || entry["@level"] != "warn" {
+		return
+	}
+
+	diag := Diagnostic{Severity: SeverityWarning}
+	diag.Summary, _ = entry["@message"].(string)
+	diag.Resource, _ = entry["address"].(string)
+	w.view.Diagnostic(diag)
+}
@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Artemka007/derraform/internal/config"
+	"github.com/Artemka007/derraform/internal/logging"
+	"github.com/Artemka007/derraform/internal/providers"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func decodeEvents(t *testing.T, out *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var events []map[string]interface{}
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		var event map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// Sensitive значение, совпадающее с ключами и значениями схемы, не должно ломать события
+func TestJSONViewRedactsValuesNotSchema(t *testing.T) {
+	logging.AddSensitive("info", "resource", "apply_start")
+
+	var out bytes.Buffer
+	view := NewJSONView(&out)
+	view.ProgressInterval = 0
+	view.ApplyStart("docker_container.resource", providers.ActionCreate)
+
+	events := decodeEvents(t, &out)
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	event := events[1]
+	if event["type"] != "apply_start" || event["@level"] != "info" {
+		t.Errorf("schema values were redacted: %v", event)
+	}
+	hook, ok := event["hook"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("hook key was redacted: %v", event)
+	}
+	resource, ok := hook["resource"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("resource key was redacted: %v", hook)
+	}
+	if addr := resource["addr"]; addr != "docker_container."+logging.SensitivePlaceholder {
+		t.Errorf("addr = %v, want the sensitive part replaced", addr)
+	}
+}
+
+// Значения с символами, которые JSON экранирует, не должны утекать
+func TestJSONViewRedactsEscapedSecrets(t *testing.T) {
+	secret := `p"a\ss<w>&rd`
+	logging.AddSensitive(secret)
+
+	var out bytes.Buffer
+	view := NewJSONView(&out)
+	view.Diagnostic(Diagnostic{Severity: SeverityError, Summary: "login failed", Detail: "password " + secret + " rejected"})
+	view.Outputs([]config.Output{{Name: "leaked", Value: cty.StringVal("token=" + secret)}})
+
+	escaped, _ := json.Marshal(secret)
+	if strings.Contains(out.String(), strings.Trim(string(escaped), `"`)) {
+		t.Fatalf("secret leaked: %s", out.String())
+	}
+	events := decodeEvents(t, &out)
+	diagnostic := events[1]["diagnostic"].(map[string]interface{})
+	if detail := diagnostic["detail"]; detail != "password "+logging.SensitivePlaceholder+" rejected" {
+		t.Errorf("detail = %v", detail)
+	}
+	outputs := events[2]["outputs"].(map[string]interface{})
+	if value := outputs["leaked"].(map[string]interface{})["value"]; value != "token="+logging.SensitivePlaceholder {
+		t.Errorf("output value = %v", value)
+	}
+}
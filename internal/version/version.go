@@ -0,0 +1,5 @@
+package version
+
+// Version версия derraform. При релизной сборке задается через
+// -ldflags "-X github.com/Artemka007/derraform/internal/version.Version=1.2.3"
+var Version = "0.1.0-dev"